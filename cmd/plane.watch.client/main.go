@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"github.com/rs/zerolog/log"
 	"github.com/urfave/cli/v2"
-	"math"
 	"net/url"
 	"os"
 	"plane.watch/lib/dedupe"
@@ -12,8 +11,11 @@ import (
 	"plane.watch/lib/producer"
 	"plane.watch/lib/sink"
 	"plane.watch/lib/tracker"
+	"plane.watch/lib/tracker/mode_s"
+	"runtime"
 	"strconv"
 	"strings"
+	"time"
 )
 
 func main() {
@@ -31,22 +33,22 @@ func main() {
 	app.Flags = []cli.Flag{
 		&cli.StringSliceFlag{
 			Name:    "fetch",
-			Usage:   "The Source in URL Form. [avr|beast|sbs1]://host:port?tag=MYTAG&refLat=-31.0&refLon=115.0",
+			Usage:   "The Source in URL Form. [avr|beast|sbs1]://host:port?tag=MYTAG&refLat=-31.0&refLon=115.0 or [avr|beast|sbs1]+tls://host:port?tlsCa=ca.pem&tlsCert=cert.pem&tlsKey=key.pem",
 			EnvVars: []string{"SOURCE"},
 		},
 		&cli.StringSliceFlag{
 			Name:    "listen",
-			Usage:   "The Source in URL Form. [avr|beast|sbs1]://host:port?tag=MYTAG&refLat=-31.0&refLon=115.0",
+			Usage:   "The Source in URL Form. [avr|beast|sbs1]://host:port?tag=MYTAG&refLat=-31.0&refLon=115.0 or udp://host:port?type=[avr|beast|sbs1] for datagrams. TCP listeners take maxConnections=10&idleTimeout=5m and apiKeys=/path/to/keys, [avr|beast|sbs1]+tls:// listeners take tlsCert=cert.pem&tlsKey=key.pem&tlsClientCa=ca.pem",
 			EnvVars: []string{"LISTEN"},
 		},
 		&cli.StringSliceFlag{
 			Name:    "sink",
-			Usage:   "The place to send decoded JSON in URL Form. [redis|amqp]://user:pass@host:port/vhost?ttl=60",
+			Usage:   "The place to send decoded JSON in URL Form. [redis|amqp]://user:pass@host:port/vhost?ttl=60 (amqps:// takes tlsCa=ca.pem&tlsCert=cert.pem&tlsKey=key.pem) or http://host:port?globe=true to serve aircraft.json (and globe tiles) or [sbs1|beast|avr]://host:port?dedupe=true to serve BaseStation or re-serve frames",
 			EnvVars: []string{"SINK"},
 		},
 		&cli.StringSliceFlag{
 			Name:    "file",
-			Usage:   "The Source in URL Form. [avr|beast|sbs1]:///path/to/file?tag=MYTAG&refLat=-31.0&refLon=115.0",
+			Usage:   "The Source in URL Form. [avr|beast|sbs1]:///path/to/file?tag=MYTAG&refLat=-31.0&refLon=115.0 (.gz, .bz2, .zst and .xz are decompressed). With follow=true the file (or newest file matching a /path/to/*.glob) is followed as it is written, like tail -F",
 			EnvVars: []string{"FILE"},
 		},
 		&cli.StringSliceFlag{
@@ -72,6 +74,18 @@ func main() {
 			Name:  "ref-lon",
 			Usage: "The reference longitude for decoding messages. Needs to be within 45nm of where the messages are generated.",
 		},
+		&cli.IntFlag{
+			Name:    "error-correction",
+			Value:   mode_s.CorrectionSingleBit,
+			Usage:   "How hard to try and fix frames with a bad CRC. 0 = none, 1 = single bit errors, 2 = also two bit errors in DF17/18",
+			EnvVars: []string{"ERROR_CORRECTION"},
+		},
+		&cli.IntFlag{
+			Name:    "decode-workers",
+			Value:   runtime.NumCPU(),
+			Usage:   "How many frames to decode at once",
+			EnvVars: []string{"DECODE_WORKERS"},
+		},
 		&cli.BoolFlag{
 			Name:    "debug",
 			Usage:   "Show Extra Debug Information",
@@ -122,6 +136,16 @@ func getTag(parsedUrl *url.URL, defaultTag string) string {
 	}
 	return defaultTag
 }
+func getTtl(parsedUrl *url.URL, defaultTtl int) (int, error) {
+	if !parsedUrl.Query().Has("ttl") {
+		return defaultTtl, nil
+	}
+	ttl, err := strconv.ParseInt(parsedUrl.Query().Get("ttl"), 10, 32)
+	if nil != err || ttl <= 0 {
+		return 0, fmt.Errorf("invalid ttl %q, expected a number of seconds", parsedUrl.Query().Get("ttl"))
+	}
+	return int(ttl), nil
+}
 func getRef(parsedUrl *url.URL, what string, defaultRef float64) float64 {
 	if nil == parsedUrl {
 		return 0
@@ -136,25 +160,29 @@ func getRef(parsedUrl *url.URL, what string, defaultRef float64) float64 {
 	return defaultRef
 }
 
-func handleSink(urlSink, defaultTag string, defaultTtl int, defaultQueues []string) (tracker.Sink, error) {
+func handleSink(urlSink, defaultTag string, defaultTtl int, defaultQueues []string, defaultRefLat, defaultRefLon float64) (tracker.Sink, error) {
 	parsedUrl, err := url.Parse(urlSink)
 	if nil != err {
 		return nil, err
 	}
 	switch strings.ToLower(parsedUrl.Scheme) {
 	case "redis":
-		redisHost := parsedUrl.Hostname()
-		redisPort := parsedUrl.Port()
-		return sink.NewRedisSink(sink.WithHost(redisHost, redisPort), sink.WithSourceTag(getTag(parsedUrl, defaultTag))), nil
-	case "amqp", "rabbitmq":
+		redisPass, _ := parsedUrl.User.Password()
+		messageTtl, err := getTtl(parsedUrl, defaultTtl)
+		if nil != err {
+			return nil, err
+		}
+		return sink.NewRedisSink(
+			sink.WithHost(parsedUrl.Hostname(), parsedUrl.Port()),
+			sink.WithUserPass(parsedUrl.User.Username(), redisPass),
+			sink.WithSourceTag(getTag(parsedUrl, defaultTag)),
+			sink.WithMessageTtl(messageTtl),
+		)
+	case "amqp", "amqps", "rabbitmq":
 		rabbitPass, _ := parsedUrl.User.Password()
-		messageTtl := defaultTtl
-		if parsedUrl.Query().Has("ttl") {
-			var requestedTtl int64
-			requestedTtl, err = strconv.ParseInt(parsedUrl.Query().Get("ttl"), 10, 32)
-			if requestedTtl > 0 && requestedTtl < math.MaxInt32 {
-				messageTtl = int(requestedTtl)
-			}
+		messageTtl, err := getTtl(parsedUrl, defaultTtl)
+		if nil != err {
+			return nil, err
 		}
 
 		rabbitQueues := defaultQueues
@@ -162,17 +190,47 @@ func handleSink(urlSink, defaultTag string, defaultTtl int, defaultQueues []stri
 			rabbitQueues = strings.Split(parsedUrl.Query().Get("queues"), ",")
 		}
 
-		return sink.NewRabbitMqSink(
+		rabbitOpts := []sink.Option{
 			sink.WithHost(parsedUrl.Hostname(), parsedUrl.Port()),
 			sink.WithUserPass(parsedUrl.User.Username(), rabbitPass),
 			sink.WithRabbitVhost(parsedUrl.Path),
 			sink.WithRabbitQueues(rabbitQueues),
 			sink.WithSourceTag(getTag(parsedUrl, defaultTag)),
 			sink.WithMessageTtl(messageTtl),
-		)
+		}
+		if "amqps" == strings.ToLower(parsedUrl.Scheme) {
+			rabbitOpts = append(rabbitOpts, sink.WithRabbitTLS(
+				parsedUrl.Query().Get("tlsCert"),
+				parsedUrl.Query().Get("tlsKey"),
+				parsedUrl.Query().Get("tlsCa"),
+			))
+		}
+		return sink.NewRabbitMqSink(rabbitOpts...)
+	case "http":
+		opts := []sink.Option{sink.WithHost(parsedUrl.Hostname(), parsedUrl.Port())}
+		refLat := getRef(parsedUrl, "refLat", defaultRefLat)
+		refLon := getRef(parsedUrl, "refLon", defaultRefLon)
+		if refLat != 0 && refLon != 0 {
+			opts = append(opts, sink.WithReceiverLocation(refLat, refLon))
+		}
+		if "true" == parsedUrl.Query().Get("globe") {
+			opts = append(opts, sink.WithGlobeIndex())
+		}
+		return sink.NewAircraftJsonSink(opts...)
+	case "sbs1":
+		return sink.NewSbs1Sink(sink.WithHost(parsedUrl.Hostname(), parsedUrl.Port()))
+	case "beast", "avr":
+		opts := []sink.Option{sink.WithHost(parsedUrl.Hostname(), parsedUrl.Port())}
+		if "true" == parsedUrl.Query().Get("dedupe") {
+			opts = append(opts, sink.WithDedupedFramesOnly())
+		}
+		if "beast" == strings.ToLower(parsedUrl.Scheme) {
+			return sink.NewBeastFrameSink(opts...)
+		}
+		return sink.NewAvrFrameSink(opts...)
 
 	default:
-		return nil, fmt.Errorf("unknown scheme: %s, expected one of [redis|amqp|rabbitmq]", parsedUrl.Scheme)
+		return nil, fmt.Errorf("unknown scheme: %s, expected one of [redis|amqp|amqps|rabbitmq|http|sbs1|beast|avr]", parsedUrl.Scheme)
 	}
 
 }
@@ -186,7 +244,26 @@ func handleSource(urlSource, defaultTag string, defaultRefLat, defaultRefLon flo
 	producerOpts := make([]producer.Option, 2)
 	producerOpts[0] = producer.WithSourceTag(getTag(parsedUrl, defaultTag))
 
-	switch strings.ToLower(parsedUrl.Scheme) {
+	// udp://host:port?type=avr has its frame type in the query, everything else has it as the scheme,
+	// with a +tls on the end (beast+tls://) for TLS
+	frameType := strings.ToLower(parsedUrl.Scheme)
+	isTls := strings.HasSuffix(frameType, "+tls")
+	frameType = strings.TrimSuffix(frameType, "+tls")
+	isUdp := "udp" == frameType
+	if isUdp && isTls {
+		return nil, fmt.Errorf("udp sources cannot use tls")
+	}
+	if isUdp {
+		if !listen {
+			return nil, fmt.Errorf("udp sources can only be listened for")
+		}
+		frameType = "beast"
+		if parsedUrl.Query().Has("type") {
+			frameType = strings.ToLower(parsedUrl.Query().Get("type"))
+		}
+	}
+
+	switch frameType {
 	case "avr":
 		producerOpts[1] = producer.WithType(producer.Avr)
 	case "beast":
@@ -194,7 +271,7 @@ func handleSource(urlSource, defaultTag string, defaultRefLat, defaultRefLon flo
 	case "sbs1":
 		producerOpts[1] = producer.WithType(producer.Sbs1)
 	default:
-		return nil, fmt.Errorf("unknown scheme: %s, expected one of [avr|beast|sbs1]", parsedUrl.Scheme)
+		return nil, fmt.Errorf("unknown scheme: %s, expected one of [avr|beast|sbs1|udp] with an optional +tls", parsedUrl.Scheme)
 	}
 
 	refLat := getRef(parsedUrl, "refLat", defaultRefLat)
@@ -203,10 +280,50 @@ func handleSource(urlSource, defaultTag string, defaultRefLat, defaultRefLon flo
 		producerOpts = append(producerOpts, producer.WithReferenceLatLon(refLat, refLon))
 	}
 
-	if listen {
+	if isUdp {
+		producerOpts = append(producerOpts, producer.WithUDPListener(parsedUrl.Hostname(), parsedUrl.Port()))
+	} else if listen {
 		producerOpts = append(producerOpts, producer.WithListener(parsedUrl.Hostname(), parsedUrl.Port()))
+		if parsedUrl.Query().Has("maxConnections") {
+			maxConnections, err := strconv.Atoi(parsedUrl.Query().Get("maxConnections"))
+			if nil != err {
+				return nil, fmt.Errorf("invalid maxConnections: %s", err)
+			}
+			producerOpts = append(producerOpts, producer.WithMaxConnections(maxConnections))
+		}
+		if parsedUrl.Query().Has("idleTimeout") {
+			idleTimeout, err := time.ParseDuration(parsedUrl.Query().Get("idleTimeout"))
+			if nil != err {
+				return nil, fmt.Errorf("invalid idleTimeout: %s", err)
+			}
+			producerOpts = append(producerOpts, producer.WithIdleTimeout(idleTimeout))
+		}
+		if parsedUrl.Query().Has("apiKeys") {
+			apiKeys, err := producer.LoadApiKeys(parsedUrl.Query().Get("apiKeys"))
+			if nil != err {
+				return nil, err
+			}
+			producerOpts = append(producerOpts, producer.WithApiKeys(apiKeys))
+		}
+		if isTls {
+			if !parsedUrl.Query().Has("tlsCert") || !parsedUrl.Query().Has("tlsKey") {
+				return nil, fmt.Errorf("tls listeners need a tlsCert and tlsKey")
+			}
+			producerOpts = append(producerOpts, producer.WithListenerTLS(
+				parsedUrl.Query().Get("tlsCert"),
+				parsedUrl.Query().Get("tlsKey"),
+				parsedUrl.Query().Get("tlsClientCa"),
+			))
+		}
 	} else {
 		producerOpts = append(producerOpts, producer.WithFetcher(parsedUrl.Hostname(), parsedUrl.Port()))
+		if isTls {
+			producerOpts = append(producerOpts, producer.WithFetcherTLS(
+				parsedUrl.Query().Get("tlsCert"),
+				parsedUrl.Query().Get("tlsKey"),
+				parsedUrl.Query().Get("tlsCa"),
+			))
+		}
 	}
 
 	return producer.New(producerOpts...), nil
@@ -234,11 +351,12 @@ func handleFileSource(urlFile, defaultTag string, defaultRefLat, defaultRefLon f
 		producerOpts = append(producerOpts, producer.WithReferenceLatLon(refLat, refLon))
 	}
 
-	producerOpts = append(
-		producerOpts,
-		producer.WithSourceTag(getTag(parsedUrl, defaultTag)),
-		producer.WithFiles([]string{parsedUrl.Path}),
-	)
+	producerOpts = append(producerOpts, producer.WithSourceTag(getTag(parsedUrl, defaultTag)))
+	if "true" == parsedUrl.Query().Get("follow") {
+		producerOpts = append(producerOpts, producer.WithFollowFiles([]string{parsedUrl.Path}))
+	} else {
+		producerOpts = append(producerOpts, producer.WithFiles([]string{parsedUrl.Path}))
+	}
 
 	return producer.New(producerOpts...), nil
 }
@@ -253,13 +371,15 @@ func commonSetup(c *cli.Context) (*tracker.Tracker, error) {
 	defaultQueues := c.StringSlice("rabbit-queue")
 
 	trackerOpts := make([]tracker.Option, 0)
+	trackerOpts = append(trackerOpts, tracker.WithErrorCorrection(c.Int("error-correction")))
+	trackerOpts = append(trackerOpts, tracker.WithDecodeWorkerCount(c.Int("decode-workers")))
 	trk := tracker.NewTracker(trackerOpts...)
 
 	trk.AddMiddleware(dedupe.NewFilter())
 
 	for _, sinkUrl := range c.StringSlice("sink") {
 		log.Debug().Str("sink-url", sinkUrl).Send()
-		p, err := handleSink(sinkUrl, defaultTag, defaultTTl, defaultQueues)
+		p, err := handleSink(sinkUrl, defaultTag, defaultTTl, defaultQueues, refLat, refLon)
 		if nil != err {
 			log.Error().Err(err).Str("url", sinkUrl).Msgf("Failed to understand URL: %s", err)
 		} else {
@@ -1,7 +1,6 @@
 package dedupe
 
 import (
-	"fmt"
 	"plane.watch/lib/tracker"
 	"plane.watch/lib/tracker/beast"
 	"plane.watch/lib/tracker/mode_s"
@@ -59,9 +58,9 @@ func (f *Filter) Handle(frame tracker.Frame, src *tracker.FrameSource) tracker.F
 	var key interface{}
 	switch (frame).(type) {
 	case *beast.Frame:
-		key = fmt.Sprintf("%X", frame.(*beast.Frame).AvrRaw())
+		key = string(frame.(*beast.Frame).AvrRaw())
 	case *mode_s.Frame:
-		key = fmt.Sprintf("%X", frame.(*mode_s.Frame).Raw())
+		key = string(frame.(*mode_s.Frame).Raw())
 	case *sbs1.Frame:
 		// todo: investigate better dedupe detection for sbs1
 		key = string(frame.(*sbs1.Frame).Raw())
@@ -21,10 +21,137 @@ type (
 		HasVerticalRate   bool
 		HasVelocity       bool
 		SourceTag         string
+		AddressType       string // icao or non-icao, non-icao addresses have a ~ in front of the Icao
+		Source            string // where we heard about this plane from: mode-s, adsb, tis-b or ads-r
 		Squawk            string
 		Special           string
 		TileLocation      string
 		TrackedSince      time.Time
 		LastMsg           time.Time
+
+		// Enhanced Surveillance (Comm-B BDS 4,0 / 5,0 / 6,0), selected altitudes and baro setting can also come from
+		// ADS-B Target State and Status
+		McpSelectedAltitude     int
+		FmsSelectedAltitude     int
+		BaroSetting             float64
+		RollAngle               float64
+		TrueTrack               float64
+		TrackRate               float64
+		GroundSpeed             int
+		TrueAirSpeed            int
+		MagneticHeading         float64
+		IndicatedAirSpeed       int
+		Mach                    float64
+		BaroVerticalRate        int
+		InertialVerticalRate    int
+		HasMcpSelectedAltitude  bool
+		HasFmsSelectedAltitude  bool
+		HasBaroSetting          bool
+		HasRollAngle            bool
+		HasTrueTrack            bool
+		HasTrackRate            bool
+		HasGroundSpeed          bool
+		HasTrueAirSpeed         bool
+		HasMagneticHeading      bool
+		HasIndicatedAirSpeed    bool
+		HasMach                 bool
+		HasBaroVerticalRate     bool
+		HasInertialVerticalRate bool
+
+		// Autopilot intent (ADS-B Target State and Status, EHS BDS 4,0)
+		SelectedHeading    float64
+		HasSelectedHeading bool
+		VnavMode           bool
+		AltHoldMode        bool
+		ApproachMode       bool
+		HasMcpModes        bool
+		AutopilotEngaged   bool
+		LnavMode           bool
+		HasAutopilotModes  bool
+
+		// ADS-B quality indicators, NIC and NACp are interpreted using AdsbVersion
+		AdsbVersion       byte
+		HasAdsbVersion    bool
+		Nic               byte
+		ContainmentRadius float64 // metres, 0 is unknown
+		HasNic            bool
+		NacP              byte
+		HasNacP           bool
+		NacV              byte
+		HasNacV           bool
+		Sil               byte
+		SilPerSample      bool
+		HasSil            bool
+
+		// Signal strength (RSSI) in dBFS of the frames we have received, average is of the signal power
+		SignalLast    float64
+		SignalMin     float64
+		SignalMax     float64
+		SignalAverage float64
+		HasSignal     bool
+
+		// TCAS Resolution Advisory, the last one we have seen
+		ResolutionAdvisory     string
+		ResolutionAdvisoryTime time.Time
+		HasResolutionAdvisory  bool
+	}
+
+	// WeatherReport is a meteorological observation from a plane (Comm-B BDS 4,4 or 4,5), along with where the
+	// plane was when it made the report
+	WeatherReport struct {
+		Icao          string
+		Bds           string
+		Lat, Lon      float64
+		HasLocation   bool
+		Altitude      int
+		AltitudeUnits string
+		SourceTag     string
+		When          time.Time
+
+		WindSpeed            int
+		WindDirection        float64
+		StaticAirTemperature float64
+		StaticPressure       int
+		Humidity             float64
+		Turbulence           string
+		WindShear            string
+		Microburst           string
+		Icing                string
+		WakeVortex           string
+		RadioHeight          int
+
+		HasWind                 bool
+		HasStaticAirTemperature bool
+		HasStaticPressure       bool
+		HasHumidity             bool
+		HasTurbulence           bool
+		HasWindShear            bool
+		HasMicroburst           bool
+		HasIcing                bool
+		HasWakeVortex           bool
+		HasRadioHeight          bool
+	}
+
+	// TcasAlert is sent whenever a plane reports a new or changed TCAS Resolution Advisory
+	TcasAlert struct {
+		Icao           string
+		FlightNumber   string
+		DownLinkFormat int
+		Lat, Lon       float64
+		HasLocation    bool
+		Altitude       int
+		AltitudeUnits  string
+		SourceTag      string
+		When           time.Time
+
+		Advisories     []string
+		ActiveRA       int
+		RAComplement   int
+		Active         bool
+		Terminated     bool
+		MultipleThreat bool
+		ThreatType     int
+		ThreatIcao     string
+		Threat         string
 	}
 )
@@ -0,0 +1,74 @@
+package producer
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// handshakeKey is how a feeder authenticates with an API key, as a line before its frames: "KEY my-api-key"
+const handshakeKey = "KEY "
+
+var (
+	ErrBadApiKey        = errors.New("unknown api key")
+	ErrNotAuthenticated = errors.New("feeder did not authenticate")
+)
+
+// WithApiKeys requires feeders connecting to our listener to authenticate with one of keys (unless they have a
+// client certificate, see WithListenerTLS). keys maps each API key to the name of the feeder using it, which
+// becomes the Name and Tag of their frames
+func WithApiKeys(keys map[string]string) Option {
+	return func(p *producer) {
+		p.apiKeys = keys
+	}
+}
+
+// LoadApiKeys reads API keys for WithApiKeys from a file with a "<key> <feeder name>" per line.
+// Blank lines and lines starting with # are ignored
+func LoadApiKeys(fileName string) (map[string]string, error) {
+	f, err := os.Open(fileName)
+	if nil != err {
+		return nil, err
+	}
+	defer f.Close()
+
+	keys := map[string]string{}
+	scan := bufio.NewScanner(f)
+	lineNum := 0
+	for scan.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scan.Text())
+		if "" == line || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if 2 != len(fields) {
+			return nil, fmt.Errorf("%s:%d expected '<key> <feeder name>'", fileName, lineNum)
+		}
+		keys[fields[0]] = fields[1]
+	}
+	if err = scan.Err(); nil != err {
+		return nil, err
+	}
+	if 0 == len(keys) {
+		return nil, fmt.Errorf("no api keys in %s", fileName)
+	}
+	return keys, nil
+}
+
+// lookupApiKey is the name of the feeder using key, taking the same time for every key we know about
+func (p *producer) lookupApiKey(key string) (string, error) {
+	feeder := ""
+	for k, name := range p.apiKeys {
+		if 1 == subtle.ConstantTimeCompare([]byte(k), []byte(key)) {
+			feeder = name
+		}
+	}
+	if "" == feeder {
+		return "", ErrBadApiKey
+	}
+	return feeder, nil
+}
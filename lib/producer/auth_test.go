@@ -0,0 +1,81 @@
+package producer
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"path/filepath"
+	"plane.watch/lib/tracker"
+	"strings"
+	"testing"
+)
+
+func TestProducer_readHandshake(t *testing.T) {
+	keys := map[string]string{"secret-1": "feeder-1", "secret-2": "feeder-2"}
+	tests := []struct {
+		name      string
+		apiKeys   map[string]string
+		send      string
+		wantTag   string
+		wantName  string
+		wantErr   error
+		wantFrame string
+	}{
+		{name: "No Auth", send: "*5D7C49F828E943;\n", wantTag: "ours", wantName: "listener", wantFrame: "*5D7C49F828E943;"},
+		{name: "No Auth Tagged", send: "TAG theirs\n*5D7C49F828E943;\n", wantTag: "theirs", wantName: "listener", wantFrame: "*5D7C49F828E943;"},
+		{name: "Api Key", apiKeys: keys, send: "KEY secret-2\n*5D7C49F828E943;\n", wantTag: "feeder-2", wantName: "feeder-2", wantFrame: "*5D7C49F828E943;"},
+		{name: "Api Key Ignores Tag", apiKeys: keys, send: "TAG theirs\nKEY secret-1\n*5D7C49F828E943;\n", wantTag: "feeder-1", wantName: "feeder-1", wantFrame: "*5D7C49F828E943;"},
+		{name: "Bad Api Key", apiKeys: keys, send: "KEY secret-3\n*5D7C49F828E943;\n", wantErr: ErrBadApiKey},
+		{name: "Missing Api Key", apiKeys: keys, send: "TAG theirs\n*5D7C49F828E943;\n", wantErr: ErrNotAuthenticated},
+		{name: "Long Tag", send: "TAG " + strings.Repeat("x", handshakeMaxLine) + "\n*5D7C49F828E943;\n", wantErr: ErrHandshakeLineTooLong},
+		{name: "Endless Key", apiKeys: keys, send: "KEY " + strings.Repeat("x", 64*1024), wantErr: ErrHandshakeLineTooLong},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New(WithType(Avr), WithApiKeys(tt.apiKeys))
+			source := &tracker.FrameSource{Name: "listener", Tag: "ours"}
+			conn, _ := net.Pipe()
+			defer conn.Close()
+			reader := bufio.NewReader(strings.NewReader(tt.send))
+
+			err := p.readHandshake(conn, reader, source)
+			if tt.wantErr != err {
+				t.Fatalf("readHandshake() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if nil != err {
+				return
+			}
+			if tt.wantTag != source.Tag {
+				t.Errorf("Incorrect tag %s, expected %s", source.Tag, tt.wantTag)
+			}
+			if tt.wantName != source.Name {
+				t.Errorf("Incorrect name %s, expected %s", source.Name, tt.wantName)
+			}
+			if line, _ := reader.ReadString('\n'); tt.wantFrame != strings.TrimSpace(line) {
+				t.Errorf("Incorrect frame after handshake %q, expected %q", line, tt.wantFrame)
+			}
+		})
+	}
+}
+
+func TestLoadApiKeys(t *testing.T) {
+	dir := t.TempDir()
+	good := filepath.Join(dir, "good")
+	bad := filepath.Join(dir, "bad")
+	_ = os.WriteFile(good, []byte("# our feeders\nsecret-1 feeder-1\n\n  secret-2   feeder-2\n"), 0600)
+	_ = os.WriteFile(bad, []byte("secret-1 feeder-1\nsecret-2\n"), 0600)
+
+	keys, err := LoadApiKeys(good)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if 2 != len(keys) || "feeder-1" != keys["secret-1"] || "feeder-2" != keys["secret-2"] {
+		t.Errorf("Incorrect keys %v", keys)
+	}
+	if _, err = LoadApiKeys(bad); nil == err {
+		t.Error("Expected an error for a bad line")
+	}
+	if _, err = LoadApiKeys(filepath.Join(dir, "missing")); nil == err {
+		t.Error("Expected an error for a missing file")
+	}
+}
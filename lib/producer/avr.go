@@ -2,14 +2,15 @@ package producer
 
 import (
 	"bufio"
+	"plane.watch/lib/tracker"
 	"plane.watch/lib/tracker/mode_s"
 	"time"
 )
 
-func (p *producer) avrScanner(scan *bufio.Scanner) error {
+func (p *producer) avrScanner(scan *bufio.Scanner, source *tracker.FrameSource) error {
 	for scan.Scan() {
 		line := scan.Text()
-		p.addFrame(mode_s.NewFrame(line, time.Now()), &p.FrameSource)
+		p.addFrame(mode_s.NewFrame(line, time.Now()), source)
 		p.addDebug("AVR Frame: %s", line)
 	}
 	return scan.Err()
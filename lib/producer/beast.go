@@ -8,16 +8,20 @@ import (
 	"time"
 )
 
-func (p *producer) beastScanner(scan *bufio.Scanner) error {
+func (p *producer) beastScanner(scan *bufio.Scanner, source *tracker.FrameSource) error {
 	lastTimeStamp := time.Duration(0)
+	// each connection/file is its own receiver, with its own idea of time
+	clock := beast.NewClock()
+	if p.isReplay && !p.beastDelay {
+		clock = beast.NewReplayClock()
+	}
 	for scan.Scan() {
 		msg := scan.Bytes()
-		p.addFrame(beast.NewFrame(msg, false), &p.FrameSource)
-
 		frame := beast.NewFrame(msg, false)
 		if nil == frame {
 			continue
 		}
+		clock.Apply(frame, time.Now())
 		if p.beastDelay {
 			currentTs := frame.BeastTicksNs()
 			if lastTimeStamp > 0 && lastTimeStamp < currentTs {
@@ -26,7 +30,7 @@ func (p *producer) beastScanner(scan *bufio.Scanner) error {
 			lastTimeStamp = currentTs
 		}
 
-		p.AddEvent(tracker.NewFrameEvent(frame, &p.FrameSource))
+		p.AddEvent(tracker.NewFrameEvent(frame, source))
 	}
 	return scan.Err()
 }
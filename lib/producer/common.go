@@ -4,16 +4,21 @@ import (
 	"bufio"
 	"compress/bzip2"
 	"compress/gzip"
+	"crypto/tls"
 	"errors"
 	"fmt"
+	"github.com/klauspost/compress/zstd"
 	"github.com/rs/zerolog/log"
+	"github.com/ulikunitz/xz"
 	"io"
 	"math/rand"
 	"net"
 	"os"
+	"path/filepath"
 	"plane.watch/lib/tracker"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -27,6 +32,10 @@ const (
 
 type (
 	producer struct {
+		// numDroppedDatagrams is how many UDP datagrams we had to drop because their sender's queue was full.
+		// It is first so that it is 64 bit aligned for atomic access
+		numDroppedDatagrams uint64
+
 		tracker.FrameSource
 		producerType int
 
@@ -38,6 +47,19 @@ type (
 
 		splitter   bufio.SplitFunc
 		beastDelay bool
+		// isReplay is true when we are reading recorded data (files), not a live feed
+		isReplay bool
+
+		// maxConnections limits how many feeders can connect to our listener, 0 is unlimited
+		maxConnections int
+		// idleTimeout is how long a listener connection can go without sending us anything, 0 is forever
+		idleTimeout time.Duration
+		// apiKeys maps the keys feeders can authenticate with to who they are
+		apiKeys map[string]string
+		// tlsEnabled makes our fetcher or listener use TLS. tlsCertFile and tlsKeyFile are our certificate, and
+		// tlsCaFile is the CA we trust for the other end of the connection
+		tlsEnabled                         bool
+		tlsCertFile, tlsKeyFile, tlsCaFile string
 
 		run func()
 	}
@@ -74,29 +96,9 @@ func New(opts ...Option) *producer {
 
 func WithListener(host, port string) Option {
 	return func(p *producer) {
+		p.FrameSource.OriginIdentifier = net.JoinHostPort(host, port)
 		p.run = func() {
-			addr := net.JoinHostPort(host, port)
-			ln, err := net.Listen("tcp", addr)
-			if err != nil {
-				// handle error
-				log.Error().Err(err).Str("host:port", addr).Msg("Failed to listen")
-			}
-			for {
-				conn, errConn := ln.Accept()
-				if errConn != nil {
-					// handle error
-					log.Error().Err(errConn).Msg("Failed to accept a connection")
-				}
-
-				go func(c net.Conn) {
-					scan := bufio.NewScanner(c)
-					scan.Split(p.splitter)
-					errRead := p.readFromScanner(scan)
-					if nil != errRead {
-						log.Error().Err(errRead).Msg("No more reading")
-					}
-				}(conn)
-			}
+			p.listener(host, port)
 		}
 	}
 }
@@ -116,7 +118,7 @@ func WithFetcher(host, port string) Option {
 			p.fetcher(host, port, func(conn net.Conn) error {
 				scan := bufio.NewScanner(conn)
 				scan.Split(p.splitter)
-				return p.readFromScanner(scan)
+				return p.readFromScanner(scan, &p.FrameSource)
 			})
 		}
 	}
@@ -130,11 +132,12 @@ func WithOriginName(name string) Option {
 
 func WithFiles(filePaths []string) Option {
 	return func(p *producer) {
+		p.isReplay = true
 		p.run = func() {
 			p.readFiles(filePaths, func(reader io.Reader, fileName string) error {
 				scanner := bufio.NewScanner(reader)
 				p.FrameSource.OriginIdentifier = "file://" + fileName
-				return p.readFromScanner(scanner)
+				return p.readFromScanner(scanner, &p.FrameSource)
 			})
 		}
 	}
@@ -161,16 +164,16 @@ func WithType(producerType int) Option {
 	}
 }
 
-func (p *producer) readFromScanner(scan *bufio.Scanner) error {
+func (p *producer) readFromScanner(scan *bufio.Scanner, source *tracker.FrameSource) error {
 	scan.Split(p.splitter)
 
 	switch p.producerType {
 	case Avr:
-		return p.avrScanner(scan)
+		return p.avrScanner(scan, source)
 	case Sbs1:
-		return p.sbsScanner(scan)
+		return p.sbsScanner(scan, source)
 	case Beast:
-		return p.beastScanner(scan)
+		return p.beastScanner(scan, source)
 	default:
 		return errors.New("unknown producer type")
 	}
@@ -184,6 +187,23 @@ func WithReferenceLatLon(lat, lon float64) Option {
 	}
 }
 
+// newSource is a FrameSource for one of our inputs (a connection, sender or file), the same as ours but with its
+// own originIdentifier
+func (p *producer) newSource(originIdentifier string) *tracker.FrameSource {
+	return &tracker.FrameSource{
+		OriginIdentifier: originIdentifier,
+		Name:             p.FrameSource.Name,
+		Tag:              p.FrameSource.Tag,
+		RefLat:           p.FrameSource.RefLat,
+		RefLon:           p.FrameSource.RefLon,
+	}
+}
+
+// NumDropped is how many UDP datagrams we have had to drop, our tracker reports it in its InfoEvent
+func (p *producer) NumDropped() uint64 {
+	return atomic.LoadUint64(&p.numDroppedDatagrams)
+}
+
 func (p *producer) String() string {
 	return p.Name
 }
@@ -221,6 +241,18 @@ func (p *producer) AddEvent(e tracker.Event) {
 	}
 }
 
+// giveUp is for when we could not even start. There is nothing to read, but Stop() still needs to work
+func (p *producer) giveUp() {
+	p.Cleanup()
+	go func() {
+		for cmd := range p.cmdChan {
+			if cmdExit == cmd {
+				return
+			}
+		}
+	}()
+}
+
 func (p *producer) Cleanup() {
 	p.outLocker.Lock()
 	defer p.outLocker.Unlock()
@@ -234,7 +266,6 @@ func (p *producer) Cleanup() {
 func (p *producer) readFiles(dataFiles []string, read func(io.Reader, string) error) {
 	var err error
 	var inFile *os.File
-	var gzipFile *gzip.Reader
 	go func() {
 		for _, inFileName := range dataFiles {
 			p.addDebug("Loading lines from %s", inFileName)
@@ -245,21 +276,9 @@ func (p *producer) readFiles(dataFiles []string, read func(io.Reader, string) er
 				continue
 			}
 
-			isGzip := strings.ToLower(inFileName[len(inFileName)-2:]) == "gz"
-			isBzip2 := strings.ToLower(inFileName[len(inFileName)-3:]) == "bz2"
-			p.addDebug("Is Gzip? %t Is Bzip2? %t", isGzip, isBzip2)
-
-			if isGzip {
-				gzipFile, err = gzip.NewReader(inFile)
-				if nil != err {
-					err = read(gzipFile, inFileName)
-				}
-				err = read(gzipFile, inFileName)
-			} else if isBzip2 {
-				bzip2File := bzip2.NewReader(inFile)
-				err = read(bzip2File, inFileName)
-			} else {
-				err = read(inFile, inFileName)
+			var reader io.Reader
+			if reader, err = decompress(inFile, inFileName); nil == err {
+				err = read(reader, inFileName)
 			}
 			if nil != err {
 				p.addError(err)
@@ -281,6 +300,32 @@ func (p *producer) readFiles(dataFiles []string, read func(io.Reader, string) er
 	}()
 }
 
+// isCompressed is true for the files that decompress() knows about
+func isCompressed(fileName string) bool {
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".gz", ".bz2", ".zst", ".xz":
+		return true
+	}
+	return false
+}
+
+// decompress reads compressed files (by their extension) as if they were not
+func decompress(reader io.Reader, fileName string) (io.Reader, error) {
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".gz":
+		return gzip.NewReader(reader)
+	case ".bz2":
+		return bzip2.NewReader(reader), nil
+	case ".zst":
+		// a single decoder decodes in our goroutine, so there is nothing left running when we are done
+		return zstd.NewReader(reader, zstd.WithDecoderConcurrency(1))
+	case ".xz":
+		return xz.NewReader(reader)
+	default:
+		return reader, nil
+	}
+}
+
 func (p *producer) fetcher(host, port string, read func(net.Conn) error) {
 	var conn net.Conn
 	var wLock sync.RWMutex
@@ -292,13 +337,20 @@ func (p *producer) fetcher(host, port string, read func(net.Conn) error) {
 		return working
 	}
 
+	tlsConfig, err := p.fetcherTlsConfig(host)
+	if nil != err {
+		p.addError(err)
+		p.giveUp()
+		return
+	}
+
 	go func() {
 		var backOff = time.Second
 		var err error
 		for isWorking() {
 			p.addDebug("We are working!")
 			wLock.Lock()
-			conn, err = net.Dial("tcp", net.JoinHostPort(host, port))
+			conn, err = dial(net.JoinHostPort(host, port), tlsConfig)
 			wLock.Unlock()
 			if nil != err {
 				p.addError(err)
@@ -337,3 +389,15 @@ func (p *producer) fetcher(host, port string, read func(net.Conn) error) {
 	}()
 
 }
+
+// dial connects to addr, over TLS if we have a tlsConfig
+func dial(addr string, tlsConfig *tls.Config) (net.Conn, error) {
+	if nil == tlsConfig {
+		return net.Dial("tcp", addr)
+	}
+	conn, err := tls.Dial("tcp", addr, tlsConfig)
+	if nil != err {
+		return nil, err
+	}
+	return conn, nil
+}
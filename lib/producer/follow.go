@@ -0,0 +1,204 @@
+package producer
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"plane.watch/lib/tracker"
+	"sync"
+	"time"
+)
+
+// followPollInterval is how often we check a followed file for new data, rotation and newer files
+var followPollInterval = time.Second
+
+type (
+	// followReader reads a file that is still being written to, like tail -F. Instead of giving an io.EOF at the
+	// end of the file it waits for more, until the file is rotated, removed or a newer file matches our pattern
+	followReader struct {
+		file              *os.File
+		fileName, pattern string
+		stop              chan struct{}
+	}
+)
+
+// WithFollowFiles reads files as they are written to, instead of once. Each pattern can be a glob (e.g.
+// /var/log/dump1090/*.bin), and the newest file that matches it is followed. We move on to the next file when
+// the one we are reading is rotated (its name now belongs to a new file) or a newer file turns up.
+// Files that are already there when we start are followed from their end, files that turn up after we start
+// are read from the beginning
+func WithFollowFiles(patterns []string) Option {
+	return func(p *producer) {
+		p.run = func() {
+			p.followFiles(patterns)
+		}
+	}
+}
+
+func (p *producer) followFiles(patterns []string) {
+	stop := make(chan struct{})
+	go func() {
+		for cmd := range p.cmdChan {
+			switch cmd {
+			case cmdExit:
+				p.addDebug("Got Cmd Exit")
+				close(stop)
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for _, pattern := range patterns {
+		if _, err := filepath.Match(pattern, ""); nil != err {
+			p.addError(err)
+			continue
+		}
+		wg.Add(1)
+		go func(pattern string) {
+			defer wg.Done()
+			p.followPattern(pattern, stop)
+		}(pattern)
+	}
+	wg.Wait()
+	p.addDebug("Done following files")
+	p.Cleanup()
+}
+
+// followPattern reads the newest file matching pattern until we are stopped. Rotated files are the same feed, so
+// they all share the one FrameSource
+func (p *producer) followPattern(pattern string, stop chan struct{}) {
+	source := p.newSource("file://" + pattern)
+	fromStart := "" == newestFile(pattern)
+	// the file we last followed, a rotated file can still match our pattern and we do not want to read it again
+	var last os.FileInfo
+	for {
+		fileName := waitForFile(pattern, last, stop)
+		if "" == fileName {
+			return
+		}
+		var err error
+		if last, err = p.followFile(fileName, pattern, source, fromStart, stop); nil != err {
+			p.addError(err)
+			// do not spin on a file we cannot read
+			select {
+			case <-stop:
+			case <-time.After(followPollInterval):
+			}
+		}
+		fromStart = true
+
+		select {
+		case <-stop:
+			return
+		default:
+		}
+	}
+}
+
+// followFile reads fileName until it is finished with, returning the file it read so we do not follow it again
+func (p *producer) followFile(fileName, pattern string, source *tracker.FrameSource, fromStart bool, stop chan struct{}) (os.FileInfo, error) {
+	f, err := os.Open(fileName)
+	if nil != err {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if nil != err {
+		return nil, err
+	}
+
+	// compressed files have to be read from the start to make any sense of them
+	if !fromStart && !isCompressed(fileName) {
+		if _, err = f.Seek(0, io.SeekEnd); nil != err {
+			return info, err
+		}
+	}
+	p.addInfo("Following %s", fileName)
+
+	reader, err := decompress(&followReader{file: f, fileName: fileName, pattern: pattern, stop: stop}, fileName)
+	if nil != err {
+		return info, err
+	}
+	scanner := bufio.NewScanner(reader)
+	err = p.readFromScanner(scanner, source)
+	p.addDebug("Finished following %s", fileName)
+	return info, err
+}
+
+func (f *followReader) Read(b []byte) (int, error) {
+	for {
+		n, err := f.file.Read(b)
+		if n > 0 || io.EOF != err {
+			return n, err
+		}
+		if f.finished() {
+			// anything written just before we noticed?
+			if n, err = f.file.Read(b); n > 0 {
+				return n, nil
+			}
+			return 0, io.EOF
+		}
+		select {
+		case <-f.stop:
+			return 0, io.EOF
+		case <-time.After(followPollInterval):
+		}
+	}
+}
+
+// finished is true once nothing more is going to be written to our file
+func (f *followReader) finished() bool {
+	info, err := f.file.Stat()
+	if nil != err {
+		return true
+	}
+	if pos, err := f.file.Seek(0, io.SeekCurrent); nil == err && info.Size() < pos {
+		// truncated in place (copytruncate), so start again from the top
+		_, _ = f.file.Seek(0, io.SeekStart)
+		return false
+	}
+	current, err := os.Stat(f.fileName)
+	if nil != err || !os.SameFile(info, current) {
+		// removed, or rotated and our name now belongs to a new file
+		return true
+	}
+	return newestFile(f.pattern) != f.fileName
+}
+
+// newestFile is the most recently modified file matching pattern, "" if there are none
+func newestFile(pattern string) string {
+	matches, _ := filepath.Glob(pattern)
+	newest := ""
+	var newestTime time.Time
+	for _, match := range matches {
+		info, err := os.Stat(match)
+		if nil != err || info.IsDir() {
+			continue
+		}
+		if "" == newest || info.ModTime().After(newestTime) || (info.ModTime().Equal(newestTime) && match > newest) {
+			newest = match
+			newestTime = info.ModTime()
+		}
+	}
+	return newest
+}
+
+// waitForFile waits until the newest file matching pattern is not the one we last followed (e.g. that file has been
+// renamed to something else that matches, and its replacement is not there yet), "" if we are stopped first
+func waitForFile(pattern string, last os.FileInfo, stop chan struct{}) string {
+	for {
+		if fileName := newestFile(pattern); "" != fileName {
+			info, err := os.Stat(fileName)
+			if nil == err && (nil == last || !os.SameFile(info, last)) {
+				return fileName
+			}
+		}
+		select {
+		case <-stop:
+			return ""
+		case <-time.After(followPollInterval):
+		}
+	}
+}
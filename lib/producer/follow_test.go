@@ -0,0 +1,167 @@
+package producer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"plane.watch/lib/tracker"
+	"testing"
+	"time"
+)
+
+func appendLine(t *testing.T, fileName, line string) {
+	f, err := os.OpenFile(fileName, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if _, err = f.WriteString(line + "\n"); nil != err {
+		t.Fatal(err)
+	}
+	_ = f.Close()
+}
+
+func TestProducer_followFiles(t *testing.T) {
+	followPollInterval = 10 * time.Millisecond
+	dir := t.TempDir()
+	first := filepath.Join(dir, "a.avr")
+	second := filepath.Join(dir, "b.avr")
+	appendLine(t, first, "*8D4840D6202CC371C32CE0576098;")
+
+	pattern := filepath.Join(dir, "*.avr")
+	p := New(WithType(Avr), WithFollowFiles([]string{pattern}))
+	events := p.Listen()
+	// let it find the end of our file
+	time.Sleep(100 * time.Millisecond)
+
+	// every file we follow for the pattern is the same feed
+	var source *tracker.FrameSource
+	expectFrame := func(want string) {
+		t.Helper()
+		select {
+		case e := <-events:
+			fe := e.(*tracker.FrameEvent)
+			if want != string(fe.Frame().Raw()) {
+				t.Errorf("Incorrect frame %s, expected %s", fe.Frame().Raw(), want)
+			}
+			if "file://"+pattern != fe.Source().OriginIdentifier {
+				t.Errorf("Incorrect source %s, expected %s", fe.Source().OriginIdentifier, pattern)
+			}
+			if nil == source {
+				source = fe.Source()
+			} else if source != fe.Source() {
+				t.Error("Expected the same FrameSource for every file")
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Timed out waiting for %s", want)
+		}
+	}
+
+	// appended to the file we are following
+	appendLine(t, first, "*5D7C49F828E943;")
+	expectFrame("5D7C49F828E943")
+
+	// rotated, the new file is read from the start
+	if err := os.Rename(first, first+".1"); nil != err {
+		t.Fatal(err)
+	}
+	appendLine(t, first, "*8D7C49F8E11E2F00000000EECC47;")
+	expectFrame("8D7C49F8E11E2F00000000EECC47")
+
+	// a newer file turns up
+	appendLine(t, second, "*02E19718E8A9B0;")
+	later := time.Now().Add(time.Minute)
+	_ = os.Chtimes(second, later, later)
+	expectFrame("02E19718E8A9B0")
+
+	p.Stop()
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Error("Expected no more events once stopped")
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("Expected our events to be closed once stopped")
+	}
+}
+
+func TestProducer_followFilesRenamedToMatch(t *testing.T) {
+	followPollInterval = 10 * time.Millisecond
+	dir := t.TempDir()
+	log := filepath.Join(dir, "out.log")
+	appendLine(t, log, "*8D4840D6202CC371C32CE0576098;")
+
+	// the rotated file still matches our pattern
+	p := New(WithType(Avr), WithFollowFiles([]string{log + "*"}))
+	events := p.Listen()
+	defer p.Stop()
+	time.Sleep(100 * time.Millisecond)
+
+	appendLine(t, log, "*5D7C49F828E943;")
+	if err := os.Rename(log, log+".1"); nil != err {
+		t.Fatal(err)
+	}
+	// give it plenty of polls to (not) pick up out.log.1 before out.log is back
+	time.Sleep(100 * time.Millisecond)
+	appendLine(t, log, "*8D7C49F8E11E2F00000000EECC47;")
+
+	for _, want := range []string{"5D7C49F828E943", "8D7C49F8E11E2F00000000EECC47"} {
+		select {
+		case e := <-events:
+			if fe := e.(*tracker.FrameEvent); want != string(fe.Frame().Raw()) {
+				t.Errorf("Incorrect frame %s, expected %s", fe.Frame().Raw(), want)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Timed out waiting for %s", want)
+		}
+	}
+}
+
+func TestDecompress(t *testing.T) {
+	want := []byte("*5D7C49F828E943;\n")
+	compress := func(newWriter func(io.Writer) (io.WriteCloser, error)) []byte {
+		var buf bytes.Buffer
+		w, err := newWriter(&buf)
+		if nil != err {
+			t.Fatal(err)
+		}
+		_, _ = w.Write(want)
+		_ = w.Close()
+		return buf.Bytes()
+	}
+	tests := []struct {
+		name     string
+		fileName string
+		data     []byte
+	}{
+		{name: "Plain", fileName: "plain.avr", data: want},
+		{name: "Gzip", fileName: "data.avr.gz", data: compress(func(w io.Writer) (io.WriteCloser, error) {
+			return gzip.NewWriter(w), nil
+		})},
+		{name: "Zstd", fileName: "data.avr.zst", data: compress(func(w io.Writer) (io.WriteCloser, error) {
+			return zstd.NewWriter(w)
+		})},
+		{name: "Xz", fileName: "data.avr.XZ", data: compress(func(w io.Writer) (io.WriteCloser, error) {
+			return xz.NewWriter(w)
+		})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader, err := decompress(bytes.NewReader(tt.data), tt.fileName)
+			if nil != err {
+				t.Fatal(err)
+			}
+			got, err := ioutil.ReadAll(reader)
+			if nil != err {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(want, got) {
+				t.Errorf("Incorrect data %q, expected %q", got, want)
+			}
+		})
+	}
+}
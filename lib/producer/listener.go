@@ -0,0 +1,239 @@
+package producer
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"github.com/rs/zerolog/log"
+	"net"
+	"plane.watch/lib/tracker"
+	"strings"
+	"sync"
+	"time"
+)
+
+// handshakeTag is how a feeder can tell us who they are. If the first line of their connection is "TAG my-tag"
+// then their frames are tagged with my-tag instead of our own tag
+const handshakeTag = "TAG "
+
+// handshakeTimeout is how long a feeder has to finish its TLS handshake and tell us who they are
+var handshakeTimeout = 10 * time.Second
+
+// handshakeMaxLine is the longest TAG or KEY line we will read, anything longer is not a handshake
+const handshakeMaxLine = 256
+
+var ErrHandshakeLineTooLong = errors.New("handshake line too long")
+
+type (
+	// idleConn is a connection that gives up when nothing has been read from it for timeout
+	idleConn struct {
+		net.Conn
+		timeout time.Duration
+	}
+)
+
+// WithMaxConnections limits the number of feeders that can connect to our listener at once
+func WithMaxConnections(maxConnections int) Option {
+	return func(p *producer) {
+		p.maxConnections = maxConnections
+	}
+}
+
+// WithIdleTimeout disconnects feeders that have not sent us anything for the given duration
+func WithIdleTimeout(idleTimeout time.Duration) Option {
+	return func(p *producer) {
+		p.idleTimeout = idleTimeout
+	}
+}
+
+func (c *idleConn) Read(b []byte) (int, error) {
+	if c.timeout > 0 {
+		_ = c.Conn.SetReadDeadline(time.Now().Add(c.timeout))
+	}
+	return c.Conn.Read(b)
+}
+
+func (p *producer) listener(host, port string) {
+	addr := net.JoinHostPort(host, port)
+	tlsConfig, err := p.listenerTlsConfig()
+	if nil != err {
+		log.Error().Err(err).Str("host:port", addr).Msg("Failed to setup TLS")
+		p.giveUp()
+		return
+	}
+	ln, err := net.Listen("tcp", addr)
+	if nil != err {
+		log.Error().Err(err).Str("host:port", addr).Msg("Failed to listen")
+		p.giveUp()
+		return
+	}
+	if nil != tlsConfig {
+		ln = tls.NewListener(ln, tlsConfig)
+	}
+	p.addInfo("Listening on: %s", ln.Addr())
+	p.acceptConnections(ln)
+}
+
+// acceptConnections reads from everyone that connects to ln, until we are stopped
+func (p *producer) acceptConnections(ln net.Listener) {
+	var connLock sync.Mutex
+	var connWait sync.WaitGroup
+	conns := map[net.Conn]bool{}
+	stopping := false
+
+	go func() {
+		for cmd := range p.cmdChan {
+			switch cmd {
+			case cmdExit:
+				p.addDebug("Got Cmd Exit")
+				connLock.Lock()
+				stopping = true
+				_ = ln.Close()
+				for conn := range conns {
+					_ = conn.Close()
+				}
+				connLock.Unlock()
+				return
+			}
+		}
+	}()
+
+	backOff := 5 * time.Millisecond
+	for {
+		conn, err := ln.Accept()
+		if nil != err {
+			if errors.Is(err, net.ErrClosed) {
+				break
+			}
+			// most likely out of file handles, give things a chance to settle down
+			log.Error().Err(err).Msg("Failed to accept a connection")
+			time.Sleep(backOff)
+			if backOff *= 2; backOff > time.Second {
+				backOff = time.Second
+			}
+			continue
+		}
+		backOff = 5 * time.Millisecond
+
+		connLock.Lock()
+		if stopping {
+			connLock.Unlock()
+			_ = conn.Close()
+			continue
+		}
+		if p.maxConnections > 0 && len(conns) >= p.maxConnections {
+			connLock.Unlock()
+			log.Warn().Str("remote", conn.RemoteAddr().String()).Int("max", p.maxConnections).Msg("Too many connections, rejecting feeder")
+			_ = conn.Close()
+			continue
+		}
+		conns[conn] = true
+		connWait.Add(1)
+		connLock.Unlock()
+
+		go func(c net.Conn) {
+			defer connWait.Done()
+			p.readConnection(c)
+			connLock.Lock()
+			delete(conns, c)
+			connLock.Unlock()
+			_ = c.Close()
+		}(conn)
+	}
+
+	connWait.Wait()
+	p.addDebug("Done with Producer %s", p)
+	p.Cleanup()
+}
+
+// readConnection reads frames from a single feeder, with its own FrameSource
+func (p *producer) readConnection(conn net.Conn) {
+	source := p.newSource(conn.RemoteAddr().String())
+
+	// every feeder only gets so long to tell us who they are. A feeder that does not have to authenticate can
+	// stay quiet instead, that just means no handshake. The idle timeout would keep pushing the deadline back,
+	// so it only starts once they are done
+	idle := &idleConn{Conn: conn}
+	reader := bufio.NewReader(idle)
+	_ = conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	err := p.readHandshake(conn, reader, source)
+	_ = conn.SetDeadline(time.Time{})
+	idle.timeout = p.idleTimeout
+	if nil != err {
+		log.Warn().Str("section", p.Name).Str("remote", source.OriginIdentifier).Err(err).Msg("Feeder failed handshake")
+		return
+	}
+	log.Info().Str("section", p.Name).Str("remote", source.OriginIdentifier).Str("tag", source.Tag).Msg("Feeder connected")
+
+	scan := bufio.NewScanner(reader)
+	scan.Split(p.splitter)
+	err = p.readFromScanner(scan, source)
+	log.Info().Str("section", p.Name).Str("remote", source.OriginIdentifier).Err(err).Msg("Feeder disconnected")
+}
+
+// readHandshake works out who a feeder is, from their client certificate or from the optional KEY and TAG lines
+// they can send before their frames. Once a feeder has authenticated their name is used as their Tag, they cannot
+// choose their own
+func (p *producer) readHandshake(conn net.Conn, reader *bufio.Reader, source *tracker.FrameSource) error {
+	var err error
+	feeder := ""
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		if feeder, err = tlsIdentity(tlsConn); nil != err {
+			return err
+		}
+		if "" != feeder {
+			// their certificate says it all
+			source.Name = feeder
+			source.Tag = feeder
+			return nil
+		}
+	}
+
+	tag := ""
+	for {
+		peek, err := reader.Peek(len(handshakeTag))
+		if nil != err {
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() && nil == p.apiKeys && !isHandshakePrefix(peek) {
+				// a quiet feeder that is not going to send us a handshake, its frames are on their way
+				break
+			}
+			return err
+		}
+		if handshakeTag != string(peek) && handshakeKey != string(peek) {
+			break
+		}
+		// ReadSlice only reads as far as the reader's buffer, so a feeder cannot make us hold an endless line
+		slice, err := reader.ReadSlice('\n')
+		if bufio.ErrBufferFull == err || len(slice) > handshakeMaxLine {
+			return ErrHandshakeLineTooLong
+		}
+		if nil != err {
+			return err
+		}
+		line := string(slice)
+		value := strings.TrimSpace(line[len(handshakeTag):])
+		switch line[:len(handshakeTag)] {
+		case handshakeTag:
+			tag = value
+		case handshakeKey:
+			if feeder, err = p.lookupApiKey(value); nil != err {
+				return err
+			}
+		}
+	}
+
+	if "" != feeder {
+		source.Name = feeder
+		source.Tag = feeder
+	} else if nil != p.apiKeys {
+		return ErrNotAuthenticated
+	} else if "" != tag {
+		source.Tag = tag
+	}
+	return nil
+}
+
+// isHandshakePrefix is true if b is the start of a TAG or KEY line
+func isHandshakePrefix(b []byte) bool {
+	return len(b) > 0 && (strings.HasPrefix(handshakeTag, string(b)) || strings.HasPrefix(handshakeKey, string(b)))
+}
@@ -0,0 +1,146 @@
+package producer
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func listenForTest(t *testing.T, opts ...Option) (*producer, net.Listener) {
+	p := New(append([]Option{WithType(Beast), WithSourceTag("listener-test")}, opts...)...)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if nil != err {
+		t.Fatal(err)
+	}
+	go p.acceptConnections(ln)
+	return p, ln
+}
+
+func connectForTest(t *testing.T, ln net.Listener, send ...[]byte) net.Conn {
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if nil != err {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+	for _, b := range send {
+		if _, err = conn.Write(b); nil != err {
+			t.Fatal(err)
+		}
+	}
+	return conn
+}
+
+// expectClosed checks that conn has been hung up on by the listener
+func expectClosed(t *testing.T, conn net.Conn) {
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := conn.Read(make([]byte, 1)); io.EOF != err {
+		t.Errorf("Expected to be disconnected, got %v", err)
+	}
+}
+
+func TestProducer_acceptConnections(t *testing.T) {
+	p, ln := listenForTest(t)
+
+	first := connectForTest(t, ln, beastModeSShort)
+	fe := nextFrameEvent(t, p)
+	if want := first.LocalAddr().String(); want != fe.Source().OriginIdentifier {
+		t.Errorf("Incorrect source %s, expected %s", fe.Source().OriginIdentifier, want)
+	}
+	if "listener-test" != fe.Source().Tag {
+		t.Errorf("Incorrect tag %s, expected our own", fe.Source().Tag)
+	}
+
+	second := connectForTest(t, ln, []byte("TAG feeder-2\n"), beastModeSLong)
+	fe = nextFrameEvent(t, p)
+	if !bytes.Equal(beastModeSLong, fe.Frame().Raw()) {
+		t.Errorf("Incorrect frame %X, expected %X", fe.Frame().Raw(), beastModeSLong)
+	}
+	if want := second.LocalAddr().String(); want != fe.Source().OriginIdentifier {
+		t.Errorf("Incorrect source %s, expected %s", fe.Source().OriginIdentifier, want)
+	}
+	if "feeder-2" != fe.Source().Tag {
+		t.Errorf("Incorrect tag %s, expected feeder-2", fe.Source().Tag)
+	}
+
+	p.Stop()
+	expectClosed(t, first)
+	expectClosed(t, second)
+	select {
+	case _, ok := <-p.out:
+		if ok {
+			t.Error("Expected no more events once stopped")
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("Expected our events to be closed once stopped")
+	}
+	if _, err := net.Dial("tcp", ln.Addr().String()); nil == err {
+		t.Error("Expected our listener to be closed once stopped")
+	}
+}
+
+func TestProducer_acceptConnectionsMax(t *testing.T) {
+	p, ln := listenForTest(t, WithMaxConnections(1))
+	defer p.Stop()
+
+	connectForTest(t, ln, beastModeSShort)
+	nextFrameEvent(t, p)
+
+	expectClosed(t, connectForTest(t, ln))
+}
+
+func TestProducer_acceptConnectionsIdle(t *testing.T) {
+	p, ln := listenForTest(t, WithIdleTimeout(50*time.Millisecond))
+	defer p.Stop()
+
+	conn := connectForTest(t, ln, beastModeSShort)
+	nextFrameEvent(t, p)
+	expectClosed(t, conn)
+}
+
+func TestProducer_acceptConnectionsHandshakeTimeout(t *testing.T) {
+	defer func(timeout time.Duration) {
+		handshakeTimeout = timeout
+	}(handshakeTimeout)
+	handshakeTimeout = 50 * time.Millisecond
+
+	// no keys and no TLS, once they start a TAG line they still have to finish it in time. The idle timeout
+	// does not help them
+	p, ln := listenForTest(t, WithIdleTimeout(time.Minute))
+	defer p.Stop()
+
+	expectClosed(t, connectForTest(t, ln, []byte("TAG feeder-")))
+	expectClosed(t, connectForTest(t, ln, []byte("TA")))
+
+	// a feeder that has to authenticate cannot just stay quiet
+	authenticated, authLn := listenForTest(t, WithApiKeys(map[string]string{"secret-1": "feeder-1"}))
+	defer authenticated.Stop()
+	expectClosed(t, connectForTest(t, authLn))
+}
+
+func TestProducer_acceptConnectionsQuietFeeder(t *testing.T) {
+	defer func(timeout time.Duration) {
+		handshakeTimeout = timeout
+	}(handshakeTimeout)
+	handshakeTimeout = 50 * time.Millisecond
+
+	// without keys a feeder does not have to say anything, so a quiet one is not a failed handshake
+	p, ln := listenForTest(t)
+	defer p.Stop()
+
+	conn := connectForTest(t, ln)
+	time.Sleep(4 * handshakeTimeout)
+	if _, err := conn.Write(beastModeSLong); nil != err {
+		t.Fatal(err)
+	}
+	fe := nextFrameEvent(t, p)
+	if !bytes.Equal(beastModeSLong, fe.Frame().Raw()) {
+		t.Errorf("Incorrect frame %X, expected %X", fe.Frame().Raw(), beastModeSLong)
+	}
+	if "listener-test" != fe.Source().Tag {
+		t.Errorf("Incorrect tag %s, expected listener-test", fe.Source().Tag)
+	}
+}
@@ -2,13 +2,14 @@ package producer
 
 import (
 	"bufio"
+	"plane.watch/lib/tracker"
 	"plane.watch/lib/tracker/sbs1"
 )
 
-func (p *producer) sbsScanner(scan *bufio.Scanner) error {
+func (p *producer) sbsScanner(scan *bufio.Scanner, source *tracker.FrameSource) error {
 	for scan.Scan() {
 		line := scan.Text()
-		p.addFrame(sbs1.NewFrame(scan.Text()), &p.FrameSource)
+		p.addFrame(sbs1.NewFrame(scan.Text()), source)
 		p.addDebug("SBS Frame: %s", line)
 	}
 	return scan.Err()
@@ -0,0 +1,68 @@
+package producer
+
+import (
+	"crypto/tls"
+	"plane.watch/lib/tlsconfig"
+)
+
+// WithListenerTLS makes our listener TLS only. With a clientCaFile feeders must have a client certificate
+// signed by it, and the certificate's CommonName becomes the Name and Tag of their frames
+func WithListenerTLS(certFile, keyFile, clientCaFile string) Option {
+	return func(p *producer) {
+		p.tlsEnabled = true
+		p.tlsCertFile = certFile
+		p.tlsKeyFile = keyFile
+		p.tlsCaFile = clientCaFile
+	}
+}
+
+// WithFetcherTLS makes our fetcher connect with TLS. certFile and keyFile are our client certificate, if the
+// server wants one, and with a caFile we only trust servers with a certificate signed by it
+func WithFetcherTLS(certFile, keyFile, caFile string) Option {
+	return func(p *producer) {
+		p.tlsEnabled = true
+		p.tlsCertFile = certFile
+		p.tlsKeyFile = keyFile
+		p.tlsCaFile = caFile
+	}
+}
+
+// listenerTlsConfig is the TLS setup for our listener, nil if we are not using TLS
+func (p *producer) listenerTlsConfig() (*tls.Config, error) {
+	if !p.tlsEnabled {
+		return nil, nil
+	}
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	cert, err := tls.LoadX509KeyPair(p.tlsCertFile, p.tlsKeyFile)
+	if nil != err {
+		return nil, err
+	}
+	config.Certificates = []tls.Certificate{cert}
+	if "" != p.tlsCaFile {
+		if config.ClientCAs, err = tlsconfig.LoadCaFile(p.tlsCaFile); nil != err {
+			return nil, err
+		}
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config, nil
+}
+
+// fetcherTlsConfig is the TLS setup for fetching from host, nil if we are not using TLS
+func (p *producer) fetcherTlsConfig(host string) (*tls.Config, error) {
+	if !p.tlsEnabled {
+		return nil, nil
+	}
+	return tlsconfig.Client(host, p.tlsCertFile, p.tlsKeyFile, p.tlsCaFile)
+}
+
+// tlsIdentity is who the client certificate on conn says they are, if they have one
+func tlsIdentity(conn *tls.Conn) (string, error) {
+	if err := conn.Handshake(); nil != err {
+		return "", err
+	}
+	state := conn.ConnectionState()
+	if 0 == len(state.VerifiedChains) {
+		return "", nil
+	}
+	return state.VerifiedChains[0][0].Subject.CommonName, nil
+}
@@ -0,0 +1,120 @@
+package producer
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"path/filepath"
+	"plane.watch/internal/testcert"
+	"plane.watch/lib/tracker"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProducer_listenerTls(t *testing.T) {
+	dir := t.TempDir()
+	ca, caKey := testcert.Write(t, dir, "ca", nil, nil)
+	testcert.Write(t, dir, "server", ca, caKey)
+	testcert.Write(t, dir, "feeder-3", ca, caKey)
+
+	p := New(
+		WithType(Beast),
+		WithListenerTLS(filepath.Join(dir, "server.pem"), filepath.Join(dir, "server.key"), filepath.Join(dir, "ca.pem")),
+	)
+	tlsConfig, err := p.listenerTlsConfig()
+	if nil != err {
+		t.Fatal(err)
+	}
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", tlsConfig)
+	if nil != err {
+		t.Fatal(err)
+	}
+	go p.acceptConnections(ln)
+	defer p.Stop()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	clientCert, err := tls.LoadX509KeyPair(filepath.Join(dir, "feeder-3.pem"), filepath.Join(dir, "feeder-3.key"))
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	// without a client certificate we are turned away
+	anon, err := tls.Dial("tcp", ln.Addr().String(), &tls.Config{RootCAs: roots})
+	if nil == err {
+		_, _ = anon.Write(beastModeSShort)
+		_ = anon.SetReadDeadline(time.Now().Add(2 * time.Second))
+		if _, err = anon.Read(make([]byte, 1)); nil == err || strings.Contains(err.Error(), "timeout") {
+			t.Errorf("Expected to be turned away without a client certificate, got %v", err)
+		}
+		_ = anon.Close()
+	}
+
+	conn, err := tls.Dial("tcp", ln.Addr().String(), &tls.Config{RootCAs: roots, Certificates: []tls.Certificate{clientCert}})
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err = conn.Write(beastModeSLong); nil != err {
+		t.Fatal(err)
+	}
+	fe := nextFrameEvent(t, p)
+	if "feeder-3" != fe.Source().Name || "feeder-3" != fe.Source().Tag {
+		t.Errorf("Incorrect source name/tag %s/%s, expected feeder-3", fe.Source().Name, fe.Source().Tag)
+	}
+}
+
+func TestProducer_fetcherTls(t *testing.T) {
+	dir := t.TempDir()
+	ca, caKey := testcert.Write(t, dir, "ca", nil, nil)
+	testcert.Write(t, dir, "server", ca, caKey)
+	serverCert, err := tls.LoadX509KeyPair(filepath.Join(dir, "server.pem"), filepath.Join(dir, "server.key"))
+	if nil != err {
+		t.Fatal(err)
+	}
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{serverCert}})
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if nil != err {
+			return
+		}
+		defer conn.Close()
+		_, _ = conn.Write(beastModeSLongDoubleEsc)
+		_, _ = conn.Read(make([]byte, 1))
+	}()
+
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	p := New(WithType(Beast), WithFetcher(host, port), WithFetcherTLS("", "", filepath.Join(dir, "ca.pem")))
+	events := p.Listen()
+	defer p.Stop()
+
+	select {
+	case e := <-events:
+		fe := e.(*tracker.FrameEvent)
+		if !bytes.Equal(beastModeSLongDoubleRemoved, fe.Frame().Raw()) {
+			t.Errorf("Incorrect frame %X, expected %X", fe.Frame().Raw(), beastModeSLongDoubleRemoved)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for a frame over TLS")
+	}
+}
+
+func TestProducer_fetcherTlsBadCa(t *testing.T) {
+	p := New(WithType(Beast), WithFetcher("127.0.0.1", "1"), WithFetcherTLS("", "", filepath.Join(t.TempDir(), "missing.pem")))
+	events := p.Listen()
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Error("Expected no events without our CA")
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("Expected to give up without our CA")
+	}
+	p.Stop()
+}
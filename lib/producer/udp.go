@@ -0,0 +1,178 @@
+package producer
+
+import (
+	"bufio"
+	"github.com/rs/zerolog/log"
+	"io"
+	"net"
+	"plane.watch/lib/tracker"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// udpMaxDatagram is the biggest datagram we can be sent
+	udpMaxDatagram = 65535
+	// udpSenderTimeout is how long we remember a sender after we last heard from it
+	udpSenderTimeout = 5 * time.Minute
+	// udpSenderBuffer is how many datagrams we queue for a sender whose scanner is busy, before dropping them
+	udpSenderBuffer = 128
+)
+
+type (
+	// udpSender is someone sending us datagrams. Their datagrams are joined back into a stream, so frames
+	// that are split across (or combined into) datagrams are read the same way as they would be over TCP.
+	// Each sender has its own queue, so a sender we cannot keep up with does not hold up everyone else
+	udpSender struct {
+		source    *tracker.FrameSource
+		datagrams chan []byte
+		// done is closed when their scanner has given up on them
+		done     chan struct{}
+		lastSeen time.Time
+		dropped  uint64
+	}
+)
+
+// WithUDPListener listens for AVR, BEAST or SBS1 datagrams on host:port. Each sender is treated as its own
+// receiver, with its own FrameSource
+func WithUDPListener(host, port string) Option {
+	hp := net.JoinHostPort(host, port)
+	return func(p *producer) {
+		p.FrameSource.OriginIdentifier = "udp://" + hp
+		p.run = func() {
+			conn, err := net.ListenPacket("udp", hp)
+			if nil != err {
+				log.Error().Err(err).Str("host:port", hp).Msg("Failed to listen")
+				p.giveUp()
+				return
+			}
+			p.addInfo("Listening for UDP on: %s", conn.LocalAddr())
+
+			go func() {
+				for cmd := range p.cmdChan {
+					switch cmd {
+					case cmdExit:
+						p.addDebug("Got Cmd Exit")
+						_ = conn.Close()
+						return
+					}
+				}
+			}()
+
+			p.readDatagrams(conn)
+		}
+	}
+}
+
+// readDatagrams reads from conn until it is closed, sending each sender's datagrams to their own scanner
+func (p *producer) readDatagrams(conn net.PacketConn) {
+	senders := map[string]*udpSender{}
+	defer func() {
+		for addr, s := range senders {
+			p.forgetSender(addr, s)
+		}
+		p.Cleanup()
+	}()
+
+	buf := make([]byte, udpMaxDatagram)
+	lastPrune := time.Now()
+	for {
+		// wake up every now and then, even when nobody is talking, so we can forget about quiet senders
+		_ = conn.SetReadDeadline(time.Now().Add(udpSenderTimeout / 5))
+		n, addr, err := conn.ReadFrom(buf)
+		now := time.Now()
+		if now.Sub(lastPrune) > udpSenderTimeout/5 {
+			p.pruneSenders(senders, now)
+			lastPrune = now
+		}
+		if nil != err {
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				continue
+			}
+			p.addDebug("Done with UDP Producer %s: %s", p, err)
+			return
+		}
+
+		key := addr.String()
+		sender, ok := senders[key]
+		if ok {
+			select {
+			case <-sender.done:
+				// their scanner gave up on them, start afresh with this datagram
+				p.forgetSender(key, sender)
+				ok = false
+			default:
+			}
+		}
+		if !ok {
+			sender = p.newUdpSender(addr)
+			senders[key] = sender
+		}
+		sender.lastSeen = now
+
+		datagram := make([]byte, n)
+		copy(datagram, buf[:n])
+		select {
+		case sender.datagrams <- datagram:
+		default:
+			if 0 == sender.dropped {
+				log.Warn().Str("section", p.Name).Str("sender", key).Msg("UDP sender is sending faster than we can read, dropping datagrams")
+			}
+			sender.dropped++
+			atomic.AddUint64(&p.numDroppedDatagrams, 1)
+		}
+	}
+}
+
+// newUdpSender starts a scanner for a sender we have not heard from before
+func (p *producer) newUdpSender(addr net.Addr) *udpSender {
+	p.addDebug("New UDP sender: %s", addr)
+	reader, writer := io.Pipe()
+	sender := &udpSender{
+		source:    p.newSource("udp://" + addr.String()),
+		datagrams: make(chan []byte, udpSenderBuffer),
+		done:      make(chan struct{}),
+	}
+
+	go func() {
+		defer close(sender.done)
+		for datagram := range sender.datagrams {
+			if _, err := writer.Write(datagram); nil != err {
+				break
+			}
+		}
+		_ = writer.Close()
+		// anything still queued has nowhere to go
+		for range sender.datagrams {
+		}
+	}()
+	go func() {
+		scan := bufio.NewScanner(reader)
+		scan.Split(p.splitter)
+		if err := p.readFromScanner(scan, sender.source); nil != err {
+			p.addError(err)
+		}
+		// make sure nobody is left waiting on us
+		_ = reader.Close()
+	}()
+	return sender
+}
+
+// pruneSenders forgets senders we have not heard from in a while
+func (p *producer) pruneSenders(senders map[string]*udpSender, now time.Time) {
+	for addr, s := range senders {
+		if now.Sub(s.lastSeen) > udpSenderTimeout {
+			p.addDebug("UDP sender %s has gone quiet", addr)
+			p.forgetSender(addr, s)
+			delete(senders, addr)
+		}
+	}
+}
+
+// forgetSender stops queueing datagrams for a sender, their scanner gets whatever is already queued
+func (p *producer) forgetSender(addr string, s *udpSender) {
+	close(s.datagrams)
+	if s.dropped > 0 {
+		log.Warn().Str("section", p.Name).Str("sender", addr).Uint64("dropped", s.dropped).Msg("Dropped datagrams from UDP sender")
+	}
+}
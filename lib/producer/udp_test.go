@@ -0,0 +1,147 @@
+package producer
+
+import (
+	"bytes"
+	"net"
+	"plane.watch/lib/tracker"
+	"testing"
+	"time"
+)
+
+func sendDatagrams(t *testing.T, to net.Addr, datagrams ...[]byte) net.Conn {
+	conn, err := net.Dial("udp", to.String())
+	if nil != err {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+	for _, d := range datagrams {
+		if _, err = conn.Write(d); nil != err {
+			t.Fatal(err)
+		}
+		// give each datagram its own read
+		time.Sleep(10 * time.Millisecond)
+	}
+	return conn
+}
+
+func nextFrameEvent(t *testing.T, p *producer) *tracker.FrameEvent {
+	select {
+	case e := <-p.out:
+		fe, ok := e.(*tracker.FrameEvent)
+		if !ok {
+			t.Fatalf("Expected a frame event, got %#v", e)
+		}
+		return fe
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for a frame")
+	}
+	return nil
+}
+
+func TestProducer_readDatagramsBeast(t *testing.T) {
+	p := New(WithType(Beast), WithSourceTag("udp-test"))
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if nil != err {
+		t.Fatal(err)
+	}
+	go p.readDatagrams(conn)
+
+	// a frame split across two datagrams
+	split := sendDatagrams(t, conn.LocalAddr(), beastModeSLongDoubleEsc[:7], beastModeSLongDoubleEsc[7:])
+	fe := nextFrameEvent(t, p)
+	if !bytes.Equal(beastModeSLongDoubleRemoved, fe.Frame().Raw()) {
+		t.Errorf("Incorrect frame %X, expected %X", fe.Frame().Raw(), beastModeSLongDoubleRemoved)
+	}
+	if want := "udp://" + split.LocalAddr().String(); want != fe.Source().OriginIdentifier {
+		t.Errorf("Incorrect source %s, expected %s", fe.Source().OriginIdentifier, want)
+	}
+	if "udp-test" != fe.Source().Tag {
+		t.Errorf("Incorrect tag %s", fe.Source().Tag)
+	}
+
+	// two frames in the one datagram, from someone else
+	combined := sendDatagrams(t, conn.LocalAddr(), append(append([]byte{}, beastModeSShort...), beastModeSLong...))
+	for _, want := range [][]byte{beastModeSShort, beastModeSLong} {
+		fe = nextFrameEvent(t, p)
+		if !bytes.Equal(want, fe.Frame().Raw()) {
+			t.Errorf("Incorrect frame %X, expected %X", fe.Frame().Raw(), want)
+		}
+		if want := "udp://" + combined.LocalAddr().String(); want != fe.Source().OriginIdentifier {
+			t.Errorf("Incorrect source %s, expected %s", fe.Source().OriginIdentifier, want)
+		}
+	}
+
+	_ = conn.Close()
+	select {
+	case _, ok := <-p.out:
+		if ok {
+			t.Error("Expected no more events once closed")
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("Expected our events to be closed")
+	}
+}
+
+func TestProducer_readDatagramsAvr(t *testing.T) {
+	p := New(WithType(Avr))
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	go p.readDatagrams(conn)
+
+	sendDatagrams(t, conn.LocalAddr(), []byte("*8D4840D6202CC371C32CE0576098;\n*5D7C"), []byte("49F828E943;\n"))
+	for _, want := range []string{"8D4840D6202CC371C32CE0576098", "5D7C49F828E943"} {
+		fe := nextFrameEvent(t, p)
+		if want != string(fe.Frame().Raw()) {
+			t.Errorf("Incorrect frame %s, expected %s", fe.Frame().Raw(), want)
+		}
+	}
+}
+
+func TestProducer_readDatagramsStuckSender(t *testing.T) {
+	p := New(WithType(Avr))
+	// the scanner of whoever sends "stuck" stops reading until we let it go
+	release := make(chan struct{})
+	splitter := p.splitter
+	p.splitter = func(data []byte, atEOF bool) (int, []byte, error) {
+		if bytes.HasPrefix(data, []byte("stuck")) {
+			<-release
+			return len(data), nil, nil
+		}
+		return splitter(data, atEOF)
+	}
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	defer close(release)
+	go p.readDatagrams(conn)
+
+	stuck := sendDatagrams(t, conn.LocalAddr(), []byte("stuck\n"))
+	for i := 0; i < 2*udpSenderBuffer; i++ {
+		if _, err = stuck.Write([]byte("*8D4840D6202CC371C32CE0576098;\n")); nil != err {
+			t.Fatal(err)
+		}
+		// not so fast that the socket buffer overflows
+		if 0 == i%16 {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	for deadline := time.Now().Add(2 * time.Second); 0 == p.NumDropped(); {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the stuck sender's datagrams to be dropped")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// someone else still gets through
+	sendDatagrams(t, conn.LocalAddr(), []byte("*5D7C49F828E943;\n"))
+	if fe := nextFrameEvent(t, p); "5D7C49F828E943" != string(fe.Frame().Raw()) {
+		t.Errorf("Incorrect frame %s", fe.Frame().Raw())
+	}
+}
@@ -1,11 +1,13 @@
 package rabbitmq
 
 import (
+	"crypto/tls"
 	"fmt"
 	"github.com/rs/zerolog/log"
 	"github.com/streadway/amqp"
 	"net"
 	"net/url"
+	"plane.watch/lib/tlsconfig"
 	"strings"
 	"time"
 )
@@ -16,15 +18,20 @@ const rabbitmqRetryIntervalMax = 120
 
 type RabbitMQ struct {
 	uri          string
+	tlsConfig    *tls.Config
 	conn         *amqp.Connection
 	channel      *amqp.Channel
 	disconnected chan *amqp.Error
 	connected    bool
 }
 
+// ConfigSSL turns on amqps. The key and cert are our client certificate, if the server wants one. With a
+// CaCertFile we only trust servers with certificates signed by it, otherwise the system roots are used
 type ConfigSSL struct {
+	Enabled        bool   `json:"enabled"`
 	PrivateKeyFile string `json:"private_key_file"`
 	CertChainFile  string `json:"cert_chain_file"`
+	CaCertFile     string `json:"ca_cert_file"`
 }
 
 type Config struct {
@@ -42,7 +49,20 @@ func (c Config) String() string {
 
 func New(cfg Config) *RabbitMQ {
 	uri := createRabbitmqUri(cfg)
-	return &RabbitMQ{uri: uri}
+	r := &RabbitMQ{uri: uri}
+	if cfg.Ssl.Enabled {
+		var err error
+		if r.tlsConfig, err = cfg.Ssl.TlsConfig(); nil != err {
+			// we will not be able to connect, but we will keep telling you why
+			log.Error().Err(err).Msg("Failed to setup TLS for RabbitMQ")
+		}
+	}
+	return r
+}
+
+// TlsConfig is the TLS setup for connecting to the server, with our client certificate and pinned CA if we have them
+func (c ConfigSSL) TlsConfig() (*tls.Config, error) {
+	return tlsconfig.Client("", c.CertChainFile, c.PrivateKeyFile, c.CaCertFile)
 }
 
 func (r *RabbitMQ) Connect(connected chan bool) {
@@ -161,7 +181,15 @@ func (r *RabbitMQ) connect(uri string, done chan bool) {
 	var err error
 
 	log.Debug().Msgf("Dialing %q", uri)
-	r.conn, err = amqp.Dial(uri)
+	if strings.HasPrefix(uri, "amqps://") {
+		if nil == r.tlsConfig {
+			log.Error().Msg("Cannot connect to RabbitMQ without a valid TLS setup")
+			return
+		}
+		r.conn, err = amqp.DialTLS(uri, r.tlsConfig)
+	} else {
+		r.conn, err = amqp.Dial(uri)
+	}
 	if err != nil {
 		log.Printf("Dial: %s", err)
 		return
@@ -182,8 +210,12 @@ func (r *RabbitMQ) connect(uri string, done chan bool) {
 }
 
 func createRabbitmqUri(cfg Config) string {
+	scheme := "amqp"
+	if cfg.Ssl.Enabled {
+		scheme = "amqps"
+	}
 	u := url.URL{
-		Scheme: "amqp",
+		Scheme: scheme,
 		Host:   net.JoinHostPort(cfg.Host, cfg.Port),
 		Path:   strings.TrimLeft(fmt.Sprintf("/%s", cfg.Vhost), "/"),
 		User:   url.UserPassword(cfg.User, cfg.Password),
@@ -0,0 +1,392 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/rs/zerolog/log"
+	"math"
+	"net"
+	"net/http"
+	"plane.watch/lib/tracker"
+	"plane.watch/lib/tracker/mode_s"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const aircraftJsonVersion = "plane.watch"
+
+type (
+	// AircraftJsonSink serves the planes we are tracking as aircraft.json and receiver.json, in the same
+	// format as dump1090-fa and readsb. Map front ends like tar1090 can be pointed straight at it
+	AircraftJsonSink struct {
+		Config
+
+		listener net.Listener
+		server   *http.Server
+
+		planes      sync.Map
+		numMessages uint64
+	}
+
+	aircraftJson struct {
+		Now      float64             `json:"now"`
+		Messages uint64              `json:"messages"`
+		Aircraft []aircraftJsonPlane `json:"aircraft"`
+	}
+
+	aircraftJsonPlane struct {
+		Hex            string      `json:"hex"`
+		Type           string      `json:"type"`
+		Flight         string      `json:"flight,omitempty"`
+		AltBaro        interface{} `json:"alt_baro,omitempty"`
+		Gs             *float64    `json:"gs,omitempty"`
+		Ias            *int        `json:"ias,omitempty"`
+		Tas            *int        `json:"tas,omitempty"`
+		Mach           *float64    `json:"mach,omitempty"`
+		Track          *float64    `json:"track,omitempty"`
+		TrackRate      *float64    `json:"track_rate,omitempty"`
+		Roll           *float64    `json:"roll,omitempty"`
+		MagHeading     *float64    `json:"mag_heading,omitempty"`
+		BaroRate       *int        `json:"baro_rate,omitempty"`
+		GeomRate       *int        `json:"geom_rate,omitempty"`
+		Squawk         string      `json:"squawk,omitempty"`
+		Emergency      string      `json:"emergency,omitempty"`
+		Category       string      `json:"category,omitempty"`
+		NavQnh         *float64    `json:"nav_qnh,omitempty"`
+		NavAltitudeMcp *int32      `json:"nav_altitude_mcp,omitempty"`
+		NavAltitudeFms *int32      `json:"nav_altitude_fms,omitempty"`
+		NavHeading     *float64    `json:"nav_heading,omitempty"`
+		NavModes       []string    `json:"nav_modes,omitempty"`
+		Lat            *float64    `json:"lat,omitempty"`
+		Lon            *float64    `json:"lon,omitempty"`
+		Nic            *byte       `json:"nic,omitempty"`
+		Rc             *float64    `json:"rc,omitempty"`
+		SeenPos        *float64    `json:"seen_pos,omitempty"`
+		Version        *byte       `json:"version,omitempty"`
+		NacP           *byte       `json:"nac_p,omitempty"`
+		NacV           *byte       `json:"nac_v,omitempty"`
+		Sil            *byte       `json:"sil,omitempty"`
+		SilType        string      `json:"sil_type,omitempty"`
+		Messages       uint64      `json:"messages"`
+		Seen           float64     `json:"seen"`
+		Rssi           *float64    `json:"rssi,omitempty"`
+	}
+
+	receiverJson struct {
+		Version string   `json:"version"`
+		Refresh int      `json:"refresh"`
+		History int      `json:"history"`
+		Lat     *float64 `json:"lat,omitempty"`
+		Lon     *float64 `json:"lon,omitempty"`
+
+		// only when we are serving globe tiles
+		BinCraft               bool                            `json:"binCraft,omitempty"`
+		GlobeIndexGrid         int                             `json:"globeIndexGrid,omitempty"`
+		GlobeIndexSpecialTiles []tracker.GlobeIndexSpecialTile `json:"globeIndexSpecialTiles,omitempty"`
+	}
+)
+
+// emergencies maps our emergency states to the dump1090/readsb names
+var emergencies = map[string]string{
+	"No emergency":                        "none",
+	"General emergency (squawk 7700)":     "general",
+	"Lifeguard/Medical":                   "lifeguard",
+	"Minimum fuel":                        "minfuel",
+	"No communications (squawk 7600)":     "nordo",
+	"Unlawful interference (squawk 7500)": "unlawful",
+	"Downed Aircraft":                     "downed",
+	"Reserved":                            "reserved",
+}
+
+// WithReceiverLocation is where the receiver is, for receiver.json
+func WithReceiverLocation(lat, lon float64) Option {
+	return func(config *Config) {
+		config.receiverLat = &lat
+		config.receiverLon = &lon
+	}
+}
+
+// NewAircraftJsonSink starts serving aircraft.json on the host/port given by WithHost()
+func NewAircraftJsonSink(opts ...Option) (*AircraftJsonSink, error) {
+	a := &AircraftJsonSink{}
+	for _, opt := range opts {
+		opt(&a.Config)
+	}
+
+	var err error
+	if a.listener, err = net.Listen("tcp", net.JoinHostPort(a.host, a.port)); nil != err {
+		return nil, err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/data/aircraft.json", a.serveAircraft)
+	mux.HandleFunc("/aircraft.json", a.serveAircraft)
+	mux.HandleFunc("/data/receiver.json", a.serveReceiver)
+	mux.HandleFunc("/receiver.json", a.serveReceiver)
+	if a.globeIndex {
+		mux.HandleFunc("/data/", a.serveGlobe)
+	}
+	a.server = &http.Server{Handler: mux}
+
+	a.waiter.Add(1)
+	go func() {
+		defer a.waiter.Done()
+		log.Info().Str("addr", a.listener.Addr().String()).Msg("Serving aircraft.json")
+		if err := a.server.Serve(a.listener); nil != err && http.ErrServerClosed != err {
+			log.Error().Err(err).Msg("aircraft.json server stopped")
+		}
+	}()
+	return a, nil
+}
+
+// Addr is the address we are listening on
+func (a *AircraftJsonSink) Addr() net.Addr {
+	return a.listener.Addr()
+}
+
+func (a *AircraftJsonSink) OnEvent(e tracker.Event) {
+	switch e.(type) {
+	case *tracker.PlaneLocationEvent:
+		ple := e.(*tracker.PlaneLocationEvent)
+		if ple.Removed() {
+			a.planes.Delete(ple.Plane().IcaoIdentifier())
+		} else {
+			a.planes.Store(ple.Plane().IcaoIdentifier(), ple.Plane())
+		}
+	case *tracker.InfoEvent:
+		atomic.StoreUint64(&a.numMessages, e.(*tracker.InfoEvent).NumFrames())
+	}
+}
+
+func (a *AircraftJsonSink) Stop() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_ = a.server.Shutdown(ctx)
+	a.Config.Finish()
+}
+
+func (a *AircraftJsonSink) serveAircraft(w http.ResponseWriter, _ *http.Request) {
+	now := time.Now()
+	out := aircraftJson{
+		Now:      float64(now.UnixNano()) / float64(time.Second),
+		Messages: atomic.LoadUint64(&a.numMessages),
+		Aircraft: make([]aircraftJsonPlane, 0),
+	}
+	a.planes.Range(func(key, value interface{}) bool {
+		out.Aircraft = append(out.Aircraft, newAircraftJsonPlane(value.(*tracker.Plane), now))
+		return true
+	})
+	sort.Slice(out.Aircraft, func(i, j int) bool {
+		return out.Aircraft[i].Hex < out.Aircraft[j].Hex
+	})
+	writeJson(w, out)
+}
+
+func (a *AircraftJsonSink) serveReceiver(w http.ResponseWriter, _ *http.Request) {
+	out := receiverJson{
+		Version: aircraftJsonVersion,
+		Refresh: 1000,
+		Lat:     a.receiverLat,
+		Lon:     a.receiverLon,
+	}
+	if a.globeIndex {
+		def := tracker.GlobeDefinition()
+		out.BinCraft = true
+		out.GlobeIndexGrid = def.GlobeIndexGrid
+		out.GlobeIndexSpecialTiles = def.GlobeIndexSpecialTiles
+	}
+	writeJson(w, out)
+}
+
+func writeJson(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	if err := json.NewEncoder(w).Encode(v); nil != err {
+		log.Error().Err(err).Msg("Failed to send json")
+	}
+}
+
+// newAircraftJsonPlane takes a snapshot of our plane in the dump1090/readsb format
+func newAircraftJsonPlane(p *tracker.Plane, now time.Time) aircraftJsonPlane {
+	ap := aircraftJsonPlane{
+		Hex:      strings.ToLower(p.IcaoIdentifierStr()),
+		Type:     aircraftJsonType(p),
+		Flight:   p.FlightNumber(),
+		Messages: p.MsgCount(),
+		Seen:     roundTo(now.Sub(p.LastSeen()).Seconds(), 1),
+	}
+
+	if p.OnGround() {
+		ap.AltBaro = "ground"
+	} else if p.HasAltitude() {
+		ap.AltBaro = p.Altitude()
+	}
+	if p.HasVelocity() {
+		ap.Gs = floatPtr(roundTo(p.Velocity(), 1))
+	}
+	if p.HasHeading() {
+		ap.Track = floatPtr(roundTo(p.Heading(), 2))
+	}
+	if p.HasVerticalRate() {
+		rate := p.VerticalRate()
+		ap.BaroRate = &rate
+	}
+	if squawk := p.SquawkIdentity(); 0 != squawk {
+		ap.Squawk = fmt.Sprintf("%04d", squawk)
+	}
+	if emergency, ok := emergencies[p.Emergency()]; ok {
+		ap.Emergency = emergency
+	}
+	ap.Category = aircraftJsonCategory(p.AirFrameType())
+
+	if p.HasLocation() {
+		ap.Lat = floatPtr(roundTo(p.Lat(), 6))
+		ap.Lon = floatPtr(roundTo(p.Lon(), 6))
+		if history := p.LocationHistory(); len(history) > 0 {
+			ap.SeenPos = floatPtr(roundTo(now.Sub(history[len(history)-1].TimeStamp()).Seconds(), 1))
+		}
+	}
+
+	// Comm-B
+	if p.HasIndicatedAirSpeed() {
+		ias := p.IndicatedAirSpeed()
+		ap.Ias = &ias
+	}
+	if p.HasTrueAirSpeed() {
+		tas := p.TrueAirSpeed()
+		ap.Tas = &tas
+	}
+	if p.HasMach() {
+		ap.Mach = floatPtr(roundTo(p.Mach(), 3))
+	}
+	if p.HasTrackRate() {
+		ap.TrackRate = floatPtr(roundTo(p.TrackRate(), 2))
+	}
+	if p.HasRollAngle() {
+		ap.Roll = floatPtr(roundTo(p.RollAngle(), 1))
+	}
+	if p.HasMagneticHeading() {
+		ap.MagHeading = floatPtr(roundTo(p.MagneticHeading(), 1))
+	}
+	if p.HasInertialVerticalRate() {
+		rate := p.InertialVerticalRate()
+		ap.GeomRate = &rate
+	}
+	if p.HasBaroSetting() {
+		ap.NavQnh = floatPtr(roundTo(p.BaroSetting(), 1))
+	}
+	if p.HasMcpSelectedAltitude() {
+		alt := p.McpSelectedAltitude()
+		ap.NavAltitudeMcp = &alt
+	}
+	if p.HasFmsSelectedAltitude() {
+		alt := p.FmsSelectedAltitude()
+		ap.NavAltitudeFms = &alt
+	}
+	if p.HasSelectedHeading() {
+		ap.NavHeading = floatPtr(roundTo(p.SelectedHeading(), 1))
+	}
+	ap.NavModes = aircraftJsonNavModes(p)
+
+	// ADS-B quality
+	if p.HasNic() {
+		nic := p.Nic()
+		ap.Nic = &nic
+		if rc := p.ContainmentRadius(); rc > 0 {
+			ap.Rc = floatPtr(roundTo(rc, 0))
+		}
+	}
+	if p.HasAdsbVersion() {
+		version := p.AdsbVersion()
+		ap.Version = &version
+	}
+	if p.HasNacP() {
+		nacP := p.NacP()
+		ap.NacP = &nacP
+	}
+	if p.HasNacV() {
+		nacV := p.NacV()
+		ap.NacV = &nacV
+	}
+	if p.HasSil() {
+		sil := p.Sil()
+		ap.Sil = &sil
+		ap.SilType = "perhour"
+		if p.SilPerSample() {
+			ap.SilType = "persample"
+		}
+	}
+
+	if p.HasSignal() {
+		ap.Rssi = floatPtr(roundTo(p.SignalLast(), 1))
+	}
+	return ap
+}
+
+// aircraftJsonType is where the information about this plane came from, in readsb terms
+func aircraftJsonType(p *tracker.Plane) string {
+	suffix := "_icao"
+	if mode_s.AddressTypeNonIcao == p.AddressType() {
+		suffix = "_other"
+	}
+	switch p.Source() {
+	case mode_s.SourceAdsb:
+		return "adsb" + suffix
+	case mode_s.SourceTisb:
+		return "tisb" + suffix
+	case mode_s.SourceAdsr:
+		return "adsr" + suffix
+	default:
+		return "mode_s"
+	}
+}
+
+// aircraftJsonCategory turns our "type/sub type" (see mode_s.Frame.CategoryType()) into A1, B2 etc
+func aircraftJsonCategory(categoryType string) string {
+	var catType, catSubType int
+	if n, _ := fmt.Sscanf(categoryType, "%d/%d", &catType, &catSubType); 2 != n {
+		return ""
+	}
+	if catType < 0 || catType > 3 || 0 == catSubType {
+		return ""
+	}
+	return fmt.Sprintf("%c%d", 'A'+catType, catSubType)
+}
+
+func aircraftJsonNavModes(p *tracker.Plane) []string {
+	var modes []string
+	if p.HasAutopilotModes() {
+		if p.AutopilotEngaged() {
+			modes = append(modes, "autopilot")
+		}
+		if p.LnavMode() {
+			modes = append(modes, "lnav")
+		}
+	}
+	if p.HasMcpModes() {
+		if p.VnavMode() {
+			modes = append(modes, "vnav")
+		}
+		if p.AltHoldMode() {
+			modes = append(modes, "althold")
+		}
+		if p.ApproachMode() {
+			modes = append(modes, "approach")
+		}
+	}
+	return modes
+}
+
+func floatPtr(f float64) *float64 {
+	return &f
+}
+
+func roundTo(f float64, places int) float64 {
+	shift := math.Pow(10, float64(places))
+	return math.Round(f*shift) / shift
+}
@@ -0,0 +1,191 @@
+package sink
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"plane.watch/lib/tracker"
+	"plane.watch/lib/tracker/mode_s"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// handleFrames feeds encoded frames to a plane, the way the tracker would
+func handleFrames(t *testing.T, p *tracker.Plane, frames ...mode_s.EncodedFrame) {
+	t.Helper()
+	for _, encoded := range frames {
+		frame, err := mode_s.DecodeBytes(encoded.Bytes(), time.Now())
+		if nil != err {
+			t.Fatal(err)
+		}
+		p.HandleModeSFrame(frame, nil, nil)
+	}
+}
+
+func mustEncode(t *testing.T) func(mode_s.EncodedFrame, error) mode_s.EncodedFrame {
+	return func(encoded mode_s.EncodedFrame, err error) mode_s.EncodedFrame {
+		t.Helper()
+		if nil != err {
+			t.Fatal(err)
+		}
+		return encoded
+	}
+}
+
+func TestAircraftJsonSink_serveAircraft(t *testing.T) {
+	encode := mustEncode(t)
+	trk := tracker.NewTracker()
+	defer trk.Finish()
+
+	airborne := trk.GetPlane(0x7C7DAA)
+	handleFrames(t, airborne,
+		encode(mode_s.EncodeExtendedSquitter(0x7C7DAA, mode_s.AdsbIdentification{Category: 3, Callsign: "QFA123"})),
+		encode(mode_s.EncodeExtendedSquitter(0x7C7DAA, mode_s.AdsbAirbornePosition{Altitude: 35000, Lat: -31.95, Lon: 115.94})),
+		encode(mode_s.EncodeExtendedSquitter(0x7C7DAA, mode_s.AdsbAirbornePosition{Altitude: 35000, Lat: -31.95, Lon: 115.94, Odd: true})),
+		encode(mode_s.EncodeExtendedSquitter(0x7C7DAA, mode_s.AdsbAirborneVelocity{Heading: 90, GroundSpeed: 400, VerticalRate: -832, BaroRate: true})),
+		encode(mode_s.EncodeExtendedSquitter(0x7C7DAA, mode_s.AdsbEmergency{Emergency: 1, Squawk: 7700})),
+	)
+	// sea level is an altitude too
+	seaLevel := trk.GetPlane(0x7C0001)
+	handleFrames(t, seaLevel, encode(mode_s.EncodeSurveillanceAltitude(0x7C0001, 0, 0)))
+	// and we know nothing about this one's altitude
+	unknown := trk.GetPlane(0x7C0002)
+	handleFrames(t, unknown, mode_s.EncodeAllCall(0x7C0002, 5))
+	taxiing := trk.GetPlane(0x7C0003)
+	handleFrames(t, taxiing, encode(mode_s.EncodeExtendedSquitter(0x7C0003, mode_s.AdsbSurfacePosition{Speed: 10, Lat: -31.94, Lon: 115.97})))
+
+	a := &AircraftJsonSink{numMessages: 42}
+	for _, p := range []*tracker.Plane{airborne, seaLevel, unknown, taxiing} {
+		a.planes.Store(p.IcaoIdentifier(), p)
+	}
+
+	w := httptest.NewRecorder()
+	a.serveAircraft(w, httptest.NewRequest("GET", "/data/aircraft.json", nil))
+	if "application/json" != w.Header().Get("Content-Type") {
+		t.Errorf("Incorrect content type %s", w.Header().Get("Content-Type"))
+	}
+
+	var got struct {
+		Now      float64                  `json:"now"`
+		Messages uint64                   `json:"messages"`
+		Aircraft []map[string]interface{} `json:"aircraft"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &got); nil != err {
+		t.Fatalf("Invalid json %s: %s", w.Body.String(), err)
+	}
+	if 42 != got.Messages {
+		t.Errorf("Incorrect message count %d", got.Messages)
+	}
+	if now := float64(time.Now().Unix()); got.Now < now-5 || got.Now > now+5 {
+		t.Errorf("now should be in seconds since the epoch, got %f", got.Now)
+	}
+	if 4 != len(got.Aircraft) {
+		t.Fatalf("Expected 4 aircraft, got %d", len(got.Aircraft))
+	}
+
+	// json numbers come back as float64. seen is left out, it depends on how quickly we ran
+	want := []map[string]interface{}{
+		{"hex": "7c0001", "type": "mode_s", "alt_baro": 0.0, "messages": 1.0},
+		{"hex": "7c0002", "type": "mode_s", "messages": 1.0},
+		{"hex": "7c0003", "type": "adsb_icao", "alt_baro": "ground", "gs": 10.0, "nic": 11.0, "rc": 8.0, "nac_p": 11.0, "messages": 1.0},
+		{
+			"hex":       "7c7daa",
+			"type":      "adsb_icao",
+			"flight":    "QFA123  ",
+			"alt_baro":  35000.0,
+			"gs":        400.0,
+			"track":     90.0,
+			"baro_rate": -832.0,
+			"squawk":    "7700",
+			"emergency": "general",
+			"category":  "A3",
+			"lat":       -31.949982,
+			"lon":       115.939984,
+			"nic":       8.0,
+			"rc":        185.0,
+			"seen_pos":  0.0,
+			"nac_p":     8.0,
+			"nac_v":     0.0,
+			"messages":  5.0,
+		},
+	}
+	for i, ac := range got.Aircraft {
+		if _, ok := ac["seen"].(float64); !ok {
+			t.Errorf("%s: expected seen in seconds, got %v", ac["hex"], ac["seen"])
+		}
+		delete(ac, "seen")
+		if !reflect.DeepEqual(want[i], ac) {
+			t.Errorf("Incorrect aircraft\n%v, expected\n%v", ac, want[i])
+		}
+	}
+}
+
+func TestAircraftJsonSink_serveReceiver(t *testing.T) {
+	tests := []struct {
+		name     string
+		lat, lon *float64
+		want     map[string]interface{}
+	}{
+		{
+			name: "no location",
+			want: map[string]interface{}{"version": aircraftJsonVersion, "refresh": 1000.0, "history": 0.0},
+		},
+		{
+			name: "location",
+			lat:  floatPtr(-31.95),
+			lon:  floatPtr(115.86),
+			want: map[string]interface{}{"version": aircraftJsonVersion, "refresh": 1000.0, "history": 0.0, "lat": -31.95, "lon": 115.86},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &AircraftJsonSink{}
+			a.receiverLat, a.receiverLon = tt.lat, tt.lon
+			w := httptest.NewRecorder()
+			a.serveReceiver(w, httptest.NewRequest("GET", "/data/receiver.json", nil))
+			var got map[string]interface{}
+			if err := json.Unmarshal(w.Body.Bytes(), &got); nil != err {
+				t.Fatalf("Invalid json %s: %s", w.Body.String(), err)
+			}
+			if !reflect.DeepEqual(tt.want, got) {
+				t.Errorf("Incorrect receiver.json\n%v, expected\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAircraftJsonSink_serveReceiverGlobe(t *testing.T) {
+	a := &AircraftJsonSink{Config: Config{globeIndex: true}}
+	w := httptest.NewRecorder()
+	a.serveReceiver(w, httptest.NewRequest("GET", "/data/receiver.json", nil))
+	var got receiverJson
+	if err := json.Unmarshal(w.Body.Bytes(), &got); nil != err {
+		t.Fatalf("Invalid json %s: %s", w.Body.String(), err)
+	}
+	def := tracker.GlobeDefinition()
+	if !got.BinCraft || def.GlobeIndexGrid != got.GlobeIndexGrid || !reflect.DeepEqual(def.GlobeIndexSpecialTiles, got.GlobeIndexSpecialTiles) {
+		t.Errorf("Incorrect globe definition in receiver.json: %s", w.Body.String())
+	}
+}
+
+func TestNewAircraftJsonSink_routes(t *testing.T) {
+	a, err := NewAircraftJsonSink(WithHost("127.0.0.1", "0"))
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer a.Stop()
+
+	for _, path := range []string{"/data/aircraft.json", "/aircraft.json", "/data/receiver.json", "/receiver.json"} {
+		resp, err := http.Get("http://" + a.Addr().String() + path)
+		if nil != err {
+			t.Fatal(err)
+		}
+		var got map[string]interface{}
+		err = json.NewDecoder(resp.Body).Decode(&got)
+		_ = resp.Body.Close()
+		if http.StatusOK != resp.StatusCode || nil != err {
+			t.Errorf("%s: status %d, %v", path, resp.StatusCode, err)
+		}
+	}
+}
@@ -4,6 +4,9 @@ import (
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"os"
+	"plane.watch/lib/export"
+	"plane.watch/lib/tracker"
+	"strings"
 	"sync"
 )
 
@@ -11,6 +14,8 @@ type (
 	Config struct {
 		host, port string
 		secure     bool
+		// tlsCertFile and tlsKeyFile are our client certificate, tlsCaFile is the CA we trust for the server
+		tlsCertFile, tlsKeyFile, tlsCaFile string
 
 		vhost      string
 		user, pass string
@@ -21,6 +26,11 @@ type (
 		logLocation       bool
 		sourceTag         string
 		messageTtlSeconds int
+
+		receiverLat, receiverLon *float64
+		globeIndex               bool
+
+		dedupedFramesOnly bool
 	}
 	Option func(*Config)
 )
@@ -72,3 +82,101 @@ func WithLogFile(file string) Option {
 func (c *Config) Finish() {
 	c.waiter.Wait()
 }
+
+// newExportPlaneLocation takes a snapshot of the plane in a location event, in the format we send to our consumers
+func newExportPlaneLocation(le *tracker.PlaneLocationEvent, sourceTag string) export.PlaneLocation {
+	plane := le.Plane()
+	eventStruct := export.PlaneLocation{
+		New:           le.New(),
+		Removed:       le.Removed(),
+		Icao:          plane.IcaoIdentifierStr(),
+		Lat:           plane.Lat(),
+		Lon:           plane.Lon(),
+		Heading:       plane.Heading(),
+		Altitude:      int(plane.Altitude()),
+		VerticalRate:  plane.VerticalRate(),
+		AltitudeUnits: plane.AltitudeUnits(),
+		Velocity:      plane.Velocity(),
+		FlightNumber:  strings.TrimSpace(plane.FlightNumber()),
+		FlightStatus:  plane.FlightStatus(),
+		OnGround:      plane.OnGround(),
+		Airframe:      plane.AirFrame(),
+		AirframeType:  plane.AirFrameType(),
+		Squawk:        plane.SquawkIdentityStr(),
+		Special:       plane.Special(),
+
+		HasLocation:     plane.HasLocation(),
+		HasHeading:      plane.HasHeading(),
+		HasVerticalRate: plane.HasVerticalRate(),
+		HasVelocity:     plane.HasVelocity(),
+		SourceTag:       sourceTag,
+		AddressType:     plane.AddressTypeStr(),
+		Source:          plane.SourceStr(),
+		TileLocation:    plane.GridTileLocation(),
+		LastMsg:         plane.LastSeen().UTC(),
+		TrackedSince:    plane.TrackedSince().UTC(),
+
+		McpSelectedAltitude:     int(plane.McpSelectedAltitude()),
+		FmsSelectedAltitude:     int(plane.FmsSelectedAltitude()),
+		BaroSetting:             plane.BaroSetting(),
+		RollAngle:               plane.RollAngle(),
+		TrueTrack:               plane.TrueTrack(),
+		TrackRate:               plane.TrackRate(),
+		GroundSpeed:             plane.GroundSpeed(),
+		TrueAirSpeed:            plane.TrueAirSpeed(),
+		MagneticHeading:         plane.MagneticHeading(),
+		IndicatedAirSpeed:       plane.IndicatedAirSpeed(),
+		Mach:                    plane.Mach(),
+		BaroVerticalRate:        plane.BaroVerticalRate(),
+		InertialVerticalRate:    plane.InertialVerticalRate(),
+		HasMcpSelectedAltitude:  plane.HasMcpSelectedAltitude(),
+		HasFmsSelectedAltitude:  plane.HasFmsSelectedAltitude(),
+		HasBaroSetting:          plane.HasBaroSetting(),
+		HasRollAngle:            plane.HasRollAngle(),
+		HasTrueTrack:            plane.HasTrueTrack(),
+		HasTrackRate:            plane.HasTrackRate(),
+		HasGroundSpeed:          plane.HasGroundSpeed(),
+		HasTrueAirSpeed:         plane.HasTrueAirSpeed(),
+		HasMagneticHeading:      plane.HasMagneticHeading(),
+		HasIndicatedAirSpeed:    plane.HasIndicatedAirSpeed(),
+		HasMach:                 plane.HasMach(),
+		HasBaroVerticalRate:     plane.HasBaroVerticalRate(),
+		HasInertialVerticalRate: plane.HasInertialVerticalRate(),
+
+		SelectedHeading:    plane.SelectedHeading(),
+		HasSelectedHeading: plane.HasSelectedHeading(),
+		VnavMode:           plane.VnavMode(),
+		AltHoldMode:        plane.AltHoldMode(),
+		ApproachMode:       plane.ApproachMode(),
+		HasMcpModes:        plane.HasMcpModes(),
+		AutopilotEngaged:   plane.AutopilotEngaged(),
+		LnavMode:           plane.LnavMode(),
+		HasAutopilotModes:  plane.HasAutopilotModes(),
+
+		AdsbVersion:       plane.AdsbVersion(),
+		HasAdsbVersion:    plane.HasAdsbVersion(),
+		Nic:               plane.Nic(),
+		ContainmentRadius: plane.ContainmentRadius(),
+		HasNic:            plane.HasNic(),
+		NacP:              plane.NacP(),
+		HasNacP:           plane.HasNacP(),
+		NacV:              plane.NacV(),
+		HasNacV:           plane.HasNacV(),
+		Sil:               plane.Sil(),
+		SilPerSample:      plane.SilPerSample(),
+		HasSil:            plane.HasSil(),
+
+		SignalLast:    plane.SignalLast(),
+		SignalMin:     plane.SignalMin(),
+		SignalMax:     plane.SignalMax(),
+		SignalAverage: plane.SignalAverage(),
+		HasSignal:     plane.HasSignal(),
+
+		HasResolutionAdvisory: plane.HasResolutionAdvisory(),
+	}
+	if eventStruct.HasResolutionAdvisory {
+		eventStruct.ResolutionAdvisory = plane.ResolutionAdvisory().String()
+		eventStruct.ResolutionAdvisoryTime = plane.ResolutionAdvisoryTime().UTC()
+	}
+	return eventStruct
+}
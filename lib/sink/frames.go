@@ -0,0 +1,133 @@
+package sink
+
+import (
+	"encoding/hex"
+	"net"
+	"plane.watch/lib/tracker"
+	"plane.watch/lib/tracker/beast"
+	"plane.watch/lib/tracker/mode_s"
+)
+
+const (
+	FrameFormatBeast = "beast"
+	FrameFormatAvr   = "avr"
+)
+
+type (
+	// FrameSink re-serves the frames we receive to anyone that connects, as BEAST binary (like dump1090's
+	// --net-bo-port) or AVR text (--net-ro-port). With WithDedupedFramesOnly() clients only get the frames
+	// that made it through the dedupe.Filter middleware, a clean merged feed of all our receivers
+	FrameSink struct {
+		Config
+		format string
+		server *tcpServer
+	}
+)
+
+// WithDedupedFramesOnly only sends frames that made it through deduplication, instead of every frame
+func WithDedupedFramesOnly() Option {
+	return func(config *Config) {
+		config.dedupedFramesOnly = true
+	}
+}
+
+// NewBeastFrameSink starts serving BEAST on the host/port given by WithHost()
+func NewBeastFrameSink(opts ...Option) (*FrameSink, error) {
+	return newFrameSink(FrameFormatBeast, opts...)
+}
+
+// NewAvrFrameSink starts serving AVR on the host/port given by WithHost()
+func NewAvrFrameSink(opts ...Option) (*FrameSink, error) {
+	return newFrameSink(FrameFormatAvr, opts...)
+}
+
+func newFrameSink(format string, opts ...Option) (*FrameSink, error) {
+	f := &FrameSink{format: format}
+	for _, opt := range opts {
+		opt(&f.Config)
+	}
+
+	var err error
+	if f.server, err = newTcpServer(format, f.host, f.port, &f.waiter); nil != err {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Addr is the address we are listening on
+func (f *FrameSink) Addr() net.Addr {
+	return f.server.listener.Addr()
+}
+
+func (f *FrameSink) OnEvent(e tracker.Event) {
+	var frame tracker.Frame
+	switch e.(type) {
+	case *tracker.FrameEvent:
+		if f.dedupedFramesOnly {
+			return
+		}
+		frame = e.(*tracker.FrameEvent).Frame()
+	case *tracker.DedupedFrameEvent:
+		if !f.dedupedFramesOnly {
+			return
+		}
+		frame = e.(*tracker.DedupedFrameEvent).Frame()
+	default:
+		return
+	}
+
+	var out []byte
+	if FrameFormatBeast == f.format {
+		out = beastBytes(frame)
+	} else {
+		out = avrBytes(frame)
+	}
+	if nil != out {
+		f.server.broadcast(out)
+	}
+}
+
+func (f *FrameSink) Stop() {
+	f.server.close()
+	f.Config.Finish()
+}
+
+// beastBytes is our frame in BEAST binary format, ready for the wire. AVR frames do not have a signal level
+// or timestamp, so they get zeros (as dump1090 does)
+func beastBytes(frame tracker.Frame) []byte {
+	switch frame.(type) {
+	case *beast.Frame:
+		bf := frame.(*beast.Frame)
+		if bf.IsRadarcapeStatus() {
+			// the status of one receiver means nothing in a merged feed
+			return nil
+		}
+		return bf.Escaped()
+	case *mode_s.Frame:
+		// the tracker may be decoding (and correcting) this frame right now, so we send it as we received it
+		message, err := hex.DecodeString(frame.(*mode_s.Frame).ReceivedHex())
+		if nil != err {
+			return nil
+		}
+		if len(message) != 7 && len(message) != 14 {
+			return nil
+		}
+		return mode_s.EncodedFrame(message).Beast(0, 0)
+	}
+	return nil
+}
+
+// avrBytes is our frame as an AVR line
+func avrBytes(frame tracker.Frame) []byte {
+	var avr string
+	switch frame.(type) {
+	case *beast.Frame:
+		avr = frame.(*beast.Frame).Avr()
+	case *mode_s.Frame:
+		avr = "*" + frame.(*mode_s.Frame).ReceivedHex() + ";"
+	}
+	if "" == avr {
+		return nil
+	}
+	return []byte(avr + "\n")
+}
@@ -0,0 +1,104 @@
+package sink
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net"
+	"plane.watch/lib/tracker"
+	"plane.watch/lib/tracker/beast"
+	"plane.watch/lib/tracker/mode_s"
+	"testing"
+	"time"
+)
+
+// beastNeedsEscaping has a 0x1A in its timestamp
+var beastNeedsEscaping = []byte{0x1a, 0x33, 0x22, 0x1b, 0x55, 0xe4, 0x1a, 0xa2, 0x2d, 0x8d, 0x7c, 0x49, 0xf8, 0xe1, 0x1e, 0x2f, 0x00, 0x00, 0x00, 0x00, 0xee, 0xcc, 0x47}
+
+func connectFrameSink(t *testing.T, f *FrameSink) net.Conn {
+	conn, err := net.Dial("tcp", f.Addr().String())
+	if nil != err {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+	for 0 == f.server.numClients() {
+		time.Sleep(time.Millisecond)
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	return conn
+}
+
+func TestFrameSink_Beast(t *testing.T) {
+	f, err := NewBeastFrameSink(WithHost("127.0.0.1", "0"))
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer f.Stop()
+	conn := connectFrameSink(t, f)
+
+	avr := mode_s.NewFrame("*5D7C49F828E943;", time.Now())
+	f.OnEvent(tracker.NewFrameEvent(beast.NewFrame(beastNeedsEscaping, false), nil))
+	// deduped frames are not for us
+	f.OnEvent(tracker.NewDedupedFrameEvent(beast.NewFrame(beastNeedsEscaping, false), nil))
+	f.OnEvent(tracker.NewFrameEvent(avr, nil))
+
+	want := []byte{0x1a, 0x33, 0x22, 0x1b, 0x55, 0xe4, 0x1a, 0x1a, 0xa2, 0x2d, 0x8d, 0x7c, 0x49, 0xf8, 0xe1, 0x1e, 0x2f, 0x00, 0x00, 0x00, 0x00, 0xee, 0xcc, 0x47,
+		0x1a, 0x32, 0, 0, 0, 0, 0, 0, 0, 0x5D, 0x7C, 0x49, 0xF8, 0x28, 0xE9, 0x43}
+	got := make([]byte, len(want))
+	if _, err = io.ReadFull(conn, got); nil != err {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(want, got) {
+		t.Errorf("Incorrect BEAST\n%X, expected\n%X", got, want)
+	}
+}
+
+func TestFrameSink_AvrDeduped(t *testing.T) {
+	f, err := NewAvrFrameSink(WithHost("127.0.0.1", "0"), WithDedupedFramesOnly())
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer f.Stop()
+	conn := connectFrameSink(t, f)
+
+	f.OnEvent(tracker.NewFrameEvent(mode_s.NewFrame("*8D4840D6202CC371C32CE0576098;", time.Now()), nil))
+	f.OnEvent(tracker.NewDedupedFrameEvent(beast.NewFrame(beastNeedsEscaping, false), nil))
+	f.OnEvent(tracker.NewDedupedFrameEvent(mode_s.NewFrame("@0000000000015D7C49F828E943;", time.Now()), nil))
+
+	reader := bufio.NewReader(conn)
+	for _, want := range []string{"*8D7C49F8E11E2F00000000EECC47;\n", "*5D7C49F828E943;\n"} {
+		line, err := reader.ReadString('\n')
+		if nil != err {
+			t.Fatal(err)
+		}
+		if want != line {
+			t.Errorf("Incorrect AVR %q, expected %q", line, want)
+		}
+	}
+}
+
+// the tracker decodes frames while the sinks are sending them, make sure the sink is not upset by that (run with -race)
+func TestFrameSink_WhileDecoding(t *testing.T) {
+	// one bit is wrong, decoding corrects it
+	const received = "8D40621D58C382D690C8AC2863A6"
+	for i := 0; i < 100; i++ {
+		frame := mode_s.NewFrame("*"+received+";", time.Now())
+		done := make(chan struct{})
+		go func() {
+			_, _ = frame.DecodeWithCorrection(mode_s.CorrectionSingleBit)
+			close(done)
+		}()
+		avr := avrBytes(frame)
+		b := beastBytes(frame)
+		<-done
+
+		if "*"+received+";\n" != string(avr) {
+			t.Fatalf("Expected the frame as we received it, got %s", avr)
+		}
+		if want := beast.NewFrame(b, false).Avr(); "*"+received+";" != want {
+			t.Fatalf("Expected the frame as we received it, got %s", want)
+		}
+	}
+}
@@ -0,0 +1,323 @@
+package sink
+
+import (
+	"encoding/binary"
+	"math"
+	"net/http"
+	"plane.watch/lib/tracker"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// binCraftStride is the size of the header and of each aircraft in a binCraft file
+	binCraftStride = 112
+	// binCraftVersion is the readsb binCraft layout we write
+	binCraftVersion = 20220916
+)
+
+type (
+	// globeJson is a readsb globe_XXXX.json, the aircraft in a single globe tile
+	globeJson struct {
+		Now                  float64             `json:"now"`
+		Messages             uint64              `json:"messages"`
+		GlobalAcCountWithPos int                 `json:"global_ac_count_withpos"`
+		GlobeIndex           int                 `json:"globeIndex"`
+		South                float64             `json:"south"`
+		West                 float64             `json:"west"`
+		North                float64             `json:"north"`
+		East                 float64             `json:"east"`
+		Aircraft             []aircraftJsonPlane `json:"aircraft"`
+	}
+
+	globePlane struct {
+		plane *tracker.Plane
+		json  aircraftJsonPlane
+	}
+)
+
+// binCraftAddrTypes maps our readsb style type (see aircraftJsonType()) to the binCraft addrtype
+var binCraftAddrTypes = map[string]byte{
+	"adsb_icao":  0,
+	"adsr_icao":  2,
+	"tisb_icao":  3,
+	"mode_s":     7,
+	"adsb_other": 8,
+	"adsr_other": 9,
+	"tisb_other": 11,
+}
+
+// binCraftEmergencies is the readsb emergency enum
+var binCraftEmergencies = map[string]byte{
+	"none":      0,
+	"general":   1,
+	"lifeguard": 2,
+	"minfuel":   3,
+	"nordo":     4,
+	"unlawful":  5,
+	"downed":    6,
+	"reserved":  7,
+}
+
+// binCraftSilTypes are readsb's sil_type values, 0 is invalid (no SIL)
+var binCraftSilTypes = map[string]byte{
+	"unknown":   1,
+	"persample": 2,
+	"perhour":   3,
+}
+
+// WithGlobeIndex also serves the readsb globe tiles (/data/globe_XXXX.json and /data/globe_XXXX.binCraft),
+// so a tar1090 "globe" view can be pointed at the aircraft.json sink
+func WithGlobeIndex() Option {
+	return func(config *Config) {
+		config.globeIndex = true
+	}
+}
+
+// serveGlobe serves /data/globe_XXXX.json and /data/globe_XXXX.binCraft
+func (a *AircraftJsonSink) serveGlobe(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.URL.Path, "/data/globe_") {
+		http.NotFound(w, r)
+		return
+	}
+	name := strings.TrimPrefix(r.URL.Path, "/data/globe_")
+	binCraft := strings.HasSuffix(name, ".binCraft")
+	name = strings.TrimSuffix(strings.TrimSuffix(name, ".binCraft"), ".json")
+	index, err := strconv.Atoi(name)
+	if nil != err {
+		http.NotFound(w, r)
+		return
+	}
+	bounds, ok := tracker.GlobeTileBounds(index)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	now := time.Now()
+	var withPos int
+	planes := make([]globePlane, 0)
+	a.planes.Range(func(key, value interface{}) bool {
+		p := value.(*tracker.Plane)
+		if !p.HasLocation() {
+			return true
+		}
+		withPos++
+		if tracker.GlobeIndex(p.Lat(), p.Lon()) == index {
+			planes = append(planes, globePlane{plane: p, json: newAircraftJsonPlane(p, now)})
+		}
+		return true
+	})
+	sort.Slice(planes, func(i, j int) bool {
+		return planes[i].json.Hex < planes[j].json.Hex
+	})
+
+	if binCraft {
+		a.writeBinCraft(w, now, withPos, index, bounds, planes)
+		return
+	}
+
+	out := globeJson{
+		Now:                  float64(now.UnixNano()) / float64(time.Second),
+		Messages:             atomic.LoadUint64(&a.numMessages),
+		GlobalAcCountWithPos: withPos,
+		GlobeIndex:           index,
+		South:                bounds.South,
+		West:                 bounds.West,
+		North:                bounds.North,
+		East:                 bounds.East,
+		Aircraft:             make([]aircraftJsonPlane, 0, len(planes)),
+	}
+	for _, gp := range planes {
+		out.Aircraft = append(out.Aircraft, gp.json)
+	}
+	writeJson(w, out)
+}
+
+// writeBinCraft sends a globe tile in readsb's binary format, a header followed by a fixed size record per aircraft
+func (a *AircraftJsonSink) writeBinCraft(w http.ResponseWriter, now time.Time, withPos, index int, bounds tracker.GlobeIndexSpecialTile, planes []globePlane) {
+	buf := make([]byte, binCraftStride*(len(planes)+1))
+	le := binary.LittleEndian
+
+	le.PutUint64(buf[0:], uint64(now.UnixNano()/int64(time.Millisecond)))
+	le.PutUint32(buf[8:], binCraftStride)
+	le.PutUint32(buf[12:], uint32(withPos))
+	le.PutUint32(buf[16:], uint32(index))
+	le.PutUint16(buf[20:], uint16(int16(bounds.South)))
+	le.PutUint16(buf[22:], uint16(int16(bounds.West)))
+	le.PutUint16(buf[24:], uint16(int16(bounds.North)))
+	le.PutUint16(buf[26:], uint16(int16(bounds.East)))
+	le.PutUint32(buf[28:], uint32(atomic.LoadUint64(&a.numMessages)))
+	if nil != a.receiverLat && nil != a.receiverLon {
+		le.PutUint32(buf[32:], uint32(int32(*a.receiverLat*1e6)))
+		le.PutUint32(buf[36:], uint32(int32(*a.receiverLon*1e6)))
+	}
+	le.PutUint32(buf[40:], binCraftVersion)
+
+	for i, gp := range planes {
+		encodeBinCraftPlane(buf[binCraftStride*(i+1):binCraftStride*(i+2)], gp.plane, gp.json)
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	_, _ = w.Write(buf)
+}
+
+// encodeBinCraftPlane fills in a single binCraft aircraft record from our aircraft.json snapshot
+func encodeBinCraftPlane(b []byte, p *tracker.Plane, ap aircraftJsonPlane) {
+	le := binary.LittleEndian
+	putInt16 := func(offset int, f float64) {
+		le.PutUint16(b[offset:], uint16(int16(math.Round(f))))
+	}
+	putUint16 := func(offset int, f float64) {
+		le.PutUint16(b[offset:], uint16(math.Round(f)))
+	}
+	var valid [5]byte
+
+	le.PutUint32(b[0:], p.IcaoIdentifier())
+	putUint16(6, ap.Seen*10)
+	if nil != ap.Lat && nil != ap.Lon {
+		if nil != ap.SeenPos {
+			putUint16(4, *ap.SeenPos*10)
+		}
+		le.PutUint32(b[8:], uint32(int32(math.Round(*ap.Lon*1e6))))
+		le.PutUint32(b[12:], uint32(int32(math.Round(*ap.Lat*1e6))))
+		valid[0] |= 64
+	}
+	if nil != ap.BaroRate {
+		putInt16(16, float64(*ap.BaroRate)/8)
+		valid[2] |= 1
+	}
+	if nil != ap.GeomRate {
+		putInt16(18, float64(*ap.GeomRate)/8)
+		valid[2] |= 2
+	}
+	if alt, ok := ap.AltBaro.(int32); ok {
+		putInt16(20, float64(alt)/25)
+		valid[0] |= 16
+	}
+	if nil != ap.NavAltitudeMcp {
+		putUint16(24, float64(*ap.NavAltitudeMcp)/4)
+		valid[3] |= 64
+	}
+	if nil != ap.NavAltitudeFms {
+		putUint16(26, float64(*ap.NavAltitudeFms)/4)
+		valid[3] |= 128
+	}
+	if nil != ap.NavQnh {
+		putInt16(28, *ap.NavQnh*10)
+		valid[3] |= 32
+	}
+	if nil != ap.NavHeading {
+		putInt16(30, *ap.NavHeading*90)
+		valid[4] |= 2
+	}
+	if "" != ap.Squawk {
+		// readsb keeps the squawk as 4 hex digits
+		squawk, _ := strconv.ParseUint(ap.Squawk, 16, 16)
+		le.PutUint16(b[32:], uint16(squawk))
+		valid[3] |= 4
+	}
+	if nil != ap.Gs {
+		putInt16(34, *ap.Gs*10)
+		valid[0] |= 128
+	}
+	if nil != ap.Mach {
+		putInt16(36, *ap.Mach*1000)
+		valid[1] |= 4
+	}
+	if nil != ap.Roll {
+		putInt16(38, *ap.Roll*100)
+		valid[1] |= 32
+	}
+	if nil != ap.Track {
+		putInt16(40, *ap.Track*90)
+		valid[1] |= 8
+	}
+	if nil != ap.TrackRate {
+		putInt16(42, *ap.TrackRate*100)
+		valid[1] |= 16
+	}
+	if nil != ap.MagHeading {
+		putInt16(44, *ap.MagHeading*90)
+		valid[1] |= 64
+	}
+	if nil != ap.Tas {
+		putUint16(56, float64(*ap.Tas))
+		valid[1] |= 2
+	}
+	if nil != ap.Ias {
+		putUint16(58, float64(*ap.Ias))
+		valid[1] |= 1
+	}
+	if nil != ap.Rc {
+		putUint16(60, *ap.Rc)
+	}
+	le.PutUint16(b[62:], uint16(ap.Messages))
+
+	if "" != ap.Category {
+		// A3 is 0xA3
+		category, _ := strconv.ParseUint(ap.Category, 16, 8)
+		b[64] = byte(category)
+	}
+	if nil != ap.Nic {
+		b[65] = *ap.Nic
+	}
+	if nil != ap.NavModes {
+		for _, mode := range ap.NavModes {
+			switch mode {
+			case "autopilot":
+				b[66] |= 1
+			case "vnav":
+				b[66] |= 2
+			case "althold":
+				b[66] |= 4
+			case "approach":
+				b[66] |= 8
+			case "lnav":
+				b[66] |= 16
+			}
+		}
+		valid[4] |= 4
+	}
+	if emergency, ok := binCraftEmergencies[ap.Emergency]; ok && "" != ap.Emergency {
+		b[67] = emergency
+		valid[3] |= 8
+	}
+	b[67] |= binCraftAddrTypes[ap.Type] << 4
+	if p.OnGround() {
+		b[68] = 1
+	} else if nil != ap.AltBaro {
+		b[68] = 2
+	}
+	if nil != ap.Sil {
+		b[69] = binCraftSilTypes[ap.SilType]
+		b[72] = *ap.Sil & 0x3
+		valid[2] |= 128
+	}
+	if nil != ap.Version {
+		b[69] |= *ap.Version << 4
+	}
+	if nil != ap.NacP {
+		b[71] = *ap.NacP & 0xF
+		valid[2] |= 32
+	}
+	if nil != ap.NacV {
+		b[71] |= *ap.NacV << 4
+		valid[2] |= 64
+	}
+	if "" != ap.Flight {
+		copy(b[78:86], ap.Flight)
+		valid[0] |= 8
+	}
+	if p.HasSignal() {
+		// readsb turns this back into rssi with 10 * log10(signal^2 / 65025 + 1.125e-5)
+		signal := math.Sqrt(math.Max(0, math.Pow(10, p.SignalLast()/10)-1.125e-5) * 65025)
+		b[105] = byte(math.Min(255, math.Round(signal)))
+	}
+	copy(b[73:78], valid[:])
+}
@@ -0,0 +1,193 @@
+package sink
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"plane.watch/lib/tracker"
+	"plane.watch/lib/tracker/mode_s"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// newGlobeTestSink gives us a sink tracking a plane over Perth (tile 35), one over London (a grid tile) and one
+// we do not have a position for
+func newGlobeTestSink(t *testing.T) (*AircraftJsonSink, *tracker.Tracker) {
+	encode := mustEncode(t)
+	trk := tracker.NewTracker()
+
+	perth := trk.GetPlane(0x7C7DAA)
+	handleFrames(t, perth,
+		encode(mode_s.EncodeExtendedSquitter(0x7C7DAA, mode_s.AdsbIdentification{Category: 3, Callsign: "QFA123"})),
+		encode(mode_s.EncodeExtendedSquitter(0x7C7DAA, mode_s.AdsbAirbornePosition{Altitude: 35000, Lat: -31.95, Lon: 115.94})),
+		encode(mode_s.EncodeExtendedSquitter(0x7C7DAA, mode_s.AdsbAirbornePosition{Altitude: 35000, Lat: -31.95, Lon: 115.94, Odd: true})),
+		encode(mode_s.EncodeExtendedSquitter(0x7C7DAA, mode_s.AdsbAirborneVelocity{Heading: 90, GroundSpeed: 400, VerticalRate: -832, BaroRate: true})),
+		encode(mode_s.EncodeExtendedSquitter(0x7C7DAA, mode_s.AdsbEmergency{Emergency: 1, Squawk: 7700})),
+	)
+	london := trk.GetPlane(0x400001)
+	handleFrames(t, london,
+		encode(mode_s.EncodeExtendedSquitter(0x400001, mode_s.AdsbAirbornePosition{Altitude: 5000, Lat: 51.47, Lon: -0.45})),
+		encode(mode_s.EncodeExtendedSquitter(0x400001, mode_s.AdsbAirbornePosition{Altitude: 5000, Lat: 51.47, Lon: -0.45, Odd: true})),
+	)
+	nowhere := trk.GetPlane(0x7C0002)
+	handleFrames(t, nowhere, mode_s.EncodeAllCall(0x7C0002, 5))
+
+	a := &AircraftJsonSink{numMessages: 42}
+	a.globeIndex = true
+	a.receiverLat, a.receiverLon = floatPtr(-31.95), floatPtr(115.86)
+	for _, p := range []*tracker.Plane{perth, london, nowhere} {
+		a.planes.Store(p.IcaoIdentifier(), p)
+	}
+	return a, trk
+}
+
+func TestAircraftJsonSink_serveGlobe(t *testing.T) {
+	a, trk := newGlobeTestSink(t)
+	defer trk.Finish()
+	londonTile := tracker.GlobeIndex(51.47, -0.45)
+
+	tests := []struct {
+		name   string
+		path   string
+		status int
+		index  int
+		hex    []string
+	}{
+		{name: "Perth", path: "/data/globe_0035.json", status: http.StatusOK, index: 35, hex: []string{"7c7daa"}},
+		{name: "London", path: "/data/globe_" + strconv.Itoa(londonTile) + ".json", status: http.StatusOK, index: londonTile, hex: []string{"400001"}},
+		{name: "empty tile", path: "/data/globe_0000.json", status: http.StatusOK, index: 0, hex: []string{}},
+		{name: "not a number", path: "/data/globe_abc.json", status: http.StatusNotFound},
+		{name: "no such tile", path: "/data/globe_0999.json", status: http.StatusNotFound},
+		{name: "not a globe file", path: "/data/something.json", status: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			a.serveGlobe(w, httptest.NewRequest("GET", tt.path, nil))
+			if tt.status != w.Code {
+				t.Fatalf("Expected status %d, got %d", tt.status, w.Code)
+			}
+			if http.StatusOK != w.Code {
+				return
+			}
+			var got globeJson
+			if err := json.Unmarshal(w.Body.Bytes(), &got); nil != err {
+				t.Fatalf("Invalid json %s: %s", w.Body.String(), err)
+			}
+			bounds, _ := tracker.GlobeTileBounds(tt.index)
+			if tt.index != got.GlobeIndex || bounds.South != got.South || bounds.West != got.West || bounds.North != got.North || bounds.East != got.East {
+				t.Errorf("Incorrect tile %d %0.0f,%0.0f %0.0f,%0.0f", got.GlobeIndex, got.South, got.West, got.North, got.East)
+			}
+			if 2 != got.GlobalAcCountWithPos || 42 != got.Messages {
+				t.Errorf("Incorrect counts, %d with a position, %d messages", got.GlobalAcCountWithPos, got.Messages)
+			}
+			if len(tt.hex) != len(got.Aircraft) {
+				t.Fatalf("Expected %d aircraft, got %d", len(tt.hex), len(got.Aircraft))
+			}
+			for i, hex := range tt.hex {
+				if hex != got.Aircraft[i].Hex {
+					t.Errorf("Expected %s, got %s", hex, got.Aircraft[i].Hex)
+				}
+			}
+		})
+	}
+}
+
+func TestAircraftJsonSink_writeBinCraft(t *testing.T) {
+	a, trk := newGlobeTestSink(t)
+	defer trk.Finish()
+
+	w := httptest.NewRecorder()
+	a.serveGlobe(w, httptest.NewRequest("GET", "/data/globe_0035.binCraft", nil))
+	if http.StatusOK != w.Code || "application/octet-stream" != w.Header().Get("Content-Type") {
+		t.Fatalf("Unexpected response %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	b := w.Body.Bytes()
+	if 2*binCraftStride != len(b) {
+		t.Fatalf("Expected a header and 1 aircraft (%d bytes), got %d bytes", 2*binCraftStride, len(b))
+	}
+	le := binary.LittleEndian
+	i16 := func(offset int) int16 {
+		return int16(le.Uint16(b[offset:]))
+	}
+	i32 := func(offset int) int32 {
+		return int32(le.Uint32(b[offset:]))
+	}
+
+	// the header
+	if ms := int64(le.Uint64(b[0:])); math.Abs(float64(time.Now().UnixNano()/int64(time.Millisecond)-ms)) > 5000 {
+		t.Errorf("now should be in milliseconds, got %d", ms)
+	}
+	bounds, _ := tracker.GlobeTileBounds(35)
+	header := []struct {
+		name      string
+		got, want int64
+	}{
+		{"stride", int64(le.Uint32(b[8:])), binCraftStride},
+		{"aircraft with a position", int64(le.Uint32(b[12:])), 2},
+		{"globe index", int64(le.Uint32(b[16:])), 35},
+		{"south", int64(i16(20)), int64(bounds.South)},
+		{"west", int64(i16(22)), int64(bounds.West)},
+		{"north", int64(i16(24)), int64(bounds.North)},
+		{"east", int64(i16(26)), int64(bounds.East)},
+		{"messages", int64(le.Uint32(b[28:])), 42},
+		{"receiver lat", int64(i32(32)), -31950000},
+		{"receiver lon", int64(i32(36)), 115860000},
+		{"version", int64(le.Uint32(b[40:])), binCraftVersion},
+	}
+	for _, h := range header {
+		if h.want != h.got {
+			t.Errorf("header %s: expected %d, got %d", h.name, h.want, h.got)
+		}
+	}
+
+	// the aircraft, in readsb units
+	ac := b[binCraftStride:]
+	i16 = func(offset int) int16 {
+		return int16(le.Uint16(ac[offset:]))
+	}
+	fields := []struct {
+		name      string
+		got, want int64
+	}{
+		{"icao", int64(le.Uint32(ac[0:])), 0x7C7DAA},
+		{"lon", int64(int32(le.Uint32(ac[8:]))), 115939984},
+		{"lat", int64(int32(le.Uint32(ac[12:]))), -31949982},
+		{"baro rate / 8", int64(i16(16)), -104},
+		{"alt baro / 25", int64(i16(20)), 1400},
+		{"squawk as hex", int64(le.Uint16(ac[32:])), 0x7700},
+		{"gs * 10", int64(i16(34)), 4000},
+		{"track * 90", int64(i16(40)), 8100},
+		{"messages", int64(le.Uint16(ac[62:])), 5},
+		{"category", int64(ac[64]), 0xA3},
+		{"nic", int64(ac[65]), 8},
+		{"emergency | addrtype", int64(ac[67]), 1},
+		{"airground", int64(ac[68]), 2},
+		{"nac_p", int64(ac[71] & 0xF), 8},
+		{"valid[0]", int64(ac[73]), 8 | 16 | 64 | 128},
+		{"valid[1]", int64(ac[74]), 8},
+		{"valid[2]", int64(ac[75]), 1 | 32 | 64},
+		{"valid[3]", int64(ac[76]), 4 | 8},
+	}
+	for _, f := range fields {
+		if f.want != f.got {
+			t.Errorf("%s: expected %d, got %d", f.name, f.want, f.got)
+		}
+	}
+	if "QFA123  " != string(ac[78:86]) {
+		t.Errorf("Incorrect flight %q", ac[78:86])
+	}
+
+	// sil_type and version share a byte, sil_type follows readsb's enum
+	version, sil := byte(2), byte(3)
+	for silType, want := range map[string]byte{"perhour": 3 | 2<<4, "persample": 2 | 2<<4} {
+		plane := make([]byte, binCraftStride)
+		encodeBinCraftPlane(plane, trk.GetPlane(0x7C7DAA), aircraftJsonPlane{Version: &version, Sil: &sil, SilType: silType})
+		if want != plane[69] || sil != plane[72] || 0 == plane[75]&128 {
+			t.Errorf("%s: expected sil_type|version %#x and sil %d, got %#x and %d", silType, want, sil, plane[69], plane[72])
+		}
+	}
+}
@@ -34,11 +34,19 @@ func (l *LoggerSink) OnEvent(e tracker.Event) {
 		if l.logLocation {
 			log.Info().Msg(e.String())
 		}
+	case *tracker.TcasAlertEvent:
+		log.Warn().Str("event", "tcas").Msg(e.String())
+	case *tracker.WeatherEvent:
+		log.Info().Str("event", "weather").Msg(e.String())
 	case *tracker.InfoEvent:
 		i := e.(*tracker.InfoEvent)
 		log.Info().
 			Int("num-receivers", i.NumReceivers()).
 			Uint64("num-frames", i.NumFrames()).
+			Uint64("num-corrected-frames", i.NumCorrectedFrames()).
+			Uint64("num-rejected-frames", i.NumRejectedFrames()).
+			Uint64("num-dropped", i.NumDropped()).
+			Int("num-unidentified-mode-ac", i.NumUnidentifiedModeAC()).
 			Float64("uptime", i.Uptime()).
 			Msg(e.String())
 	}
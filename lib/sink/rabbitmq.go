@@ -28,6 +28,8 @@ const (
 	QueueTypeDecodedJson = "decoded-json"
 	QueueTypeLogs        = "logs"
 	QueueLocationUpdates = "location-updates"
+	QueueWeatherReports  = "weather-reports"
+	QueueTcasAlerts      = "tcas-alerts"
 )
 
 type (
@@ -46,6 +48,9 @@ type (
 		Type, RouteKey string
 		Body           []byte
 		Source         *tracker.FrameSource
+		// Signal is the signal level in dBFS, if the receiver told us (BEAST)
+		Signal    float64
+		HasSignal bool
 	}
 )
 
@@ -59,6 +64,8 @@ var AllQueues = [...]string{
 	QueueTypeDecodedJson,
 	QueueTypeLogs,
 	QueueLocationUpdates,
+	QueueWeatherReports,
+	QueueTcasAlerts,
 }
 
 const ansi = "[\u001B\u009B][[\\]()#;?]*(?:(?:(?:[a-zA-Z\\d]*(?:;[a-zA-Z\\d]*)*)?\u0007)|(?:(?:\\d{1,4}(?:;\\d{0,4})*)?[\\dA-PRZcf-ntqry=><~]))"
@@ -114,6 +121,17 @@ func WithRabbitVhost(vhost string) Option {
 	}
 }
 
+// WithRabbitTLS connects using amqps. certFile and keyFile are our client certificate and caFile pins the CA
+// that signed the server's certificate, they are all optional
+func WithRabbitTLS(certFile, keyFile, caFile string) Option {
+	return func(config *Config) {
+		config.secure = true
+		config.tlsCertFile = certFile
+		config.tlsKeyFile = keyFile
+		config.tlsCaFile = caFile
+	}
+}
+
 func WithRabbitQueues(queues []string) Option {
 	return func(conf *Config) {
 		if 0 == len(queues) {
@@ -147,6 +165,8 @@ func WithAllRabbitQueues() Option {
 		conf.queue[QueueTypeDecodedJson] = QueueTypeDecodedJson
 		conf.queue[QueueTypeLogs] = QueueTypeLogs
 		conf.queue[QueueLocationUpdates] = QueueLocationUpdates
+		conf.queue[QueueWeatherReports] = QueueWeatherReports
+		conf.queue[QueueTcasAlerts] = QueueTcasAlerts
 	}
 }
 
@@ -158,34 +178,7 @@ func (r *RabbitMqSink) sendLocationEventToQueue(queue string, le *tracker.PlaneL
 	var err error
 	plane := le.Plane()
 	if nil != plane {
-		eventStruct := export.PlaneLocation{
-			New:           le.New(),
-			Removed:       le.Removed(),
-			Icao:          plane.IcaoIdentifierStr(),
-			Lat:           plane.Lat(),
-			Lon:           plane.Lon(),
-			Heading:       plane.Heading(),
-			Altitude:      int(plane.Altitude()),
-			VerticalRate:  plane.VerticalRate(),
-			AltitudeUnits: plane.AltitudeUnits(),
-			Velocity:      plane.Velocity(),
-			FlightNumber:  strings.TrimSpace(plane.FlightNumber()),
-			FlightStatus:  plane.FlightStatus(),
-			OnGround:      plane.OnGround(),
-			Airframe:      plane.AirFrame(),
-			AirframeType:  plane.AirFrameType(),
-			Squawk:        plane.SquawkIdentityStr(),
-			Special:       plane.Special(),
-
-			HasLocation:     plane.HasLocation(),
-			HasHeading:      plane.HasHeading(),
-			HasVerticalRate: plane.HasVerticalRate(),
-			HasVelocity:     plane.HasVelocity(),
-			SourceTag:       r.Config.sourceTag,
-			TileLocation:    plane.GridTileLocation(),
-			LastMsg:         plane.LastSeen().UTC(),
-			TrackedSince:    plane.TrackedSince().UTC(),
-		}
+		eventStruct := newExportPlaneLocation(le, r.Config.sourceTag)
 
 		var jsonBuf []byte
 		jsonBuf, err = json.MarshalIndent(&eventStruct, "", "  ")
@@ -207,6 +200,104 @@ func (r *RabbitMqSink) sendLocationEventToQueue(queue string, le *tracker.PlaneL
 	return err
 }
 
+func (r *RabbitMqSink) sendWeatherEventToQueue(queue string, we *tracker.WeatherEvent) error {
+	if _, ok := r.queue[queue]; !ok {
+		return nil
+	}
+	hazard := func(has bool, level byte) string {
+		if !has {
+			return ""
+		}
+		return mode_s.HazardLevelString(level)
+	}
+	eventStruct := export.WeatherReport{
+		Icao:          we.IcaoStr,
+		Bds:           we.Bds,
+		Lat:           we.Lat,
+		Lon:           we.Lon,
+		HasLocation:   we.HasLocation,
+		Altitude:      int(we.Altitude),
+		AltitudeUnits: we.AltitudeUnits,
+		SourceTag:     r.Config.sourceTag,
+		When:          we.When.UTC(),
+
+		WindSpeed:            we.WindSpeed,
+		WindDirection:        we.WindDirection,
+		StaticAirTemperature: we.StaticAirTemperature,
+		StaticPressure:       we.StaticPressure,
+		Humidity:             we.Humidity,
+		Turbulence:           hazard(we.HasTurbulence, we.Turbulence),
+		WindShear:            hazard(we.HasWindShear, we.WindShear),
+		Microburst:           hazard(we.HasMicroburst, we.Microburst),
+		Icing:                hazard(we.HasIcing, we.Icing),
+		WakeVortex:           hazard(we.HasWakeVortex, we.WakeVortex),
+		RadioHeight:          we.RadioHeight,
+
+		HasWind:                 we.HasWind,
+		HasStaticAirTemperature: we.HasStaticAirTemperature,
+		HasStaticPressure:       we.HasStaticPressure,
+		HasHumidity:             we.HasHumidity,
+		HasTurbulence:           we.HasTurbulence,
+		HasWindShear:            we.HasWindShear,
+		HasMicroburst:           we.HasMicroburst,
+		HasIcing:                we.HasIcing,
+		HasWakeVortex:           we.HasWakeVortex,
+		HasRadioHeight:          we.HasRadioHeight,
+	}
+
+	jsonBuf, err := json.Marshal(&eventStruct)
+	if nil != err {
+		return err
+	}
+	return r.mq.Publish(r.exchange, queue, amqp.Publishing{
+		ContentType:     "application/json",
+		ContentEncoding: "utf-8",
+		Timestamp:       time.Now(),
+		Body:            jsonBuf,
+	})
+}
+
+func (r *RabbitMqSink) sendTcasAlertToQueue(queue string, te *tracker.TcasAlertEvent) error {
+	if _, ok := r.queue[queue]; !ok {
+		return nil
+	}
+	eventStruct := export.TcasAlert{
+		Icao:           te.IcaoStr,
+		FlightNumber:   strings.TrimSpace(te.FlightNumber),
+		DownLinkFormat: int(te.DownLinkFormat),
+		Lat:            te.Lat,
+		Lon:            te.Lon,
+		HasLocation:    te.HasLocation,
+		Altitude:       int(te.Altitude),
+		AltitudeUnits:  te.AltitudeUnits,
+		SourceTag:      r.Config.sourceTag,
+		When:           te.When.UTC(),
+
+		Advisories:     te.RA.Advisories(),
+		ActiveRA:       int(te.RA.ActiveRA),
+		RAComplement:   int(te.RA.RAComplement),
+		Active:         te.RA.Active(),
+		Terminated:     te.RA.Terminated,
+		MultipleThreat: te.RA.MultipleThreat,
+		ThreatType:     int(te.RA.ThreatType),
+		Threat:         te.RA.ThreatString(),
+	}
+	if mode_s.ThreatTypeIcao == te.RA.ThreatType {
+		eventStruct.ThreatIcao = fmt.Sprintf("%06X", te.RA.ThreatIcao)
+	}
+
+	jsonBuf, err := json.Marshal(&eventStruct)
+	if nil != err {
+		return err
+	}
+	return r.mq.Publish(r.exchange, queue, amqp.Publishing{
+		ContentType:     "application/json",
+		ContentEncoding: "utf-8",
+		Timestamp:       time.Now(),
+		Body:            jsonBuf,
+	})
+}
+
 func (r *RabbitMqSink) sendFrameEvent(queueAvr, queueBeast, queueSbs1 string) func(tracker.Frame, *tracker.FrameSource) error {
 	return func(ourFrame tracker.Frame, source *tracker.FrameSource) error {
 		var err error
@@ -236,8 +327,13 @@ func (r *RabbitMqSink) sendFrameEvent(queueAvr, queueBeast, queueSbs1 string) fu
 		case *mode_s.Frame:
 			err = sendMessage(rabbitFrameMsg{Type: "avr", Body: ourFrame.Raw(), RouteKey: queueAvr, Source: source})
 		case *beast.Frame:
-			err = sendMessage(rabbitFrameMsg{Type: "beast", Body: ourFrame.Raw(), RouteKey: queueBeast, Source: source})
-			err = sendMessage(rabbitFrameMsg{Type: "avr", Body: ourFrame.(*beast.Frame).AvrFrame().Raw(), RouteKey: queueAvr, Source: source})
+			bf := ourFrame.(*beast.Frame)
+			var signal float64
+			if bf.SignalValid() {
+				signal = bf.SignalDbfs()
+			}
+			err = sendMessage(rabbitFrameMsg{Type: "beast", Body: ourFrame.Raw(), RouteKey: queueBeast, Source: source, Signal: signal, HasSignal: bf.SignalValid()})
+			err = sendMessage(rabbitFrameMsg{Type: "avr", Body: bf.AvrFrame().Raw(), RouteKey: queueAvr, Source: source, Signal: signal, HasSignal: bf.SignalValid()})
 		case *sbs1.Frame:
 			err = sendMessage(rabbitFrameMsg{Type: "sbs1", Body: ourFrame.Raw(), RouteKey: queueSbs1, Source: source})
 		}
@@ -259,6 +355,12 @@ func (r *RabbitMqSink) OnEvent(e tracker.Event) {
 		le := e.(*tracker.PlaneLocationEvent)
 		err = r.sendLocationEventToQueue(QueueLocationUpdates, le)
 
+	case *tracker.WeatherEvent:
+		err = r.sendWeatherEventToQueue(QueueWeatherReports, e.(*tracker.WeatherEvent))
+
+	case *tracker.TcasAlertEvent:
+		err = r.sendTcasAlertToQueue(QueueTcasAlerts, e.(*tracker.TcasAlertEvent))
+
 	case *tracker.FrameEvent:
 		//println("Got a Frame!")
 		ourFrame := e.(*tracker.FrameEvent).Frame()
@@ -283,6 +385,18 @@ func (r *RabbitMqSink) connect(timeout time.Duration) (*rabbitmq.RabbitMQ, error
 	rabbitConfig.User = r.user
 	rabbitConfig.Password = r.pass
 	rabbitConfig.Vhost = r.vhost
+	rabbitConfig.Ssl = rabbitmq.ConfigSSL{
+		Enabled:        r.secure,
+		PrivateKeyFile: r.tlsKeyFile,
+		CertChainFile:  r.tlsCertFile,
+		CaCertFile:     r.tlsCaFile,
+	}
+	if r.secure {
+		// a bad TLS setup is not going to fix itself, no point waiting for it
+		if _, err := rabbitConfig.Ssl.TlsConfig(); nil != err {
+			return nil, err
+		}
+	}
 
 	log.Info().Str("host", rabbitConfig.String()).Msg("Connecting to RabbitMQ")
 	rabbit := rabbitmq.New(rabbitConfig)
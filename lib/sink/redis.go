@@ -1,32 +1,189 @@
 package sink
 
-import "plane.watch/lib/tracker"
+import (
+	"context"
+	"encoding/json"
+	"github.com/go-redis/redis/v8"
+	"github.com/rs/zerolog/log"
+	"net"
+	"plane.watch/lib/export"
+	"plane.watch/lib/tracker"
+	"strconv"
+	"time"
+)
+
+const (
+	// RedisPlanePrefix is prepended to the ICAO of each plane hash
+	RedisPlanePrefix = "plane:"
+	// RedisLocationsKey is the GEO set of every plane we know the location of, for GEOSEARCH/GEORADIUS
+	RedisLocationsKey = "plane-locations"
+	// RedisLocationsSeenKey is when (unix seconds) each plane in the GEO set was last updated. GEO set members
+	// cannot expire, so with WithMessageTtl we use it to prune the ones whose hash has expired
+	RedisLocationsSeenKey = "plane-locations-seen"
+)
 
 type (
+	// RedisSink keeps the current state of each plane in redis. Every plane gets a hash (plane:<ICAO>) that
+	// expires after WithMessageTtl seconds, planes with a location are in a GEO set (plane-locations, pruned
+	// along with the hashes) and each update is published on a per tile channel (location-updates:<tile>)
 	RedisSink struct {
 		Config
-		events chan tracker.Event
+		client *redis.Client
+		done   chan struct{}
 	}
 )
 
-func NewRedisSink(opts ...Option) *RedisSink {
-	r := &RedisSink{
-		events: make(chan tracker.Event),
-	}
+func NewRedisSink(opts ...Option) (*RedisSink, error) {
+	r := &RedisSink{}
 	for _, opt := range opts {
 		opt(&r.Config)
 	}
-	return r
+
+	r.client = redis.NewClient(&redis.Options{
+		Addr:     net.JoinHostPort(r.host, r.port),
+		Username: r.user,
+		Password: r.pass,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	log.Info().Str("host", net.JoinHostPort(r.host, r.port)).Msg("Connecting to Redis")
+	if err := r.client.Ping(ctx).Err(); nil != err {
+		_ = r.client.Close()
+		return nil, err
+	}
+
+	r.done = make(chan struct{})
+	if r.messageTtlSeconds > 0 {
+		r.waiter.Add(1)
+		go r.pruneLocationsEvery(time.Duration(r.messageTtlSeconds) * time.Second)
+	}
+	return r, nil
+}
+
+// RedisLocationChannel is the pub/sub channel location updates for planes in the given tile are published on
+func RedisLocationChannel(tile string) string {
+	return QueueLocationUpdates + ":" + tile
 }
 
 func (r *RedisSink) OnEvent(e tracker.Event) {
-	panic("Implement REDIS")
+	var err error
+	switch e.(type) {
+	case *tracker.PlaneLocationEvent:
+		le := e.(*tracker.PlaneLocationEvent)
+		if nil == le.Plane() {
+			return
+		}
+		loc := newExportPlaneLocation(le, r.sourceTag)
+		if loc.Removed {
+			err = r.removePlane(loc)
+		} else {
+			err = r.updatePlane(loc)
+		}
+	}
+
+	if nil != err {
+		log.Error().Err(err).Msg("Failed to update redis")
+	}
+}
+
+// updatePlane stores the plane's hash, moves it in the GEO set and lets everyone watching its tile know
+func (r *RedisSink) updatePlane(loc export.PlaneLocation) error {
+	jsonBuf, err := json.Marshal(&loc)
+	if nil != err {
+		return err
+	}
+	// the hash has the same fields as the JSON
+	var fields map[string]interface{}
+	if err = json.Unmarshal(jsonBuf, &fields); nil != err {
+		return err
+	}
+	for k, v := range fields {
+		if b, ok := v.(bool); ok {
+			fields[k] = strconv.FormatBool(b)
+		}
+	}
+
+	ctx := context.Background()
+	key := RedisPlanePrefix + loc.Icao
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key, fields)
+		if r.messageTtlSeconds > 0 {
+			pipe.Expire(ctx, key, time.Duration(r.messageTtlSeconds)*time.Second)
+		}
+		if loc.HasLocation {
+			pipe.GeoAdd(ctx, RedisLocationsKey, &redis.GeoLocation{Name: loc.Icao, Longitude: loc.Lon, Latitude: loc.Lat})
+			pipe.ZAdd(ctx, RedisLocationsSeenKey, &redis.Z{Score: float64(time.Now().Unix()), Member: loc.Icao})
+			if "" != loc.TileLocation {
+				pipe.Publish(ctx, RedisLocationChannel(loc.TileLocation), jsonBuf)
+			}
+		}
+		return nil
+	})
+	return err
 }
 
-func (r *RedisSink) Listen() chan tracker.Event {
-	return r.events
+// removePlane forgets about a plane the tracker has stopped tracking
+func (r *RedisSink) removePlane(loc export.PlaneLocation) error {
+	jsonBuf, err := json.Marshal(&loc)
+	if nil != err {
+		return err
+	}
+
+	ctx := context.Background()
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, RedisPlanePrefix+loc.Icao)
+		pipe.ZRem(ctx, RedisLocationsKey, loc.Icao)
+		pipe.ZRem(ctx, RedisLocationsSeenKey, loc.Icao)
+		if "" != loc.TileLocation {
+			pipe.Publish(ctx, RedisLocationChannel(loc.TileLocation), jsonBuf)
+		}
+		return nil
+	})
+	return err
+}
+
+// pruneLocationsEvery drops planes from the GEO set once their hash has expired, until we are stopped
+func (r *RedisSink) pruneLocationsEvery(interval time.Duration) {
+	defer r.waiter.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.done:
+			return
+		case now := <-ticker.C:
+			if err := r.pruneLocations(now); nil != err {
+				log.Error().Err(err).Msg("Failed to prune redis plane locations")
+			}
+		}
+	}
+}
+
+// pruneLocations removes the planes that have not been updated for WithMessageTtl seconds from the GEO set
+func (r *RedisSink) pruneLocations(now time.Time) error {
+	ctx := context.Background()
+	expired := strconv.FormatInt(now.Unix()-int64(r.messageTtlSeconds), 10)
+	stale, err := r.client.ZRangeByScore(ctx, RedisLocationsSeenKey, &redis.ZRangeBy{Min: "-inf", Max: expired}).Result()
+	if nil != err || 0 == len(stale) {
+		return err
+	}
+	members := make([]interface{}, len(stale))
+	for i, icao := range stale {
+		members[i] = icao
+	}
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRem(ctx, RedisLocationsKey, members...)
+		pipe.ZRemRangeByScore(ctx, RedisLocationsSeenKey, "-inf", expired)
+		return nil
+	})
+	return err
 }
 
 func (r *RedisSink) Stop() {
-	close (r.events)
+	close(r.done)
+	r.Config.Finish()
+	if err := r.client.Close(); nil != err {
+		log.Error().Err(err).Msg("Failed to close redis connection")
+	}
 }
@@ -0,0 +1,215 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"math"
+	"plane.watch/lib/export"
+	"plane.watch/lib/tracker"
+	"plane.watch/lib/tracker/mode_s"
+	"testing"
+	"time"
+)
+
+func newTestRedisSink(t *testing.T, opts ...Option) (*RedisSink, *miniredis.Miniredis, *redis.Client) {
+	mr := miniredis.RunT(t)
+	r, err := NewRedisSink(append([]Option{WithHost(mr.Host(), mr.Port())}, opts...)...)
+	if nil != err {
+		t.Fatal(err)
+	}
+	t.Cleanup(r.Stop)
+
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() {
+		_ = client.Close()
+	})
+	return r, mr, client
+}
+
+func TestNewRedisSink_NoServer(t *testing.T) {
+	mr := miniredis.RunT(t)
+	host, port := mr.Host(), mr.Port()
+	mr.Close()
+	if _, err := NewRedisSink(WithHost(host, port)); nil == err {
+		t.Error("Expected an error connecting to a redis that is not there")
+	}
+}
+
+func TestRedisSink_updatePlane(t *testing.T) {
+	r, mr, client := newTestRedisSink(t, WithMessageTtl(60))
+	ctx := context.Background()
+
+	sub := client.Subscribe(ctx, RedisLocationChannel("tile38"))
+	defer sub.Close()
+	if _, err := sub.Receive(ctx); nil != err {
+		t.Fatal(err)
+	}
+
+	loc := export.PlaneLocation{
+		Icao:         "7C7DAA",
+		Lat:          -31.95,
+		Lon:          115.94,
+		Altitude:     35000,
+		FlightNumber: "QFA123",
+		HasLocation:  true,
+		TileLocation: "tile38",
+	}
+	if err := r.updatePlane(loc); nil != err {
+		t.Fatal(err)
+	}
+
+	if got := mr.HGet(RedisPlanePrefix+"7C7DAA", "FlightNumber"); "QFA123" != got {
+		t.Errorf("Incorrect FlightNumber %q", got)
+	}
+	if got := mr.HGet(RedisPlanePrefix+"7C7DAA", "Altitude"); "35000" != got {
+		t.Errorf("Incorrect Altitude %q", got)
+	}
+	if ttl := mr.TTL(RedisPlanePrefix + "7C7DAA"); 60*time.Second != ttl {
+		t.Errorf("Incorrect TTL %s", ttl)
+	}
+
+	positions, err := client.GeoPos(ctx, RedisLocationsKey, "7C7DAA").Result()
+	if nil != err {
+		t.Fatal(err)
+	}
+	if 1 != len(positions) || nil == positions[0] ||
+		math.Abs(-31.95-positions[0].Latitude) > 0.0001 || math.Abs(115.94-positions[0].Longitude) > 0.0001 {
+		t.Errorf("Incorrect GEO position %+v", positions)
+	}
+	nearby, err := client.GeoRadius(ctx, RedisLocationsKey, 115.86, -31.95, &redis.GeoRadiusQuery{Radius: 10, Unit: "km"}).Result()
+	if nil != err {
+		t.Fatal(err)
+	}
+	if 1 != len(nearby) || "7C7DAA" != nearby[0].Name {
+		t.Errorf("Expected to find our plane nearby, got %+v", nearby)
+	}
+	if seen, err := client.ZScore(ctx, RedisLocationsSeenKey, "7C7DAA").Result(); nil != err || math.Abs(float64(time.Now().Unix())-seen) > 5 {
+		t.Errorf("Expected to know when we last saw the plane, got %0.0f %v", seen, err)
+	}
+
+	select {
+	case msg := <-sub.Channel():
+		var got export.PlaneLocation
+		if err = json.Unmarshal([]byte(msg.Payload), &got); nil != err {
+			t.Fatal(err)
+		}
+		if "7C7DAA" != got.Icao || "QFA123" != got.FlightNumber {
+			t.Errorf("Incorrect location update %+v", got)
+		}
+	case <-time.After(time.Second):
+		t.Error("Did not get a location update on tile38")
+	}
+}
+
+func TestRedisSink_updatePlaneWithoutLocation(t *testing.T) {
+	r, mr, client := newTestRedisSink(t)
+
+	if err := r.updatePlane(export.PlaneLocation{Icao: "7C7DAA", FlightNumber: "QFA123"}); nil != err {
+		t.Fatal(err)
+	}
+	if !mr.Exists(RedisPlanePrefix + "7C7DAA") {
+		t.Error("Expected the plane to be stored")
+	}
+	if ttl := mr.TTL(RedisPlanePrefix + "7C7DAA"); 0 != ttl {
+		t.Errorf("Did not expect a TTL without WithMessageTtl, got %s", ttl)
+	}
+	if n, _ := client.ZCard(context.Background(), RedisLocationsKey).Result(); 0 != n {
+		t.Errorf("Did not expect a plane without a location in the GEO set, got %d", n)
+	}
+}
+
+func TestRedisSink_removePlane(t *testing.T) {
+	r, mr, client := newTestRedisSink(t)
+	loc := export.PlaneLocation{Icao: "7C7DAA", Lat: -31.95, Lon: 115.94, HasLocation: true, TileLocation: "tile38"}
+	if err := r.updatePlane(loc); nil != err {
+		t.Fatal(err)
+	}
+
+	loc.Removed = true
+	if err := r.removePlane(loc); nil != err {
+		t.Fatal(err)
+	}
+	if mr.Exists(RedisPlanePrefix + "7C7DAA") {
+		t.Error("Expected the plane hash to be removed")
+	}
+	for _, key := range []string{RedisLocationsKey, RedisLocationsSeenKey} {
+		if n, _ := client.ZCard(context.Background(), key).Result(); 0 != n {
+			t.Errorf("Expected the plane to be removed from %s, %d left", key, n)
+		}
+	}
+}
+
+func TestRedisSink_pruneLocations(t *testing.T) {
+	r, _, client := newTestRedisSink(t, WithMessageTtl(60))
+	ctx := context.Background()
+	for _, icao := range []string{"7C7DAA", "400001"} {
+		if err := r.updatePlane(export.PlaneLocation{Icao: icao, Lat: -31.95, Lon: 115.94, HasLocation: true}); nil != err {
+			t.Fatal(err)
+		}
+	}
+	// 7C7DAA has not been updated for longer than the TTL, its hash has expired so its location should go too
+	if _, err := client.ZAdd(ctx, RedisLocationsSeenKey, &redis.Z{Score: float64(time.Now().Add(-2 * time.Minute).Unix()), Member: "7C7DAA"}).Result(); nil != err {
+		t.Fatal(err)
+	}
+
+	if err := r.pruneLocations(time.Now()); nil != err {
+		t.Fatal(err)
+	}
+	for key, expected := range map[string][]string{RedisLocationsKey: {"400001"}, RedisLocationsSeenKey: {"400001"}} {
+		members, err := client.ZRange(ctx, key, 0, -1).Result()
+		if nil != err {
+			t.Fatal(err)
+		}
+		if len(expected) != len(members) || expected[0] != members[0] {
+			t.Errorf("%s: expected %v, got %v", key, expected, members)
+		}
+	}
+
+	if err := r.pruneLocations(time.Now().Add(61 * time.Second)); nil != err {
+		t.Fatal(err)
+	}
+	if n, _ := client.ZCard(ctx, RedisLocationsKey).Result(); 0 != n {
+		t.Errorf("Expected every location to be pruned, %d left", n)
+	}
+}
+
+func TestRedisSink_OnEvent(t *testing.T) {
+	r, mr, _ := newTestRedisSink(t, WithSourceTag("test"), WithMessageTtl(30))
+
+	trk := tracker.NewTracker()
+	trk.AddSink(r)
+	p := trk.GetPlane(0x7C7DAA)
+	for _, payload := range []mode_s.Payload{
+		mode_s.AdsbIdentification{Callsign: "QFA123"},
+		mode_s.AdsbAirbornePosition{Altitude: 35000, Lat: -31.95, Lon: 115.94},
+		mode_s.AdsbAirbornePosition{Altitude: 35000, Lat: -31.95, Lon: 115.94, Odd: true},
+	} {
+		encoded, err := mode_s.EncodeExtendedSquitter(0x7C7DAA, payload)
+		if nil != err {
+			t.Fatal(err)
+		}
+		frame, err := mode_s.DecodeBytes(encoded.Bytes(), time.Now())
+		if nil != err {
+			t.Fatal(err)
+		}
+		p.HandleModeSFrame(frame, nil, nil)
+	}
+
+	// the tracker hands events to its sinks in the background
+	deadline := time.Now().Add(2 * time.Second)
+	for "true" != mr.HGet(RedisPlanePrefix+"7C7DAA", "HasLocation") && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if got := mr.HGet(RedisPlanePrefix+"7C7DAA", "HasLocation"); "true" != got {
+		t.Fatalf("Expected the plane to have a location, got %q", got)
+	}
+	if got := mr.HGet(RedisPlanePrefix+"7C7DAA", "SourceTag"); "test" != got {
+		t.Errorf("Incorrect SourceTag %q", got)
+	}
+	if ttl := mr.TTL(RedisPlanePrefix + "7C7DAA"); 30*time.Second != ttl {
+		t.Errorf("Incorrect TTL %s", ttl)
+	}
+}
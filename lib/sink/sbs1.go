@@ -0,0 +1,210 @@
+package sink
+
+import (
+	"fmt"
+	"net"
+	"plane.watch/lib/tracker"
+	"plane.watch/lib/tracker/mode_s"
+	"plane.watch/lib/tracker/sbs1"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// Sbs1Sink serves BaseStation (SBS-1) lines to anyone that connects, the same as dump1090's port 30003.
+	// Every Mode S frame we decode is sent as a MSG line, and we send AIR, ID and STA lines as planes come and go
+	Sbs1Sink struct {
+		Config
+		server *tcpServer
+
+		// callsigns is the last callsign we sent an ID line for, keyed by the plane's ICAO. Planes are in here
+		// once we have sent their AIR line
+		callsigns sync.Map
+	}
+)
+
+// NewSbs1Sink starts serving SBS-1 on the host/port given by WithHost()
+func NewSbs1Sink(opts ...Option) (*Sbs1Sink, error) {
+	s := &Sbs1Sink{}
+	for _, opt := range opts {
+		opt(&s.Config)
+	}
+
+	var err error
+	if s.server, err = newTcpServer("sbs1", s.host, s.port, &s.waiter); nil != err {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Addr is the address we are listening on
+func (s *Sbs1Sink) Addr() net.Addr {
+	return s.server.listener.Addr()
+}
+
+// WantsDecodedFrames tells the tracker we send a MSG line for every frame it decodes
+func (s *Sbs1Sink) WantsDecodedFrames() bool {
+	return true
+}
+
+func (s *Sbs1Sink) OnEvent(e tracker.Event) {
+	switch e.(type) {
+	case *tracker.DecodedFrameEvent:
+		de := e.(*tracker.DecodedFrameEvent)
+		s.sendFrame(de.Frame(), de.Plane())
+	case *tracker.PlaneLocationEvent:
+		ple := e.(*tracker.PlaneLocationEvent)
+		if !ple.Removed() {
+			return
+		}
+		p := ple.Plane()
+		if _, ok := s.callsigns.Load(p.IcaoIdentifier()); !ok {
+			return
+		}
+		s.callsigns.Delete(p.IcaoIdentifier())
+		now := time.Now()
+		sta := newSbs1StatusFrame("STA", p, now)
+		sta.Status = "RM"
+		s.server.broadcast([]byte(sta.Format()))
+	}
+}
+
+func (s *Sbs1Sink) Stop() {
+	s.server.close()
+	s.Config.Finish()
+}
+
+// sendFrame sends the MSG line for our frame, along with an AIR line for a new plane and an ID line when its
+// callsign changes
+func (s *Sbs1Sink) sendFrame(frame *mode_s.Frame, p *tracker.Plane) {
+	msg, ok := newSbs1Frame(frame, p)
+	if !ok {
+		return
+	}
+	logged := time.Now()
+	msg.Logged = logged
+
+	var out strings.Builder
+	callsign := strings.TrimSpace(p.FlightNumber())
+	sent, known := s.callsigns.Load(p.IcaoIdentifier())
+	if !known {
+		out.WriteString(newSbs1StatusFrame("AIR", p, logged).Format())
+		sent = ""
+	}
+	if callsign != sent {
+		id := newSbs1StatusFrame("ID", p, logged)
+		id.CallSign = callsign
+		out.WriteString(id.Format())
+	}
+	if !known || callsign != sent {
+		s.callsigns.Store(p.IcaoIdentifier(), callsign)
+	}
+
+	out.WriteString(msg.Format())
+	s.server.broadcast([]byte(out.String()))
+}
+
+// newSbs1StatusFrame is an AIR, ID or STA line for our plane
+func newSbs1StatusFrame(msgType string, p *tracker.Plane, when time.Time) *sbs1.Frame {
+	return &sbs1.Frame{
+		MsgType:  msgType,
+		IcaoInt:  p.IcaoIdentifier(),
+		NonIcao:  mode_s.AddressTypeNonIcao == p.AddressType(),
+		Received: when,
+		Logged:   when,
+	}
+}
+
+// newSbs1Frame turns a decoded Mode S frame into a SBS-1 MSG line, using the same transmission types as
+// dump1090. Positions are CPR decoded by the tracker, so they come from the plane, and only if this frame
+// gave us a new one
+func newSbs1Frame(frame *mode_s.Frame, p *tracker.Plane) (*sbs1.Frame, bool) {
+	msg := &sbs1.Frame{
+		MsgType:  "MSG",
+		IcaoInt:  p.IcaoIdentifier(),
+		NonIcao:  mode_s.AddressTypeNonIcao == p.AddressType(),
+		Received: frame.TimeStamp(),
+	}
+
+	switch frame.DownLinkType() {
+	case 4, 20:
+		msg.TransmissionType = 5
+	case 5, 21:
+		msg.TransmissionType = 6
+	case 11:
+		msg.TransmissionType = 8
+	case 0, 16:
+		msg.TransmissionType = 7
+	case 17, 18:
+		switch frame.MessageKind() {
+		case mode_s.MessageKindIdCat:
+			msg.TransmissionType = 1
+		case mode_s.MessageKindSurfacePos:
+			msg.TransmissionType = 2
+		case mode_s.MessageKindAirPositionBarometric, mode_s.MessageKindAirPositionGnss:
+			msg.TransmissionType = 3
+		case mode_s.MessageKindAirVelocity:
+			msg.TransmissionType = 4
+		default:
+			return nil, false
+		}
+	default:
+		return nil, false
+	}
+
+	if frame.AltitudeValid() {
+		msg.Altitude = int(frame.MustAltitude())
+		msg.HasAltitude = true
+	}
+	if frame.VerticalStatusValid() {
+		msg.OnGround = frame.MustOnGround()
+		msg.HasOnGround = true
+	}
+	if frame.VelocityValid() {
+		msg.GroundSpeed = int(frame.MustVelocity())
+		msg.HasGroundSpeed = true
+	}
+	if frame.HeadingValid() {
+		msg.Track = frame.MustHeading()
+		msg.HasTrack = true
+	}
+	if frame.VerticalRateValid() {
+		msg.VerticalRate = frame.MustVerticalRate()
+		msg.HasVerticalRate = true
+	}
+
+	switch msg.TransmissionType {
+	case 1:
+		msg.CallSign = strings.TrimSpace(p.FlightNumber())
+	case 2, 3:
+		if history := p.LocationHistory(); len(history) > 0 && history[len(history)-1].TimeStamp().Equal(frame.TimeStamp()) {
+			msg.Lat = history[len(history)-1].Lat()
+			msg.Lon = history[len(history)-1].Lon()
+			msg.HasPosition = true
+		}
+		msg.OnGround = 2 == msg.TransmissionType
+		msg.HasOnGround = true
+	case 4:
+		msg.HasOnGround = true
+	}
+
+	switch msg.TransmissionType {
+	case 3:
+		ss := frame.SurveillanceStatusCode()
+		msg.Emergency = sbs1.Flag(1 == ss)
+		msg.Alert = sbs1.Flag(2 == ss)
+		msg.Spi = sbs1.Flag(3 == ss)
+	case 5, 6:
+		// the flight status tells us about alerts and SPI
+		fs := frame.FlightStatus()
+		msg.Alert = sbs1.Flag(2 == fs || 3 == fs || 4 == fs)
+		msg.Spi = sbs1.Flag(4 == fs || 5 == fs)
+		if 6 == msg.TransmissionType {
+			squawk := frame.SquawkIdentity()
+			msg.Squawk = fmt.Sprintf("%04d", squawk)
+			msg.Emergency = sbs1.Flag(7500 == squawk || 7600 == squawk || 7700 == squawk)
+		}
+	}
+	return msg, true
+}
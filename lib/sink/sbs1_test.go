@@ -0,0 +1,93 @@
+package sink
+
+import (
+	"bufio"
+	"net"
+	"plane.watch/lib/tracker"
+	"plane.watch/lib/tracker/mode_s"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSbs1Sink_sendFrame(t *testing.T) {
+	s, err := NewSbs1Sink(WithHost("127.0.0.1", "0"))
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+	var sink tracker.Sink = s
+	if ds, ok := sink.(tracker.DecodedFrameSink); !ok || !ds.WantsDecodedFrames() {
+		t.Error("Expected the SBS-1 sink to ask for every decoded frame")
+	}
+
+	conn, err := net.Dial("tcp", s.Addr().String())
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	for 0 == s.server.numClients() {
+		time.Sleep(time.Millisecond)
+	}
+
+	trk := tracker.NewTracker()
+	defer trk.Finish()
+	p := trk.GetPlane(0x7C7DAA)
+	for _, payload := range []mode_s.Payload{
+		mode_s.AdsbIdentification{Callsign: "QFA123"},
+		mode_s.AdsbAirbornePosition{Altitude: 35000, Lat: -31.95, Lon: 115.94},
+		mode_s.AdsbAirbornePosition{Altitude: 35000, Lat: -31.95, Lon: 115.94, Odd: true},
+		mode_s.AdsbAirborneVelocity{Heading: 90, GroundSpeed: 400},
+	} {
+		encoded, err := mode_s.EncodeExtendedSquitter(0x7C7DAA, payload)
+		if nil != err {
+			t.Fatal(err)
+		}
+		frame, err := mode_s.DecodeBytes(encoded.Bytes(), time.Now())
+		if nil != err {
+			t.Fatal(err)
+		}
+		p.HandleModeSFrame(frame, nil, nil)
+		s.sendFrame(frame, p)
+	}
+	squawk, err := mode_s.EncodeSurveillanceIdentity(0x7C7DAA, 0, 7700)
+	if nil != err {
+		t.Fatal(err)
+	}
+	frame, err := mode_s.DecodeBytes(squawk.Bytes(), time.Now())
+	if nil != err {
+		t.Fatal(err)
+	}
+	p.HandleModeSFrame(frame, nil, nil)
+	s.sendFrame(frame, p)
+
+	// the timestamps change, so we compare the fields before them and (after the |) the fields after them
+	want := []string{
+		"AIR,,1,1,7C7DAA,1",
+		"ID,,1,1,7C7DAA,1|QFA123",
+		"MSG,1,1,1,7C7DAA,1|QFA123,,,,,,,,,,,",
+		"MSG,3,1,1,7C7DAA,1|,35000,,,,,,,0,0,0,0",
+		"MSG,3,1,1,7C7DAA,1|,35000,,,-31.94998,115.93998,,,0,0,0,0",
+		"MSG,4,1,1,7C7DAA,1|,,400,90,,,0,,,,,0",
+		"MSG,6,1,1,7C7DAA,1|,,,,,,,7700,0,-1,0,0",
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	reader := bufio.NewReader(conn)
+	for _, w := range want {
+		line, err := reader.ReadString('\n')
+		if nil != err {
+			t.Fatalf("Failed to read %q: %s", w, err)
+		}
+		if !strings.HasSuffix(line, "\r\n") {
+			t.Errorf("Expected a \\r\\n line ending, got %q", line)
+		}
+		bits := strings.Split(strings.TrimSpace(line), ",")
+		got := strings.Join(bits[:6], ",")
+		if len(bits) > 10 {
+			got += "|" + strings.Join(bits[10:], ",")
+		}
+		if got != w {
+			t.Errorf("Incorrect line\n%q, want\n%q", got, w)
+		}
+	}
+}
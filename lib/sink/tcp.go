@@ -0,0 +1,140 @@
+package sink
+
+import (
+	"github.com/rs/zerolog/log"
+	"io"
+	"io/ioutil"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	// tcpClientBuffer is how many messages we will queue for a client before deciding it is too slow to keep up
+	tcpClientBuffer = 1024
+	// tcpWriteTimeout is how long we wait for a client to take a single message before giving up on it
+	tcpWriteTimeout = 10 * time.Second
+)
+
+type (
+	// tcpServer sends everything it is given to every connected client. Clients that cannot keep up are
+	// disconnected, rather than holding up everyone else
+	tcpServer struct {
+		name     string
+		listener net.Listener
+
+		clientLock sync.Mutex
+		clients    map[net.Conn]chan []byte
+
+		waiter *sync.WaitGroup
+	}
+)
+
+// newTcpServer starts listening on host:port and accepting clients
+func newTcpServer(name, host, port string, waiter *sync.WaitGroup) (*tcpServer, error) {
+	listener, err := net.Listen("tcp", net.JoinHostPort(host, port))
+	if nil != err {
+		return nil, err
+	}
+	s := &tcpServer{
+		name:     name,
+		listener: listener,
+		clients:  map[net.Conn]chan []byte{},
+		waiter:   waiter,
+	}
+	log.Info().Str("addr", listener.Addr().String()).Str("format", name).Msg("Serving TCP clients")
+
+	s.waiter.Add(1)
+	go s.accept()
+	return s, nil
+}
+
+func (s *tcpServer) accept() {
+	defer s.waiter.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if nil != err {
+			// we have been closed
+			return
+		}
+		log.Debug().Str("client", conn.RemoteAddr().String()).Str("format", s.name).Msg("Client connected")
+
+		out := make(chan []byte, tcpClientBuffer)
+		s.clientLock.Lock()
+		s.clients[conn] = out
+		s.clientLock.Unlock()
+
+		s.waiter.Add(2)
+		go s.write(conn, out)
+		go s.discard(conn)
+	}
+}
+
+// write sends our messages to a single client
+func (s *tcpServer) write(conn net.Conn, out chan []byte) {
+	defer s.waiter.Done()
+	for msg := range out {
+		_ = conn.SetWriteDeadline(time.Now().Add(tcpWriteTimeout))
+		if _, err := conn.Write(msg); nil != err {
+			log.Debug().Err(err).Str("client", conn.RemoteAddr().String()).Str("format", s.name).Msg("Client went away")
+			s.disconnect(conn)
+			break
+		}
+	}
+	_ = conn.Close()
+	// drain anything queued before the disconnect
+	for range out {
+	}
+}
+
+// discard reads (and ignores) anything a client sends, so we notice when it hangs up
+func (s *tcpServer) discard(conn net.Conn) {
+	defer s.waiter.Done()
+	_, _ = io.Copy(ioutil.Discard, conn)
+	s.disconnect(conn)
+}
+
+func (s *tcpServer) disconnect(conn net.Conn) {
+	s.clientLock.Lock()
+	defer s.clientLock.Unlock()
+	if out, ok := s.clients[conn]; ok {
+		delete(s.clients, conn)
+		close(out)
+	}
+}
+
+// broadcast sends msg to every client
+func (s *tcpServer) broadcast(msg []byte) {
+	s.clientLock.Lock()
+	defer s.clientLock.Unlock()
+	for conn, out := range s.clients {
+		select {
+		case out <- msg:
+		default:
+			log.Warn().Str("client", conn.RemoteAddr().String()).Str("format", s.name).Msg("Client is too slow, disconnecting")
+			delete(s.clients, conn)
+			close(out)
+			// the writer is most likely stuck in conn.Write(), closing the connection gets it out
+			_ = conn.Close()
+		}
+	}
+}
+
+// numClients is how many clients are currently connected
+func (s *tcpServer) numClients() int {
+	s.clientLock.Lock()
+	defer s.clientLock.Unlock()
+	return len(s.clients)
+}
+
+// close stops accepting clients and hangs up on the ones we have
+func (s *tcpServer) close() {
+	_ = s.listener.Close()
+	s.clientLock.Lock()
+	defer s.clientLock.Unlock()
+	for conn, out := range s.clients {
+		delete(s.clients, conn)
+		close(out)
+		_ = conn.Close()
+	}
+}
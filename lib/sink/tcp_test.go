@@ -0,0 +1,44 @@
+package sink
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestTcpServer_slowClient(t *testing.T) {
+	f, err := NewAvrFrameSink(WithHost("127.0.0.1", "0"))
+	if nil != err {
+		t.Fatal(err)
+	}
+	// a client that never reads anything we send it
+	conn, err := net.Dial("tcp", f.Addr().String())
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	for 0 == f.server.numClients() {
+		time.Sleep(time.Millisecond)
+	}
+
+	// enough to fill the socket buffers and then the client's queue
+	msg := bytes.Repeat([]byte("*8D7C49F85841D26CCA3933E41ECF;\n"), 2048)
+	for i := 0; i < 100000 && 0 != f.server.numClients(); i++ {
+		f.server.broadcast(msg)
+	}
+	if 0 != f.server.numClients() {
+		t.Fatal("Expected the slow client to be disconnected")
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		f.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Stop() did not return, the slow client's writer is still stuck")
+	}
+}
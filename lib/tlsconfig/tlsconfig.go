@@ -0,0 +1,42 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+// Client is the TLS setup for connecting to serverName. certFile and keyFile are our client certificate, if the
+// server wants one, and with a caFile we only trust servers with a certificate signed by it (otherwise the
+// system roots are used)
+func Client(serverName, certFile, keyFile, caFile string) (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12, ServerName: serverName}
+	if "" != certFile {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if nil != err {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	if "" != caFile {
+		var err error
+		if config.RootCAs, err = LoadCaFile(caFile); nil != err {
+			return nil, err
+		}
+	}
+	return config, nil
+}
+
+// LoadCaFile is a pool of just the certificates in fileName
+func LoadCaFile(fileName string) (*x509.CertPool, error) {
+	caPem, err := os.ReadFile(fileName)
+	if nil != err {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPem) {
+		return nil, fmt.Errorf("no certificates found in %s", fileName)
+	}
+	return pool, nil
+}
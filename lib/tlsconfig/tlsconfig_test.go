@@ -0,0 +1,84 @@
+package tlsconfig
+
+import (
+	"crypto/x509"
+	"os"
+	"path/filepath"
+	"plane.watch/internal/testcert"
+	"testing"
+)
+
+func TestClient(t *testing.T) {
+	dir := t.TempDir()
+	ca, caKey := testcert.Write(t, dir, "ca", nil, nil)
+	server, _ := testcert.Write(t, dir, "server", ca, caKey)
+	testcert.Write(t, dir, "client", ca, caKey)
+	other, _ := testcert.Write(t, dir, "other", nil, nil)
+	_ = os.WriteFile(filepath.Join(dir, "empty.pem"), []byte("not a certificate"), 0600)
+	file := func(name string) string {
+		return filepath.Join(dir, name)
+	}
+
+	// with no files we use the system roots and have no client certificate
+	config, err := Client("server", "", "", "")
+	if nil != err {
+		t.Fatal(err)
+	}
+	if nil != config.RootCAs || 0 != len(config.Certificates) {
+		t.Error("Did not expect a pinned CA or a client certificate")
+	}
+	if "server" != config.ServerName {
+		t.Errorf("Incorrect server name %q", config.ServerName)
+	}
+
+	config, err = Client("server", file("client.pem"), file("client.key"), file("ca.pem"))
+	if nil != err {
+		t.Fatal(err)
+	}
+	if 1 != len(config.Certificates) {
+		t.Fatalf("Expected our client certificate, got %d certificates", len(config.Certificates))
+	}
+	if leaf, err := x509.ParseCertificate(config.Certificates[0].Certificate[0]); nil != err || "client" != leaf.Subject.CommonName {
+		t.Errorf("Incorrect client certificate %v", err)
+	}
+	// only servers signed by our CA are trusted
+	if _, err = server.Verify(x509.VerifyOptions{Roots: config.RootCAs, DNSName: "server"}); nil != err {
+		t.Errorf("Expected to trust a server signed by our CA: %s", err)
+	}
+	if _, err = other.Verify(x509.VerifyOptions{Roots: config.RootCAs, DNSName: "other"}); nil == err {
+		t.Error("Did not expect to trust a server signed by someone else")
+	}
+
+	for name, bad := range map[string][3]string{
+		"missing key":        {file("client.pem"), file("missing.key"), ""},
+		"mismatched key":     {file("client.pem"), file("other.key"), ""},
+		"missing CA":         {"", "", file("missing.pem")},
+		"no CA certificates": {"", "", file("empty.pem")},
+	} {
+		if _, err = Client("server", bad[0], bad[1], bad[2]); nil == err {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestLoadCaFile(t *testing.T) {
+	dir := t.TempDir()
+	ca, caKey := testcert.Write(t, dir, "ca", nil, nil)
+	server, _ := testcert.Write(t, dir, "server", ca, caKey)
+	_ = os.WriteFile(filepath.Join(dir, "empty.pem"), []byte("not a certificate"), 0600)
+
+	pool, err := LoadCaFile(filepath.Join(dir, "ca.pem"))
+	if nil != err {
+		t.Fatal(err)
+	}
+	if _, err = server.Verify(x509.VerifyOptions{Roots: pool, DNSName: "server"}); nil != err {
+		t.Errorf("Expected the pool to have our CA: %s", err)
+	}
+
+	if _, err = LoadCaFile(filepath.Join(dir, "missing.pem")); nil == err {
+		t.Error("Expected an error for a missing file")
+	}
+	if _, err = LoadCaFile(filepath.Join(dir, "empty.pem")); nil == err {
+		t.Error("Expected an error for a file without certificates")
+	}
+}
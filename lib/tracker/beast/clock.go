@@ -0,0 +1,177 @@
+package beast
+
+import (
+	"plane.watch/lib/tracker/mode_s"
+	"sync"
+	"time"
+)
+
+const (
+	// beastTickRate is the frequency of the 48 bit MLAT counter on a Beast (and a Radarcape not using GPS time)
+	beastTickRate = mode_s.BeastTickRate
+
+	// clockResyncThreshold is how far the counter can disagree with the wall clock before we assume the
+	// receiver has restarted (or we have been paused) and start again
+	clockResyncThreshold = 2 * time.Second
+
+	// clockDriftCorrection is how quickly we let the offset creep forward to follow crystal drift. The offset
+	// only ever jumps backwards because network latency only ever makes frames look late
+	clockDriftCorrection = 0.001
+
+	// radarcapeGpsTimestamps is the status settings bit that tells us the timestamps are GPS time
+	radarcapeGpsTimestamps = 0x10
+)
+
+type (
+	// Clock turns the MLAT timestamp of frames from a single receiver into wall clock time.
+	// For a Beast (or a Radarcape without GPS) the timestamp is a 12MHz counter from when it was turned on, so
+	// we model the offset between the counter and the wall clock. A Radarcape with a GPS fix sends the time of day.
+	// Each source needs its own Clock
+	Clock struct {
+		mu sync.Mutex
+
+		synced    bool
+		refTicks  uint64
+		refTime   time.Time
+		offset    time.Duration // refTime + offset + ticks since refTicks is the frame time
+		gpsTime   bool
+		replay    bool // frames are not arriving in real time, so only the counter matters
+		status    RadarcapeStatus
+		hasStatus bool
+	}
+
+	// RadarcapeStatus is the 0x34 status frame a Radarcape sends once a second
+	RadarcapeStatus struct {
+		// Settings is the DIP switch/config byte
+		Settings byte
+		// GpsTimestamps is true when the MLAT timestamps are GPS time of day, not a 12MHz counter
+		GpsTimestamps bool
+		// TimestampError is the difference between the GPS PPS and the counter in ticks
+		TimestampError int8
+	}
+)
+
+// NewClock creates a clock for a new source
+func NewClock() *Clock {
+	return &Clock{}
+}
+
+// NewReplayClock creates a clock for frames that are being read faster than real time (e.g. from a file).
+// The first frame is anchored to the wall clock and the rest follow the counter
+func NewReplayClock() *Clock {
+	return &Clock{replay: true}
+}
+
+// decodeRadarcapeStatus decodes the body of a 0x34 status frame
+func decodeRadarcapeStatus(body []byte) (RadarcapeStatus, bool) {
+	if len(body) < 2 {
+		return RadarcapeStatus{}, false
+	}
+	return RadarcapeStatus{
+		Settings:       body[0],
+		GpsTimestamps:  body[0]&radarcapeGpsTimestamps != 0,
+		TimestampError: int8(body[1]),
+	}, true
+}
+
+// Status is the last Radarcape status we have seen from this source
+func (c *Clock) Status() (RadarcapeStatus, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.status, c.hasStatus
+}
+
+// Apply works out the time a frame was received and sets its timestamp. received is when we read the frame
+func (c *Clock) Apply(f *Frame, received time.Time) {
+	if nil == f {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if f.IsRadarcapeStatus() {
+		if status, ok := decodeRadarcapeStatus(f.body); ok {
+			c.status = status
+			c.hasStatus = true
+			if c.gpsTime != status.GpsTimestamps {
+				c.gpsTime = status.GpsTimestamps
+				c.synced = false
+			}
+		}
+		f.SetTimeStamp(received)
+		return
+	}
+
+	if f.IsMlat() {
+		// a synthetic frame from an MLAT server, the timestamp means nothing
+		f.SetTimeStamp(received)
+		return
+	}
+
+	ticks := f.mlatTicks()
+	if 0 == ticks {
+		f.SetTimeStamp(received)
+		return
+	}
+
+	if c.gpsTime || (f.isRadarCape && !c.hasStatus) {
+		if t, ok := gpsTimeOfDay(ticks, received); ok {
+			f.SetTimeStamp(t)
+			return
+		}
+	}
+	f.SetTimeStamp(c.counterTime(ticks, received))
+}
+
+// counterTime maps a 12MHz counter value onto the wall clock
+func (c *Clock) counterTime(ticks uint64, received time.Time) time.Time {
+	if !c.synced || ticks < c.refTicks {
+		c.resync(ticks, received)
+		return received
+	}
+	elapsed := mode_s.BeastTicksToDuration(ticks - c.refTicks)
+	t := c.refTime.Add(c.offset + elapsed)
+	if c.replay {
+		return t
+	}
+
+	// how late did this frame turn up compared to what we expected?
+	late := received.Sub(t)
+	if late > clockResyncThreshold || late < -clockResyncThreshold {
+		c.resync(ticks, received)
+		return received
+	}
+	if late < 0 {
+		// it got here quicker than any frame before it, so our offset was too big
+		c.offset += late
+		return received
+	}
+	c.offset += time.Duration(float64(late) * clockDriftCorrection)
+	return c.refTime.Add(c.offset + elapsed)
+}
+
+func (c *Clock) resync(ticks uint64, received time.Time) {
+	c.synced = true
+	c.refTicks = ticks
+	c.refTime = received
+	c.offset = 0
+}
+
+// gpsTimeOfDay decodes a Radarcape GPS timestamp, 18 bits of seconds since midnight (UTC) and 30 bits of
+// nanoseconds. The date comes from when we received the frame, allowing for frames either side of midnight
+func gpsTimeOfDay(ticks uint64, received time.Time) (time.Time, bool) {
+	secondOfDay := ticks >> 30
+	nanoseconds := ticks & 0x3FFFFFFF
+	if secondOfDay >= 86400 || nanoseconds >= uint64(time.Second) {
+		return time.Time{}, false
+	}
+	utc := received.UTC()
+	midnight := time.Date(utc.Year(), utc.Month(), utc.Day(), 0, 0, 0, 0, time.UTC)
+	t := midnight.Add(time.Duration(secondOfDay)*time.Second + time.Duration(nanoseconds))
+	if diff := t.Sub(utc); diff > 12*time.Hour {
+		t = t.Add(-24 * time.Hour)
+	} else if diff < -12*time.Hour {
+		t = t.Add(24 * time.Hour)
+	}
+	return t, true
+}
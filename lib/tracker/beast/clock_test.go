@@ -0,0 +1,127 @@
+package beast
+
+import (
+	"testing"
+	"time"
+)
+
+func testFrame(msgType byte, ticks uint64, body ...byte) *Frame {
+	raw := []byte{0x1A, msgType}
+	for shift := 40; shift >= 0; shift -= 8 {
+		raw = append(raw, byte(ticks>>uint(shift)))
+	}
+	raw = append(raw, 0x20)
+	return NewFrame(append(raw, body...), false)
+}
+
+func testModeSFrame(ticks uint64) *Frame {
+	return testFrame(0x32, ticks, 0x5d, 0x7c, 0x49, 0xf8, 0x28, 0xe9, 0x43)
+}
+
+func TestClock_Counter(t *testing.T) {
+	c := NewClock()
+	start := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+	base := uint64(1_000 * beastTickRate)
+
+	f := testModeSFrame(base)
+	c.Apply(f, start)
+	if !start.Equal(f.TimeStamp()) {
+		t.Errorf("First frame should anchor the clock. %s != %s", start, f.TimeStamp())
+	}
+	if !start.Equal(f.AvrFrame().TimeStamp()) {
+		t.Error("The Mode S frame should have the same timestamp")
+	}
+
+	// one second later by the counter, but it took 50ms longer to get to us
+	f = testModeSFrame(base + beastTickRate)
+	c.Apply(f, start.Add(1050*time.Millisecond))
+	if diff := f.TimeStamp().Sub(start.Add(time.Second)); diff < 0 || diff > time.Millisecond {
+		t.Errorf("Expected the counter time, got %s", f.TimeStamp())
+	}
+
+	// this one got here faster than we thought possible, so our offset is pulled back
+	f = testModeSFrame(base + 2*beastTickRate)
+	received := start.Add(1990 * time.Millisecond)
+	c.Apply(f, received)
+	if !received.Equal(f.TimeStamp()) {
+		t.Errorf("Expected the received time, got %s", f.TimeStamp())
+	}
+	f = testModeSFrame(base + 3*beastTickRate)
+	c.Apply(f, start.Add(3100*time.Millisecond))
+	if diff := f.TimeStamp().Sub(start.Add(2990 * time.Millisecond)); diff < 0 || diff > time.Millisecond {
+		t.Errorf("Expected the offset to follow the fastest frame, got %s", f.TimeStamp())
+	}
+
+	// the receiver restarted
+	f = testModeSFrame(beastTickRate)
+	received = start.Add(5 * time.Second)
+	c.Apply(f, received)
+	if !received.Equal(f.TimeStamp()) {
+		t.Errorf("Expected a resync after a restart, got %s", f.TimeStamp())
+	}
+}
+
+func TestClock_Replay(t *testing.T) {
+	c := NewReplayClock()
+	start := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+	c.Apply(testModeSFrame(beastTickRate), start)
+
+	// read straight after the first, but it happened a minute later
+	f := testModeSFrame(61 * beastTickRate)
+	c.Apply(f, start.Add(time.Millisecond))
+	if !start.Add(time.Minute).Equal(f.TimeStamp()) {
+		t.Errorf("Expected the replay to follow the counter, got %s", f.TimeStamp())
+	}
+}
+
+func TestClock_Mlat(t *testing.T) {
+	c := NewClock()
+	f := testFrame(0x32, 0xFF004D4C4154, 0x5d, 0x7c, 0x49, 0xf8, 0x28, 0xe9, 0x43)
+	if !f.IsMlat() {
+		t.Fatal("Expected the MLAT magic timestamp")
+	}
+	received := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+	c.Apply(f, received)
+	if !received.Equal(f.TimeStamp()) {
+		t.Errorf("MLAT frames should use the received time, got %s", f.TimeStamp())
+	}
+}
+
+func TestClock_RadarcapeGps(t *testing.T) {
+	c := NewClock()
+	status := testFrame(0x34, 0, 0x10|0x01, 0xFE)
+	c.Apply(status, time.Now())
+	s, ok := c.Status()
+	if !ok || !s.GpsTimestamps || -2 != s.TimestampError {
+		t.Errorf("Incorrect Radarcape status: %+v", s)
+	}
+
+	tests := []struct {
+		name     string
+		ticks    uint64
+		received time.Time
+		want     time.Time
+	}{
+		{
+			name:     "same day",
+			ticks:    3600<<30 | 500_000_000,
+			received: time.Date(2021, 6, 1, 1, 0, 0, 600_000_000, time.UTC),
+			want:     time.Date(2021, 6, 1, 1, 0, 0, 500_000_000, time.UTC),
+		},
+		{
+			name:     "just before midnight",
+			ticks:    86399 << 30,
+			received: time.Date(2021, 6, 2, 0, 0, 1, 0, time.UTC),
+			want:     time.Date(2021, 6, 1, 23, 59, 59, 0, time.UTC),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := testModeSFrame(tt.ticks)
+			c.Apply(f, tt.received)
+			if !tt.want.Equal(f.TimeStamp()) {
+				t.Errorf("Expected %s, got %s", tt.want, f.TimeStamp())
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package beast
 
 import (
 	"fmt"
+	"math"
 	"plane.watch/lib/tracker/mode_s"
 	"time"
 )
@@ -17,10 +18,19 @@ type (
 		isRadarCape  bool
 		hasDecoded   bool
 		decodedModeS *mode_s.Frame
+		modeS        mode_s.Frame // decodedModeS points in here, saves an allocation per frame
+		timeStamp    time.Time
+
+		modeAC    mode_s.ModeAC
+		modeACErr error
 	}
 )
 
 func (f *Frame) Icao() uint32 {
+	if nil == f.decodedModeS {
+		// Mode A/C replies and status frames do not carry an address
+		return 0
+	}
 	if !f.hasDecoded {
 		_, _ = f.Decode()
 	}
@@ -28,6 +38,9 @@ func (f *Frame) Icao() uint32 {
 }
 
 func (f *Frame) IcaoStr() string {
+	if nil == f.decodedModeS {
+		return ""
+	}
 	if !f.hasDecoded {
 		_, _ = f.Decode()
 	}
@@ -35,13 +48,39 @@ func (f *Frame) IcaoStr() string {
 }
 
 func (f *Frame) Decode() (bool, error) {
+	return f.DecodeWithCorrection(mode_s.CorrectionNone)
+}
+
+// DecodeWithCorrection decodes the frame, trying as hard as level says to fix the Mode S checksum (see
+// mode_s.DecodeWithCorrection)
+func (f *Frame) DecodeWithCorrection(level int) (bool, error) {
 	f.hasDecoded = true
-	return f.decodedModeS.Decode()
+	if f.IsModeAC() {
+		return nil == f.modeACErr, f.modeACErr
+	}
+	return f.decodedModeS.DecodeWithCorrection(level)
 }
 
+// CorrectedBits is the number of bit errors error correction fixed in the Mode S frame
+func (f *Frame) CorrectedBits() byte {
+	if nil == f.decodedModeS {
+		return 0
+	}
+	return f.decodedModeS.CorrectedBits()
+}
+
+// TimeStamp is when the frame was received. Use a Clock to work this out from the MLAT timestamp, otherwise
+// it is when the frame was created
 func (f *Frame) TimeStamp() time.Time {
-	// todo: calculate this off the mlat timestamp
-	return time.Now()
+	return f.timeStamp
+}
+
+// SetTimeStamp sets when the frame was received (and the timestamp of the Mode S frame inside it)
+func (f *Frame) SetTimeStamp(t time.Time) {
+	f.timeStamp = t
+	if nil != f.decodedModeS {
+		f.decodedModeS.SetTimeStamp(t)
+	}
 }
 
 func (f *Frame) Raw() []byte {
@@ -51,82 +90,128 @@ func (f *Frame) Raw() []byte {
 var magicTimestampMLAT = []byte{0xFF, 0x00, 0x4D, 0x4C, 0x41, 0x54}
 
 func newBeastMsg(rawBytes []byte) *Frame {
-	if len(rawBytes) <= 8 {
+	f := &Frame{}
+	if !f.resetBeastMsg(rawBytes) {
 		return nil
 	}
+	return f
+}
+
+// resetBeastMsg turns f into a new (not yet decoded) frame for rawBytes, false if it is not a BEAST frame
+func (f *Frame) resetBeastMsg(rawBytes []byte) bool {
+	if len(rawBytes) <= 8 {
+		return false
+	}
 	// decode beast into AVR
 	if rawBytes[0] != 0x1A {
 		// invalid frame
-		return nil
+		return false
 	}
 	if rawBytes[1] < 0x31 || rawBytes[1] > 0x34 {
-		return nil
+		return false
 	}
-	return &Frame{
+	*f = Frame{
 		raw:           rawBytes,
 		msgType:       rawBytes[1],
 		mlatTimestamp: rawBytes[2:8],
 		signalLevel:   rawBytes[8],
 		body:          rawBytes[9:],
+		timeStamp:     time.Now(),
 	}
+	return true
 }
 
 func NewFrame(rawBytes []byte, isRadarCape bool) *Frame {
-	if f := newBeastMsg(rawBytes); nil != f {
-		f.isRadarCape = isRadarCape
-		//if (mm->signalLevel > 0)
-		//        printf("RSSI: %.1f dBFS\n", 10 * log10(mm->signalLevel));
-		switch f.msgType {
-		case 0x31:
-			// mode-ac 10 bytes (2+8)
-			f.decodeModeAc()
-		case 0x32:
-			// mode-s short 15 bytes
-			f.decodedModeS = f.decodeModeSShort()
-		case 0x33:
-			// mode-s long 22 bytes
-			f.decodedModeS = f.decodeModeSLong()
-		case 0x34:
-			// signal strength 10 bytes
-			f.decodeConfig()
-		default:
-			return nil
-		}
-		return f
+	f := &Frame{}
+	if !f.ResetFromBytes(rawBytes, isRadarCape) {
+		return nil
 	}
+	return f
+}
 
-	return nil
+// ResetFromBytes turns f into a new frame for rawBytes, the same as NewFrame but without allocating, so a frame can
+// be reused for every frame off the wire. Everything f knew before is forgotten, and like NewFrame, rawBytes is not
+// copied. false if rawBytes is not a BEAST frame
+func (f *Frame) ResetFromBytes(rawBytes []byte, isRadarCape bool) bool {
+	if !f.resetBeastMsg(rawBytes) {
+		return false
+	}
+	f.isRadarCape = isRadarCape
+	//if (mm->signalLevel > 0)
+	//        printf("RSSI: %.1f dBFS\n", 10 * log10(mm->signalLevel));
+	switch f.msgType {
+	case 0x31:
+		// mode-ac 10 bytes (2+8)
+		f.decodeModeAc()
+	case 0x32:
+		// mode-s short 15 bytes
+		f.decodedModeS = f.decodeModeS()
+	case 0x33:
+		// mode-s long 22 bytes
+		f.decodedModeS = f.decodeModeS()
+	case 0x34:
+		// signal strength 10 bytes
+		f.decodeConfig()
+	default:
+		return false
+	}
+	return true
 }
 
 func (f *Frame) decodeModeAc() {
-	// TODO: Decode ModeAC
+	f.modeAC, f.modeACErr = mode_s.DecodeModeAC(f.body)
 }
 
-func (f *Frame) decodeModeSShort() *mode_s.Frame {
-	return mode_s.NewFrame(f.avr(), time.Now())
+// IsModeAC tells us if this is a Mode A/C reply (0x31) instead of a Mode S frame
+func (f *Frame) IsModeAC() bool {
+	return 0x31 == f.msgType
 }
 
-func (f *Frame) decodeModeSLong() *mode_s.Frame {
-	return mode_s.NewFrame(f.avr(), time.Now())
+// ModeAC is the decoded Mode A/C reply, only valid if IsModeAC()
+func (f *Frame) ModeAC() mode_s.ModeAC {
+	return f.modeAC
+}
+
+// decodeModeS hands the message straight to mode_s, there is no need to go via an AVR string
+func (f *Frame) decodeModeS() *mode_s.Frame {
+	if !f.modeS.ResetFromBytes(f.body, f.timeStamp) {
+		return nil
+	}
+	f.modeS.SetBeastTicks(f.mlatTicks())
+	return &f.modeS
 }
 
 func (f *Frame) decodeConfig() {
-	// TODO: Decode RadarCape Config Info
+	f.isRadarCape = true
 }
 
-func (f *Frame) avr() string {
-	return fmt.Sprintf("@%X%X;", f.mlatTimestamp, f.body)
+// IsRadarcapeStatus tells us if this is a Radarcape status (0x34) frame
+func (f *Frame) IsRadarcapeStatus() bool {
+	return 0x34 == f.msgType
 }
 
-// BeastTicksNs returns the number of nanoseconds the beast has been on for (the mlat timestamp is calculated from power on)
-func (f *Frame) BeastTicksNs() time.Duration {
+// RadarcapeStatus decodes a Radarcape status frame
+func (f *Frame) RadarcapeStatus() (RadarcapeStatus, bool) {
+	if !f.IsRadarcapeStatus() {
+		return RadarcapeStatus{}, false
+	}
+	return decodeRadarcapeStatus(f.body)
+}
+
+// mlatTicks is the 48 bit MLAT timestamp
+func (f *Frame) mlatTicks() uint64 {
 	var t uint64
 	inc := 40
 	for i := 0; i < 6; i++ {
 		t = t | uint64(f.mlatTimestamp[i])<<inc
 		inc -= 8
 	}
-	return time.Duration(t * 500)
+	return t
+}
+
+// BeastTicksNs returns how long the beast has been on for (the mlat timestamp is a 12MHz counter from power on)
+func (f *Frame) BeastTicksNs() time.Duration {
+	return mode_s.BeastTicksToDuration(f.mlatTicks())
 }
 
 func (f *Frame) String() string {
@@ -145,7 +230,24 @@ func (f *Frame) String() string {
 	)
 }
 
-func (f *Frame) isMlat() bool {
+// SignalLevel is the raw signal level (RSSI) byte, 0 means the receiver did not tell us
+func (f *Frame) SignalLevel() byte {
+	return f.signalLevel
+}
+
+// SignalValid tells us if the receiver gave us a signal level for this frame
+func (f *Frame) SignalValid() bool {
+	return 0 != f.signalLevel && !f.IsRadarcapeStatus()
+}
+
+// SignalDbfs is the signal level in dBFS. The byte is the square root of the signal power, scaled to 255
+func (f *Frame) SignalDbfs() float64 {
+	level := float64(f.signalLevel) / 255
+	return 10 * math.Log10(level*level)
+}
+
+// IsMlat tells us if this frame has the magic MLAT timestamp, meaning it was made up by an MLAT server
+func (f *Frame) IsMlat() bool {
 	for i, b := range magicTimestampMLAT {
 		if b != f.raw[i+2] {
 			return false
@@ -164,3 +266,25 @@ func (f *Frame) AvrFrame() *mode_s.Frame {
 func (f *Frame) AvrRaw() []byte {
 	return f.body
 }
+
+// Avr is the Mode S (or Mode A/C) message in AVR format, e.g. *8D4840D6202CC371C32CE0576098; Radarcape
+// status frames have no AVR version, so they give us ""
+func (f *Frame) Avr() string {
+	if f.IsRadarcapeStatus() {
+		return ""
+	}
+	return fmt.Sprintf("*%X;", f.body)
+}
+
+// Escaped is the frame as it goes over the wire, every 0x1A after the start of the frame is doubled up
+func (f *Frame) Escaped() []byte {
+	out := make([]byte, 0, 2*len(f.raw))
+	out = append(out, f.raw[0])
+	for _, b := range f.raw[1:] {
+		out = append(out, b)
+		if 0x1A == b {
+			out = append(out, b)
+		}
+	}
+	return out
+}
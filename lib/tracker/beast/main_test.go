@@ -2,6 +2,8 @@ package beast
 
 import (
 	"bytes"
+	"math"
+	"plane.watch/lib/tracker/mode_s"
 	"reflect"
 	"testing"
 )
@@ -25,6 +27,39 @@ func TestNewBeastMsgModeAC(t *testing.T) {
 	}
 }
 
+func TestNewFrameModeAC(t *testing.T) {
+	f := NewFrame([]byte{0x1A, 0x31, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x20, 0x65, 0x20}, false)
+	if nil == f {
+		t.Fatal("Did not get a beast frame")
+	}
+	if !f.IsModeAC() {
+		t.Error("Expected a Mode A/C frame")
+	}
+	if ok, err := f.Decode(); !ok || nil != err {
+		t.Errorf("Failed to decode the Mode A/C frame: %s", err)
+	}
+	if 0 != f.Icao() {
+		t.Error("Mode A/C frames do not have an ICAO")
+	}
+	ac := f.ModeAC()
+	if 6520 != ac.Squawk || !ac.HasAltitude || 10000 != ac.Altitude {
+		t.Errorf("Incorrectly decoded Mode A/C: %s", ac)
+	}
+}
+
+func TestFrame_SignalDbfs(t *testing.T) {
+	f := NewFrame(beastModeSShort, false)
+	if !f.SignalValid() {
+		t.Fatal("Expected a signal level")
+	}
+	if dbfs := f.SignalDbfs(); math.Abs(dbfs-(-16.535)) > 0.001 {
+		t.Errorf("Incorrect signal level. expected -16.535 dBFS, got %0.3f", dbfs)
+	}
+	if f = NewFrame(beastModeAc, false); f.SignalValid() {
+		t.Error("A signal level of 0 is not valid")
+	}
+}
+
 func TestNewBeastMsgModeSShort(t *testing.T) {
 	f := newBeastMsg(beastModeSShort)
 
@@ -113,3 +148,100 @@ func Test_newBeastMsg(t *testing.T) {
 		})
 	}
 }
+
+func TestNewFrameModeSLong(t *testing.T) {
+	f := NewFrame(beastModeSLong, false)
+	if ok, err := f.Decode(); !ok || nil != err {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+	if "7C49F8" != f.IcaoStr() {
+		t.Errorf("Incorrect ICAO: %s", f.IcaoStr())
+	}
+	if "8D7C49F85841D26CCA3933E41ECF" != string(f.AvrFrame().Raw()) {
+		t.Errorf("Incorrect AVR frame: %s", f.AvrFrame().Raw())
+	}
+	if f.AvrFrame().BeastTicksNs() == 0 {
+		t.Error("Did not pass the MLAT timestamp on")
+	}
+	if f.AvrFrame().BeastTicksNs() != f.BeastTicksNs() {
+		t.Errorf("Mode S frame disagrees on the MLAT timestamp: %s != %s", f.AvrFrame().BeastTicksNs(), f.BeastTicksNs())
+	}
+}
+
+func TestNewFrameModeSLongCorrected(t *testing.T) {
+	msg := make([]byte, len(beastModeSLong))
+	copy(msg, beastModeSLong)
+	msg[14] ^= 0x01 // one bit into the ME field
+
+	f := NewFrame(msg, false)
+	if ok, err := f.DecodeWithCorrection(mode_s.CorrectionSingleBit); !ok || nil != err {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+	if 1 != f.AvrFrame().CorrectedBits() {
+		t.Errorf("Expected 1 corrected bit, got %d", f.AvrFrame().CorrectedBits())
+	}
+	if "8D7C49F85841D26CCA3933E41ECF" != string(f.AvrFrame().Raw()) {
+		t.Errorf("Frame was not corrected: %s", f.AvrFrame().Raw())
+	}
+}
+
+func TestFrame_Escaped(t *testing.T) {
+	unescaped := []byte{0x1a, 0x33, 0x22, 0x1b, 0x55, 0xe4, 0x1a, 0xa2, 0x2d, 0x8d, 0x7c, 0x49, 0xf8, 0xe1, 0x1e, 0x2f, 0x00, 0x00, 0x00, 0x00, 0xee, 0xcc, 0x47}
+	escaped := []byte{0x1a, 0x33, 0x22, 0x1b, 0x55, 0xe4, 0x1a, 0x1a, 0xa2, 0x2d, 0x8d, 0x7c, 0x49, 0xf8, 0xe1, 0x1e, 0x2f, 0x00, 0x00, 0x00, 0x00, 0xee, 0xcc, 0x47}
+	if got := NewFrame(unescaped, false).Escaped(); !bytes.Equal(escaped, got) {
+		t.Errorf("Incorrectly escaped\n%X, expected\n%X", got, escaped)
+	}
+	if got := NewFrame(beastModeSLong, false).Escaped(); !bytes.Equal(beastModeSLong, got) {
+		t.Errorf("Did not expect any escaping\n%X, expected\n%X", got, beastModeSLong)
+	}
+}
+
+func TestFrame_Avr(t *testing.T) {
+	if avr := NewFrame(beastModeSLong, false).Avr(); "*8D7C49F85841D26CCA3933E41ECF;" != avr {
+		t.Errorf("Incorrect AVR %s", avr)
+	}
+	if avr := NewFrame(beastModeSShort, false).Avr(); "*5D7C49F828E943;" != avr {
+		t.Errorf("Incorrect AVR %s", avr)
+	}
+}
+
+func BenchmarkNewFrameModeSLong(b *testing.B) {
+	var f Frame
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		f.ResetFromBytes(beastModeSLong, false)
+		_, _ = f.Decode()
+	}
+}
+
+func TestFrame_ResetFromBytes(t *testing.T) {
+	var f Frame
+	if !f.ResetFromBytes(beastModeSLong, false) {
+		t.Fatal("Failed to create a Mode S long frame")
+	}
+	if ok, err := f.Decode(); !ok || nil != err {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+
+	// reusing the frame should be no different to starting again
+	for _, raw := range [][]byte{beastModeSShort, beastModeAc} {
+		if !f.ResetFromBytes(raw, true) {
+			t.Fatalf("Failed to reuse the frame for %X", raw)
+		}
+		fresh := NewFrame(raw, true)
+		fresh.timeStamp = f.timeStamp
+		if nil != fresh.decodedModeS {
+			fresh.decodedModeS.SetTimeStamp(f.timeStamp)
+		}
+		if f.IcaoStr() != fresh.IcaoStr() || f.Avr() != fresh.Avr() {
+			t.Errorf("Reused frame is different to a new one %s/%s != %s/%s", f.IcaoStr(), f.Avr(), fresh.IcaoStr(), fresh.Avr())
+		}
+		if !reflect.DeepEqual(f.AvrFrame(), fresh.AvrFrame()) {
+			t.Errorf("Reused Mode S frame is different to a new one\n%+v\n%+v", f.AvrFrame(), fresh.AvrFrame())
+		}
+	}
+
+	if f.ResetFromBytes([]byte{0x1A, 0x35, 0, 0, 0, 0, 0, 0, 0, 0}, false) {
+		t.Error("Should not create a frame of an unknown type")
+	}
+}
@@ -29,8 +29,39 @@ type CprLocation struct {
 	globalSurfaceRange float64
 
 	refLat, refLon float64
+
+	// the last airborne position we decoded, the reference for local decoding
+	lastLat, lastLon float64
+	lastTime         time.Time
+	hasLast          bool
+	// lastFromReceiver is true when our last position came from (a chain of local decodes starting at)
+	// the receiver location, it tells us nothing about where the receiver is
+	lastFromReceiver bool
+	// how many global decodes in a row have been too far from the last position
+	rejected int
 }
 
+const (
+	nauticalMile = 1852.0
+	// cprMaxSpeed is the fastest (in metres/second) we expect anything to move between two positions
+	cprMaxSpeed = 500.0
+	// cprPositionSlack allows for the resolution of the CPR encoding and for timestamp jitter
+	cprPositionSlack = 1000.0
+	// cprLocalReferenceAge is how long our last position is good for as a local decoding reference.
+	// at cprMaxSpeed that is 150km, well inside half a CPR zone (~180 nautical miles)
+	cprLocalReferenceAge = 5 * time.Minute
+	// cprReceiverLocalRange is how far away from the receiver a locally decoded position can be when we do not
+	// know the receiver's range. A plane heard from past half a zone decodes to the wrong place, this keeps us
+	// well clear of that
+	cprReceiverLocalRange = 45 * nauticalMile
+	// cprReceiverMaxLocalRange is half a zone, past it the local decode is ambiguous whatever the receiver's range
+	cprReceiverMaxLocalRange = 180 * nauticalMile
+	// cprReceiverGlobalRange is a sanity check for global decodes, nothing is heard this far away
+	cprReceiverGlobalRange = 450 * nauticalMile
+	// cprMaxRejections is how many global decodes we throw away before we stop trusting our last position
+	cprMaxRejections = 3
+)
+
 var NLTable = map[int32]float64{
 	59: 10.47047130,
 	58: 14.82817437,
@@ -115,6 +146,31 @@ func (cpr *CprLocation) zero(lock bool) {
 	cpr.oddFrame = false
 }
 
+// latestIsOdd tells us if the most recent frame we have is the odd one
+func (cpr *CprLocation) latestIsOdd() bool {
+	if cpr.oddFrame && cpr.evenFrame {
+		return !cpr.time1.Before(cpr.time0)
+	}
+	return cpr.oddFrame
+}
+
+// latestTime is the time of the most recent frame we have
+func (cpr *CprLocation) latestTime() time.Time {
+	if cpr.latestIsOdd() {
+		return cpr.time1
+	}
+	return cpr.time0
+}
+
+// dropOldest throws away the older frame of a pair we could not decode, the newer one may pair with the next frame
+func (cpr *CprLocation) dropOldest() {
+	if cpr.latestIsOdd() {
+		cpr.evenLat, cpr.evenLon, cpr.evenFrame = 0, 0, false
+	} else {
+		cpr.oddLat, cpr.oddLon, cpr.oddFrame = 0, 0, false
+	}
+}
+
 func (cpr *CprLocation) SetEvenLocation(lat, lon float64, t time.Time) error {
 	// cpr locations are 17 bits long, if we get a value outside of this then we have a problem
 	if lat > max17Bits || lat < 0 || lon > max17Bits || lon < 0 {
@@ -174,6 +230,117 @@ func (cpr *CprLocation) decode(onGround bool) (*PlaneLocation, error) {
 
 }
 
+// decodeAir works out an airborne position from the latest frame. With a recent position of our own we decode
+// locally against it, so every frame gives us a new position. Without one we need the global decode of an even/odd
+// pair, or a local decode against the receiver location (refLat/refLon) within refRange metres of it.
+// Global decodes can be wrong (frames from different zones, corrupted frames) so they have to be a reasonable
+// distance from our reference. While decoding locally, global decodes that keep disagreeing with us tell us our
+// own position has gone bad.
+// independent tells us the position did not come from the receiver location, so it can be used to estimate it. A
+// global decode too far from the receiver still comes back (with an error) so it can show the reference is wrong
+func (cpr *CprLocation) decodeAir(refLat, refLon *float64, refRange float64) (loc *PlaneLocation, independent bool, err error) {
+	cpr.rwLock.Lock()
+	defer cpr.rwLock.Unlock()
+
+	if !cpr.oddFrame && !cpr.evenFrame {
+		return nil, false, nil
+	}
+	ts := cpr.latestTime()
+
+	if cpr.hasLast && ts.Sub(cpr.lastTime) < cprLocalReferenceAge && cpr.lastTime.Sub(ts) < cprLocalReferenceAge {
+		maxRange := math.Abs(ts.Sub(cpr.lastTime).Seconds())*cprMaxSpeed + cprPositionSlack
+		if cpr.oddFrame && cpr.evenFrame {
+			if global, err := cpr.decodeGlobalAir(); nil == err {
+				if distance(cpr.lastLat, cpr.lastLon, global.latitude, global.longitude) > maxRange {
+					cpr.rejected++
+				} else {
+					cpr.rejected = 0
+				}
+			}
+			if cpr.rejected >= cprMaxRejections {
+				cpr.hasLast = false
+				cpr.rejected = 0
+				return nil, false, fmt.Errorf("our last position {%0.4f,%0.4f} keeps disagreeing with global CPR decodes, dropping it", cpr.lastLat, cpr.lastLon)
+			}
+		}
+		loc, err = cpr.decodeLocalAirChecked(cpr.lastLat, cpr.lastLon, maxRange, ts)
+		return loc, nil != loc && !cpr.lastFromReceiver, err
+	}
+
+	hasRef := nil != refLat && nil != refLon && !(0 == *refLat && 0 == *refLon)
+	if cpr.oddFrame && cpr.evenFrame {
+		loc, err = cpr.decodeGlobalAir()
+		if nil == err {
+			cpr.zero(false)
+			if hasRef {
+				if d := distance(*refLat, *refLon, loc.latitude, loc.longitude); d > cprReceiverGlobalRange {
+					return loc, true, fmt.Errorf("global CPR decode {%0.4f,%0.4f} is %0.0fm from the receiver, ignoring it", loc.latitude, loc.longitude, d)
+				}
+			}
+			cpr.rejected = 0
+			cpr.lastFromReceiver = false
+			cpr.setLast(loc, ts)
+			return loc, true, nil
+		}
+		cpr.dropOldest()
+		if !hasRef {
+			return nil, false, err
+		}
+	}
+	if !hasRef {
+		return nil, false, nil
+	}
+	loc, err = cpr.decodeLocalAirChecked(*refLat, *refLon, refRange, ts)
+	if nil == err {
+		cpr.lastFromReceiver = true
+	}
+	return loc, false, err
+}
+
+// decodeLocalAirChecked decodes the latest frame locally and makes sure it is within maxRange metres of the reference
+func (cpr *CprLocation) decodeLocalAirChecked(refLat, refLon, maxRange float64, ts time.Time) (*PlaneLocation, error) {
+	loc, err := cpr.decodeLocalAir(refLat, refLon)
+	if nil != err {
+		return nil, err
+	}
+	if d := distance(refLat, refLon, loc.latitude, loc.longitude); d > maxRange {
+		return nil, fmt.Errorf("local CPR decode {%0.4f,%0.4f} is %0.0fm from our reference {%0.4f,%0.4f}, ignoring it", loc.latitude, loc.longitude, d, refLat, refLon)
+	}
+	cpr.setLast(loc, ts)
+	return loc, nil
+}
+
+func (cpr *CprLocation) setLast(loc *PlaneLocation, ts time.Time) {
+	cpr.lastLat = loc.latitude
+	cpr.lastLon = loc.longitude
+	cpr.lastTime = ts
+	cpr.hasLast = true
+}
+
+// decodeLocalAir decodes the latest airborne frame using a reference position within half a zone of the plane
+func (cpr *CprLocation) decodeLocalAir(refLat, refLon float64) (*PlaneLocation, error) {
+	var isOdd int32
+	yz, xz := cpr.evenLat, cpr.evenLon
+	if cpr.latestIsOdd() {
+		isOdd = 1
+		yz, xz = cpr.oddLat, cpr.oddLon
+	}
+
+	dLat := 360.0 / float64(60-isOdd)
+	j := math.Floor(refLat/dLat) + math.Floor(0.5+cprModFloat(refLat, dLat)/dLat-yz/131072)
+	var loc PlaneLocation
+	loc.latitude = dLat * (j + yz/131072)
+	if loc.latitude < -90 || loc.latitude > 90 {
+		return nil, fmt.Errorf("Failed to decode local CPR Lat %0.13f is out of range", loc.latitude)
+	}
+
+	dLon := 360.0 / float64(cprNFunction(loc.latitude, isOdd))
+	m := math.Floor(refLon/dLon) + math.Floor(0.5+cprModFloat(refLon, dLon)/dLon-xz/131072)
+	loc.longitude = dLon * (m + xz/131072)
+	loc.longitude -= math.Floor((loc.longitude+180.0)/360.0) * 360.0
+	return &loc, nil
+}
+
 // computeLatitudeIndex computes `j` in the decode algorithm
 func (cpr *CprLocation) computeLatitudeIndex() {
 	cpr.latitudeIndex = int32(math.Floor((((59 * cpr.evenLat) - (60 * cpr.oddLat)) / 131072) + 0.5))
@@ -252,7 +419,7 @@ func (cpr *CprLocation) surfaceLongitudeTwiddle(refLon float64, loc *PlaneLocati
 
 func (cpr *CprLocation) normaliseLatLon(loc *PlaneLocation) error {
 	if loc.longitude > 180.0 {
-		loc.longitude -= 180.0
+		loc.longitude -= 360.0
 	}
 	//log.Printf("post normalise rlat = %0.6f, rlon = %0.6f\n", loc.latitude, loc.longitude);
 
@@ -439,6 +606,15 @@ func (cpr *CprLocation) dlonFunction(lat float64, isOdd int32) float64 {
 	return cpr.globalSurfaceRange / float64(cprNFunction(lat, isOdd))
 }
 
+// cprModFloat is the always positive MOD for our reference positions
+func cprModFloat(a, b float64) float64 {
+	res := math.Mod(a, b)
+	if res < 0 {
+		res += b
+	}
+	return res
+}
+
 /* Always positive MOD operation, used for CPR decoding. */
 func cprModFunction(a, b int32) float64 {
 	res := math.Mod(float64(a), float64(b))
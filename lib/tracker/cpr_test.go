@@ -346,3 +346,145 @@ func TestCprDecode2(t *testing.T) {
 	}
 
 }
+
+// rawCpr encodes a position with our encoder and gives back the raw CPR values
+func rawCpr(t *testing.T, lat, lon float64, odd bool) (float64, float64) {
+	t.Helper()
+	encoded, err := mode_s.EncodeExtendedSquitter(0x7C7DAA, mode_s.AdsbAirbornePosition{Altitude: 30000, Lat: lat, Lon: lon, Odd: odd})
+	if nil != err {
+		t.Fatal(err)
+	}
+	frame, err := mode_s.DecodeBytes(encoded.Bytes(), time.Now())
+	if nil != err {
+		t.Fatal(err)
+	}
+	return float64(frame.Latitude()), float64(frame.Longitude())
+}
+
+func TestCprDecodeLocalAir(t *testing.T) {
+	tests := []struct {
+		name             string
+		lat, lon         float64
+		refLat, refLon   float64
+		refRange         float64
+		odd              bool
+		expectedLocation bool
+	}{
+		{name: "Perth even", lat: -31.9403, lon: 115.9669, refLat: -32.0, refLon: 116.0, expectedLocation: true},
+		{name: "Perth odd", lat: -31.9403, lon: 115.9669, refLat: -32.0, refLon: 116.0, odd: true, expectedLocation: true},
+		{name: "West of Greenwich", lat: 51.47, lon: -0.4543, refLat: 51.2, refLon: 0.2, expectedLocation: true},
+		{name: "Past the unambiguous range", lat: 51.47, lon: -0.4543, refLat: 51.0, refLon: 0.5},
+		{name: "Within the receiver's range", lat: 51.47, lon: -0.4543, refLat: 51.0, refLon: 0.5, refRange: 100 * nauticalMile, expectedLocation: true},
+		{name: "Across the date line", lat: -17.75, lon: -179.9, refLat: -18.0, refLon: 179.5, odd: true, expectedLocation: true},
+		{name: "Too far from the receiver", lat: -31.9403, lon: 115.9669, refLat: -29.0, refLon: 119.0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cpr := CprLocation{}
+			rawLat, rawLon := rawCpr(t, tt.lat, tt.lon, tt.odd)
+			if tt.odd {
+				_ = cpr.SetOddLocation(rawLat, rawLon, time.Now())
+			} else {
+				_ = cpr.SetEvenLocation(rawLat, rawLon, time.Now())
+			}
+			refRange := tt.refRange
+			if 0 == refRange {
+				refRange = cprReceiverLocalRange
+			}
+			loc, _, err := cpr.decodeAir(&tt.refLat, &tt.refLon, refRange)
+			if !tt.expectedLocation {
+				if nil != loc || nil == err {
+					t.Errorf("Expected the local decode to be rejected, got %+v", loc)
+				}
+				return
+			}
+			if nil != err {
+				t.Fatal(err)
+			}
+			if d := distance(tt.lat, tt.lon, loc.latitude, loc.longitude); d > 10 {
+				t.Errorf("Decoded {%0.5f,%0.5f} is %0.1fm from {%0.5f,%0.5f}", loc.latitude, loc.longitude, d, tt.lat, tt.lon)
+			}
+		})
+	}
+}
+
+func TestCprDecodeAirEveryFrame(t *testing.T) {
+	cpr := CprLocation{}
+	ts := time.Now()
+
+	// a single frame and no reference does not give us anything
+	lat, lon := rawCpr(t, 51.47, -0.4543, false)
+	_ = cpr.SetEvenLocation(lat, lon, ts)
+	if loc, _, err := cpr.decodeAir(nil, nil, cprReceiverLocalRange); nil != loc || nil != err {
+		t.Fatalf("Expected nothing from a single frame, got %+v %v", loc, err)
+	}
+
+	// the pair gets a global decode, then every frame after that is decoded against it
+	positions := [][2]float64{{51.4705, -0.4500}, {51.4710, -0.4457}, {51.4715, -0.4414}, {51.4720, -0.4371}}
+	for i, pos := range positions {
+		ts = ts.Add(time.Second)
+		lat, lon = rawCpr(t, pos[0], pos[1], 0 == i%2)
+		if 0 == i%2 {
+			_ = cpr.SetOddLocation(lat, lon, ts)
+		} else {
+			_ = cpr.SetEvenLocation(lat, lon, ts)
+		}
+		loc, _, err := cpr.decodeAir(nil, nil, cprReceiverLocalRange)
+		if nil != err || nil == loc {
+			t.Fatalf("Frame %d failed to decode: %v", i, err)
+		}
+		if 0 == i {
+			// the global decode is of the older frame of the pair
+			pos = [2]float64{51.47, -0.4543}
+		}
+		if d := distance(pos[0], pos[1], loc.latitude, loc.longitude); d > 10 {
+			t.Errorf("Frame %d decoded {%0.5f,%0.5f} %0.1fm away from {%0.5f,%0.5f}", i, loc.latitude, loc.longitude, d, pos[0], pos[1])
+		}
+	}
+}
+
+func TestCprDecodeAirRejectsUnreasonableGlobal(t *testing.T) {
+	cpr := CprLocation{}
+	ts := time.Now()
+	lat, lon := rawCpr(t, -31.9403, 115.9669, false)
+	_ = cpr.SetEvenLocation(lat, lon, ts)
+	lat, lon = rawCpr(t, -31.9410, 115.9675, true)
+	_ = cpr.SetOddLocation(lat, lon, ts.Add(time.Second))
+
+	// a receiver in London has no business hearing a plane in Perth
+	refLat, refLon := 51.47, -0.4543
+	loc, independent, err := cpr.decodeAir(&refLat, &refLon, cprReceiverLocalRange)
+	if nil == err {
+		t.Errorf("Expected the global decode to be rejected, got %+v", loc)
+	}
+	// it is still handed back, so it can tell the source its reference is wrong
+	if nil == loc || !independent {
+		t.Error("Expected the rejected global decode to come back as an independent position")
+	}
+	if cpr.hasLast {
+		t.Error("A rejected position should not be used as a reference")
+	}
+}
+
+func TestCprDecodeAirDropsBadReference(t *testing.T) {
+	cpr := CprLocation{}
+	ts := time.Now()
+	// we believe the plane is ~100NM north of where it really is, the local decodes follow our belief
+	cpr.setLast(&PlaneLocation{latitude: -30.3, longitude: 115.9669}, ts)
+
+	var err error
+	// the first frame does not have a pair to check against
+	for i := 0; i <= cprMaxRejections; i++ {
+		ts = ts.Add(time.Second)
+		lat, lon := rawCpr(t, -31.9403, 115.9669, 1 == i%2)
+		if 1 == i%2 {
+			_ = cpr.SetOddLocation(lat, lon, ts)
+		} else {
+			_ = cpr.SetEvenLocation(lat, lon, ts)
+		}
+		_, _, err = cpr.decodeAir(nil, nil, cprReceiverLocalRange)
+	}
+	if nil == err || cpr.hasLast {
+		t.Errorf("Expected our last position to be dropped after %d bad global decodes", cprMaxRejections)
+	}
+}
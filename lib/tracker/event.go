@@ -2,12 +2,16 @@ package tracker
 
 import (
 	"fmt"
+	"plane.watch/lib/tracker/mode_s"
 	"time"
 )
 
 const LogEventType = "log-event"
 const PlaneLocationEventType = "plane-location-event"
 const InfoEventType = "info-event"
+const WeatherEventType = "weather-event"
+const TcasAlertEventType = "tcas-alert-event"
+const DecodedFrameEventType = "decoded-frame-event"
 
 type (
 	// Event is something that we want to know about. This is the base of our sending of data
@@ -39,17 +43,86 @@ type (
 		FrameEvent
 	}
 
+	// DecodedFrameEvent is sent for every Mode S frame once the plane it belongs to has been updated with it.
+	// Outputs that want a message per frame (e.g. SBS-1) can use the frame along with the plane's current state,
+	// they ask for it by being a DecodedFrameSink
+	DecodedFrameEvent struct {
+		frame  *mode_s.Frame
+		plane  *Plane
+		source *FrameSource
+	}
+
 	FrameSource struct {
 		OriginIdentifier string
 		Name, Tag        string
 		RefLat, RefLon   *float64
+
+		// location is our estimate of where the receiver is, see ReferenceLocation()
+		location receiverLocation
+	}
+
+	// WeatherEvent is sent whenever a plane gives us a meteorological report (Comm-B BDS 4,4 or 4,5).
+	// The plane's position and altitude are captured at the time of the report
+	WeatherEvent struct {
+		Icao uint32
+		// IcaoStr is Icao fit for human consumption, non ICAO addresses start with a ~
+		IcaoStr       string
+		When          time.Time
+		Lat, Lon      float64
+		HasLocation   bool
+		Altitude      int32
+		AltitudeUnits string
+		Bds           string
+
+		WindSpeed            int
+		WindDirection        float64
+		StaticAirTemperature float64
+		StaticPressure       int
+		Humidity             float64
+		Turbulence           byte
+		WindShear            byte
+		Microburst           byte
+		Icing                byte
+		WakeVortex           byte
+		RadioHeight          int
+
+		HasWind                 bool
+		HasStaticAirTemperature bool
+		HasStaticPressure       bool
+		HasHumidity             bool
+		HasTurbulence           bool
+		HasWindShear            bool
+		HasMicroburst           bool
+		HasIcing                bool
+		HasWakeVortex           bool
+		HasRadioHeight          bool
+	}
+
+	// TcasAlertEvent is sent whenever a plane reports a new or changed TCAS Resolution Advisory
+	TcasAlertEvent struct {
+		Icao uint32
+		// IcaoStr is Icao fit for human consumption, non ICAO addresses start with a ~
+		IcaoStr        string
+		FlightNumber   string
+		When           time.Time
+		DownLinkFormat byte
+		Lat, Lon       float64
+		HasLocation    bool
+		Altitude       int32
+		AltitudeUnits  string
+		RA             mode_s.ResolutionAdvisory
 	}
 
 	// InfoEvent periodically sends out some interesting stats
 	InfoEvent struct {
-		receivedFrames uint64
-		numReceivers   int
-		uptime         float64
+		receivedFrames    uint64
+		correctedFrames   uint64
+		rejectedFrames    uint64
+		numModeAC         int
+		numTisbManagement uint64
+		numReceivers      int
+		numDropped        uint64
+		uptime            float64
 	}
 )
 
@@ -62,7 +135,6 @@ func (t *Tracker) AddEvent(e Event) {
 }
 
 func (t *Tracker) processEvents() {
-	t.eventsWaiter.Add(1)
 	for e := range t.events {
 		for _, sink := range t.sinks {
 			sink.OnEvent(e)
@@ -126,12 +198,36 @@ func (f *FrameEvent) Source() *FrameSource {
 	return f.source
 }
 
+func newDecodedFrameEvent(f *mode_s.Frame, p *Plane, s *FrameSource) *DecodedFrameEvent {
+	return &DecodedFrameEvent{frame: f, plane: p, source: s}
+}
+
+func (d *DecodedFrameEvent) Type() string {
+	return DecodedFrameEventType
+}
+
+func (d *DecodedFrameEvent) String() string {
+	return d.frame.IcaoStr()
+}
+
+func (d *DecodedFrameEvent) Frame() *mode_s.Frame {
+	return d.frame
+}
+
+func (d *DecodedFrameEvent) Plane() *Plane {
+	return d.plane
+}
+
+func (d *DecodedFrameEvent) Source() *FrameSource {
+	return d.source
+}
+
 func (i *InfoEvent) Type() string {
 	return InfoEventType
 }
 
 func (i *InfoEvent) String() string {
-	return fmt.Sprintf("Info: #feeders=%d, #frames=%d. uptime(s)=%0.2f", i.numReceivers, i.receivedFrames, i.uptime)
+	return fmt.Sprintf("Info: #feeders=%d, #frames=%d, #corrected=%d, #rejected=%d, #dropped=%d, #modeAC=%d, #tisbManagement=%d. uptime(s)=%0.2f", i.numReceivers, i.receivedFrames, i.correctedFrames, i.rejectedFrames, i.numDropped, i.numModeAC, i.numTisbManagement, i.uptime)
 }
 
 func (i *InfoEvent) NumReceivers() int {
@@ -142,6 +238,127 @@ func (i *InfoEvent) NumFrames() uint64 {
 	return i.receivedFrames
 }
 
+// NumCorrectedFrames is how many frames have been saved by CRC error correction
+func (i *InfoEvent) NumCorrectedFrames() uint64 {
+	return i.correctedFrames
+}
+
+// NumRejectedFrames is how many Address/Parity frames were dropped because we have not confirmed their ICAO
+func (i *InfoEvent) NumRejectedFrames() uint64 {
+	return i.rejectedFrames
+}
+
+// NumDropped is how much input our producers had to drop before it became frames, see DroppingProducer
+func (i *InfoEvent) NumDropped() uint64 {
+	return i.numDropped
+}
+
+// NumTisbManagement is how many DF18 TIS-B/ADS-R management messages we have received
+func (i *InfoEvent) NumTisbManagement() uint64 {
+	return i.numTisbManagement
+}
+
+// NumUnidentifiedModeAC is how many Mode A/C targets we cannot match to a Mode S plane
+func (i *InfoEvent) NumUnidentifiedModeAC() int {
+	return i.numModeAC
+}
+
 func (i *InfoEvent) Uptime() float64 {
 	return i.uptime
 }
+
+func newWeatherEvent(p *Plane, frame *mode_s.Frame) *WeatherEvent {
+	p.rwLock.RLock()
+	w := &WeatherEvent{
+		Icao:          p.icaoIdentifier,
+		IcaoStr:       p.icao,
+		When:          frame.TimeStamp(),
+		Lat:           p.location.latitude,
+		Lon:           p.location.longitude,
+		HasLocation:   p.location.hasLatLon,
+		Altitude:      p.location.altitude,
+		AltitudeUnits: p.location.altitudeUnits,
+		Bds:           frame.BdsMessageType(),
+	}
+	p.rwLock.RUnlock()
+
+	if w.HasWind = frame.WindValid(); w.HasWind {
+		w.WindSpeed = frame.MustWindSpeed()
+		w.WindDirection = frame.MustWindDirection()
+	}
+	if w.HasStaticAirTemperature = frame.StaticAirTemperatureValid(); w.HasStaticAirTemperature {
+		w.StaticAirTemperature = frame.MustStaticAirTemperature()
+	}
+	if w.HasStaticPressure = frame.StaticPressureValid(); w.HasStaticPressure {
+		w.StaticPressure = frame.MustStaticPressure()
+	}
+	if w.HasHumidity = frame.HumidityValid(); w.HasHumidity {
+		w.Humidity = frame.MustHumidity()
+	}
+	if w.HasTurbulence = frame.TurbulenceValid(); w.HasTurbulence {
+		w.Turbulence = frame.MustTurbulence()
+	}
+	if w.HasWindShear = frame.WindShearValid(); w.HasWindShear {
+		w.WindShear = frame.MustWindShear()
+	}
+	if w.HasMicroburst = frame.MicroburstValid(); w.HasMicroburst {
+		w.Microburst = frame.MustMicroburst()
+	}
+	if w.HasIcing = frame.IcingValid(); w.HasIcing {
+		w.Icing = frame.MustIcing()
+	}
+	if w.HasWakeVortex = frame.WakeVortexValid(); w.HasWakeVortex {
+		w.WakeVortex = frame.MustWakeVortex()
+	}
+	if w.HasRadioHeight = frame.RadioHeightValid(); w.HasRadioHeight {
+		w.RadioHeight = frame.MustRadioHeight()
+	}
+	return w
+}
+
+func (w *WeatherEvent) Type() string {
+	return WeatherEventType
+}
+
+func (w *WeatherEvent) String() string {
+	report := fmt.Sprintf("Weather: %s BDS %s at %d %s", w.IcaoStr, w.Bds, w.Altitude, w.AltitudeUnits)
+	if w.HasLocation {
+		report += fmt.Sprintf(" (%0.4f, %0.4f)", w.Lat, w.Lon)
+	}
+	if w.HasWind {
+		report += fmt.Sprintf(", wind %d knots from %0.1f", w.WindSpeed, w.WindDirection)
+	}
+	if w.HasStaticAirTemperature {
+		report += fmt.Sprintf(", temperature %0.2fC", w.StaticAirTemperature)
+	}
+	if w.HasTurbulence {
+		report += ", turbulence " + mode_s.HazardLevelString(w.Turbulence)
+	}
+	return report
+}
+
+func newTcasAlertEvent(p *Plane, frame *mode_s.Frame) *TcasAlertEvent {
+	p.rwLock.RLock()
+	defer p.rwLock.RUnlock()
+	return &TcasAlertEvent{
+		Icao:           p.icaoIdentifier,
+		IcaoStr:        p.icao,
+		FlightNumber:   p.flight.identifier,
+		When:           frame.TimeStamp(),
+		DownLinkFormat: frame.DownLinkType(),
+		Lat:            p.location.latitude,
+		Lon:            p.location.longitude,
+		HasLocation:    p.location.hasLatLon,
+		Altitude:       p.location.altitude,
+		AltitudeUnits:  p.location.altitudeUnits,
+		RA:             p.tcas.ra,
+	}
+}
+
+func (t *TcasAlertEvent) Type() string {
+	return TcasAlertEventType
+}
+
+func (t *TcasAlertEvent) String() string {
+	return fmt.Sprintf("TCAS RA: %s %s (DF%d) at %d %s: %s", t.IcaoStr, t.FlightNumber, t.DownLinkFormat, t.Altitude, t.AltitudeUnits, t.RA)
+}
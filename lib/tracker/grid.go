@@ -28,7 +28,12 @@ type (
 )
 var (
 	jsonData = `{"refresh":1600,"history":1,"dbServer":true,"binCraft":true,"globeIndexGrid":3,"globeIndexSpecialTiles":[{"south":60,"east":0,"north":90,"west":-126},{"south":60,"east":150,"north":90,"west":0},{"south":51,"east":-126,"north":90,"west":150},{"south":9,"east":-126,"north":51,"west":150},{"south":51,"east":-69,"north":60,"west":-126},{"south":45,"east":-114,"north":51,"west":-120},{"south":45,"east":-102,"north":51,"west":-114},{"south":45,"east":-90,"north":51,"west":-102},{"south":45,"east":-75,"north":51,"west":-90},{"south":45,"east":-69,"north":51,"west":-75},{"south":42,"east":18,"north":48,"west":12},{"south":42,"east":24,"north":48,"west":18},{"south":48,"east":24,"north":54,"west":18},{"south":54,"east":24,"north":60,"west":12},{"south":54,"east":12,"north":60,"west":3},{"south":54,"east":3,"north":60,"west":-9},{"south":42,"east":0,"north":48,"west":-9},{"south":42,"east":51,"north":51,"west":24},{"south":51,"east":51,"north":60,"west":24},{"south":30,"east":90,"north":60,"west":51},{"south":30,"east":120,"north":60,"west":90},{"south":30,"east":129,"north":39,"west":120},{"south":30,"east":138,"north":39,"west":129},{"south":30,"east":150,"north":39,"west":138},{"south":39,"east":150,"north":60,"west":120},{"south":9,"east":111,"north":21,"west":90},{"south":21,"east":111,"north":30,"west":90},{"south":9,"east":129,"north":24,"west":111},{"south":24,"east":120,"north":30,"west":111},{"south":24,"east":129,"north":30,"west":120},{"south":9,"east":150,"north":30,"west":129},{"south":9,"east":69,"north":30,"west":51},{"south":9,"east":90,"north":30,"west":69},{"south":-90,"east":51,"north":9,"west":-30},{"south":-90,"east":111,"north":9,"west":51},{"south":-90,"east":160,"north":-18,"west":111},{"south":-18,"east":160,"north":9,"west":111},{"south":-90,"east":-90,"north":-42,"west":160},{"south":-42,"east":-90,"north":9,"west":160},{"south":-9,"east":-42,"north":9,"west":-90},{"south":-90,"east":-63,"north":-9,"west":-90},{"south":-21,"east":-42,"north":-9,"west":-63},{"south":-90,"east":-42,"north":-21,"west":-63},{"south":-90,"east":-30,"north":9,"west":-42},{"south":9,"east":-117,"north":33,"west":-126},{"south":9,"east":-102,"north":30,"west":-117},{"south":9,"east":-90,"north":27,"west":-102},{"south":24,"east":-84,"north":30,"west":-90},{"south":9,"east":-69,"north":18,"west":-90},{"south":18,"east":-69,"north":24,"west":-90},{"south":36,"east":18,"north":42,"west":6},{"south":36,"east":30,"north":42,"west":18},{"south":9,"east":6,"north":39,"west":-9},{"south":9,"east":30,"north":36,"west":6},{"south":9,"east":51,"north":42,"west":30},{"south":24,"east":-69,"north":39,"west":-75},{"south":9,"east":-33,"north":30,"west":-69},{"south":30,"east":-33,"north":60,"west":-69},{"south":9,"east":-9,"north":30,"west":-33},{"south":30,"east":-9,"north":60,"west":-33}],"version":"adsbexchange backend"}`
-	worldGrid GridLocations
+	globeDef GlobalDef
+)
+
+const (
+	// globeGridTileOffset is where the regular grid tiles start, readsb keeps the first 1000 for special tiles
+	globeGridTileOffset = 1000
 )
 
 func init() {
@@ -43,38 +48,75 @@ func setupWorldGrid(data string) error {
 	if nil != err {
 		return err
 	}
-	worldGrid = make(map[string]GlobeIndexSpecialTile)
-	for i, tile := range def.GlobeIndexSpecialTiles {
-		worldGrid["tile"+strconv.Itoa(i)] = tile
-	}
+	globeDef = def
 	return nil
 }
 
+// GlobeDefinition is the readsb globe index definition (tile size and special tiles) we use, tar1090
+// needs it in receiver.json to know which tiles to ask for
+func GlobeDefinition() GlobalDef {
+	return globeDef
+}
+
+// GlobeIndex is the readsb globe tile this location is in. It is the same calculation as readsb's globe_index(),
+// the location is snapped to the grid and then checked against the special tiles (west to east, wrapping around
+// the date line). Anything not in a special tile is in a regular globeIndexGrid sized tile, numbered from 1000
+func GlobeIndex(lat, lon float64) int {
+	grid := globeDef.GlobeIndexGrid
+	gridLat := grid*int((lat+90)/float64(grid)) - 90
+	gridLon := grid*int((lon+180)/float64(grid)) - 180
+
+	for i, t := range globeDef.GlobeIndexSpecialTiles {
+		if float64(gridLat) < t.South || float64(gridLat) >= t.North {
+			continue
+		}
+		if t.West < t.East && float64(gridLon) >= t.West && float64(gridLon) < t.East {
+			return i
+		}
+		if t.West > t.East && (float64(gridLon) >= t.West || float64(gridLon) < t.East) {
+			return i
+		}
+	}
+	return globeGridIndex(gridLat, gridLon)
+}
+
+// globeGridIndex is the index of the regular grid tile with its south west corner at lat/lon
+func globeGridIndex(lat, lon int) int {
+	grid := globeDef.GlobeIndexGrid
+	latMultiplier := 360/grid + 1
+	return ((lat+90)/grid)*latMultiplier + (lon+180)/grid + globeGridTileOffset
+}
+
+// GlobeTileBounds gives us the area covered by a readsb globe tile, in readsb terms (West < East unless the
+// tile wraps around the date line)
+func GlobeTileBounds(index int) (tile GlobeIndexSpecialTile, ok bool) {
+	if index >= 0 && index < len(globeDef.GlobeIndexSpecialTiles) {
+		return globeDef.GlobeIndexSpecialTiles[index], true
+	}
+	grid := globeDef.GlobeIndexGrid
+	latMultiplier := 360/grid + 1
+	index -= globeGridTileOffset
+	if index < 0 || index/latMultiplier > 180/grid || index%latMultiplier >= 360/grid {
+		return tile, false
+	}
+	tile.South = float64((index/latMultiplier)*grid - 90)
+	tile.West = float64((index%latMultiplier)*grid - 180)
+	tile.North = tile.South + float64(grid)
+	tile.East = tile.West + float64(grid)
+	return tile, true
+}
+
 func lookupTile(lat, lon float64) string {
 	if lat < -95.0 || lat > 95 || lon < -180 || lon > 180 {
 		log.Error().Err(fmt.Errorf("cannot lookup invalid coordinates {%0.6f, %0.6f}", lat, lon)).Msg("Using No Tile")
 		return ""
 	}
-
-	for name, t := range worldGrid {
-		if t.contains(lat, lon) {
-			return name
-		}
-	}
-
-	log.Debug().
-		Float64("lat", lat).
-		Float64("lon", lon).
-		Err(fmt.Errorf("could Not Place {%0.6f, %0.6f} in a grid location", lat, lon)).
-		Msg("Using No tileUnknown")
-	return "tileUnknown"
+	return "tile" + strconv.Itoa(GlobeIndex(lat, lon))
 }
 
+// InGridLocation tells us if lat/lon is still in the tile lookupTile gave us
 func InGridLocation(lat, lon float64, tileName string) bool {
-	if t, ok := worldGrid[tileName]; ok {
-		return t.contains(lat, lon)
-	}
-	return false
+	return "" != tileName && lookupTile(lat, lon) == tileName
 }
 
 // contains determines whether or not the
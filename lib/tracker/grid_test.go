@@ -1,6 +1,9 @@
 package tracker
 
-import "testing"
+import (
+	"strconv"
+	"testing"
+)
 
 func TestGlobeIndexSpecialTile_contains(t1 *testing.T) {
 	type fields struct {
@@ -91,12 +94,22 @@ func Test_lookupTile(t *testing.T) {
 		{
 			"Perth is found",
 			args{lat: -31.952162, lon: 115.943482},
-			"tile38",
+			"tile35",
 		},
 		{
 			"53.253113, 179.723145",
 			args{53.253113, 179.723145},
-			"tileUnknown",
+			"tile2",
+		},
+		{
+			"London is in a grid tile",
+			args{lat: 51.47, lon: -0.4543},
+			"tile" + strconv.Itoa(1000+47*121+59),
+		},
+		{
+			"invalid",
+			args{lat: 100, lon: 0},
+			"",
 		},
 	}
 	for _, tt := range tests {
@@ -104,6 +117,45 @@ func Test_lookupTile(t *testing.T) {
 			if got := lookupTile(tt.args.lat, tt.args.lon); got != tt.want {
 				t.Errorf("lookupTile() = %v, want %v", got, tt.want)
 			}
+			if "" != tt.want && !InGridLocation(tt.args.lat, tt.args.lon, tt.want) {
+				t.Errorf("InGridLocation() says we are not in %s", tt.want)
+			}
 		})
 	}
-}
\ No newline at end of file
+}
+
+func TestGlobeIndex(t *testing.T) {
+	tests := []struct {
+		name     string
+		lat, lon float64
+		want     int
+	}{
+		{name: "Perth", lat: -31.952162, lon: 115.943482, want: 35},
+		{name: "wraps around the date line", lat: 53.253113, lon: 179.723145, want: 2},
+		{name: "London, grid tile", lat: 51.47, lon: -0.4543, want: 1000 + 47*121 + 59},
+		{name: "Denver, grid tile", lat: 39.8561, lon: -104.6737, want: 1000 + 43*121 + 25},
+		{name: "Sydney", lat: -33.9461, lon: 151.1772, want: 35},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := GlobeIndex(tt.lat, tt.lon)
+			if got != tt.want {
+				t.Fatalf("GlobeIndex() = %d, want %d", got, tt.want)
+			}
+			bounds, ok := GlobeTileBounds(got)
+			if !ok {
+				t.Fatalf("No bounds for tile %d", got)
+			}
+			if tt.lat < bounds.South || tt.lat > bounds.North {
+				t.Errorf("Tile %d %+v does not contain %0.4f", got, bounds, tt.lat)
+			}
+		})
+	}
+
+	if _, ok := GlobeTileBounds(999); ok {
+		t.Error("Tile 999 should not exist")
+	}
+	if !InGridLocation(39.8561, -104.6737, "tile"+strconv.Itoa(1000+43*121+25)) {
+		t.Error("Denver should be in its grid tile")
+	}
+}
@@ -34,6 +34,12 @@ type (
 		Raw() []byte
 	}
 
+	// correctableFrame is a Frame that can have its bit errors fixed while decoding it
+	correctableFrame interface {
+		DecodeWithCorrection(level int) (bool, error)
+		CorrectedBits() byte
+	}
+
 	// A Producer can listen for or generate Frames, it provides the output via a channel that the handler can then
 	// processes further.
 	// A Producer can send *LogEvent and  *FrameEvent events
@@ -42,12 +48,26 @@ type (
 		fmt.Stringer
 	}
 
+	// DroppingProducer is a Producer that can lose input before it becomes frames, e.g. when a UDP sender is
+	// sending faster than we can read. Our InfoEvent adds up how much they have dropped
+	DroppingProducer interface {
+		Producer
+		NumDropped() uint64
+	}
+
 	// Sink is something that takes the output from our producers and trackers
 	Sink interface {
 		EventListener
 		Stopper
 	}
 
+	// DecodedFrameSink is a Sink that wants a DecodedFrameEvent for every Mode S frame we decode. We only make
+	// them while one of our sinks wants them
+	DecodedFrameSink interface {
+		Sink
+		WantsDecodedFrames() bool
+	}
+
 	// Middleware has a chance to modify a frame before we send it to the plane Tracker
 	Middleware interface {
 		EventMaker
@@ -61,6 +81,22 @@ func WithDecodeWorkerCount(numDecodeWorkers int) Option {
 		t.decodeWorkerCount = numDecodeWorkers
 	}
 }
+
+// WithIcaoConfirmTimeout sets how recently we need to have heard from a plane with a checksummed frame
+// (DF11/17/18) before we believe the ICAO recovered from an Address/Parity frame (DF0/4/5/16/20/21)
+func WithIcaoConfirmTimeout(timeout time.Duration) Option {
+	return func(t *Tracker) {
+		t.icaoConfirmTimeout = timeout
+	}
+}
+
+// WithErrorCorrection sets how hard we try to fix frames with a bad checksum (mode_s.Correction*)
+func WithErrorCorrection(level int) Option {
+	return func(t *Tracker) {
+		t.errorCorrection = level
+	}
+}
+
 func WithPruneTiming(pruneTick, pruneAfter time.Duration) Option {
 	return func(t *Tracker) {
 		t.pruneTick = pruneTick
@@ -139,6 +175,9 @@ func (t *Tracker) AddSink(s Sink) {
 		return
 	}
 	t.sinks = append(t.sinks, s)
+	if ds, ok := s.(DecodedFrameSink); ok && ds.WantsDecodedFrames() {
+		t.decodedFrames = true
+	}
 }
 
 // Stop attempts to stop all the things, mid flight. Use this if you have something else waiting for things to finish
@@ -174,7 +213,16 @@ func (t *Tracker) decodeQueue() {
 		}
 		atomic.AddUint64(&t.numFrames, 1)
 		frame := f.Frame()
-		ok, err := frame.Decode()
+		var ok bool
+		var err error
+		if cf, isCorrectable := frame.(correctableFrame); isCorrectable {
+			ok, err = cf.DecodeWithCorrection(t.errorCorrection)
+			if nil == err && cf.CorrectedBits() > 0 {
+				atomic.AddUint64(&t.numCorrectedFrames, 1)
+			}
+		} else {
+			ok, err = frame.Decode()
+		}
 		if nil != err {
 			// the decode operation failed to produce valid output, and we tell someone about it
 			t.handleError(err)
@@ -186,6 +234,18 @@ func (t *Tracker) decodeQueue() {
 			continue
 		}
 
+		if bf, isBeast := frame.(*beast.Frame); isBeast && bf.IsModeAC() {
+			// Mode A/C replies have no address, so they do not belong to a plane (yet)
+			t.handleModeAC(bf.ModeAC(), bf.TimeStamp(), f.Source())
+			continue
+		}
+
+		if isTisbManagement(frame) {
+			// about the ground station's TIS-B/ADS-R service, not a target, so there is no plane for it
+			atomic.AddUint64(&t.numTisbManagement, 1)
+			continue
+		}
+
 		for _, m := range t.middlewares {
 			frame = m.Handle(frame, f.source)
 			if nil == frame {
@@ -196,14 +256,28 @@ func (t *Tracker) decodeQueue() {
 			// invalid frame || unable to determine planes ICAO
 			continue
 		}
-		plane := t.GetPlane(frame.Icao())
+		if !t.icaoTrusted(frame) {
+			atomic.AddUint64(&t.numRejectedFrames, 1)
+			continue
+		}
+		plane := t.GetPlane(planeKey(frame))
 
 		switch frame.(type) {
 		case *beast.Frame:
-			plane.HandleModeSFrame(frame.(*beast.Frame).AvrFrame(), f.Source().RefLat, f.Source().RefLon)
-			// todo: include signal strength
+			if frame.(*beast.Frame).SignalValid() {
+				plane.setSignalLevel(frame.(*beast.Frame).SignalDbfs())
+			}
+			refLat, refLon := f.Source().ReferenceLocation()
+			plane.handleModeSFrame(frame.(*beast.Frame).AvrFrame(), refLat, refLon, f.Source())
+			if t.decodedFrames {
+				t.AddEvent(newDecodedFrameEvent(frame.(*beast.Frame).AvrFrame(), plane, f.Source()))
+			}
 		case *mode_s.Frame:
-			plane.HandleModeSFrame(frame.(*mode_s.Frame), f.Source().RefLat, f.Source().RefLon)
+			refLat, refLon := f.Source().ReferenceLocation()
+			plane.handleModeSFrame(frame.(*mode_s.Frame), refLat, refLon, f.Source())
+			if t.decodedFrames {
+				t.AddEvent(newDecodedFrameEvent(frame.(*mode_s.Frame), plane, f.Source()))
+			}
 		case *sbs1.Frame:
 			plane.HandleSbs1Frame(frame.(*sbs1.Frame))
 		default:
@@ -211,4 +285,38 @@ func (t *Tracker) decodeQueue() {
 		}
 	}
 	t.decodingQueueWaiter.Done()
+}
+
+// isTisbManagement tells us if frame is a DF18 CF4 TIS-B/ADS-R management message
+func isTisbManagement(frame Frame) bool {
+	switch frame.(type) {
+	case *beast.Frame:
+		return mode_s.MessageKindTisbManagement == frame.(*beast.Frame).AvrFrame().MessageKind()
+	case *mode_s.Frame:
+		return mode_s.MessageKindTisbManagement == frame.(*mode_s.Frame).MessageKind()
+	}
+	return false
+}
+
+// icaoTrusted tells us if we believe the ICAO of a frame. Frames with an Address/Parity field cannot be
+// checksummed, so noise gives us a random ICAO. We only believe them if we have recently seen that ICAO
+// in a frame that has passed its CRC check
+func (t *Tracker) icaoTrusted(frame Frame) bool {
+	var modeS *mode_s.Frame
+	switch frame.(type) {
+	case *beast.Frame:
+		modeS = frame.(*beast.Frame).AvrFrame()
+	case *mode_s.Frame:
+		modeS = frame.(*mode_s.Frame)
+	default:
+		return true
+	}
+	if !modeS.IcaoFromParity() {
+		return true
+	}
+	plane, ok := t.planeList.Load(planeKey(modeS))
+	if !ok {
+		return false
+	}
+	return plane.(*Plane).IcaoConfirmedSince(modeS.TimeStamp().Add(-t.icaoConfirmTimeout))
 }
\ No newline at end of file
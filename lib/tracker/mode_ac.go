@@ -0,0 +1,163 @@
+package tracker
+
+import (
+	"plane.watch/lib/tracker/mode_s"
+	"sort"
+	"time"
+)
+
+const (
+	// modeACAltitudeTolerance is how close (in feet) a Mode C altitude needs to be to a Mode S plane's altitude
+	modeACAltitudeTolerance = 100
+	// modeACMinReplies is how many times we need to see a Mode A/C code before we believe it is a real target
+	modeACMinReplies = 3
+	// modeACPlaneWindow is how recently a Mode S plane needs to have been seen for us to match against it
+	modeACPlaneWindow = time.Minute
+)
+
+type (
+	// ModeACTarget is a Mode A/C code a receiver has been receiving replies for. If we can match the code to the
+	// squawk of a Mode S plane the same receiver hears, or to its altitude when the receiver is also hearing its
+	// squawk as a Mode A reply, Icao is set. Otherwise, it is an unidentified (A/C only) target
+	ModeACTarget struct {
+		mode_s.ModeAC
+		Source    *FrameSource
+		Replies   uint64
+		FirstSeen time.Time
+		LastSeen  time.Time
+
+		// Icao is the Mode S plane this code belongs to, 0 if we cannot tell
+		Icao uint32
+		// MatchedSquawk is true if the match was on squawk, false if it was on altitude
+		MatchedSquawk bool
+	}
+
+	// modeACKey keeps the same code from different receivers apart, they are not necessarily the same aircraft
+	modeACKey struct {
+		source *FrameSource
+		code   uint16
+	}
+)
+
+// handleModeAC records a Mode A/C reply heard by source
+func (t *Tracker) handleModeAC(ac mode_s.ModeAC, when time.Time, source *FrameSource) {
+	t.modeACLock.Lock()
+	defer t.modeACLock.Unlock()
+	key := modeACKey{source: source, code: ac.Code}
+	target, ok := t.modeACTargets[key]
+	if !ok {
+		target = &ModeACTarget{ModeAC: ac, Source: source, FirstSeen: when}
+		t.modeACTargets[key] = target
+	}
+	target.Replies++
+	target.LastSeen = when
+}
+
+// correlateModeAC tries to match our Mode A/C targets to Mode S planes and forgets about old ones.
+// Only planes heard by the same receiver are candidates. An altitude alone is not enough, plenty of A/C only
+// aircraft fly near the altitude of a Mode S one, so a Mode C altitude only matches a plane whose squawk the
+// receiver is also hearing as a Mode A reply
+func (t *Tracker) correlateModeAC() {
+	oldest := time.Now().Add(-t.pruneAfter)
+	recent := time.Now().Add(-modeACPlaneWindow)
+
+	// grab what we need from the planes first, so we are not holding a plane lock and our lock together
+	type modeSPlane struct {
+		icao     uint32
+		squawk   uint32
+		altitude int32
+		heardBy  []*FrameSource
+	}
+	var planes []modeSPlane
+	t.EachPlane(func(p *Plane) bool {
+		if p.LastSeen().After(recent) {
+			planes = append(planes, modeSPlane{icao: p.IcaoIdentifier(), squawk: p.SquawkIdentity(), altitude: p.Altitude(), heardBy: p.heardBySince(recent)})
+		}
+		return true
+	})
+	heardBy := func(p modeSPlane, source *FrameSource) bool {
+		for _, s := range p.heardBy {
+			if s == source {
+				return true
+			}
+		}
+		return false
+	}
+
+	type sourcePlane struct {
+		source *FrameSource
+		icao   uint32
+	}
+
+	t.modeACLock.Lock()
+	defer t.modeACLock.Unlock()
+	// Mode A replies first, the planes each receiver is hearing squawk
+	squawking := make(map[sourcePlane]bool)
+	for key, target := range t.modeACTargets {
+		if target.LastSeen.Before(oldest) {
+			delete(t.modeACTargets, key)
+			continue
+		}
+		target.Icao = 0
+		target.MatchedSquawk = false
+		for _, p := range planes {
+			if 0 != p.squawk && p.squawk == target.Squawk && heardBy(p, target.Source) {
+				target.Icao = p.icao
+				target.MatchedSquawk = true
+				squawking[sourcePlane{source: target.Source, icao: p.icao}] = true
+				break
+			}
+		}
+	}
+
+	// then Mode C replies, but only for those planes
+	for _, target := range t.modeACTargets {
+		if target.MatchedSquawk || !target.HasAltitude {
+			continue
+		}
+		closest := int32(modeACAltitudeTolerance + 1)
+		for _, p := range planes {
+			if 0 == p.altitude || !squawking[sourcePlane{source: target.Source, icao: p.icao}] {
+				continue
+			}
+			diff := p.altitude - target.Altitude
+			if diff < 0 {
+				diff = -diff
+			}
+			if diff < closest {
+				closest = diff
+				target.Icao = p.icao
+			}
+		}
+	}
+}
+
+// ModeACTargets is every Mode A/C code we are currently receiving replies for
+func (t *Tracker) ModeACTargets() []ModeACTarget {
+	t.correlateModeAC()
+	t.modeACLock.Lock()
+	defer t.modeACLock.Unlock()
+	targets := make([]ModeACTarget, 0, len(t.modeACTargets))
+	for _, target := range t.modeACTargets {
+		targets = append(targets, *target)
+	}
+	sort.Slice(targets, func(i, j int) bool {
+		if targets[i].Code == targets[j].Code && nil != targets[i].Source && nil != targets[j].Source {
+			return targets[i].Source.OriginIdentifier < targets[j].Source.OriginIdentifier
+		}
+		return targets[i].Code < targets[j].Code
+	})
+	return targets
+}
+
+// UnidentifiedModeACTargets are the Mode A/C targets that we cannot match to a Mode S plane. These are most
+// likely aircraft with an A/C only transponder
+func (t *Tracker) UnidentifiedModeACTargets() []ModeACTarget {
+	var targets []ModeACTarget
+	for _, target := range t.ModeACTargets() {
+		if 0 == target.Icao && target.Replies >= modeACMinReplies {
+			targets = append(targets, target)
+		}
+	}
+	return targets
+}
@@ -0,0 +1,223 @@
+package mode_s
+
+const (
+	// AddressTypeIcao is a real 24 bit ICAO airframe address
+	AddressTypeIcao = iota
+	// AddressTypeNonIcao is an anonymous, self assigned or ground station (track file) address
+	AddressTypeNonIcao
+)
+
+const (
+	// SourceModeS is a Mode S reply (DF0/4/5/11/16/20/21)
+	SourceModeS = iota
+	// SourceAdsb is an Extended Squitter from the aircraft itself (DF17, DF18 CF 0/1)
+	SourceAdsb
+	// SourceTisb is a ground station telling us about a target it can see (DF18 CF 2/3/5)
+	SourceTisb
+	// SourceAdsr is a ground station rebroadcasting ADS-B it received on another link (DF18 CF 6)
+	SourceAdsr
+)
+
+const (
+	// DF18 Control Field values
+	cfAdsbIcao       = 0
+	cfAdsbNonIcao    = 1
+	cfTisbFine       = 2
+	cfTisbCoarse     = 3
+	cfTisbManagement = 4
+	cfTisbNonIcao    = 5
+	cfAdsr           = 6
+)
+
+type (
+	addressing struct {
+		cf          byte // DF18 control field
+		addressType byte
+		source      byte
+		// tisbServiceVolume is the Service Volume ID (SVID) of a coarse TIS-B position, which ground
+		// station service it came from
+		tisbServiceVolume byte
+	}
+)
+
+var (
+	addressTypeTable = map[byte]string{
+		AddressTypeIcao:    "icao",
+		AddressTypeNonIcao: "non-icao",
+	}
+	sourceTable = map[byte]string{
+		SourceModeS: "mode-s",
+		SourceAdsb:  "adsb",
+		SourceTisb:  "tis-b",
+		SourceAdsr:  "ads-r",
+	}
+	controlFieldTable = map[byte]string{
+		cfAdsbIcao:       "ADS-B, ICAO address (non-transponder)",
+		cfAdsbNonIcao:    "ADS-B, non-ICAO address",
+		cfTisbFine:       "TIS-B, fine format",
+		cfTisbCoarse:     "TIS-B, coarse format",
+		cfTisbManagement: "TIS-B/ADS-R management",
+		cfTisbNonIcao:    "TIS-B, fine format, non-ICAO address",
+		cfAdsr:           "ADS-R",
+		7:                "Reserved",
+	}
+)
+
+// decodeControlField works out what a DF18 frame is carrying and who it is about
+func (f *Frame) decodeControlField() {
+	f.cf = f.message[0] & 7
+	switch f.cf {
+	case cfAdsbIcao:
+		f.source, f.addressType = SourceAdsb, AddressTypeIcao
+	case cfAdsbNonIcao:
+		f.source, f.addressType = SourceAdsb, AddressTypeNonIcao
+	case cfTisbFine, cfTisbCoarse:
+		f.source, f.addressType = SourceTisb, AddressTypeIcao
+	case cfTisbManagement:
+		f.source = SourceTisb
+	case cfTisbNonIcao:
+		f.source, f.addressType = SourceTisb, AddressTypeNonIcao
+	case cfAdsr:
+		f.source, f.addressType = SourceAdsr, AddressTypeIcao
+	}
+}
+
+// hasAdsbPayload tells us if the ME field of a DF18 frame is in the same format as DF17
+func (f *Frame) hasAdsbPayload() bool {
+	switch f.cf {
+	case cfAdsbIcao, cfAdsbNonIcao, cfTisbFine, cfTisbNonIcao, cfAdsr:
+		return true
+	}
+	return false
+}
+
+// hasAddress tells us if the AA field of a DF18 frame is the address of a target
+func (f *Frame) hasAddress() bool {
+	return f.cf <= cfTisbCoarse || cfTisbNonIcao == f.cf || cfAdsr == f.cf
+}
+
+// decodeImf decodes the ICAO/Mode A Flag of fine TIS-B and ADS-R messages. When it is set, the address is not
+// an ICAO address. It lives in bits that mean something else in DF17, depending on the type code
+func (f *Frame) decodeImf() {
+	if cfTisbFine != f.cf && cfAdsr != f.cf && cfTisbCoarse != f.cf {
+		return
+	}
+	var imf bool
+	if cfTisbCoarse == f.cf {
+		imf = f.meBit(1)
+	} else {
+		switch f.messageType {
+		case 5, 6, 7, 8:
+			imf = f.meBit(21)
+		case 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 20, 21, 22:
+			imf = f.meBit(8)
+		case 19:
+			imf = f.meBit(9)
+		case 31:
+			imf = f.meBit(56)
+		}
+	}
+	if imf {
+		f.addressType = AddressTypeNonIcao
+	}
+}
+
+// decodeTisbCoarse decodes a DF18 CF3 coarse TIS-B airborne position (ME bits, 1 indexed)
+// bit  1     IMF (see decodeImf)
+// bit  2-3   Surveillance Status
+// bit  4-7   Service Volume ID
+// bit  8-19  Pressure Altitude (AC12, as in an airborne position)
+// bit  20    Ground Track Status
+// bit  21-25 Ground Track Angle (360/32 degrees)
+// bit  26-31 Ground Speed (0=no data, 16 knot steps)
+// bit  32    CPR Format (0=even, 1=odd)
+// bit  33-44 CPR Encoded Latitude
+// bit  45-56 CPR Encoded Longitude
+func (f *Frame) decodeTisbCoarse() {
+	me := f.message[4:11]
+	f.onGround = false
+	f.validVerticalStatus = true
+	f.surveillanceStatus = byte(mbField(me, 2, 3))
+	f.tisbServiceVolume = byte(mbField(me, 4, 7))
+
+	f.altitude = decodeAC12Field(int32(mbField(me, 8, 19)))
+	f.validAltitude = f.altitude != 0
+
+	if mbBit(me, 20) {
+		f.heading = float64(mbField(me, 21, 25)) * 360 / 32
+		f.validHeading = true
+	}
+	if speed := mbField(me, 26, 31); 0 != speed {
+		f.velocity = float64(speed-1) * 16
+		f.validVelocity = true
+	}
+
+	// the 12 bit CPR position is scaled up to 17 bits, so it decodes like any other (with less precision)
+	f.cprFlagOddEven = int(mbField(me, 32, 32))
+	f.rawLatitude = int(mbField(me, 33, 44)) << 5
+	f.rawLongitude = int(mbField(me, 45, 56)) << 5
+}
+
+// TisbServiceVolume is the Service Volume ID (SVID) of a coarse TIS-B position, it tells us which
+// ground station service sent it
+func (f *Frame) TisbServiceVolume() byte {
+	return f.tisbServiceVolume
+}
+
+// AdsbValid tells us if this frame carries an ADS-B message in the DF17 ME format. DF18 coarse TIS-B and
+// management frames do not
+func (f *Frame) AdsbValid() bool {
+	switch f.downLinkFormat {
+	case 17:
+		return true
+	case 18:
+		return f.hasAdsbPayload()
+	}
+	return false
+}
+
+// meBit is bit n (1 is the most significant) of the 56 bit ME field
+func (f *Frame) meBit(n int) bool {
+	n--
+	return f.message[4+n/8]&(0x80>>(n%8)) != 0
+}
+
+// ControlField is the DF18 control field (CF)
+func (f *Frame) ControlField() byte {
+	return f.cf
+}
+
+// ControlFieldString is a human readable version of the DF18 control field
+func (f *Frame) ControlFieldString() string {
+	return controlFieldTable[f.cf]
+}
+
+// AddressType tells us if the address in this frame is a real ICAO address (AddressTypeIcao) or not
+func (f *Frame) AddressType() byte {
+	return f.addressType
+}
+
+// AddressTypeString is a short name for the AddressType
+func (f *Frame) AddressTypeString() string {
+	return addressTypeTable[f.addressType]
+}
+
+// Source tells us where the information in this frame came from, see the Source* constants
+func (f *Frame) Source() byte {
+	return f.source
+}
+
+// SourceString is a short name for the Source
+func (f *Frame) SourceString() string {
+	return sourceTable[f.source]
+}
+
+// SourceString is a short name for a Source* constant
+func SourceString(source byte) string {
+	return sourceTable[source]
+}
+
+// AddressTypeString is a short name for an AddressType* constant
+func AddressTypeString(addressType byte) string {
+	return addressTypeTable[addressType]
+}
@@ -28,6 +28,25 @@ func decodeAC12Field(AC12Field int32) int32 {
 	}
 }
 
+// decodeAC13Field decodes a 13 bit altitude code (C1 A1 C2 A2 C4 A4 M B1 Q B2 D2 B4 D4) in to feet.
+// metric altitudes are not decoded
+func decodeAC13Field(AC13Field int32) (int32, bool) {
+	if AC13Field&0x40 == 0x40 {
+		// M bit set, metres
+		return 0, false
+	}
+	if AC13Field&0x10 == 0x10 {
+		// N is the 11 bit integer resulting from the removal of bits M and Q
+		n := ((AC13Field & 0x1F80) >> 2) | ((AC13Field & 0x0020) >> 1) | (AC13Field & 0x000F)
+		return (n * 25) - 1000, true
+	}
+	n := modeAToModeC(decodeID13Field(AC13Field))
+	if n < -12 {
+		return 0, false
+	}
+	return 100 * n, true
+}
+
 // this code liberally lifted from: http://www.ccsinfo.com/forum/viewtopic.php?p=77544
 func gillhamToAltitude(i16GillhamValue int32) int32 {
 	var i32Result int32
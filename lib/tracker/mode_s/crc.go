@@ -1,9 +1,33 @@
 package mode_s
 
-import "fmt"
+import (
+	"fmt"
+)
+
+const (
+	// CorrectionNone rejects any PI frame with a bad checksum
+	CorrectionNone = iota
+	// CorrectionSingleBit fixes 1-bit errors in DF11, DF17 and DF18 frames
+	CorrectionSingleBit
+	// CorrectionDoubleBit also fixes 2-bit errors in DF17 and DF18 frames
+	CorrectionDoubleBit
+)
+
+type (
+	// bitError is the location of the bits that produce a given syndrome
+	bitError struct {
+		numBits byte
+		bits    [2]byte
+	}
+)
 
 var (
 	modesChecksumTable [256]uint32
+
+	// syndrome -> bit error tables, by message length. The first 5 bits (the DF) are never corrected
+	// as a change there would change the format of the frame
+	longSyndromes  map[uint32]bitError
+	shortSyndromes map[uint32]bitError
 )
 
 const modesGeneratorPoly uint32 = 0xfff409
@@ -25,38 +49,138 @@ func init() {
 
 		modesChecksumTable[i] = c & 0x00ffffff
 	}
+
+	longSyndromes = buildSyndromeTable(modesLongMsgBytes, 2)
+	shortSyndromes = buildSyndromeTable(modesShortMsgBytes, 1)
+}
+
+// buildSyndromeTable works out the syndrome of every 1 (and 2) bit error for a message length.
+// syndromes that can be caused by more than one error are ambiguous and left out
+func buildSyndromeTable(msgLen int, maxBits int) map[uint32]bitError {
+	table := make(map[uint32]bitError)
+	ambiguous := make(map[uint32]bool)
+	msg := make([]byte, msgLen)
+	numBits := msgLen * 8
+
+	add := func(be bitError) {
+		syndrome := modeSChecksum(msg)
+		if _, ok := table[syndrome]; ok || ambiguous[syndrome] {
+			delete(table, syndrome)
+			ambiguous[syndrome] = true
+			return
+		}
+		table[syndrome] = be
+	}
+
+	for i := 5; i < numBits; i++ {
+		flipBit(msg, i)
+		add(bitError{numBits: 1, bits: [2]byte{byte(i)}})
+		if maxBits > 1 {
+			for k := i + 1; k < numBits; k++ {
+				flipBit(msg, k)
+				add(bitError{numBits: 2, bits: [2]byte{byte(i), byte(k)}})
+				flipBit(msg, k)
+			}
+		}
+		flipBit(msg, i)
+	}
+	return table
+}
+
+func flipBit(msg []byte, bit int) {
+	msg[bit/8] ^= 1 << (7 - uint(bit%8))
+}
+
+// modeSChecksum gives us the syndrome of a message, 0 means the checksum is good
+func modeSChecksum(message []byte) uint32 {
+	var n = len(message)
+	var checkSum uint32
+
+	for i := 0; i < n-3; i++ {
+		index := uint32(message[i]) ^ ((checkSum & 0xff0000) >> 16)
+		checkSum = (checkSum << 8) ^ modesChecksumTable[index]
+		checkSum = checkSum & 0xffffff
+	}
+
+	return checkSum ^ (uint32(message[n-3]) << 16) ^ (uint32(message[n-2]) << 8) ^ uint32(message[n-1])
 }
 
 func (f *Frame) decodeModeSChecksum() bool {
-	var n = f.getMessageLengthBytes()
-	var i, index uint32
+	f.checkSum = modeSChecksum(f.message[:f.getMessageLengthBytes()])
+	return f.checkSum == 0
+}
+
+// fixBitErrors tries to correct the message using the syndrome in f.checkSum
+func (f *Frame) fixBitErrors() bool {
+	level := f.errorCorrection
+	if CorrectionNone == level {
+		return false
+	}
+
+	var be bitError
+	var ok bool
+	if modesLongMsgBytes == len(f.message) {
+		be, ok = longSyndromes[f.checkSum]
+	} else {
+		be, ok = shortSyndromes[f.checkSum]
+	}
+	if !ok {
+		return false
+	}
+	if 11 == f.downLinkFormat && 0 == f.checkSum&0xFFFF80 {
+		// this looks like an interrogator identifier (IID) in the parity, not something we can fix
+		return false
+	}
+	if be.numBits > 1 && (CorrectionDoubleBit != level || 11 == f.downLinkFormat) {
+		return false
+	}
 
+	for i := byte(0); i < be.numBits; i++ {
+		flipBit(f.message, int(be.bits[i]))
+	}
+	f.correctedBits = be.numBits
 	f.checkSum = 0
-	for i = 0; i < n-3; i++ {
-		index = uint32(f.message[i]) ^ ((f.checkSum & 0xff0000) >> 16)
-		f.checkSum = (f.checkSum << 8) ^ modesChecksumTable[index]
-		f.checkSum = f.checkSum & 0xffffff
+	if !f.binary {
+		f.raw = fmt.Sprintf("%X", f.message)
 	}
+	return true
+}
 
-	f.checkSum = f.checkSum ^ (uint32(f.message[n-3]) << 16) ^ (uint32(f.message[n-2]) << 8) ^ uint32(f.message[n-1])
+// CorrectedBits is the number of bit errors we fixed in this frame
+func (f *Frame) CorrectedBits() byte {
+	return f.correctedBits
+}
 
-	return f.checkSum == 0
+// decodeAddressParity recovers the ICAO from an Address/Parity field. The address is XORed over the CRC,
+// so the syndrome is the address. We cannot tell a corrupted frame from a good one, that is up to the caller
+func (f *Frame) decodeAddressParity() {
+	f.checkSum = modeSChecksum(f.message[:f.getMessageLengthBytes()])
+	f.icao = f.checkSum
+	f.icaoFromParity = true
+}
+
+// IcaoFromParity tells us the ICAO was recovered from an Address/Parity field and has not been verified
+func (f *Frame) IcaoFromParity() bool {
+	return f.icaoFromParity
+}
+
+// ChecksumPassed tells us the Parity/Interrogator field of this frame was checked and is good, so we can
+// believe its ICAO. Address/Parity frames and frames that failed (or skipped) the check are not
+func (f *Frame) ChecksumPassed() bool {
+	return f.checksumPassed
 }
 
 func (f *Frame) checkCrc() error {
-	if "MLAT" == f.mode {
-		// not currently able to checksum beast AVR timestamp format messages
-		return nil
-	}
 	switch f.downLinkFormat {
-	case 0, 4, 5, 16, 20, 21, 24:
-		// decoding/checking CRC here is tricky. Field Type AP
+	case 0, 4, 5, 16, 20, 21, 24: // Field Type AP
+		f.decodeAddressParity()
 		return nil
 	case 11, 17, 18: // Field Type PI
-		if f.decodeModeSChecksum() {
+		if f.decodeModeSChecksum() || f.fixBitErrors() {
+			f.checksumPassed = true
 			return nil
 		}
-		return fmt.Errorf("invalid checksum for DF %d (%s)", f.downLinkFormat, f.raw)
+		return fmt.Errorf("invalid checksum for DF %d (%s)", f.downLinkFormat, f.rawString())
 	default:
 		return fmt.Errorf("do not know how to CRC Downlink Format %d", f.downLinkFormat)
 	}
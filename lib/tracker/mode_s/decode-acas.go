@@ -0,0 +1,195 @@
+package mode_s
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// Threat Type Indicator (TTI) values
+	ThreatTypeNone         = 0
+	ThreatTypeIcao         = 1
+	ThreatTypeRangeBearing = 2
+
+	// vdsAcasRA is the V(DS) of a DF16 MV field that carries a resolution advisory
+	vdsAcasRA = 0x30
+)
+
+type (
+	// ResolutionAdvisory is an ACAS/TCAS Resolution Advisory report. The same layout is used in
+	// DF16 (MV field), DF20/21 (BDS 3,0) and DF17 (Type Code 28, Sub Type 2)
+	ResolutionAdvisory struct {
+		ActiveRA       uint16 // ARA - 14 bits of active resolution advisories
+		RAComplement   byte   // RAC - 4 bits of RA complements (do not pass below/above, do not turn left/right)
+		Terminated     bool   // RAT - the RA has been terminated
+		MultipleThreat bool   // MTE - more than one threat is being handled
+		ThreatType     byte   // TTI - see ThreatType* constants
+
+		// when ThreatType == ThreatTypeIcao
+		ThreatIcao uint32
+
+		// when ThreatType == ThreatTypeRangeBearing
+		HasThreatAltitude bool
+		ThreatAltitude    int32 // feet
+		HasThreatRange    bool
+		ThreatRange       float64 // nautical miles
+		HasThreatBearing  bool
+		ThreatBearing     int // degrees, relative to the aircraft heading
+	}
+
+	acas struct {
+		validRA bool
+		ra      ResolutionAdvisory
+	}
+)
+
+// decodeAcasRA decodes the 56 bit resolution advisory field (bits 1-8 are the BDS/type code)
+func (a *acas) decodeAcasRA(mb []byte) {
+	a.validRA = true
+	a.ra = ResolutionAdvisory{
+		ActiveRA:       uint16(mbField(mb, 9, 22)),
+		RAComplement:   byte(mbField(mb, 23, 26)),
+		Terminated:     mbBit(mb, 27),
+		MultipleThreat: mbBit(mb, 28),
+		ThreatType:     byte(mbField(mb, 29, 30)),
+	}
+
+	switch a.ra.ThreatType {
+	case ThreatTypeIcao:
+		a.ra.ThreatIcao = uint32(mbField(mb, 31, 54))
+	case ThreatTypeRangeBearing:
+		a.ra.ThreatAltitude, a.ra.HasThreatAltitude = decodeAC13Field(int32(mbField(mb, 31, 43)))
+
+		// 0 = no range estimate, 1 = less than 0.05NM, n = (n-1)/10 NM, 127 = more than 12.55NM
+		if tidr := mbField(mb, 44, 50); tidr > 0 {
+			a.ra.HasThreatRange = true
+			a.ra.ThreatRange = float64(tidr-1) / 10.0
+		}
+		// 0 = no bearing estimate, n = between 6(n-1) and 6n degrees, 61-63 are invalid
+		if tidb := mbField(mb, 51, 56); tidb > 0 && tidb <= 60 {
+			a.ra.HasThreatBearing = true
+			a.ra.ThreatBearing = int(tidb-1) * 6
+		}
+	}
+}
+
+// ResolutionAdvisoryValid tells us if this frame has a TCAS Resolution Advisory
+func (f *Frame) ResolutionAdvisoryValid() bool {
+	return f.validRA
+}
+
+// MustResolutionAdvisory is the TCAS Resolution Advisory carried in this frame
+func (f *Frame) MustResolutionAdvisory() ResolutionAdvisory {
+	if f.validRA {
+		return f.ra
+	}
+	panic("resolution advisory is not valid")
+}
+
+// Active tells us if there is an RA in effect (something is active and it has not been terminated)
+func (ra ResolutionAdvisory) Active() bool {
+	return !ra.Terminated && (0 != ra.ActiveRA || 0 != ra.RAComplement)
+}
+
+// Advisories gives us a human readable list of the advisories in effect
+func (ra ResolutionAdvisory) Advisories() []string {
+	var advisories []string
+	if ra.ActiveRA&0x2000 != 0 {
+		// single threat (or a multiple threat RA with a single sense)
+		upward := ra.ActiveRA&0x0800 == 0
+		positive := ra.ActiveRA&0x0080 != 0
+		corrective := ra.ActiveRA&0x1000 != 0
+		switch {
+		case positive && upward:
+			advisories = append(advisories, "Climb")
+		case positive:
+			advisories = append(advisories, "Descend")
+		case upward:
+			advisories = append(advisories, "Do not descend")
+		default:
+			advisories = append(advisories, "Do not climb")
+		}
+		if corrective {
+			advisories = append(advisories, "Corrective")
+		} else {
+			advisories = append(advisories, "Preventive")
+		}
+		if ra.ActiveRA&0x0400 != 0 {
+			advisories = append(advisories, "Increase rate")
+		}
+		if ra.ActiveRA&0x0200 != 0 {
+			advisories = append(advisories, "Sense reversal")
+		}
+		if ra.ActiveRA&0x0100 != 0 {
+			advisories = append(advisories, "Altitude crossing")
+		}
+	} else if ra.MultipleThreat {
+		if ra.ActiveRA&0x1000 != 0 {
+			advisories = append(advisories, "Correct upwards")
+		}
+		if ra.ActiveRA&0x0800 != 0 {
+			advisories = append(advisories, "Climb")
+		}
+		if ra.ActiveRA&0x0400 != 0 {
+			advisories = append(advisories, "Correct downwards")
+		}
+		if ra.ActiveRA&0x0200 != 0 {
+			advisories = append(advisories, "Descend")
+		}
+		if ra.ActiveRA&0x0100 != 0 {
+			advisories = append(advisories, "Altitude crossing")
+		}
+		if ra.ActiveRA&0x0080 != 0 {
+			advisories = append(advisories, "Sense reversal")
+		}
+	}
+
+	if ra.RAComplement&0x8 != 0 {
+		advisories = append(advisories, "Do not pass below")
+	}
+	if ra.RAComplement&0x4 != 0 {
+		advisories = append(advisories, "Do not pass above")
+	}
+	if ra.RAComplement&0x2 != 0 {
+		advisories = append(advisories, "Do not turn left")
+	}
+	if ra.RAComplement&0x1 != 0 {
+		advisories = append(advisories, "Do not turn right")
+	}
+	return advisories
+}
+
+// ThreatString describes the threat that caused the RA
+func (ra ResolutionAdvisory) ThreatString() string {
+	switch ra.ThreatType {
+	case ThreatTypeIcao:
+		return fmt.Sprintf("%06X", ra.ThreatIcao)
+	case ThreatTypeRangeBearing:
+		var parts []string
+		if ra.HasThreatAltitude {
+			parts = append(parts, fmt.Sprintf("%d feet", ra.ThreatAltitude))
+		}
+		if ra.HasThreatRange {
+			parts = append(parts, fmt.Sprintf("%0.1fNM", ra.ThreatRange))
+		}
+		if ra.HasThreatBearing {
+			parts = append(parts, fmt.Sprintf("bearing %d", ra.ThreatBearing))
+		}
+		return strings.Join(parts, ", ")
+	}
+	return ""
+}
+
+func (ra ResolutionAdvisory) String() string {
+	s := strings.Join(ra.Advisories(), ", ")
+	if ra.Terminated {
+		s = strings.TrimPrefix(s+", RA terminated", ", ")
+	}
+	if ra.MultipleThreat {
+		s = strings.TrimPrefix(s+", multiple threats", ", ")
+	}
+	if threat := ra.ThreatString(); "" != threat {
+		s += " (threat: " + threat + ")"
+	}
+	return s
+}
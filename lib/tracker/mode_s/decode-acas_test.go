@@ -0,0 +1,75 @@
+package mode_s
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFrame_decodeAcasRA(t *testing.T) {
+	tests := []struct {
+		name       string
+		frame      string
+		want       ResolutionAdvisory
+		advisories []string
+	}{
+		{
+			name:  "DF17 TC28 ST2 single threat climb",
+			frame: "8D7C1234E2C20005F159E0A47217",
+			want: ResolutionAdvisory{
+				ActiveRA:   0b11000010000000,
+				ThreatType: ThreatTypeIcao,
+				ThreatIcao: 0x7C5678,
+			},
+			advisories: []string{"Climb", "Corrective"},
+		},
+		{
+			name:  "DF16 multiple threat with range and bearing",
+			frame: "80E19AB030600218D70550000000",
+			want: ResolutionAdvisory{
+				ActiveRA:          0b01100000000000,
+				RAComplement:      0b1000,
+				MultipleThreat:    true,
+				ThreatType:        ThreatTypeRangeBearing,
+				HasThreatAltitude: true,
+				ThreatAltitude:    10000,
+				HasThreatRange:    true,
+				ThreatRange:       2.0,
+				HasThreatBearing:  true,
+				ThreatBearing:     90,
+			},
+			advisories: []string{"Correct upwards", "Climb", "Do not pass below"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := DecodeString(tt.frame, time.Now())
+			if nil != err {
+				t.Fatal(err)
+			}
+			if !f.ResolutionAdvisoryValid() {
+				t.Fatal("Expected a valid resolution advisory")
+			}
+			got := f.MustResolutionAdvisory()
+			if got != tt.want {
+				t.Errorf("MustResolutionAdvisory() = %+v, want %+v", got, tt.want)
+			}
+			if !got.Active() {
+				t.Error("Expected the RA to be active")
+			}
+			if !reflect.DeepEqual(got.Advisories(), tt.advisories) {
+				t.Errorf("Advisories() = %v, want %v", got.Advisories(), tt.advisories)
+			}
+		})
+	}
+}
+
+func TestResolutionAdvisory_String(t *testing.T) {
+	ra := ResolutionAdvisory{Terminated: true, ThreatType: ThreatTypeIcao, ThreatIcao: 0x7C5678}
+	if ra.Active() {
+		t.Error("A terminated RA should not be active")
+	}
+	if "RA terminated (threat: 7C5678)" != ra.String() {
+		t.Errorf("Incorrect RA description: %s", ra)
+	}
+}
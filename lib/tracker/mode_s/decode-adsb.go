@@ -3,12 +3,7 @@ package mode_s
 // extended squitter decoding
 
 import (
-	"errors"
-	"fmt"
 	"math"
-	"reflect"
-	"strconv"
-	"strings"
 )
 
 func (f *Frame) decodeAdsbLatLon() {
@@ -49,7 +44,7 @@ func (f *Frame) decodeAdsb() {
 		f.decodeSurfaceMovementField()
 
 		if f.message[5]&0x08 != 0 {
-			f.heading = float64(((((f.message[5] << 4) | (f.message[6] >> 4)) & 0x007F) * 45) >> 4)
+			f.heading = float64(((f.message[5]&0x07)<<4)|(f.message[6]>>4)) * 360 / 128
 			f.validHeading = true
 		}
 
@@ -107,21 +102,20 @@ func (f *Frame) decodeAdsb() {
 			f.northSouthVelocity = (int(f.message[7]&0x7f) << 3) | (int(f.message[8]&0xe0) >> 5)
 			f.verticalRateSource = int((f.message[8] & 0x10) >> 4)
 			/* Compute velocity and angle from the two speed components. */
-
 			if f.messageSubType == 2 {
 				// supersonic - unit is 4 knots
-				f.eastWestVelocity = f.eastWestVelocity << 2
-				f.northSouthVelocity = f.northSouthVelocity << 2
 				f.superSonic = true
 			}
 
-			f.velocity = math.Sqrt(float64((f.northSouthVelocity * f.northSouthVelocity) + (f.eastWestVelocity * f.eastWestVelocity)))
-			f.validVelocity = true
-
-			if f.velocity != 0 {
+			// each component is one more than the speed, 0 is no information
+			if f.eastWestVelocity != 0 && f.northSouthVelocity != 0 {
 				var heading float64
 				f.eastWestVelocity -= 1
 				f.northSouthVelocity -= 1
+				if f.superSonic {
+					f.eastWestVelocity = f.eastWestVelocity << 2
+					f.northSouthVelocity = f.northSouthVelocity << 2
+				}
 				if f.eastWestDirection != 0 {
 					// GO WEST! (0=east, 1=west)
 					f.eastWestVelocity *= -1
@@ -130,16 +124,22 @@ func (f *Frame) decodeAdsb() {
 					// Going Down South! (0=north, 1=south)
 					f.northSouthVelocity *= -1
 				}
-				heading = math.Atan2(float64(f.eastWestVelocity), float64(f.northSouthVelocity))
-				/* Convert to degrees. */
-				f.heading = heading * 360 / (math.Pi * 2)
-				/* We don't want negative values but a 0-360 scale. */
-				if f.heading < 0 {
-					f.heading += 360
+
+				f.velocity = math.Sqrt(float64((f.northSouthVelocity * f.northSouthVelocity) + (f.eastWestVelocity * f.eastWestVelocity)))
+				f.validVelocity = true
+
+				if f.velocity != 0 {
+					heading = math.Atan2(float64(f.eastWestVelocity), float64(f.northSouthVelocity))
+					/* Convert to degrees. */
+					f.heading = heading * 360 / (math.Pi * 2)
+					/* We don't want negative values but a 0-360 scale. */
+					if f.heading < 0 {
+						f.heading += 360
+					}
+					f.validHeading = true
+				} else {
+					f.heading = 0
 				}
-				f.validHeading = true
-			} else {
-				f.heading = 0
 			}
 		} else if f.messageSubType == 3 || f.messageSubType == 4 {
 			// Air Speed -- ground speed not available
@@ -192,21 +192,27 @@ func (f *Frame) decodeAdsb() {
 			var emergencyId = int((f.message[5] & 0xe0) >> 5)
 			f.alert = emergencyId != 0
 			f.emergency = emergencyStateTable[emergencyId]
+			f.decodeSquawkIdentity(5, 6)
 
 			// can get the Mode A Address too
 			//mode_a_code = (short) (msg[2]|((msg[1]&0x1F)<<8));
 
 		} else if f.messageSubType == 2 {
 			// TCAS Resolution Advisory
+			f.decodeAcasRA(f.message[4:11])
 		}
 	case 29:
 		// Target State and Status Message
 		// DO-260 - unused
 		// DO-260A = Target State and Status Information Message
 		// DO-260B =
+		// the sub type is only 2 bits for this message
+		f.messageSubType = (f.message[4] & 0x6) >> 1
 		if f.messageSubType == 0 {
 			// DO-260A
+			f.decodeTargetStateV1(f.message[4:11])
 		} else if f.messageSubType == 1 {
+			f.decodeTargetStateV2(f.message[4:11])
 			// DO-260B
 			// bit 40    SIL supplement (SIL Per Hour or Per Sample)
 			// bit 41    Selected Alt Type
@@ -252,24 +258,27 @@ func (f *Frame) decodeAdsb() {
 				f.cccHasUATReceiver = (f.compatibilityClass & 0x20) != 0
 			}
 
+			if f.compatibilityClass&0xC000 == 0 {
+				f.validCompatibilityClass = true
+				f.cccHas1090EsIn = (f.compatibilityClass & 0x1000) != 0
+			}
 		} else if f.messageSubType == 1 {
 			f.validVerticalStatus = true
 			f.onGround = true
+			// the surface capability class is only 12 bits, followed by the length/width code
 			f.compatibilityClass = int(f.message[5])<<4 | int(f.message[6]&0xF0)>>4
 			f.airframeWidthLen = f.message[6] & 0x0F
 
-			if f.compatibilityClass&0xC000 == 0 {
-				f.cccHasLowTxPower = bp((f.compatibilityClass & 0x200) != 0)
-				f.cccHasUATReceiver = (f.compatibilityClass & 0x100) != 0
+			if f.compatibilityClass&0xC00 == 0 {
+				f.validCompatibilityClass = true
+				f.cccHas1090EsIn = (f.compatibilityClass & 0x100) != 0
+				f.cccHasLowTxPower = bp((f.compatibilityClass & 0x20) != 0)
+				f.cccHasUATReceiver = (f.compatibilityClass & 0x10) != 0
 				f.validNacV = true
-				f.nacV = byte((f.compatibilityClass & 0xE0) >> 5)
-				f.nicSupplementC = byte((f.compatibilityClass & 0x10) >> 4)
+				f.nacV = byte((f.compatibilityClass & 0x0E) >> 1)
+				f.nicSupplementC = byte(f.compatibilityClass & 0x01)
 			}
 		}
-		if f.compatibilityClass&0xC000 == 0 {
-			f.validCompatibilityClass = true
-			f.cccHas1090EsIn = (f.compatibilityClass & 0x1000) != 0
-		}
 
 		f.operationalModeCode = int(f.message[7])<<8 | int(f.message[8])
 		f.adsbVersion = (f.message[9] & 0xe0) >> 5
@@ -280,110 +289,85 @@ func (f *Frame) decodeAdsb() {
 		f.sil = f.message[10] & 0x30 >> 4
 		f.nicCrossCheck = f.message[10] & 0x08 >> 3
 		f.northReference = f.message[10] & 0x04 >> 2
+		f.silSupplement = f.message[10] & 0x02 >> 1
+		f.validOperationalStatus = f.messageSubType <= 1
+		// version 0 does not send these
+		f.validNacP = f.validOperationalStatus && f.adsbVersion > 0
+		f.validSil = f.validNacP
 	}
 }
 
-func (f *Frame) bitString() string {
-	var bits string
-	for _, msg := range f.message {
-		bits += fmt.Sprintf("%08b", msg)
+// decodeTargetStateV1 decodes a DO-260A Target State and Status message (ME bits, 1 indexed)
+// bit  8-9   Vertical Data Available / Source (0=none, 1=MCP/FCU, 2=holding altitude, 3=FMS/RNAV)
+// bit  10    Target Altitude Type (0=flight level, 1=MSL)
+// bit  11    Backward Compatibility Flag
+// bit  12-13 Target Altitude Capability
+// bit  14-15 Vertical Mode Indicator
+// bit  16-25 Target Altitude
+// bit  26-27 Horizontal Data Available / Source (0=none, 1=MCP/FCU, 2=maintaining heading/track, 3=FMS/RNAV)
+// bit  28-36 Target Heading/Track Angle
+// bit  37    Target Heading/Track Indicator (0=heading, 1=track)
+func (f *Frame) decodeTargetStateV1(me []byte) {
+	// holding altitude is the altitude the aircraft is at, not one the crew selected
+	if target := mbField(me, 16, 25); target <= 1010 {
+		altitude := int32(target)*100 - 1000
+		switch mbField(me, 8, 9) {
+		case 1:
+			f.validMcpSelectedAltitude = true
+			f.mcpSelectedAltitude = altitude
+		case 3:
+			f.validFmsSelectedAltitude = true
+			f.fmsSelectedAltitude = altitude
+		}
+	}
+	if heading := mbField(me, 28, 36); 0 != mbField(me, 26, 27) && heading < 360 {
+		f.validSelectedHeading = true
+		f.selectedHeading = float64(heading)
 	}
-	return bits
 }
 
-func (f *Frame) decodeInto(bitStream string, t interface{}) error {
-	valParentPtr := reflect.ValueOf(t)
-	if reflect.Ptr != valParentPtr.Kind() || valParentPtr.IsNil() {
-		return errors.New("you need to pass in a pointer to your struct")
+// decodeTargetStateV2 decodes a DO-260B Target State and Status message (ME bits, 1 indexed)
+// bit  9     Selected Altitude Type (0=MCP/FCU, 1=FMS)
+// bit  10-20 Selected Altitude (0=no data)
+// bit  21-29 Barometric Pressure Setting (0=no data)
+// bit  30-39 Selected Heading Status, Sign and Heading
+// bit  47    Status of the MCP/FCU mode bits
+// bit  48-54 Autopilot, VNAV, Alt Hold, ADS-R, Approach, TCAS, LNAV
+func (f *Frame) decodeTargetStateV2(me []byte) {
+	if altitude := mbField(me, 10, 20); 0 != altitude {
+		if mbBit(me, 9) {
+			f.validFmsSelectedAltitude = true
+			f.fmsSelectedAltitude = int32(altitude-1) * 32
+		} else {
+			f.validMcpSelectedAltitude = true
+			f.mcpSelectedAltitude = int32(altitude-1) * 32
+		}
 	}
-	valParent := valParentPtr.Elem()
-	if !valParent.CanAddr() || !valParent.IsValid() || !valParent.CanSet() {
-		return errors.New("cannot edit struct")
+	if baro := mbField(me, 21, 29); 0 != baro {
+		f.validBaroSetting = true
+		f.baroSetting = 800 + float64(baro-1)*0.8
 	}
-	tType := reflect.TypeOf(t).Elem()
-	if tType.Kind() != reflect.Struct {
-		return errors.New("you need to pass in a struct with all the struct tags (bits,name,desc)")
+	if f.validSelectedHeading = mbBit(me, 30); f.validSelectedHeading {
+		// the sign bit is the top bit of a 9 bit heading
+		f.selectedHeading = float64(mbField(me, 31, 39)) * 180.0 / 256.0
 	}
-
-	for i := 0; i < tType.NumField(); i++ {
-		field := tType.Field(i)
-
-		bits := field.Tag.Get("bits")
-
-		splitBits := strings.SplitN(bits, "-", 2)
-		if 2 != len(splitBits) {
-			println("Incorrect Struct Tag `bits`")
-		}
-		low, err := strconv.ParseUint(splitBits[0], 10, 8)
-		if nil != err {
-			return fmt.Errorf("incorrect Struct Tag `bits` low (%s) needs to be a number", splitBits[0])
-		}
-		high, err := strconv.ParseUint(splitBits[1], 10, 8)
-		if nil != err {
-			return fmt.Errorf("incorrect Struct Tag `bits` high (%s) needs to be a number", splitBits[1])
-		}
-		fieldBits := bitStream[low:high]
-		ii, err := strconv.ParseUint(fieldBits, 2, 64)
-
-		valField := valParent.Field(i)
-		if !valField.IsValid() || !valField.CanSet() {
-			err = fmt.Errorf("cannot Set Field %s", field.Name)
-		}
-		valField.SetUint(ii)
-	}
-	return nil
-}
-
-func (f *Frame) decodeAdsb2() (*extendedSquitter, error) {
-
-	// Down Link Format 17 Message Types
-	f.messageType = f.message[4] >> 3
-	f.messageSubType = f.message[4] & 7
-	bitStream := f.bitString()
-
-	var err error
-	var es extendedSquitter
-
-	err = f.decodeInto(bitStream, &es)
-
-	switch f.messageType {
-	case 1, 2, 3, 4:
-		err = f.decodeInto(bitStream, &es.df17me1)
-	case 19:
-		switch f.messageSubType {
-		case 1, 2:
-			err = f.decodeInto(bitStream, &es.df17me19t1)
-		case 3, 4:
-			err = f.decodeInto(bitStream, &es.df17me19t3)
-		}
-	case 28:
-		switch f.messageSubType {
-		case 1:
-			err = f.decodeInto(bitStream, &es.df17me28t1)
-		case 2:
-			err = f.decodeInto(bitStream, &es.df17me28t2)
-		}
-	case 29:
-		switch f.messageSubType {
-		case 0:
-			// DO-260A
-			err = f.decodeInto(bitStream, &es.df17me29t0)
-		case 1:
-			// DO-260B
-			err = f.decodeInto(bitStream, &es.df17me29t1)
-		default:
-			err = f.decodeInto(bitStream, &es.df17me29t2)
-		}
-	case 31:
-		switch f.messageSubType {
-		case 0:
-			err = f.decodeInto(bitStream, &es.df17me31t0)
-		case 1:
-			err = f.decodeInto(bitStream, &es.df17me31t1)
-		}
-	default:
+	f.validNacP = true
+	f.nacP = byte(mbField(me, 40, 43))
+	f.nicBaro = byte(mbField(me, 44, 44))
+	f.validSil = true
+	f.sil = byte(mbField(me, 45, 46))
+	f.silSupplement = byte(mbField(me, 8, 8))
+
+	if mbBit(me, 47) {
+		f.validMcpModes = true
+		f.vnavMode = mbBit(me, 49)
+		f.altHoldMode = mbBit(me, 50)
+		f.approachMode = mbBit(me, 52)
+
+		f.validAutopilotModes = true
+		f.autopilotEngaged = mbBit(me, 48)
+		f.lnavMode = mbBit(me, 54)
 	}
-	return &es, err
 }
 
 //
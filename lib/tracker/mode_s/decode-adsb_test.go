@@ -2,6 +2,7 @@ package mode_s
 
 import (
 	"fmt"
+	"math"
 	"testing"
 	"time"
 )
@@ -108,9 +109,9 @@ func TestBeastAvrTimestampDecode112BitModeS(t *testing.T) {
 
 	raw := "@016CE3671AA88D00199A8BB80030A8000628F400;"
 	t1 := time.Now()
-	frame, err := DecodeString(raw, t1)
-	if nil != err {
-		t.Errorf("Failed to decode frame: %s", err)
+	frame := NewFrame(raw, t1)
+	if nil == frame {
+		t.Fatalf("Failed to parse frame")
 	}
 
 	if raw != frame.full {
@@ -119,6 +120,39 @@ func TestBeastAvrTimestampDecode112BitModeS(t *testing.T) {
 	if "MLAT" != frame.mode {
 		t.Errorf("Failed to identify frame as Beast AVR")
 	}
+	// 0x016CE3671AA8 ticks of a 12MHz counter
+	if want := 130598*time.Second + 606734000*time.Nanosecond; want != frame.BeastTicksNs() {
+		t.Errorf("Incorrect beast uptime. want: %s, got: %s", want, frame.BeastTicksNs())
+	}
+}
+
+func TestBeastAvrTimestampChecksum(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		wantErr bool
+	}{
+		// the jetvision wiki's example frame has a bad parity field
+		{name: "wiki frame", raw: "@016CE3671AA88D00199A8BB80030A8000628F400;", wantErr: true},
+		{name: "good parity", raw: "@016CE3671AA88D00199A8BB80030A80006520316;"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frame, err := DecodeString(tt.raw, time.Now())
+			if tt.wantErr {
+				if nil == err {
+					t.Error("Expected a checksum error")
+				}
+				return
+			}
+			if nil != err {
+				t.Fatalf("Failed to decode frame: %s", err)
+			}
+			if "MLAT" != frame.mode {
+				t.Errorf("Failed to identify frame as Beast AVR")
+			}
+		})
+	}
 }
 
 func Test_calcSurfaceSpeed(t *testing.T) {
@@ -250,6 +284,23 @@ func Test_calcSurfaceSpeed(t *testing.T) {
 	}
 }
 
+func TestDecodeDF17SurfacePosition(t *testing.T) {
+	frame, err := DecodeString("*8C4841753A9A153237AEF0F275BE;", time.Now())
+	if nil != err {
+		t.Error(err)
+		return
+	}
+	if MessageKindSurfacePos != frame.MessageKind() {
+		t.Errorf("Expected a surface position, got %s", frame.MessageKind())
+	}
+	if !frame.HeadingValid() || 92.8125 != frame.MustHeading() {
+		t.Errorf("Incorrect surface track: 92.8125 != %0.4f (valid %t)", frame.MustHeading(), frame.HeadingValid())
+	}
+	if !frame.VelocityValid() || 17 != frame.MustVelocity() {
+		t.Errorf("Incorrect surface speed: 17 != %0.2f", frame.MustVelocity())
+	}
+}
+
 func TestAltitudeDecode(t *testing.T) {
 	frame, err := DecodeString("*8D7C7DAA582886FB218A9AFB0420;", time.Now())
 	if nil != err {
@@ -272,11 +323,12 @@ func TestDecodeDF17MT28(t *testing.T) {
 		frame     string
 		emergency bool
 		cap       int
+		squawk    uint32
 	}{
-		{name: fmt.Sprintf("DF17/MT28/ST01 %s", DF17FrameEmergencyPriority), frame: "8C7C4A0CE104BC0000000069DE1A", cap: 4},
-		{name: fmt.Sprintf("DF17/MT28/ST01 %s", DF17FrameEmergencyPriority), frame: "8D7C4A0CE101950000000095FC54", cap: 5},
-		{name: fmt.Sprintf("DF17/MT28/ST01 %s", DF17FrameEmergencyPriority), frame: "8D7C4A0CE104BC0000000031AF62", cap: 5},
-		{name: fmt.Sprintf("DF17/MT28/ST01 %s", DF17FrameEmergencyPriority), frame: "8F7C4A0CE104BC00000000814D92", cap: 7},
+		{name: fmt.Sprintf("DF17/MT28/ST01 %s", DF17FrameEmergencyPriority), frame: "8C7C4A0CE104BC0000000069DE1A", cap: 4, squawk: 4323},
+		{name: fmt.Sprintf("DF17/MT28/ST01 %s", DF17FrameEmergencyPriority), frame: "8D7C4A0CE101950000000095FC54", cap: 5, squawk: 4047},
+		{name: fmt.Sprintf("DF17/MT28/ST01 %s", DF17FrameEmergencyPriority), frame: "8D7C4A0CE104BC0000000031AF62", cap: 5, squawk: 4323},
+		{name: fmt.Sprintf("DF17/MT28/ST01 %s", DF17FrameEmergencyPriority), frame: "8F7C4A0CE104BC00000000814D92", cap: 7, squawk: 4323},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -298,6 +350,12 @@ func TestDecodeDF17MT28(t *testing.T) {
 			if "7C4A0C" != frame.IcaoStr() {
 				t.Errorf("Invalid ICAO. 7C4A0C != %s", frame.IcaoStr())
 			}
+			if tt.squawk != frame.SquawkIdentity() {
+				t.Errorf("Invalid Mode A code. %04d != %04d", tt.squawk, frame.SquawkIdentity())
+			}
+			if "" != frame.FlightNumber() {
+				t.Errorf("Emergency/priority status has no callsign, got %q", frame.FlightNumber())
+			}
 
 			// todo: determine more tests
 		})
@@ -308,10 +366,24 @@ func TestDecodeDF17MT29(t *testing.T) {
 	tests := []struct {
 		name     string
 		frame    string
-		onGround bool
+		icao     string
+		subType  byte
+		mcpAlt   int32 // -1 == not valid
+		fmsAlt   int32 // -1 == not valid
+		baro     float64
+		heading  float64
+		modes    bool
+		ap, lnav bool
+		vnav     bool
+		altHold  bool
+		approach bool
 	}{
-		{name: fmt.Sprintf("DF17/MT29/ST02 %s", DF17FrameTargetStateStatus), frame: "8D7C4A0CEA0000000000005D4CDC", onGround: false},
-		{name: fmt.Sprintf("DF17/MT29/ST02 %s", DF17FrameTargetStateStatus), frame: "8D7C4A0CEA00085FBD3F04D4F47E", onGround: true},
+		{name: "DF17/MT29/ST01 No Data", frame: "8D7C4A0CEA0000000000005D4CDC", icao: "7C4A0C", subType: 1, mcpAlt: -1, fmsAlt: -1},
+		{name: "DF17/MT29/ST01 Baro and Heading", frame: "8D7C4A0CEA00085FBD3F04D4F47E", icao: "7C4A0C", subType: 1, mcpAlt: -1, fmsAlt: -1, baro: 1012.8, heading: 336.09375, modes: true, ap: true, lnav: true},
+		{name: "DF17/MT29/ST01 Autopilot", frame: "8DA05629EA21485CBF3F8CADAEEB", icao: "A05629", subType: 1, mcpAlt: 16992, fmsAlt: -1, baro: 1012.8, heading: 66.796875, modes: true, ap: true, lnav: true, vnav: true},
+		{name: "DF17/MT29/ST00 DO-260A", frame: "8D7C4A0CE992BCC394F000093D34", icao: "7C4A0C", subType: 0, mcpAlt: -1, fmsAlt: 36700, heading: 57},
+		{name: "DF17/MT29/ST00 DO-260A Holding Altitude", frame: "8D7C1474E9481900093810E5B315", icao: "7C1474", subType: 0, mcpAlt: -1, fmsAlt: -1},
+		{name: "DF17/MT29/ST00 DO-260A MCP Heading", frame: "8D7C3F18E8000020713800DA52FD", icao: "7C3F18", subType: 0, mcpAlt: -1, fmsAlt: -1, heading: 7},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -326,11 +398,38 @@ func TestDecodeDF17MT29(t *testing.T) {
 			if 29 != frame.MessageType() {
 				t.Error("Should have been Message Type 29")
 			}
-			if "7C4A0C" != frame.IcaoStr() {
-				t.Errorf("Invalid ICAO. 7C4A0C != %s", frame.IcaoStr())
+			if tt.subType != frame.MessageSubType() {
+				t.Errorf("Incorrect sub type. %d != %d", tt.subType, frame.MessageSubType())
+			}
+			if DF17FrameTargetStateStatus != frame.MessageTypeString() {
+				t.Errorf("Incorrect message type: %s", frame.MessageTypeString())
+			}
+			if tt.icao != frame.IcaoStr() {
+				t.Errorf("Invalid ICAO. %s != %s", tt.icao, frame.IcaoStr())
 			}
 
-			// todo: determine more tests
+			if (tt.mcpAlt >= 0) != frame.McpSelectedAltitudeValid() || (tt.mcpAlt >= 0 && tt.mcpAlt != frame.MustMcpSelectedAltitude()) {
+				t.Errorf("Incorrect MCP selected altitude. %d != %d", tt.mcpAlt, frame.mcpSelectedAltitude)
+			}
+			if (tt.fmsAlt >= 0) != frame.FmsSelectedAltitudeValid() || (tt.fmsAlt >= 0 && tt.fmsAlt != frame.MustFmsSelectedAltitude()) {
+				t.Errorf("Incorrect FMS selected altitude. %d != %d", tt.fmsAlt, frame.fmsSelectedAltitude)
+			}
+			if (0 != tt.baro) != frame.BaroSettingValid() || (0 != tt.baro && math.Abs(tt.baro-frame.MustBaroSetting()) > 0.01) {
+				t.Errorf("Incorrect baro setting. %0.2f != %0.2f", tt.baro, frame.baroSetting)
+			}
+			if (0 != tt.heading) != frame.SelectedHeadingValid() || (0 != tt.heading && tt.heading != frame.MustSelectedHeading()) {
+				t.Errorf("Incorrect selected heading. %0.2f != %0.2f", tt.heading, frame.selectedHeading)
+			}
+			if tt.modes != frame.AutopilotModesValid() || tt.modes != frame.McpModesValid() {
+				t.Fatalf("Incorrect mode validity. %t != %t", tt.modes, frame.AutopilotModesValid())
+			}
+			if !tt.modes {
+				return
+			}
+			if tt.ap != frame.MustAutopilotEngaged() || tt.lnav != frame.MustLnavMode() || tt.vnav != frame.MustVnavMode() ||
+				tt.altHold != frame.MustAltHoldMode() || tt.approach != frame.MustApproachMode() {
+				t.Errorf("Incorrect modes. AP %t, LNAV %t, VNAV %t, ALT %t, APP %t", frame.autopilotEngaged, frame.lnavMode, frame.vnavMode, frame.altHoldMode, frame.approachMode)
+			}
 		})
 	}
 }
@@ -371,3 +470,41 @@ func TestDecodeDF17MT31(t *testing.T) {
 		})
 	}
 }
+
+func TestDecodeDF17MT31Quality(t *testing.T) {
+	tests := []struct {
+		name                string
+		frame               string
+		version, nicA, nicC byte
+		nacP, sil, silSupp  byte
+		hasNacV             bool
+		nacV                byte
+	}{
+		{name: "Airborne v2", frame: "8D7C4A0CF8000000005ABAD432D5", version: 2, nicA: 1, nacP: 10, sil: 3, silSupp: 1},
+		{name: "Surface v2", frame: "8D7C4A0CF9105300004920A7CD97", version: 2, nicC: 1, nacP: 9, sil: 2, hasNacV: true, nacV: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frame, err := DecodeString(tt.frame, time.Now())
+			if frame == nil || nil != err {
+				t.Error(err, "failed to decode")
+				return
+			}
+			if !frame.AdsbVersionValid() || tt.version != frame.MustAdsbVersion() {
+				t.Errorf("Incorrect ADS-B version: %d", frame.adsbVersion)
+			}
+			if tt.nicA != frame.NicSupplementA() || tt.nicC != frame.NicSupplementC() {
+				t.Errorf("Incorrect NIC supplements. A %d, C %d", frame.NicSupplementA(), frame.NicSupplementC())
+			}
+			if !frame.NacPValid() || tt.nacP != frame.MustNacP() {
+				t.Errorf("Incorrect NACp: %d", frame.nacP)
+			}
+			if !frame.SilValid() || tt.sil != frame.MustSil() || tt.silSupp != frame.SilSupplement() {
+				t.Errorf("Incorrect SIL: %d (supplement %d)", frame.sil, frame.silSupplement)
+			}
+			if tt.hasNacV != frame.NacVValid() || (tt.hasNacV && tt.nacV != frame.MustNacV()) {
+				t.Errorf("Incorrect NACv: %d", frame.nacV)
+			}
+		})
+	}
+}
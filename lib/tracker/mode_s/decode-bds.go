@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"github.com/rs/zerolog/log"
+	"math"
 	"strings"
 )
 
@@ -30,6 +31,52 @@ const (
 
 type bds struct {
 	major, minor byte
+
+	// BDS 4,0 - Selected vertical intention
+	validMcpSelectedAltitude, validFmsSelectedAltitude bool
+	mcpSelectedAltitude, fmsSelectedAltitude           int32
+	validBaroSetting                                   bool
+	baroSetting                                        float64 // millibars
+	validMcpModes                                      bool
+	vnavMode, altHoldMode, approachMode                bool
+	validTargetAltSource                               bool
+	targetAltSource                                    byte
+
+	// BDS 5,0 - Track and turn report
+	validRollAngle, validTrueTrack, validTrackRate bool
+	rollAngle, trueTrack, trackRate                float64 // degrees, degrees, degrees/second
+	validGroundSpeed, validTrueAirSpeed            bool
+	groundSpeed, trueAirSpeed                      int // knots
+
+	// BDS 6,0 - Heading and speed report
+	validMagneticHeading, validMach                  bool
+	magneticHeading, mach                            float64
+	validIndicatedAirSpeed                           bool
+	indicatedAirSpeed                                int // knots
+	validBaroVerticalRate, validInertialVerticalRate bool
+	baroVerticalRate, inertialVerticalRate           int // feet/minute
+
+	// BDS 4,4 - Meteorological routine air report
+	metSource                 byte // figure of merit / source
+	validWind                 bool
+	windSpeed                 int     // knots
+	windDirection             float64 // degrees true
+	validStaticAirTemperature bool
+	staticAirTemperature      float64 // degrees celsius
+	validStaticPressure       bool
+	staticPressure            int // hPa
+	validHumidity             bool
+	humidity                  float64 // percent
+	validTurbulence           bool
+	turbulence                byte // hazard level, 4,4 and 4,5 share this
+
+	// BDS 4,5 - Meteorological hazard report
+	validWindShear, validMicroburst bool
+	windShear, microburst           byte // hazard level
+	validIcing, validWakeVortex     bool
+	icing, wakeVortex               byte // hazard level
+	validRadioHeight                bool
+	radioHeight                     int // feet
 }
 
 var (
@@ -56,6 +103,39 @@ var (
 		"5.3": "Air-referenced State Vector",
 		"6.0": "heading and Speed Report", // EHS Service
 	}
+
+	targetAltSourceTable = []string{
+		0: "Unknown",
+		1: "Aircraft altitude",
+		2: "FCU/MCP selected altitude",
+		3: "FMS selected altitude",
+	}
+
+	hazardLevelTable = []string{
+		0: "NIL",
+		1: "Light",
+		2: "Moderate",
+		3: "Severe",
+	}
+
+	// metRegisters are the meteorological registers we know how to detect
+	metRegisters = []struct {
+		major, minor byte
+		detect       func([]byte) bool
+	}{
+		{major: 4, minor: 4, detect: isBds44},
+		{major: 4, minor: 5, detect: isBds45},
+	}
+
+	// ehsRegisters are the EHS registers we know how to detect, in the order we test them
+	ehsRegisters = []struct {
+		major, minor byte
+		detect       func([]byte) bool
+	}{
+		{major: 4, minor: 0, detect: isBds40},
+		{major: 5, minor: 0, detect: isBds50},
+		{major: 6, minor: 0, detect: isBds60},
+	}
 )
 
 func (b *bds) DescribeBds() string {
@@ -89,7 +169,18 @@ func (f *Frame) decodeCommB() error {
 		// decode GICB
 	case BdsElsAircraftIdent: // 2.0
 		f.decodeFlightNumber()
-
+	case BdsElsAcasRA: // 3.0
+		f.decodeAcasRA(f.message[4:11])
+	case BdsEhsSelVertIntent: // 4.0
+		f.decodeBds40(f.message[4:11])
+	case BdsEhsTrackTurnReport: // 5.0
+		f.decodeBds50(f.message[4:11])
+	case BdsEhsHeadingSpeed: // 6.0
+		f.decodeBds60(f.message[4:11])
+	case BdsMetRoutineAirReport: // 4.4
+		f.decodeBds44(f.message[4:11])
+	case BdsMetHazartReport: // 4.5
+		f.decodeBds45(f.message[4:11])
 	}
 
 	// things get a lot murkier from here on in!
@@ -152,11 +243,635 @@ func inferCommBMessageType(mb []byte) (byte, byte, error) {
 		return 3, 0, nil
 	}
 
-	// Now onto EHS Detection
-	// TODO: implement EHS
-
-	// and lastly onto Meteorological Detection
-	// TODO: Implement MRAR and MHR
+	// Now onto EHS and Meteorological Detection
+	// These registers do not carry their BDS code, so we check the status bits and that the values are sane.
+	// if more than one register matches we cannot tell which one it is, so we do not guess.
+	var matches int
+	var major, minor byte
+	for _, register := range ehsRegisters {
+		if register.detect(mb) {
+			major, minor = register.major, register.minor
+			matches++
+		}
+	}
+	for _, register := range metRegisters {
+		if register.detect(mb) {
+			major, minor = register.major, register.minor
+			matches++
+		}
+	}
+	if 1 == matches {
+		return major, minor, nil
+	}
 
 	return 0, 0, UnknownCommBMessage
 }
+
+// mbField returns bits first to last (inclusive) of an MB field as an integer.
+// bits are numbered 1-56, the same way the BDS registers are documented in ICAO Doc 9871
+func mbField(mb []byte, first, last uint) uint64 {
+	var v uint64
+	for _, b := range mb[:7] {
+		v = v<<8 | uint64(b)
+	}
+	return (v >> (56 - last)) & (1<<(last-first+1) - 1)
+}
+
+// mbBit tells us if a single bit in the MB field is set
+func mbBit(mb []byte, bit uint) bool {
+	return 1 == mbField(mb, bit, bit)
+}
+
+// mbSignedField decodes a two's complement field, where the sign bit is separate from the value bits
+func mbSignedField(mb []byte, sign, first, last uint) int64 {
+	v := int64(mbField(mb, first, last))
+	if mbBit(mb, sign) {
+		v -= 1 << (last - first + 1)
+	}
+	return v
+}
+
+// mbStatusOk makes sure that a field that is marked as invalid by its status bit does not contain any data
+func mbStatusOk(mb []byte, status, first, last uint) bool {
+	return mbBit(mb, status) || 0 == mbField(mb, first, last)
+}
+
+func mbAllZeros(mb []byte) bool {
+	return 0 == mbField(mb, 1, 56)
+}
+
+// isBds40 - Selected vertical intention
+// status bits = 1, 14, 27, 48, 54. bits 40-47 and 52-53 are reserved (zeros)
+func isBds40(mb []byte) bool {
+	if mbAllZeros(mb) {
+		return false
+	}
+	if !mbStatusOk(mb, 1, 2, 13) || !mbStatusOk(mb, 14, 15, 26) || !mbStatusOk(mb, 27, 28, 39) ||
+		!mbStatusOk(mb, 48, 49, 51) || !mbStatusOk(mb, 54, 55, 56) {
+		return false
+	}
+	if 0 != mbField(mb, 40, 47) || 0 != mbField(mb, 52, 53) {
+		return false
+	}
+	return true
+}
+
+// isBds50 - Track and turn report
+// status bits = 1, 12, 24, 35, 46
+func isBds50(mb []byte) bool {
+	if mbAllZeros(mb) {
+		return false
+	}
+	if !mbStatusOk(mb, 1, 3, 11) || !mbStatusOk(mb, 12, 13, 23) || !mbStatusOk(mb, 24, 25, 34) ||
+		!mbStatusOk(mb, 35, 36, 45) || !mbStatusOk(mb, 46, 47, 56) {
+		return false
+	}
+	var b bds
+	b.decodeBds50(mb)
+	if b.validRollAngle && math.Abs(b.rollAngle) > 50 {
+		return false
+	}
+	if b.validGroundSpeed && b.groundSpeed > 600 {
+		return false
+	}
+	if b.validTrueAirSpeed && b.trueAirSpeed > 500 {
+		return false
+	}
+	if b.validGroundSpeed && b.validTrueAirSpeed && math.Abs(float64(b.groundSpeed-b.trueAirSpeed)) > 200 {
+		return false
+	}
+	return true
+}
+
+// isBds60 - Heading and speed report
+// status bits = 1, 13, 24, 35, 46
+func isBds60(mb []byte) bool {
+	if mbAllZeros(mb) {
+		return false
+	}
+	if !mbStatusOk(mb, 1, 2, 12) || !mbStatusOk(mb, 13, 14, 23) || !mbStatusOk(mb, 24, 25, 34) ||
+		!mbStatusOk(mb, 35, 36, 45) || !mbStatusOk(mb, 46, 47, 56) {
+		return false
+	}
+	var b bds
+	b.decodeBds60(mb)
+	if b.validIndicatedAirSpeed && b.indicatedAirSpeed > 500 {
+		return false
+	}
+	if b.validMach && b.mach > 1 {
+		return false
+	}
+	if b.validBaroVerticalRate && (b.baroVerticalRate > 6000 || b.baroVerticalRate < -6000) {
+		return false
+	}
+	if b.validInertialVerticalRate && (b.inertialVerticalRate > 6000 || b.inertialVerticalRate < -6000) {
+		return false
+	}
+	return true
+}
+
+// isBds44 - Meteorological routine air report
+// status bits = 5, 35, 47, 50. bits 1-4 are the figure of merit
+func isBds44(mb []byte) bool {
+	if mbAllZeros(mb) {
+		return false
+	}
+	if mbField(mb, 1, 4) > 4 {
+		return false
+	}
+	if !mbStatusOk(mb, 5, 6, 23) || !mbStatusOk(mb, 35, 36, 46) || !mbStatusOk(mb, 47, 48, 49) ||
+		!mbStatusOk(mb, 50, 51, 56) {
+		return false
+	}
+	var b bds
+	b.decodeBds44(mb)
+	if b.validWind && b.windSpeed > 250 {
+		return false
+	}
+	if b.staticAirTemperature < -80 || b.staticAirTemperature > 60 {
+		return false
+	}
+	return true
+}
+
+// isBds45 - Meteorological hazard report
+// status bits = 1, 4, 7, 10, 13, 16, 27, 39. bits 52-56 are reserved (zeros)
+func isBds45(mb []byte) bool {
+	if mbAllZeros(mb) {
+		return false
+	}
+	if !mbStatusOk(mb, 1, 2, 3) || !mbStatusOk(mb, 4, 5, 6) || !mbStatusOk(mb, 7, 8, 9) ||
+		!mbStatusOk(mb, 10, 11, 12) || !mbStatusOk(mb, 13, 14, 15) || !mbStatusOk(mb, 16, 17, 26) ||
+		!mbStatusOk(mb, 27, 28, 38) || !mbStatusOk(mb, 39, 40, 51) {
+		return false
+	}
+	if 0 != mbField(mb, 52, 56) {
+		return false
+	}
+	var b bds
+	b.decodeBds45(mb)
+	if b.validStaticAirTemperature && (b.staticAirTemperature < -80 || b.staticAirTemperature > 60) {
+		return false
+	}
+	return true
+}
+
+// decodeBds40 decodes the Selected Vertical Intention register
+func (b *bds) decodeBds40(mb []byte) {
+	if b.validMcpSelectedAltitude = mbBit(mb, 1); b.validMcpSelectedAltitude {
+		b.mcpSelectedAltitude = int32(mbField(mb, 2, 13)) * 16
+	}
+	if b.validFmsSelectedAltitude = mbBit(mb, 14); b.validFmsSelectedAltitude {
+		b.fmsSelectedAltitude = int32(mbField(mb, 15, 26)) * 16
+	}
+	if b.validBaroSetting = mbBit(mb, 27); b.validBaroSetting {
+		b.baroSetting = float64(mbField(mb, 28, 39))*0.1 + 800
+	}
+	if b.validMcpModes = mbBit(mb, 48); b.validMcpModes {
+		b.vnavMode = mbBit(mb, 49)
+		b.altHoldMode = mbBit(mb, 50)
+		b.approachMode = mbBit(mb, 51)
+	}
+	if b.validTargetAltSource = mbBit(mb, 54); b.validTargetAltSource {
+		b.targetAltSource = byte(mbField(mb, 55, 56))
+	}
+}
+
+// decodeBds50 decodes the Track and Turn Report register
+func (b *bds) decodeBds50(mb []byte) {
+	if b.validRollAngle = mbBit(mb, 1); b.validRollAngle {
+		b.rollAngle = float64(mbSignedField(mb, 2, 3, 11)) * 45.0 / 256.0
+	}
+	if b.validTrueTrack = mbBit(mb, 12); b.validTrueTrack {
+		b.trueTrack = float64(mbSignedField(mb, 13, 14, 23)) * 90.0 / 512.0
+		if b.trueTrack < 0 {
+			b.trueTrack += 360
+		}
+	}
+	if b.validGroundSpeed = mbBit(mb, 24); b.validGroundSpeed {
+		b.groundSpeed = int(mbField(mb, 25, 34)) * 2
+	}
+	if b.validTrackRate = mbBit(mb, 35); b.validTrackRate {
+		b.trackRate = float64(mbSignedField(mb, 36, 37, 45)) * 8.0 / 256.0
+	}
+	if b.validTrueAirSpeed = mbBit(mb, 46); b.validTrueAirSpeed {
+		b.trueAirSpeed = int(mbField(mb, 47, 56)) * 2
+	}
+}
+
+// decodeBds60 decodes the Heading and Speed Report register
+func (b *bds) decodeBds60(mb []byte) {
+	if b.validMagneticHeading = mbBit(mb, 1); b.validMagneticHeading {
+		b.magneticHeading = float64(mbSignedField(mb, 2, 3, 12)) * 90.0 / 512.0
+		if b.magneticHeading < 0 {
+			b.magneticHeading += 360
+		}
+	}
+	if b.validIndicatedAirSpeed = mbBit(mb, 13); b.validIndicatedAirSpeed {
+		b.indicatedAirSpeed = int(mbField(mb, 14, 23))
+	}
+	if b.validMach = mbBit(mb, 24); b.validMach {
+		b.mach = float64(mbField(mb, 25, 34)) * 2.048 / 512.0
+	}
+	if b.validBaroVerticalRate = mbBit(mb, 35); b.validBaroVerticalRate {
+		b.baroVerticalRate = int(mbSignedField(mb, 36, 37, 45)) * 32
+	}
+	if b.validInertialVerticalRate = mbBit(mb, 46); b.validInertialVerticalRate {
+		b.inertialVerticalRate = int(mbSignedField(mb, 47, 48, 56)) * 32
+	}
+}
+
+// decodeBds44 decodes the Meteorological Routine Air Report register
+// The temperature does not have a status bit, it is always sent
+func (b *bds) decodeBds44(mb []byte) {
+	b.metSource = byte(mbField(mb, 1, 4))
+	if b.validWind = mbBit(mb, 5); b.validWind {
+		b.windSpeed = int(mbField(mb, 6, 14))
+		b.windDirection = float64(mbField(mb, 15, 23)) * 180.0 / 256.0
+	}
+	b.validStaticAirTemperature = true
+	b.staticAirTemperature = float64(mbSignedField(mb, 24, 25, 34)) * 0.25
+	if b.validStaticPressure = mbBit(mb, 35); b.validStaticPressure {
+		b.staticPressure = int(mbField(mb, 36, 46))
+	}
+	if b.validTurbulence = mbBit(mb, 47); b.validTurbulence {
+		b.turbulence = byte(mbField(mb, 48, 49))
+	}
+	if b.validHumidity = mbBit(mb, 50); b.validHumidity {
+		b.humidity = float64(mbField(mb, 51, 56)) * 100.0 / 64.0
+	}
+}
+
+// decodeBds45 decodes the Meteorological Hazard Report register
+func (b *bds) decodeBds45(mb []byte) {
+	if b.validTurbulence = mbBit(mb, 1); b.validTurbulence {
+		b.turbulence = byte(mbField(mb, 2, 3))
+	}
+	if b.validWindShear = mbBit(mb, 4); b.validWindShear {
+		b.windShear = byte(mbField(mb, 5, 6))
+	}
+	if b.validMicroburst = mbBit(mb, 7); b.validMicroburst {
+		b.microburst = byte(mbField(mb, 8, 9))
+	}
+	if b.validIcing = mbBit(mb, 10); b.validIcing {
+		b.icing = byte(mbField(mb, 11, 12))
+	}
+	if b.validWakeVortex = mbBit(mb, 13); b.validWakeVortex {
+		b.wakeVortex = byte(mbField(mb, 14, 15))
+	}
+	if b.validStaticAirTemperature = mbBit(mb, 16); b.validStaticAirTemperature {
+		b.staticAirTemperature = float64(mbSignedField(mb, 17, 18, 26)) * 0.25
+	}
+	if b.validStaticPressure = mbBit(mb, 27); b.validStaticPressure {
+		b.staticPressure = int(mbField(mb, 28, 38))
+	}
+	if b.validRadioHeight = mbBit(mb, 39); b.validRadioHeight {
+		b.radioHeight = int(mbField(mb, 40, 51)) * 16
+	}
+}
+
+// McpSelectedAltitudeValid tells us if this frame has an MCP/FCU selected altitude (from an EHS Comm-B reply or ADS-B Target State)
+func (f *Frame) McpSelectedAltitudeValid() bool {
+	return f.validMcpSelectedAltitude
+}
+
+// MustMcpSelectedAltitude is the MCP/FCU selected altitude in feet
+func (f *Frame) MustMcpSelectedAltitude() int32 {
+	if f.validMcpSelectedAltitude {
+		return f.mcpSelectedAltitude
+	}
+	panic("MCP/FCU selected altitude is not valid")
+}
+
+// FmsSelectedAltitudeValid tells us if this frame has an FMS selected altitude (from an EHS Comm-B reply or ADS-B Target State)
+func (f *Frame) FmsSelectedAltitudeValid() bool {
+	return f.validFmsSelectedAltitude
+}
+
+// MustFmsSelectedAltitude is the FMS selected altitude in feet
+func (f *Frame) MustFmsSelectedAltitude() int32 {
+	if f.validFmsSelectedAltitude {
+		return f.fmsSelectedAltitude
+	}
+	panic("FMS selected altitude is not valid")
+}
+
+// BaroSettingValid tells us if this frame has a baro setting (from an EHS Comm-B reply or ADS-B Target State)
+func (f *Frame) BaroSettingValid() bool {
+	return f.validBaroSetting
+}
+
+// MustBaroSetting is the barometric pressure setting in millibars
+func (f *Frame) MustBaroSetting() float64 {
+	if f.validBaroSetting {
+		return f.baroSetting
+	}
+	panic("baro setting is not valid")
+}
+
+// McpModesValid tells us if this frame has the VNAV, altitude hold and approach mode flags
+// (from an EHS Comm-B reply or ADS-B Target State)
+func (f *Frame) McpModesValid() bool {
+	return f.validMcpModes
+}
+
+// MustVnavMode tells us if VNAV mode is engaged
+func (f *Frame) MustVnavMode() bool {
+	if f.validMcpModes {
+		return f.vnavMode
+	}
+	panic("MCP/FCU modes are not valid")
+}
+
+// MustAltHoldMode tells us if altitude hold mode is engaged
+func (f *Frame) MustAltHoldMode() bool {
+	if f.validMcpModes {
+		return f.altHoldMode
+	}
+	panic("MCP/FCU modes are not valid")
+}
+
+// MustApproachMode tells us if approach mode is engaged
+func (f *Frame) MustApproachMode() bool {
+	if f.validMcpModes {
+		return f.approachMode
+	}
+	panic("MCP/FCU modes are not valid")
+}
+
+// RollAngleValid tells us if this frame has a roll angle (from an EHS Comm-B reply)
+func (f *Frame) RollAngleValid() bool {
+	return f.validRollAngle
+}
+
+// MustRollAngle is the roll angle in degrees, negative is left wing down
+func (f *Frame) MustRollAngle() float64 {
+	if f.validRollAngle {
+		return f.rollAngle
+	}
+	panic("roll angle is not valid")
+}
+
+// TrueTrackValid tells us if this frame has a true track (from an EHS Comm-B reply)
+func (f *Frame) TrueTrackValid() bool {
+	return f.validTrueTrack
+}
+
+// MustTrueTrack is the true track angle in degrees
+func (f *Frame) MustTrueTrack() float64 {
+	if f.validTrueTrack {
+		return f.trueTrack
+	}
+	panic("true track is not valid")
+}
+
+// TrackRateValid tells us if this frame has a track rate (from an EHS Comm-B reply)
+func (f *Frame) TrackRateValid() bool {
+	return f.validTrackRate
+}
+
+// MustTrackRate is the rate of change of the track angle in degrees/second
+func (f *Frame) MustTrackRate() float64 {
+	if f.validTrackRate {
+		return f.trackRate
+	}
+	panic("track rate is not valid")
+}
+
+// GroundSpeedValid tells us if this frame has a ground speed (from an EHS Comm-B reply)
+func (f *Frame) GroundSpeedValid() bool {
+	return f.validGroundSpeed
+}
+
+// MustGroundSpeed is the ground speed in knots
+func (f *Frame) MustGroundSpeed() int {
+	if f.validGroundSpeed {
+		return f.groundSpeed
+	}
+	panic("ground speed is not valid")
+}
+
+// TrueAirSpeedValid tells us if this frame has a true air speed (from an EHS Comm-B reply)
+func (f *Frame) TrueAirSpeedValid() bool {
+	return f.validTrueAirSpeed
+}
+
+// MustTrueAirSpeed is the true air speed in knots
+func (f *Frame) MustTrueAirSpeed() int {
+	if f.validTrueAirSpeed {
+		return f.trueAirSpeed
+	}
+	panic("true air speed is not valid")
+}
+
+// MagneticHeadingValid tells us if this frame has a magnetic heading (from an EHS Comm-B reply)
+func (f *Frame) MagneticHeadingValid() bool {
+	return f.validMagneticHeading
+}
+
+// MustMagneticHeading is the magnetic heading in degrees
+func (f *Frame) MustMagneticHeading() float64 {
+	if f.validMagneticHeading {
+		return f.magneticHeading
+	}
+	panic("magnetic heading is not valid")
+}
+
+// IndicatedAirSpeedValid tells us if this frame has a indicated air speed (from an EHS Comm-B reply)
+func (f *Frame) IndicatedAirSpeedValid() bool {
+	return f.validIndicatedAirSpeed
+}
+
+// MustIndicatedAirSpeed is the indicated air speed in knots
+func (f *Frame) MustIndicatedAirSpeed() int {
+	if f.validIndicatedAirSpeed {
+		return f.indicatedAirSpeed
+	}
+	panic("indicated air speed is not valid")
+}
+
+// MachValid tells us if this frame has a mach (from an EHS Comm-B reply)
+func (f *Frame) MachValid() bool {
+	return f.validMach
+}
+
+// MustMach is the mach number
+func (f *Frame) MustMach() float64 {
+	if f.validMach {
+		return f.mach
+	}
+	panic("mach is not valid")
+}
+
+// BaroVerticalRateValid tells us if this frame has a baro vertical rate (from an EHS Comm-B reply)
+func (f *Frame) BaroVerticalRateValid() bool {
+	return f.validBaroVerticalRate
+}
+
+// MustBaroVerticalRate is the barometric altitude rate in feet/minute
+func (f *Frame) MustBaroVerticalRate() int {
+	if f.validBaroVerticalRate {
+		return f.baroVerticalRate
+	}
+	panic("baro vertical rate is not valid")
+}
+
+// InertialVerticalRateValid tells us if this frame has a inertial vertical rate (from an EHS Comm-B reply)
+func (f *Frame) InertialVerticalRateValid() bool {
+	return f.validInertialVerticalRate
+}
+
+// MustInertialVerticalRate is the inertial vertical velocity in feet/minute
+func (f *Frame) MustInertialVerticalRate() int {
+	if f.validInertialVerticalRate {
+		return f.inertialVerticalRate
+	}
+	panic("inertial vertical rate is not valid")
+}
+
+// HazardLevelString turns a meteorological hazard level (turbulence, wind shear, microburst, icing, wake vortex)
+// into something readable
+func HazardLevelString(level byte) string {
+	if int(level) < len(hazardLevelTable) {
+		return hazardLevelTable[level]
+	}
+	return "Unknown"
+}
+
+// WindValid tells us if this frame has a wind speed and direction (from a meteorological Comm-B reply)
+func (f *Frame) WindValid() bool {
+	return f.validWind
+}
+
+// MustWindSpeed is the wind speed in knots
+func (f *Frame) MustWindSpeed() int {
+	if f.validWind {
+		return f.windSpeed
+	}
+	panic("wind is not valid")
+}
+
+// MustWindDirection is the direction the wind is coming from in degrees (true)
+func (f *Frame) MustWindDirection() float64 {
+	if f.validWind {
+		return f.windDirection
+	}
+	panic("wind is not valid")
+}
+
+// StaticAirTemperatureValid tells us if this frame has a static air temperature (from a meteorological Comm-B reply)
+func (f *Frame) StaticAirTemperatureValid() bool {
+	return f.validStaticAirTemperature
+}
+
+// MustStaticAirTemperature is the static air temperature in degrees celsius
+func (f *Frame) MustStaticAirTemperature() float64 {
+	if f.validStaticAirTemperature {
+		return f.staticAirTemperature
+	}
+	panic("static air temperature is not valid")
+}
+
+// StaticPressureValid tells us if this frame has a static pressure (from a meteorological Comm-B reply)
+func (f *Frame) StaticPressureValid() bool {
+	return f.validStaticPressure
+}
+
+// MustStaticPressure is the static (or average static) pressure in hPa
+func (f *Frame) MustStaticPressure() int {
+	if f.validStaticPressure {
+		return f.staticPressure
+	}
+	panic("static pressure is not valid")
+}
+
+// HumidityValid tells us if this frame has a humidity (from a meteorological Comm-B reply)
+func (f *Frame) HumidityValid() bool {
+	return f.validHumidity
+}
+
+// MustHumidity is the relative humidity in percent
+func (f *Frame) MustHumidity() float64 {
+	if f.validHumidity {
+		return f.humidity
+	}
+	panic("humidity is not valid")
+}
+
+// TurbulenceValid tells us if this frame has a turbulence level (from a meteorological Comm-B reply)
+func (f *Frame) TurbulenceValid() bool {
+	return f.validTurbulence
+}
+
+// MustTurbulence is the turbulence hazard level, see HazardLevelString
+func (f *Frame) MustTurbulence() byte {
+	if f.validTurbulence {
+		return f.turbulence
+	}
+	panic("turbulence is not valid")
+}
+
+// WindShearValid tells us if this frame has a wind shear level (from a meteorological hazard report)
+func (f *Frame) WindShearValid() bool {
+	return f.validWindShear
+}
+
+// MustWindShear is the wind shear hazard level, see HazardLevelString
+func (f *Frame) MustWindShear() byte {
+	if f.validWindShear {
+		return f.windShear
+	}
+	panic("wind shear is not valid")
+}
+
+// MicroburstValid tells us if this frame has a microburst level (from a meteorological hazard report)
+func (f *Frame) MicroburstValid() bool {
+	return f.validMicroburst
+}
+
+// MustMicroburst is the microburst hazard level, see HazardLevelString
+func (f *Frame) MustMicroburst() byte {
+	if f.validMicroburst {
+		return f.microburst
+	}
+	panic("microburst is not valid")
+}
+
+// IcingValid tells us if this frame has an icing level (from a meteorological hazard report)
+func (f *Frame) IcingValid() bool {
+	return f.validIcing
+}
+
+// MustIcing is the icing hazard level, see HazardLevelString
+func (f *Frame) MustIcing() byte {
+	if f.validIcing {
+		return f.icing
+	}
+	panic("icing is not valid")
+}
+
+// WakeVortexValid tells us if this frame has a wake vortex level (from a meteorological hazard report)
+func (f *Frame) WakeVortexValid() bool {
+	return f.validWakeVortex
+}
+
+// MustWakeVortex is the wake vortex hazard level, see HazardLevelString
+func (f *Frame) MustWakeVortex() byte {
+	if f.validWakeVortex {
+		return f.wakeVortex
+	}
+	panic("wake vortex is not valid")
+}
+
+// RadioHeightValid tells us if this frame has a radio height (from a meteorological hazard report)
+func (f *Frame) RadioHeightValid() bool {
+	return f.validRadioHeight
+}
+
+// MustRadioHeight is the radio height in feet
+func (f *Frame) MustRadioHeight() int {
+	if f.validRadioHeight {
+		return f.radioHeight
+	}
+	panic("radio height is not valid")
+}
@@ -1,6 +1,10 @@
 package mode_s
 
-import "testing"
+import (
+	"math"
+	"testing"
+	"time"
+)
 
 func Test_inferCommBMessageType(t *testing.T) {
 	type args struct {
@@ -56,6 +60,41 @@ func Test_inferCommBMessageType(t *testing.T) {
 			want1:   0,
 			wantErr: false,
 		},
+		{
+			name:    "Infer BDS 4.0",
+			args:    args{mb: []byte{0x85, 0xE4, 0x2F, 0x31, 0x30, 0x00, 0x00}},
+			want:    4,
+			want1:   0,
+			wantErr: false,
+		},
+		{
+			name:    "Infer BDS 4.4",
+			args:    args{mb: []byte{0x18, 0x5B, 0xD5, 0xCF, 0x40, 0x00, 0x00}},
+			want:    4,
+			want1:   4,
+			wantErr: false,
+		},
+		{
+			name:    "Infer BDS 4.5",
+			args:    args{mb: []byte{0xA0, 0x61, 0xEC, 0x00, 0x00, 0x00, 0x00}},
+			want:    4,
+			want1:   5,
+			wantErr: false,
+		},
+		{
+			name:    "Infer BDS 5.0",
+			args:    args{mb: []byte{0x81, 0x95, 0x15, 0x36, 0xE0, 0x24, 0xD4}},
+			want:    5,
+			want1:   0,
+			wantErr: false,
+		},
+		{
+			name:    "Infer BDS 6.0",
+			args:    args{mb: []byte{0x8F, 0x39, 0xF9, 0x1A, 0x7E, 0x27, 0xC4}},
+			want:    6,
+			want1:   0,
+			wantErr: false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -73,3 +112,108 @@ func Test_inferCommBMessageType(t *testing.T) {
 		})
 	}
 }
+
+func TestFrame_decodeEhs(t *testing.T) {
+	closeTo := func(a, b float64) bool {
+		return math.Abs(a-b) < 0.01
+	}
+	t.Run("BDS 4.0", func(t *testing.T) {
+		f, err := DecodeString("A000029C85E42F313000007047D3", time.Now())
+		if nil != err {
+			t.Fatal(err)
+		}
+		if !f.McpSelectedAltitudeValid() || 3008 != f.MustMcpSelectedAltitude() {
+			t.Errorf("Incorrect MCP selected altitude. valid %t, %d", f.McpSelectedAltitudeValid(), f.mcpSelectedAltitude)
+		}
+		if !f.FmsSelectedAltitudeValid() || 3008 != f.MustFmsSelectedAltitude() {
+			t.Errorf("Incorrect FMS selected altitude. valid %t, %d", f.FmsSelectedAltitudeValid(), f.fmsSelectedAltitude)
+		}
+		if !f.BaroSettingValid() || !closeTo(1020.0, f.MustBaroSetting()) {
+			t.Errorf("Incorrect baro setting. valid %t, %0.2f", f.BaroSettingValid(), f.baroSetting)
+		}
+	})
+	t.Run("BDS 5.0", func(t *testing.T) {
+		f, err := DecodeString("A000139381951536E024D4CCF6B5", time.Now())
+		if nil != err {
+			t.Fatal(err)
+		}
+		if !f.RollAngleValid() || !closeTo(2.109, f.MustRollAngle()) {
+			t.Errorf("Incorrect roll angle. valid %t, %0.3f", f.RollAngleValid(), f.rollAngle)
+		}
+		if !f.TrueTrackValid() || !closeTo(114.258, f.MustTrueTrack()) {
+			t.Errorf("Incorrect true track. valid %t, %0.3f", f.TrueTrackValid(), f.trueTrack)
+		}
+		if !f.GroundSpeedValid() || 438 != f.MustGroundSpeed() {
+			t.Errorf("Incorrect ground speed. valid %t, %d", f.GroundSpeedValid(), f.groundSpeed)
+		}
+		if !f.TrackRateValid() || !closeTo(0.125, f.MustTrackRate()) {
+			t.Errorf("Incorrect track rate. valid %t, %0.3f", f.TrackRateValid(), f.trackRate)
+		}
+		if !f.TrueAirSpeedValid() || 424 != f.MustTrueAirSpeed() {
+			t.Errorf("Incorrect true airspeed. valid %t, %d", f.TrueAirSpeedValid(), f.trueAirSpeed)
+		}
+	})
+	t.Run("BDS 6.0", func(t *testing.T) {
+		f, err := DecodeString("A00004128F39F91A7E27C46ADC21", time.Now())
+		if nil != err {
+			t.Fatal(err)
+		}
+		if !f.MagneticHeadingValid() || !closeTo(42.715, f.MustMagneticHeading()) {
+			t.Errorf("Incorrect magnetic heading. valid %t, %0.3f", f.MagneticHeadingValid(), f.magneticHeading)
+		}
+		if !f.IndicatedAirSpeedValid() || 252 != f.MustIndicatedAirSpeed() {
+			t.Errorf("Incorrect indicated airspeed. valid %t, %d", f.IndicatedAirSpeedValid(), f.indicatedAirSpeed)
+		}
+		if !f.MachValid() || !closeTo(0.42, f.MustMach()) {
+			t.Errorf("Incorrect mach. valid %t, %0.3f", f.MachValid(), f.mach)
+		}
+		if !f.BaroVerticalRateValid() || -1920 != f.MustBaroVerticalRate() {
+			t.Errorf("Incorrect baro vertical rate. valid %t, %d", f.BaroVerticalRateValid(), f.baroVerticalRate)
+		}
+		if !f.InertialVerticalRateValid() || -1920 != f.MustInertialVerticalRate() {
+			t.Errorf("Incorrect inertial vertical rate. valid %t, %d", f.InertialVerticalRateValid(), f.inertialVerticalRate)
+		}
+	})
+}
+
+func TestFrame_decodeMeteorological(t *testing.T) {
+	t.Run("BDS 4.4", func(t *testing.T) {
+		f, err := DecodeString("A0001692185BD5CF400000DFC696", time.Now())
+		if nil != err {
+			t.Fatal(err)
+		}
+		if BdsMetRoutineAirReport != f.BdsMessageType() {
+			t.Fatalf("Expected a routine air report, got %s", f.DescribeBds())
+		}
+		if !f.WindValid() || 22 != f.MustWindSpeed() || 344.53125 != f.MustWindDirection() {
+			t.Errorf("Incorrect wind. valid %t, %d knots from %0.3f", f.WindValid(), f.windSpeed, f.windDirection)
+		}
+		if !f.StaticAirTemperatureValid() || -48.75 != f.MustStaticAirTemperature() {
+			t.Errorf("Incorrect temperature. valid %t, %0.3f", f.StaticAirTemperatureValid(), f.staticAirTemperature)
+		}
+		if f.StaticPressureValid() || f.HumidityValid() || f.TurbulenceValid() {
+			t.Error("Did not expect pressure, humidity or turbulence")
+		}
+	})
+	t.Run("BDS 4.5", func(t *testing.T) {
+		f, err := DecodeString("A0001692A061EC00000000000000", time.Now())
+		if nil != err {
+			t.Fatal(err)
+		}
+		if BdsMetHazartReport != f.BdsMessageType() {
+			t.Fatalf("Expected a hazard report, got %s", f.DescribeBds())
+		}
+		if !f.TurbulenceValid() || "Light" != HazardLevelString(f.MustTurbulence()) {
+			t.Errorf("Incorrect turbulence. valid %t, %d", f.TurbulenceValid(), f.turbulence)
+		}
+		if !f.IcingValid() || "Moderate" != HazardLevelString(f.MustIcing()) {
+			t.Errorf("Incorrect icing. valid %t, %d", f.IcingValid(), f.icing)
+		}
+		if f.WindShearValid() || f.MicroburstValid() || f.WakeVortexValid() || f.RadioHeightValid() {
+			t.Error("Did not expect wind shear, microburst, wake vortex or radio height")
+		}
+		if !f.StaticAirTemperatureValid() || -20 != f.MustStaticAirTemperature() {
+			t.Errorf("Incorrect temperature. valid %t, %0.3f", f.StaticAirTemperatureValid(), f.staticAirTemperature)
+		}
+	})
+}
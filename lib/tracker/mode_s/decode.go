@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 	"unicode"
 )
@@ -14,6 +15,9 @@ const (
 	modesShortMsgBytes = 7
 	modesLongMsgBits   = modesLongMsgBytes * 8
 	modesShortMsgBits  = modesShortMsgBytes * 8
+
+	// BeastTickRate is the frequency of the 48 bit MLAT counter on a Beast (and a Radarcape not using GPS time)
+	BeastTickRate = 12_000_000
 )
 
 //type icaoList struct {
@@ -43,6 +47,85 @@ func DecodeString(rawFrame string, t time.Time) (*Frame, error) {
 	return frame, err
 }
 
+// DecodeBytes decodes a 7 or 14 byte Mode S message, the binary version of DecodeString
+func DecodeBytes(message []byte, t time.Time) (*Frame, error) {
+	frame := &Frame{}
+	ok, err := DecodeBytesInto(frame, message, t)
+	if !ok {
+		return nil, err
+	}
+	return frame, err
+}
+
+// DecodeBytesInto is DecodeBytes for a frame the caller already has (e.g. from AcquireFrame), so decoding does not
+// allocate. Anything left in frame from before is thrown away. Like Decode, false means there was nothing to decode
+func DecodeBytesInto(frame *Frame, message []byte, t time.Time) (bool, error) {
+	if !frame.ResetFromBytes(message, t) {
+		return false, fmt.Errorf("cannot decode a %d byte frame, needs to be 7 or 14 bytes", len(message))
+	}
+	return !frame.isNoOp(), frame.Parse()
+}
+
+// NewFrameFromBytes creates a frame from a 7 or 14 byte Mode S message (e.g. the body of a BEAST frame)
+// without going through a hex string. The message is copied, so the caller can reuse its buffer
+func NewFrameFromBytes(message []byte, t time.Time) *Frame {
+	f := &Frame{}
+	if !f.ResetFromBytes(message, t) {
+		return nil
+	}
+	return f
+}
+
+// ResetFromBytes turns f into a new frame for a 7 or 14 byte Mode S message, the same as NewFrameFromBytes but
+// without allocating. Everything f knew before is forgotten. false (and f left alone) if the message is the wrong size
+func (f *Frame) ResetFromBytes(message []byte, t time.Time) bool {
+	if modesShortMsgBytes != len(message) && modesLongMsgBytes != len(message) {
+		return false
+	}
+	*f = Frame{
+		mode:      "NORMAL",
+		binary:    true,
+		timeStamp: t,
+	}
+	f.message = f.messageBuf[:copy(f.messageBuf[:], message)]
+	copy(f.receivedBuf[:], message)
+	return true
+}
+
+var framePool = sync.Pool{
+	New: func() interface{} {
+		return &Frame{}
+	},
+}
+
+// AcquireFrame gets an empty frame to decode into from our pool. Give it back with ReleaseFrame once you are done
+// with it (and anything you got from it)
+func AcquireFrame() *Frame {
+	return framePool.Get().(*Frame)
+}
+
+// ReleaseFrame gives a frame from AcquireFrame back to the pool, do not use it afterwards
+func ReleaseFrame(f *Frame) {
+	if nil != f {
+		framePool.Put(f)
+	}
+}
+
+// SetBeastTicks records the MLAT timestamp of a frame that came from a BEAST receiver
+func (f *Frame) SetBeastTicks(ticks uint64) {
+	f.mode = "MLAT"
+	f.beastTicks = ticks
+	f.beastTicksNs = uint64(BeastTicksToDuration(ticks))
+}
+
+// BeastTicksToDuration turns a count of the BEAST 12MHz MLAT counter into a duration
+func BeastTicksToDuration(ticks uint64) time.Duration {
+	// split to avoid overflowing with large counter values
+	seconds := ticks / BeastTickRate
+	remainder := ticks % BeastTickRate
+	return time.Duration(seconds)*time.Second + time.Duration(remainder*uint64(time.Second)/BeastTickRate)
+}
+
 func NewFrame(rawFrame string, t time.Time) *Frame {
 	f := Frame{
 		full:      rawFrame,
@@ -51,15 +134,25 @@ func NewFrame(rawFrame string, t time.Time) *Frame {
 	if err := f.parseIntoRaw(); nil != err {
 		return nil
 	}
+	f.received = f.raw
 
 	return &f
 }
 
 func (f *Frame) Decode() (bool, error) {
+	return f.DecodeWithCorrection(CorrectionNone)
+}
+
+// DecodeWithCorrection decodes the frame, trying as hard as level says (see the Correction* constants) to fix
+// a bad checksum
+func (f *Frame) DecodeWithCorrection(level int) (bool, error) {
 	if nil == f {
 		return false, nil
 	}
-	if err := f.parseIntoRaw(); nil != err {
+	f.errorCorrection = level
+	if f.binary {
+		f.resetDecoded()
+	} else if err := f.parseIntoRaw(); nil != err {
 		return false, err
 	}
 	return !f.isNoOp(), f.Parse()
@@ -92,7 +185,7 @@ func (f *Frame) parseIntoRaw() error {
 	if "MLAT" == f.mode {
 		frameStart = 13
 		// try and use the provided timestamp
-		f.beastTimeStamp = encodedFrame[1:12]
+		f.beastTimeStamp = encodedFrame[1:13]
 		if err := f.parseBeastTimeStamp(); nil != err {
 			return err
 		}
@@ -100,10 +193,32 @@ func (f *Frame) parseIntoRaw() error {
 		frameStart = 1
 	}
 	f.raw = encodedFrame[frameStart:]
+	f.resetDecoded()
 
 	return nil
 }
 
+// resetDecoded forgets everything a previous Decode worked out, so decoding the frame again (or decoding a
+// frame whose message has been replaced) does not leave anything behind
+func (f *Frame) resetDecoded() {
+	f.rawFields = rawFields{}
+	f.bds = bds{}
+	f.df17 = df17{}
+	f.acas = acas{}
+	f.addressing = addressing{}
+	f.Position = Position{}
+	f.downLinkFormat = 0
+	f.icao = 0
+	f.crc, f.checkSum = 0, 0
+	f.correctedBits = 0
+	f.icaoFromParity = false
+	f.checksumPassed = false
+	f.identity = 0
+	f.special, f.emergency = "", ""
+	f.alert = false
+	f.err = nil
+}
+
 func (f *Frame) Parse() error {
 	var err error
 
@@ -111,9 +226,11 @@ func (f *Frame) Parse() error {
 		return nil
 	}
 
-	err = f.parseRawToMessage()
-	if nil != err {
-		return err
+	if !f.binary {
+		err = f.parseRawToMessage()
+		if nil != err {
+			return err
+		}
 	}
 
 	f.decodeDownLinkFormat()
@@ -154,16 +271,25 @@ func (f *Frame) Parse() error {
 		err = f.decode13bitAltitudeCode()
 		f.decodeReplyInformation()
 		f.decodeSensitivityLevel()
+		if vdsAcasRA == f.message[4] {
+			f.decodeAcasRA(f.message[4:11])
+		}
 	case 17: //DF_17
+		f.source = SourceAdsb
 		f.decodeICAO()
 		f.decodeCapability()
 		f.decodeAdsb()
 	case 18: //DF_18
-		f.decodeCapability() // control field
-		if 0 == f.ca {
+		f.decodeControlField()
+		if f.hasAddress() {
 			f.decodeICAO()
+		}
+		if f.hasAdsbPayload() {
 			f.decodeAdsb()
+		} else if cfTisbCoarse == f.cf {
+			f.decodeTisbCoarse()
 		}
+		f.decodeImf()
 	case 20: //DF_20
 		f.decodeFlightStatus()
 		_ = f.decode13bitAltitudeCode()
@@ -194,10 +320,10 @@ func (f *Frame) parseRadarcapeTimeStamp() {
 }
 
 func (f *Frame) parseBeastTimeStamp() error {
-	if "" == f.beastTimeStamp || "00000000000" == f.beastTimeStamp {
+	if "" == f.beastTimeStamp || "000000000000" == f.beastTimeStamp {
 		return nil
 	}
-	// MLAT timestamps from Beast AVR are dependent on when the device started ( 500ns intervals / 12mhz)
+	// MLAT timestamps from Beast AVR are dependent on when the device started (a 12MHz counter)
 	// calculated from power on.
 	// 48 bits = 2.81474976711e+14
 	// max: 2,000,000 seconds
@@ -208,7 +334,7 @@ func (f *Frame) parseBeastTimeStamp() error {
 	if err != nil {
 		return fmt.Errorf("failed to decode beast avr timestamp: %s", err)
 	}
-	f.beastTicksNs = f.beastTicks * 500
+	f.beastTicksNs = uint64(BeastTicksToDuration(f.beastTicks))
 	return nil
 }
 
@@ -240,23 +366,32 @@ func (f *Frame) parseRawToMessage() error {
 		return fmt.Errorf("frame is incorrect length. %d != 7 or 14", messageLen)
 	}
 
-	f.message = make([]byte, messageLen)
+	f.message = f.messageBuf[:messageLen]
 	// the rest of the frame is encoded in 2 char hex values
 
-	index := 0
-	for i := 0; i < len(f.raw); i += 2 {
-		pair := f.raw[i : i+2]
-		myInt, err := strconv.ParseUint(pair, 16, 8)
-
-		if err != nil {
-			return err
+	for i := 0; i < messageLen; i++ {
+		high, okHigh := fromHexChar(f.raw[i*2])
+		low, okLow := fromHexChar(f.raw[i*2+1])
+		if !okHigh || !okLow {
+			return fmt.Errorf("frame has invalid hex %q", f.raw[i*2:i*2+2])
 		}
-		f.message[index] = byte(myInt)
-		index++
+		f.message[i] = high<<4 | low
 	}
 	return nil
 }
 
+func fromHexChar(c byte) (byte, bool) {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0', true
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10, true
+	case 'A' <= c && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
+}
+
 func (f *Frame) decodeCapability() {
 	f.ca = f.message[0] & 7
 
@@ -416,7 +551,7 @@ func (f *Frame) decode13bitAltitudeCode() error {
 func (f *Frame) getMessageLengthBits() uint32 {
 	//if f.downLinkFormat & 0x10 != 0 {
 	if f.downLinkFormat&0x10 != 0 {
-		if len(f.raw) == 14 {
+		if len(f.message) == modesShortMsgBytes {
 			return modesShortMsgBits
 		}
 		return modesLongMsgBits
@@ -1,7 +1,10 @@
 package mode_s
 
 import (
-	//"fmt"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"reflect"
 	"testing"
 	"time"
 )
@@ -168,11 +171,105 @@ func TestGillhamDecode(t *testing.T) {
 }
 
 func BenchmarkDecodeDF17Msg11(b *testing.B) {
+	b.ReportAllocs()
 	for i := 0; i < b.N; i++ {
 		DecodeString("8D75804B580FF6B283EB7A157117", time.Now())
 	}
 }
 
+func BenchmarkDecodeBytesDF17Msg11(b *testing.B) {
+	msg, _ := hex.DecodeString("8D75804B580FF6B283EB7A157117")
+	now := time.Now()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		frame := AcquireFrame()
+		_, _ = DecodeBytesInto(frame, msg, now)
+		ReleaseFrame(frame)
+	}
+}
+
+func TestDecodeBytesInto(t *testing.T) {
+	airborne, _ := hex.DecodeString("8D75804B580FF6B283EB7A157117")
+	short, _ := hex.DecodeString("5D7C7DAACD3CE9")
+	frame := AcquireFrame()
+	defer ReleaseFrame(frame)
+
+	if ok, err := DecodeBytesInto(frame, airborne, time.Now()); !ok || nil != err {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+	if !frame.AltitudeValid() {
+		t.Fatal("Expected an altitude from an airborne position")
+	}
+
+	// nothing from the airborne position should be left over once we decode a different frame into it
+	if ok, err := DecodeBytesInto(frame, short, time.Now()); !ok || nil != err {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+	fresh, err := DecodeBytes(short, frame.TimeStamp())
+	if nil != err {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(fresh, frame) {
+		t.Errorf("Reused frame is different to a new one\n%+v\n%+v", frame, fresh)
+	}
+
+	if ok, err := DecodeBytesInto(frame, airborne[:3], time.Now()); ok || nil == err {
+		t.Error("Should not decode a 3 byte frame")
+	}
+}
+
+func TestDecodeBytes(t *testing.T) {
+	frames := []string{
+		"8D75804B580FF6B283EB7A157117", // DF17 airborne position
+		"8D40621D58C382D690C8AC2863A7", // DF17 airborne position
+		"8D7C4A0CF9105300004920A7CD97", // DF17 operational status
+		"9140621D58C382D690C8AC0D1E2A", // DF18 non ICAO
+		"28001808F0DEBF",               // DF5 address/parity
+		"A000139381951536E024D4CCF6B5", // DF20 address/parity
+		"5D7C7DAACD3CE9",               // DF11
+		"8D40621D58C382D690C8AC2863A6", // DF17 single bit error
+	}
+	for _, raw := range frames {
+		t.Run(raw, func(t *testing.T) {
+			msg, err := hex.DecodeString(raw)
+			if nil != err {
+				t.Fatal(err)
+			}
+			fromString := NewFrame(raw, time.Now())
+			_, errString := fromString.DecodeWithCorrection(CorrectionSingleBit)
+			fromBytes := NewFrameFromBytes(msg, time.Now())
+			_, errBytes := fromBytes.DecodeWithCorrection(CorrectionSingleBit)
+			if (nil == errString) != (nil == errBytes) {
+				t.Fatalf("Different errors: %v != %v", errString, errBytes)
+			}
+			if nil != errString {
+				t.Fatal(errString)
+			}
+			if fromString.Icao() != fromBytes.Icao() {
+				t.Errorf("Different ICAO: %06X != %06X", fromString.Icao(), fromBytes.Icao())
+			}
+			if fromString.DownLinkType() != fromBytes.DownLinkType() {
+				t.Errorf("Different DF: %d != %d", fromString.DownLinkType(), fromBytes.DownLinkType())
+			}
+			if fromString.MessageKind() != fromBytes.MessageKind() {
+				t.Errorf("Different message kind: %s != %s", fromString.MessageKind(), fromBytes.MessageKind())
+			}
+			if fromString.MessageTypeString() != fromBytes.MessageKind().String() && MessageKindTargetStateStatusUnknown != fromBytes.MessageKind() {
+				t.Errorf("Message kind does not match the message type string: %s != %s", fromString.MessageTypeString(), fromBytes.MessageKind())
+			}
+			if !bytes.Equal(fromString.Raw(), fromBytes.Raw()) {
+				t.Errorf("Different raw: %s != %s", fromString.Raw(), fromBytes.Raw())
+			}
+			if fromString.CorrectedBits() != fromBytes.CorrectedBits() {
+				t.Errorf("Different corrected bits: %d != %d", fromString.CorrectedBits(), fromBytes.CorrectedBits())
+			}
+		})
+	}
+	if nil != NewFrameFromBytes([]byte{0x8D, 0x75}, time.Now()) {
+		t.Error("Should not create a frame from 2 bytes")
+	}
+}
+
 type tIcaoMessage struct {
 	msg, expectedIcao, df string
 }
@@ -248,6 +345,186 @@ func TestCrcDecode(t *testing.T) {
 	}
 }
 
+func TestCrcErrorCorrection(t *testing.T) {
+	tests := []struct {
+		name      string
+		prefix    string
+		frame     string
+		level     int
+		flip      []int
+		corrected byte
+		wantErr   bool
+	}{
+		{name: "DF17 1 bit, no correction", frame: "8D76AA735893E7E3F1FC2A112A9D", level: CorrectionNone, flip: []int{40}, wantErr: true},
+		{name: "DF17 1 bit", frame: "8D76AA735893E7E3F1FC2A112A9D", level: CorrectionSingleBit, flip: []int{40}, corrected: 1},
+		{name: "DF17 1 bit in parity", frame: "8D76AA735893E7E3F1FC2A112A9D", level: CorrectionSingleBit, flip: []int{100}, corrected: 1},
+		{name: "DF17 2 bits, single bit correction", frame: "8D76AA735893E7E3F1FC2A112A9D", level: CorrectionSingleBit, flip: []int{40, 80}, wantErr: true},
+		{name: "DF17 2 bits", frame: "8D76AA735893E7E3F1FC2A112A9D", level: CorrectionDoubleBit, flip: []int{40, 80}, corrected: 2},
+		{name: "DF17 good frame", frame: "8D76AA735893E7E3F1FC2A112A9D", level: CorrectionDoubleBit},
+		{name: "DF17 MLAT 1 bit", prefix: "@016CE3671AA8", frame: "8D76AA735893E7E3F1FC2A112A9D", level: CorrectionSingleBit, flip: []int{40}, corrected: 1},
+		{name: "DF17 MLAT 1 bit, no correction", prefix: "@016CE3671AA8", frame: "8D76AA735893E7E3F1FC2A112A9D", level: CorrectionNone, flip: []int{40}, wantErr: true},
+		{name: "DF11 1 bit", frame: "5D7C7DAACD3CE9", level: CorrectionSingleBit, flip: []int{20}, corrected: 1},
+		{name: "DF11 2 bits", frame: "5D7C7DAACD3CE9", level: CorrectionDoubleBit, flip: []int{20, 30}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			good, _ := hex.DecodeString(tt.frame)
+			msg := make([]byte, len(good))
+			copy(msg, good)
+			for _, bit := range tt.flip {
+				flipBit(msg, bit)
+			}
+			frame := NewFrame(fmt.Sprintf("%s%X", tt.prefix, msg), time.Now())
+			_, err := frame.DecodeWithCorrection(tt.level)
+			if tt.wantErr {
+				if nil == err {
+					t.Error("Expected a checksum error")
+				}
+				if nil != frame && frame.ChecksumPassed() {
+					t.Error("A frame with a checksum error should not have passed")
+				}
+				return
+			}
+			if nil != err {
+				t.Fatal(err)
+			}
+			if !frame.ChecksumPassed() {
+				t.Error("Expected the checksum to have passed")
+			}
+			if tt.corrected != frame.CorrectedBits() {
+				t.Errorf("Expected %d corrected bits, got %d", tt.corrected, frame.CorrectedBits())
+			}
+			if !bytes.Equal(good, frame.message) {
+				t.Errorf("Frame was not corrected. %X != %X", good, frame.message)
+			}
+			if tt.frame != frame.raw {
+				t.Errorf("Raw frame was not updated. %s != %s", tt.frame, frame.raw)
+			}
+		})
+	}
+}
+
+func TestAddressParityIcao(t *testing.T) {
+	tests := []struct {
+		frame string
+		icao  string
+	}{
+		{frame: "A000139381951536E024D4CCF6B5", icao: "3C4DD2"},
+		{frame: "A000029CFFBAA11E2004727281F1", icao: "4243D0"},
+		{frame: "28001808F0DEBF", icao: "40621D"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.frame, func(t *testing.T) {
+			frame, err := DecodeString(tt.frame, time.Now())
+			if nil != err {
+				t.Fatal(err)
+			}
+			if !frame.IcaoFromParity() || frame.ChecksumPassed() {
+				t.Error("ICAO should have come from the AP field, without a checksum")
+			}
+			if tt.icao != frame.IcaoStr() {
+				t.Errorf("Incorrect ICAO. %s != %s", tt.icao, frame.IcaoStr())
+			}
+		})
+	}
+}
+
+func TestDecodeDF18ControlField(t *testing.T) {
+	tests := []struct {
+		name        string
+		frame       string
+		icao        uint32
+		addressType byte
+		source      byte
+		adsb        bool
+		kind        MessageKind
+	}{
+		{name: "CF0 ADS-B", frame: "8D40621D58C382D690C8AC2863A7", icao: 0x40621D, addressType: AddressTypeIcao, source: SourceAdsb, adsb: true},
+		{name: "CF1 ADS-B non-ICAO", frame: "9140621D58C382D690C8AC0D1E2A", icao: 0x40621D, addressType: AddressTypeNonIcao, source: SourceAdsb, adsb: true},
+		{name: "CF2 fine TIS-B", frame: "9240621D58C382D690C8ACE58DA2", icao: 0x40621D, addressType: AddressTypeIcao, source: SourceTisb, adsb: true},
+		{name: "CF2 fine TIS-B IMF", frame: "9240621D59C382D690C8AC39F755", icao: 0x40621D, addressType: AddressTypeNonIcao, source: SourceTisb, adsb: true},
+		{name: "CF3 coarse TIS-B IMF", frame: "9340621D80C382D690C8ACB93DF1", icao: 0x40621D, addressType: AddressTypeNonIcao, source: SourceTisb, adsb: false, kind: MessageKindTisbCoarsePos},
+		{name: "CF4 management", frame: "9440621D58C382D690C8ACCB5EBB", icao: 0, addressType: AddressTypeIcao, source: SourceTisb, adsb: false, kind: MessageKindTisbManagement},
+		{name: "CF5 TIS-B non-ICAO", frame: "9540621D58C382D690C8AC932FC3", icao: 0x40621D, addressType: AddressTypeNonIcao, source: SourceTisb, adsb: true},
+		{name: "CF6 ADS-R", frame: "9640621D58C382D690C8AC7BBC4B", icao: 0x40621D, addressType: AddressTypeIcao, source: SourceAdsr, adsb: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frame, err := DecodeString(tt.frame, time.Now())
+			if nil != err {
+				t.Fatal(err)
+			}
+			if tt.icao != frame.Icao() {
+				t.Errorf("Incorrect ICAO. %06X != %06X", tt.icao, frame.Icao())
+			}
+			if tt.addressType != frame.AddressType() {
+				t.Errorf("Incorrect address type. %s != %s", AddressTypeString(tt.addressType), frame.AddressTypeString())
+			}
+			if tt.source != frame.Source() {
+				t.Errorf("Incorrect source. %s != %s", SourceString(tt.source), frame.SourceString())
+			}
+			if tt.adsb != frame.AdsbValid() {
+				t.Errorf("Incorrect ADS-B payload. %t != %t", tt.adsb, frame.AdsbValid())
+			}
+			if tt.adsb && DF17FrameAirPositionBarometric != frame.MessageTypeString() {
+				t.Errorf("Expected an airborne position, got %s", frame.MessageTypeString())
+			}
+			if !tt.adsb && tt.kind != frame.MessageKind() {
+				t.Errorf("Incorrect message kind. %s != %s", tt.kind, frame.MessageKind())
+			}
+		})
+	}
+}
+
+func TestDecodeDF18TisbCoarse(t *testing.T) {
+	tests := []struct {
+		name        string
+		frame       string
+		addressType byte
+		altitude    int32
+		heading     float64 // -1 == not valid
+		velocity    float64 // -1 == not valid
+		odd         bool
+		lat, lon    int
+	}{
+		{name: "even", frame: "937C12340A2E942AACD177456E66", addressType: AddressTypeIcao, altitude: 3500, heading: 90, velocity: 320, lat: 0xACD << 5, lon: 0x177 << 5},
+		{name: "odd", frame: "937C12340A2E942BC38C51357A8E", addressType: AddressTypeIcao, altitude: 3500, heading: 90, velocity: 320, odd: true, lat: 0xC38 << 5, lon: 0xC51 << 5},
+		{name: "IMF, no track or speed", frame: "937C12348A2E8000ACD1773106C7", addressType: AddressTypeNonIcao, altitude: 3500, heading: -1, velocity: -1, lat: 0xACD << 5, lon: 0x177 << 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frame, err := DecodeString(tt.frame, time.Now())
+			if nil != err {
+				t.Fatal(err)
+			}
+			if MessageKindTisbCoarsePos != frame.MessageKind() || DF18FrameTisbCoarsePos != frame.MessageTypeString() {
+				t.Errorf("Expected a coarse TIS-B position, got %s", frame.MessageTypeString())
+			}
+			if 0x7C1234 != frame.Icao() || tt.addressType != frame.AddressType() || SourceTisb != frame.Source() {
+				t.Errorf("Incorrect addressing %06X %s %s", frame.Icao(), frame.AddressTypeString(), frame.SourceString())
+			}
+			if 5 != frame.TisbServiceVolume() {
+				t.Errorf("Incorrect service volume 5 != %d", frame.TisbServiceVolume())
+			}
+			if !frame.VerticalStatusValid() || frame.MustOnGround() {
+				t.Error("Expected an airborne target")
+			}
+			if !frame.AltitudeValid() || tt.altitude != frame.MustAltitude() {
+				t.Errorf("Incorrect altitude %d != %d", tt.altitude, frame.altitude)
+			}
+			if (tt.heading >= 0) != frame.HeadingValid() || (tt.heading >= 0 && tt.heading != frame.MustHeading()) {
+				t.Errorf("Incorrect heading %0.2f != %0.2f", tt.heading, frame.heading)
+			}
+			if (tt.velocity >= 0) != frame.VelocityValid() || (tt.velocity >= 0 && tt.velocity != frame.MustVelocity()) {
+				t.Errorf("Incorrect velocity %0.2f != %0.2f", tt.velocity, frame.velocity)
+			}
+			if tt.odd == frame.IsEven() || tt.lat != frame.Latitude() || tt.lon != frame.Longitude() {
+				t.Errorf("Incorrect CPR position odd %t, %d, %d", !frame.IsEven(), frame.Latitude(), frame.Longitude())
+			}
+		})
+	}
+}
+
 func TestBadFuzz(t *testing.T) {
 	messages := []string{
 		"@00000000000010",
@@ -266,3 +543,34 @@ func TestBadFuzz(t *testing.T) {
 		}
 	}
 }
+
+func TestFrame_DecodeAgainResets(t *testing.T) {
+	df17, _ := hex.DecodeString("8D40621D58C382D690C8AC2863A7")
+	df5, _ := hex.DecodeString("28001808F0DEBF")
+
+	f := NewFrameFromBytes(df17, time.Now())
+	if ok, err := f.Decode(); !ok || nil != err {
+		t.Fatalf("Failed to decode the DF17 frame: %v", err)
+	}
+	if !f.AltitudeValid() || 0 == f.Latitude() {
+		t.Fatal("Expected the DF17 frame to have an altitude and a position")
+	}
+
+	// reuse the frame for a different message, the way a decoder working off one buffer would
+	f.message = f.messageBuf[:copy(f.messageBuf[:], df5)]
+	if ok, err := f.Decode(); !ok || nil != err {
+		t.Fatalf("Failed to decode the DF5 frame: %v", err)
+	}
+	if 5 != f.DownLinkType() {
+		t.Errorf("Expected DF5, got DF%d", f.DownLinkType())
+	}
+	if f.AltitudeValid() {
+		t.Error("The DF17 altitude survived decoding a DF5 frame")
+	}
+	if 0 != f.Latitude() || 0 != f.Longitude() {
+		t.Errorf("The DF17 position survived decoding a DF5 frame: %d, %d", f.Latitude(), f.Longitude())
+	}
+	if 0 != f.messageType || f.ChecksumPassed() {
+		t.Errorf("DF17 state survived decoding a DF5 frame: type %d, checksum passed %t", f.messageType, f.ChecksumPassed())
+	}
+}
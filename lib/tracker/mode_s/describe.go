@@ -353,6 +353,86 @@ var bdsFeatures = map[string][]featureBreakdown{
 		{name: "TID", start: 62, end: 86, longName: "Threat identity data"},
 		{name: "??", start: 86, end: 88, longName: "Reserved"},
 	},
+	"4.0": {
+		{name: "S", start: 32, end: 33, longName: "MCP/FCU selected altitude status"},
+		{name: "MCP ALT", start: 33, end: 45, longName: "MCP/FCU selected altitude"},
+		{name: "S", start: 45, end: 46, longName: "FMS selected altitude status"},
+		{name: "FMS ALT", start: 46, end: 58, longName: "FMS selected altitude"},
+		{name: "S", start: 58, end: 59, longName: "Barometric pressure setting status"},
+		{name: "BARO", start: 59, end: 71, longName: "Barometric pressure setting (minus 800mb)"},
+		{name: "??", start: 71, end: 79, longName: "Reserved"},
+		{name: "S", start: 79, end: 80, longName: "MCP/FCU mode bits status"},
+		{name: "VNAV", start: 80, end: 81, longName: "VNAV mode"},
+		{name: "ALT", start: 81, end: 82, longName: "Altitude hold mode"},
+		{name: "APP", start: 82, end: 83, longName: "Approach mode"},
+		{name: "??", start: 83, end: 85, longName: "Reserved"},
+		{name: "S", start: 85, end: 86, longName: "Target altitude source status"},
+		{name: "SRC", start: 86, end: 88, longName: "Target altitude source"},
+	},
+	"4.4": {
+		{name: "FOM", start: 32, end: 36, longName: "Figure of merit / source"},
+		{name: "S", start: 36, end: 37, longName: "Wind speed and direction status"},
+		{name: "WS", start: 37, end: 46, longName: "Wind speed"},
+		{name: "WD", start: 46, end: 55, longName: "Wind direction"},
+		{name: "+", start: 55, end: 56, longName: "Static air temperature sign"},
+		{name: "SAT", start: 56, end: 66, longName: "Static air temperature"},
+		{name: "S", start: 66, end: 67, longName: "Average static pressure status"},
+		{name: "ASP", start: 67, end: 78, longName: "Average static pressure"},
+		{name: "S", start: 78, end: 79, longName: "Turbulence status"},
+		{name: "TURB", start: 79, end: 81, longName: "Turbulence"},
+		{name: "S", start: 81, end: 82, longName: "Humidity status"},
+		{name: "HUM", start: 82, end: 88, longName: "Humidity"},
+	},
+	"4.5": {
+		{name: "S", start: 32, end: 33, longName: "Turbulence status"},
+		{name: "TURB", start: 33, end: 35, longName: "Turbulence"},
+		{name: "S", start: 35, end: 36, longName: "Wind shear status"},
+		{name: "WS", start: 36, end: 38, longName: "Wind shear"},
+		{name: "S", start: 38, end: 39, longName: "Microburst status"},
+		{name: "MB", start: 39, end: 41, longName: "Microburst"},
+		{name: "S", start: 41, end: 42, longName: "Icing status"},
+		{name: "ICE", start: 42, end: 44, longName: "Icing"},
+		{name: "S", start: 44, end: 45, longName: "Wake vortex status"},
+		{name: "WV", start: 45, end: 47, longName: "Wake vortex"},
+		{name: "S", start: 47, end: 48, longName: "Static air temperature status"},
+		{name: "+", start: 48, end: 49, longName: "Static air temperature sign"},
+		{name: "SAT", start: 49, end: 58, longName: "Static air temperature"},
+		{name: "S", start: 58, end: 59, longName: "Average static pressure status"},
+		{name: "ASP", start: 59, end: 70, longName: "Average static pressure"},
+		{name: "S", start: 70, end: 71, longName: "Radio height status"},
+		{name: "RH", start: 71, end: 83, longName: "Radio height"},
+		{name: "??", start: 83, end: 88, longName: "Reserved"},
+	},
+	"5.0": {
+		{name: "S", start: 32, end: 33, longName: "Roll angle status"},
+		{name: "+", start: 33, end: 34, longName: "Roll angle sign (left wing down)"},
+		{name: "ROLL", start: 34, end: 43, longName: "Roll angle"},
+		{name: "S", start: 43, end: 44, longName: "True track angle status"},
+		{name: "+", start: 44, end: 45, longName: "True track angle sign (west)"},
+		{name: "TRACK", start: 45, end: 55, longName: "True track angle"},
+		{name: "S", start: 55, end: 56, longName: "Ground speed status"},
+		{name: "GS", start: 56, end: 66, longName: "Ground speed"},
+		{name: "S", start: 66, end: 67, longName: "Track angle rate status"},
+		{name: "+", start: 67, end: 68, longName: "Track angle rate sign"},
+		{name: "RATE", start: 68, end: 77, longName: "Track angle rate"},
+		{name: "S", start: 77, end: 78, longName: "True airspeed status"},
+		{name: "TAS", start: 78, end: 88, longName: "True airspeed"},
+	},
+	"6.0": {
+		{name: "S", start: 32, end: 33, longName: "Magnetic heading status"},
+		{name: "+", start: 33, end: 34, longName: "Magnetic heading sign (west)"},
+		{name: "HDG", start: 34, end: 44, longName: "Magnetic heading"},
+		{name: "S", start: 44, end: 45, longName: "Indicated airspeed status"},
+		{name: "IAS", start: 45, end: 55, longName: "Indicated airspeed"},
+		{name: "S", start: 55, end: 56, longName: "Mach number status"},
+		{name: "MACH", start: 56, end: 66, longName: "Mach number"},
+		{name: "S", start: 66, end: 67, longName: "Barometric altitude rate status"},
+		{name: "+", start: 67, end: 68, longName: "Barometric altitude rate sign (down)"},
+		{name: "BARO VR", start: 68, end: 77, longName: "Barometric altitude rate"},
+		{name: "S", start: 77, end: 78, longName: "Inertial vertical velocity status"},
+		{name: "+", start: 78, end: 79, longName: "Inertial vertical velocity sign (down)"},
+		{name: "INS VR", start: 79, end: 88, longName: "Inertial vertical velocity"},
+	},
 }
 
 var frameFeatures = map[byte][]featureBreakdown{
@@ -455,7 +535,7 @@ func (f *Frame) String() string {
 func (f *Frame) Describe(output io.Writer) {
 	fprintf(output, "MODE S Packet:\n")
 	fprintf(output, "Length              : %d bits\n", f.getMessageLengthBits())
-	fprintf(output, "Frame               : %s\n", f.raw)
+	fprintf(output, "Frame               : %s\n", f.rawString())
 	fprintf(output, "DF: Downlink Format : (%d) %s\n", f.downLinkFormat, f.DownLinkFormat())
 	if f.mode == "MLAT" {
 		fprintf(output, "MLAT: Beast Ticks  : %d (@12mhz clock)\n", f.beastTicks)
@@ -487,18 +567,20 @@ func (f *Frame) Describe(output io.Writer) {
 		f.showSensitivityLevel(output)
 		f.showReplyInformation(output)
 		f.showAltitude(output)
+		f.showResolutionAdvisory(output)
 	case 17:
 		f.showCapability(output)
 		f.showICAO(output)
 		f.showAdsb(output)
 	case 18: //DF_18
-		//f.showCapability() // control field
-		if 0 == f.ca {
-			f.showCapability(output)
+		f.showControlField(output)
+		if f.hasAddress() {
 			f.showICAO(output)
+		}
+		if f.hasAdsbPayload() {
 			f.showAdsb(output)
-		} else {
-			fprintln(output, "Unable to decode DF18 Capability:", f.ca)
+		} else if cfTisbCoarse == f.cf {
+			f.showTisbCoarse(output)
 		}
 	case 20: //DF_20
 		f.showFlightStatus(output)
@@ -516,6 +598,25 @@ func (f *Frame) Describe(output io.Writer) {
 
 }
 
+func (f *Frame) showControlField(output io.Writer) {
+	fprintf(output, "CF: Control Field   : (%d) %s\n", f.cf, f.ControlFieldString())
+	fprintf(output, "  Address Type      : %s\n", f.AddressTypeString())
+	fprintf(output, "  Source            : %s\n", f.SourceString())
+}
+
+func (f *Frame) showTisbCoarse(output io.Writer) {
+	fprintf(output, "  Service Volume ID : %d\n", f.tisbServiceVolume)
+	f.showSurveilanceStatus(output)
+	f.showAltitude(output)
+	f.showHeading(output)
+	if f.validVelocity {
+		fprintf(output, "  velocity          : %0.2f\n", f.velocity)
+	} else {
+		fprintln(output, "  velocity          : Invalid")
+	}
+	f.showCprLatLon(output)
+}
+
 func (f *Frame) showVerticalStatus(output io.Writer) {
 	if !f.VerticalStatusValid() {
 		return
@@ -552,6 +653,18 @@ func (f *Frame) showAltitude(output io.Writer) {
 	}
 }
 
+func (f *Frame) showResolutionAdvisory(output io.Writer) {
+	if !f.validRA {
+		return
+	}
+	fprintf(output, "RA: Advisory        : %s\n", strings.Join(f.ra.Advisories(), ", "))
+	fprintf(output, "RA: Terminated      : %t\n", f.ra.Terminated)
+	fprintf(output, "RA: Multiple Threat : %t\n", f.ra.MultipleThreat)
+	if threat := f.ra.ThreatString(); "" != threat {
+		fprintf(output, "RA: Threat          : %s\n", threat)
+	}
+}
+
 func (f *Frame) showWakeVortex(output io.Writer) {
 	var wakeType string
 	if 1 == f.messageType {
@@ -743,9 +856,11 @@ func (f *Frame) showAdsb(output io.Writer) {
 			f.showIdentity(output)
 			f.showAlert(output)
 		} else if 2 == f.messageSubType {
-			// TCAS RA
+			f.showResolutionAdvisory(output)
 		}
 	case 29:
+		f.showAdsbMsgSubType(output)
+		f.showTargetState(output)
 	case 31:
 		f.showAdsbMsgSubType(output)
 		f.showCapabilityClassInfo(output)
@@ -763,6 +878,30 @@ func (f *Frame) showAdsb(output io.Writer) {
 	fprintln(output, "")
 }
 
+func (f *Frame) showTargetState(output io.Writer) {
+	if f.validMcpSelectedAltitude {
+		fprintf(output, "  MCP/FCU Alt       : %d feet\n", f.mcpSelectedAltitude)
+	}
+	if f.validFmsSelectedAltitude {
+		fprintf(output, "  FMS Alt           : %d feet\n", f.fmsSelectedAltitude)
+	}
+	if f.validBaroSetting {
+		fprintf(output, "  Baro Setting      : %0.1f mb\n", f.baroSetting)
+	}
+	if f.validSelectedHeading {
+		fprintf(output, "  Selected Heading  : %0.2f\n", f.selectedHeading)
+	}
+	if f.validAutopilotModes {
+		fprintf(output, "  Autopilot         : %t\n", f.autopilotEngaged)
+		fprintf(output, "  LNAV              : %t\n", f.lnavMode)
+	}
+	if f.validMcpModes {
+		fprintf(output, "  VNAV              : %t\n", f.vnavMode)
+		fprintf(output, "  Alt Hold          : %t\n", f.altHoldMode)
+		fprintf(output, "  Approach          : %t\n", f.approachMode)
+	}
+}
+
 func (f *Frame) showAdsbMsgSubType(output io.Writer) {
 	fprintf(output, "SUB:      Sub Type  : %d \n", f.messageSubType)
 }
@@ -832,6 +971,89 @@ func (f *Frame) showAdsbVersion(output io.Writer) {
 func (f *Frame) showBdsData(output io.Writer) {
 	fprintln(output, "BDS Info")
 	fprintf(output, "  BDS Msg       : %s\n", f.DescribeBds())
+	switch f.BdsMessageType() {
+	case BdsEhsSelVertIntent:
+		if f.validMcpSelectedAltitude {
+			fprintf(output, "  MCP/FCU Alt   : %d feet\n", f.mcpSelectedAltitude)
+		}
+		if f.validFmsSelectedAltitude {
+			fprintf(output, "  FMS Alt       : %d feet\n", f.fmsSelectedAltitude)
+		}
+		if f.validBaroSetting {
+			fprintf(output, "  Baro Setting  : %0.1f mb\n", f.baroSetting)
+		}
+		if f.validMcpModes {
+			fprintf(output, "  Modes         : VNAV=%t ALT HOLD=%t APPROACH=%t\n", f.vnavMode, f.altHoldMode, f.approachMode)
+		}
+		if f.validTargetAltSource {
+			fprintf(output, "  Target Source : %s\n", targetAltSourceTable[f.targetAltSource])
+		}
+	case BdsElsAcasRA:
+		f.showResolutionAdvisory(output)
+	case BdsMetRoutineAirReport, BdsMetHazartReport:
+		if f.validWind {
+			fprintf(output, "  Wind          : %d knots from %0.1f\n", f.windSpeed, f.windDirection)
+		}
+		if f.validStaticAirTemperature {
+			fprintf(output, "  Temperature   : %0.2f C\n", f.staticAirTemperature)
+		}
+		if f.validStaticPressure {
+			fprintf(output, "  Pressure      : %d hPa\n", f.staticPressure)
+		}
+		if f.validHumidity {
+			fprintf(output, "  Humidity      : %0.1f%%\n", f.humidity)
+		}
+		if f.validTurbulence {
+			fprintf(output, "  Turbulence    : %s\n", HazardLevelString(f.turbulence))
+		}
+		if f.validWindShear {
+			fprintf(output, "  Wind Shear    : %s\n", HazardLevelString(f.windShear))
+		}
+		if f.validMicroburst {
+			fprintf(output, "  Microburst    : %s\n", HazardLevelString(f.microburst))
+		}
+		if f.validIcing {
+			fprintf(output, "  Icing         : %s\n", HazardLevelString(f.icing))
+		}
+		if f.validWakeVortex {
+			fprintf(output, "  Wake Vortex   : %s\n", HazardLevelString(f.wakeVortex))
+		}
+		if f.validRadioHeight {
+			fprintf(output, "  Radio Height  : %d feet\n", f.radioHeight)
+		}
+	case BdsEhsTrackTurnReport:
+		if f.validRollAngle {
+			fprintf(output, "  Roll Angle    : %0.2f\n", f.rollAngle)
+		}
+		if f.validTrueTrack {
+			fprintf(output, "  True Track    : %0.2f\n", f.trueTrack)
+		}
+		if f.validGroundSpeed {
+			fprintf(output, "  Ground Speed  : %d knots\n", f.groundSpeed)
+		}
+		if f.validTrackRate {
+			fprintf(output, "  Track Rate    : %0.3f deg/s\n", f.trackRate)
+		}
+		if f.validTrueAirSpeed {
+			fprintf(output, "  True Airspeed : %d knots\n", f.trueAirSpeed)
+		}
+	case BdsEhsHeadingSpeed:
+		if f.validMagneticHeading {
+			fprintf(output, "  Mag Heading   : %0.2f\n", f.magneticHeading)
+		}
+		if f.validIndicatedAirSpeed {
+			fprintf(output, "  Ind. Airspeed : %d knots\n", f.indicatedAirSpeed)
+		}
+		if f.validMach {
+			fprintf(output, "  Mach          : %0.3f\n", f.mach)
+		}
+		if f.validBaroVerticalRate {
+			fprintf(output, "  Baro VR       : %d ft/min\n", f.baroVerticalRate)
+		}
+		if f.validInertialVerticalRate {
+			fprintf(output, "  Inertial VR   : %d ft/min\n", f.inertialVerticalRate)
+		}
+	}
 }
 
 func (f *Frame) showBitString(output io.Writer) {
@@ -905,7 +1127,7 @@ func (f *Frame) formatBitString(features []featureBreakdown) string {
 
 		if fieldBitCounter != feat.start {
 			log.Warn().
-				Str("frame", f.raw).
+				Str("frame", f.rawString()).
 				Msgf("Describe: Top Level Fields Not Adding up. (%d %s %d). Expected Start=%d, got=%d", f.downLinkFormat, sk, f.messageSubType, feat.start, fieldBitCounter)
 		}
 		fieldBitCounter = feat.end
@@ -930,7 +1152,7 @@ func (f *Frame) formatBitString(features []featureBreakdown) string {
 			for _, sf := range feat.subFields[sk] {
 				if subFieldBitCounter != sf.start {
 					log.Warn().
-						Str("frame", f.raw).
+						Str("frame", f.rawString()).
 						Msgf("Describe: Second Level Fields Not Adding up. (%d %s %d). Expected Start=%d, got=%d", f.downLinkFormat, sk, f.messageSubType, sf.start, subFieldBitCounter)
 				}
 				subFieldBitCounter = sf.end
@@ -945,7 +1167,7 @@ func (f *Frame) formatBitString(features []featureBreakdown) string {
 					for _, ssf := range sf.subFields[ssk] {
 						if subSubFieldBitCounter != ssf.start {
 							log.Warn().
-								Str("frame", f.raw).
+								Str("frame", f.rawString()).
 								Msgf("Describe: Third Level Fields Not Adding up. (%d %s %d). Expected Start=%d, got=%d", f.downLinkFormat, sk, f.messageSubType, ssf.start, subSubFieldBitCounter)
 						}
 						doMakeBitString(ssf)
@@ -0,0 +1,654 @@
+package mode_s
+
+/*
+  This file builds Mode S frames from typed values, the opposite of decode.go.
+  Good for tests and for generating synthetic traffic.
+*/
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+const (
+	beastEscape          = 0x1A
+	beastTypeModeSShort  = 0x32
+	beastTypeModeSLong   = 0x33
+	extendedSquitterCa   = 5 // level 2+ transponder, airborne or on the ground
+	cprBits              = 1 << 17
+	ac13MaxAltitude      = 50175
+	ac13MinAltitude      = -1000
+	maxVelocityComponent = 1022
+)
+
+var (
+	ErrAltitudeRange = errors.New("altitude cannot be encoded in 25ft increments (-1000 to 50175 feet)")
+	ErrSquawk        = errors.New("squawk needs to be 4 octal digits (0000-7777)")
+)
+
+type (
+	// EncodedFrame is a Mode S message we have built, ready to be written out in whichever format is needed
+	EncodedFrame []byte
+
+	// Payload is anything that fills the 56 bit ME (ADS-B) or MB (Comm-B) field of a long frame
+	Payload interface {
+		Encode() ([7]byte, error)
+	}
+
+	// AdsbIdentification is an Aircraft Identification and Category message (TC 1-4)
+	AdsbIdentification struct {
+		// TypeCode picks the category set, 4 (set A) if not given
+		TypeCode byte
+		Category byte
+		Callsign string
+	}
+
+	// AdsbAirbornePosition is an Airborne Position message with barometric altitude (TC 9-18)
+	AdsbAirbornePosition struct {
+		// TypeCode tells the receiver the NIC, 11 if not given
+		TypeCode           byte
+		SurveillanceStatus byte
+		NicSupplementB     bool
+		Altitude           int32
+		Lat, Lon           float64
+		Odd                bool
+	}
+
+	// AdsbSurfacePosition is a Surface Position message (TC 5-8)
+	AdsbSurfacePosition struct {
+		// TypeCode tells the receiver the NIC, 5 if not given
+		TypeCode   byte
+		Speed      float64
+		Heading    float64
+		HasHeading bool
+		Lat, Lon   float64
+		Odd        bool
+	}
+
+	// AdsbAirborneVelocity is an Airborne Velocity message with ground speed (TC 19, sub type 1 or 2)
+	AdsbAirborneVelocity struct {
+		Heading      float64
+		GroundSpeed  float64
+		VerticalRate int
+		BaroRate     bool // the vertical rate is from the barometer, not GNSS
+		NacV         byte
+	}
+
+	// AdsbEmergency is an Emergency/Priority Status message (TC 28, sub type 1)
+	AdsbEmergency struct {
+		Emergency byte // see emergencyStateTable
+		Squawk    uint32
+	}
+
+	// BdsIdentification is Comm-B BDS 2,0 Aircraft Identification
+	BdsIdentification struct {
+		Callsign string
+	}
+
+	// BdsSelectedVerticalIntent is Comm-B BDS 4,0
+	BdsSelectedVerticalIntent struct {
+		McpSelectedAltitude    int32
+		HasMcpSelectedAltitude bool
+		FmsSelectedAltitude    int32
+		HasFmsSelectedAltitude bool
+		BaroSetting            float64
+		HasBaroSetting         bool
+	}
+
+	// BdsTrackAndTurn is Comm-B BDS 5,0
+	BdsTrackAndTurn struct {
+		RollAngle       float64
+		HasRollAngle    bool
+		TrueTrack       float64
+		HasTrueTrack    bool
+		GroundSpeed     int
+		HasGroundSpeed  bool
+		TrackRate       float64
+		HasTrackRate    bool
+		TrueAirSpeed    int
+		HasTrueAirSpeed bool
+	}
+
+	// BdsHeadingAndSpeed is Comm-B BDS 6,0
+	BdsHeadingAndSpeed struct {
+		MagneticHeading         float64
+		HasMagneticHeading      bool
+		IndicatedAirSpeed       int
+		HasIndicatedAirSpeed    bool
+		Mach                    float64
+		HasMach                 bool
+		BaroVerticalRate        int
+		HasBaroVerticalRate     bool
+		InertialVerticalRate    int
+		HasInertialVerticalRate bool
+	}
+)
+
+// EncodeAllCall builds a DF11 All-Call reply
+func EncodeAllCall(icao uint32, capability byte) EncodedFrame {
+	msg := make(EncodedFrame, modesShortMsgBytes)
+	msg[0] = 11<<3 | capability&7
+	putIcao(msg[1:4], icao)
+	msg.setParity(0)
+	return msg
+}
+
+// EncodeSurveillanceAltitude builds a DF4 Surveillance, Altitude reply
+func EncodeSurveillanceAltitude(icao uint32, flightStatus byte, altitude int32) (EncodedFrame, error) {
+	ac, err := encodeAC13(altitude)
+	if nil != err {
+		return nil, err
+	}
+	msg := make(EncodedFrame, modesShortMsgBytes)
+	msg[0] = 4<<3 | flightStatus&7
+	msg[2], msg[3] = byte(ac>>8), byte(ac)
+	msg.setParity(icao)
+	return msg, nil
+}
+
+// EncodeSurveillanceIdentity builds a DF5 Surveillance, Identity reply
+func EncodeSurveillanceIdentity(icao uint32, flightStatus byte, squawk uint32) (EncodedFrame, error) {
+	id, err := encodeID13(squawk)
+	if nil != err {
+		return nil, err
+	}
+	msg := make(EncodedFrame, modesShortMsgBytes)
+	msg[0] = 5<<3 | flightStatus&7
+	msg[2], msg[3] = byte(id>>8), byte(id)
+	msg.setParity(icao)
+	return msg, nil
+}
+
+// EncodeCommBAltitude builds a DF20 Comm-B, Altitude reply
+func EncodeCommBAltitude(icao uint32, flightStatus byte, altitude int32, mb Payload) (EncodedFrame, error) {
+	ac, err := encodeAC13(altitude)
+	if nil != err {
+		return nil, err
+	}
+	msg, err := encodeLong(20<<3|flightStatus&7, mb)
+	if nil != err {
+		return nil, err
+	}
+	msg[2], msg[3] = byte(ac>>8), byte(ac)
+	msg.setParity(icao)
+	return msg, nil
+}
+
+// EncodeCommBIdentity builds a DF21 Comm-B, Identity reply
+func EncodeCommBIdentity(icao uint32, flightStatus byte, squawk uint32, mb Payload) (EncodedFrame, error) {
+	id, err := encodeID13(squawk)
+	if nil != err {
+		return nil, err
+	}
+	msg, err := encodeLong(21<<3|flightStatus&7, mb)
+	if nil != err {
+		return nil, err
+	}
+	msg[2], msg[3] = byte(id>>8), byte(id)
+	msg.setParity(icao)
+	return msg, nil
+}
+
+// EncodeExtendedSquitter builds a DF17 ADS-B message
+func EncodeExtendedSquitter(icao uint32, me Payload) (EncodedFrame, error) {
+	msg, err := encodeLong(17<<3|extendedSquitterCa, me)
+	if nil != err {
+		return nil, err
+	}
+	putIcao(msg[1:4], icao)
+	msg.setParity(0)
+	return msg, nil
+}
+
+func encodeLong(first byte, payload Payload) (EncodedFrame, error) {
+	if nil == payload {
+		return nil, errors.New("a long frame needs a payload")
+	}
+	field, err := payload.Encode()
+	if nil != err {
+		return nil, err
+	}
+	msg := make(EncodedFrame, modesLongMsgBytes)
+	msg[0] = first
+	copy(msg[4:11], field[:])
+	return msg, nil
+}
+
+func putIcao(b []byte, icao uint32) {
+	b[0], b[1], b[2] = byte(icao>>16), byte(icao>>8), byte(icao)
+}
+
+// setParity fills in the last 24 bits. PI frames (DF11/17) use an address of 0, AP frames XOR the address in
+func (e EncodedFrame) setParity(address uint32) {
+	n := len(e)
+	e[n-3], e[n-2], e[n-1] = 0, 0, 0
+	putIcao(e[n-3:], modeSChecksum(e)^address)
+}
+
+// Bytes is the raw Mode S message
+func (e EncodedFrame) Bytes() []byte {
+	return e
+}
+
+// Avr is the frame in AVR format, e.g. *8D4840D6202CC371C32CE0576098;
+func (e EncodedFrame) Avr() string {
+	return fmt.Sprintf("*%X;", []byte(e))
+}
+
+// AvrMlat is the frame in AVR format with a 12MHz MLAT timestamp, e.g. @0000000000018D4840D6202CC371C32CE0576098;
+func (e EncodedFrame) AvrMlat(ticks uint64) string {
+	return fmt.Sprintf("@%012X%X;", ticks&0xFFFFFFFFFFFF, []byte(e))
+}
+
+// Beast is the frame in BEAST binary format, with any 0x1A bytes escaped, ready to write to a socket
+func (e EncodedFrame) Beast(ticks uint64, signal byte) []byte {
+	msgType := byte(beastTypeModeSLong)
+	if modesShortMsgBytes == len(e) {
+		msgType = beastTypeModeSShort
+	}
+	out := make([]byte, 0, 2*(len(e)+9))
+	out = append(out, beastEscape, msgType)
+	escape := func(b byte) {
+		out = append(out, b)
+		if beastEscape == b {
+			out = append(out, b)
+		}
+	}
+	for shift := 40; shift >= 0; shift -= 8 {
+		escape(byte(ticks >> uint(shift)))
+	}
+	escape(signal)
+	for _, b := range e {
+		escape(b)
+	}
+	return out
+}
+
+// encodeAC13 encodes an altitude (feet) into a 13 bit AC field using 25ft increments (Q=1, M=0)
+func encodeAC13(altitude int32) (uint16, error) {
+	if altitude < ac13MinAltitude || altitude > ac13MaxAltitude {
+		return 0, ErrAltitudeRange
+	}
+	n := uint16((altitude + 1000) / 25)
+	return (n>>5)<<7 | (n>>4&1)<<5 | 0x10 | n&0xF, nil
+}
+
+// encodeAC12 encodes an altitude (feet) into the 12 bit ADS-B altitude field using 25ft increments
+func encodeAC12(altitude int32) (uint16, error) {
+	if altitude < ac13MinAltitude || altitude > ac13MaxAltitude {
+		return 0, ErrAltitudeRange
+	}
+	n := uint16((altitude + 1000) / 25)
+	return (n>>4)<<5 | 0x10 | n&0xF, nil
+}
+
+// encodeID13 encodes a squawk into the 13 bit identity field, C1 A1 C2 A2 C4 A4 X B1 D1 B2 D2 B4 D4
+func encodeID13(squawk uint32) (uint16, error) {
+	if squawk > 7777 {
+		return 0, ErrSquawk
+	}
+	a, b, c, d := squawk/1000, squawk/100%10, squawk/10%10, squawk%10
+	if a > 7 || b > 7 || c > 7 || d > 7 {
+		return 0, ErrSquawk
+	}
+	bit := func(digit, mask uint32, pos uint) uint16 {
+		if digit&mask != 0 {
+			return 1 << pos
+		}
+		return 0
+	}
+	return bit(c, 1, 12) | bit(a, 1, 11) | bit(c, 2, 10) | bit(a, 2, 9) | bit(c, 4, 8) | bit(a, 4, 7) |
+		bit(b, 1, 5) | bit(d, 1, 4) | bit(b, 2, 3) | bit(d, 2, 2) | bit(b, 4, 1) | bit(d, 4, 0), nil
+}
+
+// encodeCallsign packs up to 8 characters into 48 bits using the AIS charset
+func encodeCallsign(callsign string, b []byte) error {
+	callsign = strings.ToUpper(callsign)
+	if len(callsign) > 8 {
+		return fmt.Errorf("callsign %s is longer than 8 characters", callsign)
+	}
+	var packed uint64
+	for i := 0; i < 8; i++ {
+		c := byte(' ')
+		if i < len(callsign) {
+			c = callsign[i]
+		}
+		// '?' fills the gaps in the charset, it is not something we can send
+		index := strings.IndexByte(aisCharset, c)
+		if '?' == c || index < 0 {
+			return fmt.Errorf("callsign %s has a character (%c) that cannot be sent", callsign, c)
+		}
+		packed = packed<<6 | uint64(index)
+	}
+	for i := 0; i < 6; i++ {
+		b[i] = byte(packed >> uint(40-8*i))
+	}
+	return nil
+}
+
+// mbSetField sets bits first to last (inclusive, numbered 1-56) of an MB/ME field, the opposite of mbField
+func mbSetField(mb []byte, first, last uint, value uint64) {
+	for bit := last; bit >= first; bit-- {
+		byteIndex, mask := (bit-1)/8, byte(0x80>>((bit-1)%8))
+		if value&1 == 1 {
+			mb[byteIndex] |= mask
+		} else {
+			mb[byteIndex] &^= mask
+		}
+		value >>= 1
+	}
+}
+
+// mbSetSignedField is the opposite of mbSignedField
+func mbSetSignedField(mb []byte, sign, first, last uint, value int64) {
+	if value < 0 {
+		mbSetField(mb, sign, sign, 1)
+		value += 1 << (last - first + 1)
+	}
+	mbSetField(mb, first, last, uint64(value))
+}
+
+func mbSetBit(mb []byte, bit uint, set bool) {
+	if set {
+		mbSetField(mb, bit, bit, 1)
+	}
+}
+
+// cprNL is the number of longitude zones at a latitude
+func cprNL(lat float64) int {
+	lat = math.Abs(lat)
+	if lat < 1e-9 {
+		return 59
+	} else if lat > 87 {
+		return 1
+	} else if lat == 87 {
+		return 2
+	}
+	a := 1 - math.Cos(math.Pi/(2*15))
+	b := math.Cos(math.Pi / 180 * lat)
+	return int(math.Floor(2 * math.Pi / math.Acos(1-a/(b*b))))
+}
+
+func cprMod(a, b float64) float64 {
+	return a - b*math.Floor(a/b)
+}
+
+// encodeCpr turns a lat/lon into the 17 bit CPR values. surface positions cover 90 degrees instead of 360
+func encodeCpr(lat, lon float64, odd bool, surface bool) (uint32, uint32) {
+	span := 360.0
+	if surface {
+		span = 90.0
+	}
+	i := 0.0
+	if odd {
+		i = 1
+	}
+	dLat := span / (60 - i)
+	yz := math.Floor(cprBits*cprMod(lat, dLat)/dLat + 0.5)
+	rLat := dLat * (yz/cprBits + math.Floor(lat/dLat))
+	nl := float64(cprNL(rLat)) - i
+	dLon := span
+	if nl > 0 {
+		dLon = span / nl
+	}
+	xz := math.Floor(cprBits*cprMod(lon, dLon)/dLon + 0.5)
+	return uint32(yz) & (cprBits - 1), uint32(xz) & (cprBits - 1)
+}
+
+func putCpr(me []byte, lat, lon uint32, odd bool) {
+	if odd {
+		me[2] |= 0x04
+	}
+	me[2] |= byte(lat >> 15 & 0x03)
+	me[3] = byte(lat >> 7)
+	me[4] = byte(lat<<1) | byte(lon>>16&1)
+	me[5] = byte(lon >> 8)
+	me[6] = byte(lon)
+}
+
+// encodeSurfaceMovement is the opposite of calcSurfaceSpeed
+func encodeSurfaceMovement(speed float64) byte {
+	switch {
+	case speed <= 0:
+		return 1
+	case speed < 1:
+		return 1 + byte(speed/0.125)
+	case speed < 2:
+		return 9 + byte((speed-1)/0.25)
+	case speed < 15:
+		return 13 + byte((speed-2)/0.5)
+	case speed < 70:
+		return 39 + byte(speed-15)
+	case speed < 100:
+		return 94 + byte((speed-70)/2)
+	case speed < 175:
+		return 109 + byte((speed-100)/5)
+	}
+	return 124
+}
+
+func (m AdsbIdentification) Encode() ([7]byte, error) {
+	var me [7]byte
+	typeCode := m.TypeCode
+	if 0 == typeCode {
+		typeCode = 4
+	}
+	if typeCode > 4 {
+		return me, fmt.Errorf("identification type code must be 1-4, not %d", typeCode)
+	}
+	me[0] = typeCode<<3 | m.Category&7
+	err := encodeCallsign(m.Callsign, me[1:7])
+	return me, err
+}
+
+func (m AdsbAirbornePosition) Encode() ([7]byte, error) {
+	var me [7]byte
+	typeCode := m.TypeCode
+	if 0 == typeCode {
+		typeCode = 11
+	}
+	if typeCode < 9 || typeCode > 18 {
+		return me, fmt.Errorf("barometric airborne position type code must be 9-18, not %d", typeCode)
+	}
+	alt, err := encodeAC12(m.Altitude)
+	if nil != err {
+		return me, err
+	}
+	me[0] = typeCode<<3 | m.SurveillanceStatus&3<<1
+	if m.NicSupplementB {
+		me[0] |= 1
+	}
+	me[1] = byte(alt >> 4)
+	me[2] = byte(alt&0xF) << 4
+	lat, lon := encodeCpr(m.Lat, m.Lon, m.Odd, false)
+	putCpr(me[:], lat, lon, m.Odd)
+	return me, nil
+}
+
+func (m AdsbSurfacePosition) Encode() ([7]byte, error) {
+	var me [7]byte
+	typeCode := m.TypeCode
+	if 0 == typeCode {
+		typeCode = 5
+	}
+	if typeCode < 5 || typeCode > 8 {
+		return me, fmt.Errorf("surface position type code must be 5-8, not %d", typeCode)
+	}
+	movement := encodeSurfaceMovement(m.Speed)
+	me[0] = typeCode<<3 | movement>>4
+	me[1] = movement << 4
+	if m.HasHeading {
+		heading := byte(math.Floor(cprMod(m.Heading, 360)*128/360+0.5)) & 0x7F
+		me[1] |= 0x08 | heading>>4
+		me[2] = heading << 4
+	}
+	lat, lon := encodeCpr(m.Lat, m.Lon, m.Odd, true)
+	putCpr(me[:], lat, lon, m.Odd)
+	return me, nil
+}
+
+func (m AdsbAirborneVelocity) Encode() ([7]byte, error) {
+	var me [7]byte
+	radians := m.Heading * math.Pi / 180
+	ew := m.GroundSpeed * math.Sin(radians)
+	ns := m.GroundSpeed * math.Cos(radians)
+
+	subType := byte(1)
+	scale := 1.0
+	if math.Abs(ew) > maxVelocityComponent || math.Abs(ns) > maxVelocityComponent {
+		// supersonic, 4 knot units
+		subType = 2
+		scale = 4
+	}
+	component := func(v float64) (byte, uint16) {
+		var dir byte
+		if v < 0 {
+			dir = 1
+		}
+		n := math.Floor(math.Abs(v)/scale+0.5) + 1
+		return dir, uint16(math.Min(n, maxVelocityComponent+1))
+	}
+	ewDir, ewVel := component(ew)
+	nsDir, nsVel := component(ns)
+
+	var vrSign byte
+	if m.VerticalRate < 0 {
+		vrSign = 1
+	}
+	vr := uint16(math.Min(math.Floor(math.Abs(float64(m.VerticalRate))/64+0.5)+1, 511))
+
+	me[0] = 19<<3 | subType
+	me[1] = m.NacV&7<<3 | ewDir<<2 | byte(ewVel>>8&3)
+	me[2] = byte(ewVel)
+	me[3] = nsDir<<7 | byte(nsVel>>3&0x7F)
+	me[4] = byte(nsVel&7)<<5 | vrSign<<3 | byte(vr>>6&7)
+	if m.BaroRate {
+		me[4] |= 0x10
+	}
+	me[5] = byte(vr&0x3F) << 2
+	return me, nil
+}
+
+func (m AdsbEmergency) Encode() ([7]byte, error) {
+	var me [7]byte
+	id, err := encodeID13(m.Squawk)
+	if nil != err {
+		return me, err
+	}
+	me[0] = 28<<3 | 1
+	me[1] = m.Emergency&7<<5 | byte(id>>8)
+	me[2] = byte(id)
+	return me, nil
+}
+
+func (m BdsIdentification) Encode() ([7]byte, error) {
+	var mb [7]byte
+	mb[0] = 0x20
+	err := encodeCallsign(m.Callsign, mb[1:7])
+	return mb, err
+}
+
+func (m BdsSelectedVerticalIntent) Encode() ([7]byte, error) {
+	var mb [7]byte
+	if m.HasMcpSelectedAltitude {
+		if m.McpSelectedAltitude < 0 || m.McpSelectedAltitude > 65520 {
+			return mb, fmt.Errorf("MCP selected altitude %d is out of range (0-65520)", m.McpSelectedAltitude)
+		}
+		mbSetBit(mb[:], 1, true)
+		mbSetField(mb[:], 2, 13, uint64(m.McpSelectedAltitude/16))
+	}
+	if m.HasFmsSelectedAltitude {
+		if m.FmsSelectedAltitude < 0 || m.FmsSelectedAltitude > 65520 {
+			return mb, fmt.Errorf("FMS selected altitude %d is out of range (0-65520)", m.FmsSelectedAltitude)
+		}
+		mbSetBit(mb[:], 14, true)
+		mbSetField(mb[:], 15, 26, uint64(m.FmsSelectedAltitude/16))
+	}
+	if m.HasBaroSetting {
+		if m.BaroSetting < 800 || m.BaroSetting > 1209.5 {
+			return mb, fmt.Errorf("baro setting %0.1f is out of range (800-1209.5)", m.BaroSetting)
+		}
+		mbSetBit(mb[:], 27, true)
+		mbSetField(mb[:], 28, 39, uint64(math.Floor((m.BaroSetting-800)*10+0.5)))
+	}
+	return mb, nil
+}
+
+func (m BdsTrackAndTurn) Encode() ([7]byte, error) {
+	var mb [7]byte
+	if m.HasRollAngle {
+		mbSetBit(mb[:], 1, true)
+		mbSetSignedField(mb[:], 2, 3, 11, int64(math.Floor(m.RollAngle*256/45+0.5)))
+	}
+	if m.HasTrueTrack {
+		track := cprMod(m.TrueTrack, 360)
+		if track >= 180 {
+			track -= 360
+		}
+		mbSetBit(mb[:], 12, true)
+		mbSetSignedField(mb[:], 13, 14, 23, int64(math.Floor(track*512/90+0.5)))
+	}
+	if m.HasGroundSpeed {
+		if m.GroundSpeed < 0 || m.GroundSpeed > 2046 {
+			return mb, fmt.Errorf("ground speed %d is out of range (0-2046)", m.GroundSpeed)
+		}
+		mbSetBit(mb[:], 24, true)
+		mbSetField(mb[:], 25, 34, uint64(m.GroundSpeed/2))
+	}
+	if m.HasTrackRate {
+		mbSetBit(mb[:], 35, true)
+		mbSetSignedField(mb[:], 36, 37, 45, int64(math.Floor(m.TrackRate*256/8+0.5)))
+	}
+	if m.HasTrueAirSpeed {
+		if m.TrueAirSpeed < 0 || m.TrueAirSpeed > 2046 {
+			return mb, fmt.Errorf("true air speed %d is out of range (0-2046)", m.TrueAirSpeed)
+		}
+		mbSetBit(mb[:], 46, true)
+		mbSetField(mb[:], 47, 56, uint64(m.TrueAirSpeed/2))
+	}
+	return mb, nil
+}
+
+func (m BdsHeadingAndSpeed) Encode() ([7]byte, error) {
+	var mb [7]byte
+	if m.HasMagneticHeading {
+		heading := cprMod(m.MagneticHeading, 360)
+		if heading >= 180 {
+			heading -= 360
+		}
+		mbSetBit(mb[:], 1, true)
+		mbSetSignedField(mb[:], 2, 3, 12, int64(math.Floor(heading*512/90+0.5)))
+	}
+	if m.HasIndicatedAirSpeed {
+		if m.IndicatedAirSpeed < 0 || m.IndicatedAirSpeed > 1023 {
+			return mb, fmt.Errorf("indicated air speed %d is out of range (0-1023)", m.IndicatedAirSpeed)
+		}
+		mbSetBit(mb[:], 13, true)
+		mbSetField(mb[:], 14, 23, uint64(m.IndicatedAirSpeed))
+	}
+	if m.HasMach {
+		mach := math.Floor(m.Mach*512/2.048 + 0.5)
+		if m.Mach < 0 || mach > 1023 {
+			return mb, fmt.Errorf("mach %0.3f is out of range (0-4.092)", m.Mach)
+		}
+		mbSetBit(mb[:], 24, true)
+		mbSetField(mb[:], 25, 34, uint64(mach))
+	}
+	if m.HasBaroVerticalRate {
+		if m.BaroVerticalRate < -16352 || m.BaroVerticalRate > 16352 {
+			return mb, fmt.Errorf("baro vertical rate %d is out of range (±16352)", m.BaroVerticalRate)
+		}
+		mbSetBit(mb[:], 35, true)
+		mbSetSignedField(mb[:], 36, 37, 45, int64(m.BaroVerticalRate/32))
+	}
+	if m.HasInertialVerticalRate {
+		if m.InertialVerticalRate < -16352 || m.InertialVerticalRate > 16352 {
+			return mb, fmt.Errorf("inertial vertical rate %d is out of range (±16352)", m.InertialVerticalRate)
+		}
+		mbSetBit(mb[:], 46, true)
+		mbSetSignedField(mb[:], 47, 48, 56, int64(m.InertialVerticalRate/32))
+	}
+	return mb, nil
+}
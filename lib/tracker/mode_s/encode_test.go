@@ -0,0 +1,261 @@
+package mode_s
+
+import (
+	"bytes"
+	"math"
+	"testing"
+	"time"
+)
+
+// mustDecode decodes a frame we have just encoded, call it like mustDecode(t)(EncodeAllCall(...))
+func mustDecode(t *testing.T) func(EncodedFrame, error) *Frame {
+	return func(frame EncodedFrame, err error) *Frame {
+		t.Helper()
+		if nil != err {
+			t.Fatal(err)
+		}
+		decoded, err := DecodeBytes(frame.Bytes(), time.Now())
+		if nil != err {
+			t.Fatalf("Failed to decode our own frame %X: %s", frame.Bytes(), err)
+		}
+		return decoded
+	}
+}
+
+func TestEncodeKnownFrames(t *testing.T) {
+	tests := []struct {
+		name     string
+		payload  Payload
+		expected string
+	}{
+		{name: "Identification", payload: AdsbIdentification{Callsign: "KLM1023"}, expected: "*8D4840D6202CC371C32CE0576098;"},
+		{name: "Airborne Even", payload: AdsbAirbornePosition{Altitude: 38000, Lat: 52.2572021484375, Lon: 3.9193725585938}, expected: "*8D40621D58C382D690C8AC2863A7;"},
+		{name: "Airborne Odd", payload: AdsbAirbornePosition{Altitude: 38000, Lat: 52.26578017412606, Lon: 3.938912527901786, Odd: true}, expected: "*8D40621D58C386435CC412692AD6;"},
+	}
+	icaos := map[string]uint32{"Identification": 0x4840D6, "Airborne Even": 0x40621D, "Airborne Odd": 0x40621D}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frame, err := EncodeExtendedSquitter(icaos[tt.name], tt.payload)
+			if nil != err {
+				t.Fatal(err)
+			}
+			if tt.expected != frame.Avr() {
+				t.Errorf("Incorrect frame. expected %s, got %s", tt.expected, frame.Avr())
+			}
+		})
+	}
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	t.Run("DF4", func(t *testing.T) {
+		f := mustDecode(t)(EncodeSurveillanceAltitude(0x7C7DAA, 0, 36125))
+		if 4 != f.DownLinkType() || 0x7C7DAA != f.Icao() {
+			t.Errorf("Incorrect DF%d/%06X", f.DownLinkType(), f.Icao())
+		}
+		if alt, _ := f.Altitude(); 36125 != alt {
+			t.Errorf("Incorrect altitude %d", alt)
+		}
+	})
+	t.Run("DF5", func(t *testing.T) {
+		f := mustDecode(t)(EncodeSurveillanceIdentity(0x7C7DAA, 1, 7700))
+		if 0x7C7DAA != f.Icao() || 7700 != f.SquawkIdentity() || 1 != f.FlightStatus() {
+			t.Errorf("Incorrect %06X squawk %04d flight status %d", f.Icao(), f.SquawkIdentity(), f.FlightStatus())
+		}
+	})
+	t.Run("DF11", func(t *testing.T) {
+		f := mustDecode(t)(EncodeAllCall(0x7C7DAA, 5), nil)
+		if 11 != f.DownLinkType() || 0x7C7DAA != f.Icao() || f.IcaoFromParity() {
+			t.Errorf("Incorrect DF%d/%06X", f.DownLinkType(), f.Icao())
+		}
+	})
+	t.Run("DF20 BDS 4,0", func(t *testing.T) {
+		bds40 := BdsSelectedVerticalIntent{McpSelectedAltitude: 35008, HasMcpSelectedAltitude: true, BaroSetting: 1013.2, HasBaroSetting: true}
+		f := mustDecode(t)(EncodeCommBAltitude(0x7C7DAA, 0, 34000, bds40))
+		if BdsEhsSelVertIntent != f.BdsMessageType() {
+			t.Fatalf("Incorrect BDS %s", f.BdsMessageType())
+		}
+		if 35008 != f.MustMcpSelectedAltitude() || math.Abs(1013.2-f.MustBaroSetting()) > 0.01 {
+			t.Errorf("Incorrect MCP altitude %d or baro %0.1f", f.MustMcpSelectedAltitude(), f.MustBaroSetting())
+		}
+		if alt, _ := f.Altitude(); 34000 != alt {
+			t.Errorf("Incorrect altitude %d", alt)
+		}
+	})
+	t.Run("DF21 BDS 2,0", func(t *testing.T) {
+		f := mustDecode(t)(EncodeCommBIdentity(0x7C7DAA, 0, 1234, BdsIdentification{Callsign: "QFA123"}))
+		if BdsElsAircraftIdent != f.BdsMessageType() || "QFA123  " != f.FlightNumber() || 1234 != f.SquawkIdentity() {
+			t.Errorf("Incorrect BDS %s callsign %q squawk %04d", f.BdsMessageType(), f.FlightNumber(), f.SquawkIdentity())
+		}
+	})
+	t.Run("DF17 Emergency", func(t *testing.T) {
+		f := mustDecode(t)(EncodeExtendedSquitter(0x7C7DAA, AdsbEmergency{Emergency: 5, Squawk: 7500}))
+		if MessageKindEmergencyPriority != f.MessageKind() || !f.Alert() || 7500 != f.SquawkIdentity() {
+			t.Errorf("Incorrect %s alert %t squawk %04d", f.MessageKind(), f.Alert(), f.SquawkIdentity())
+		}
+	})
+	t.Run("DF17 Surface", func(t *testing.T) {
+		f := mustDecode(t)(EncodeExtendedSquitter(0x7C7DAA, AdsbSurfacePosition{Speed: 17, Heading: 90, HasHeading: true, Lat: -31.94, Lon: 115.97}))
+		if MessageKindSurfacePos != f.MessageKind() || !f.MustOnGround() {
+			t.Fatalf("Incorrect %s", f.MessageKind())
+		}
+		if 17 != f.MustVelocity() || 90 != f.MustHeading() {
+			t.Errorf("Incorrect speed %0.2f or heading %0.2f", f.MustVelocity(), f.MustHeading())
+		}
+	})
+	t.Run("DF17 Velocity", func(t *testing.T) {
+		f := mustDecode(t)(EncodeExtendedSquitter(0x7C7DAA, AdsbAirborneVelocity{Heading: 182.88, GroundSpeed: 159.2, VerticalRate: -832, NacV: 2}))
+		if MessageKindAirVelocity != f.MessageKind() {
+			t.Fatalf("Incorrect %s", f.MessageKind())
+		}
+		if math.Abs(182.88-f.MustHeading()) > 0.5 || -832 != f.MustVerticalRate() || 2 != f.MustNacV() {
+			t.Errorf("Incorrect heading %0.2f, vertical rate %d or NACv %d", f.MustHeading(), f.MustVerticalRate(), f.MustNacV())
+		}
+		// whole knot components (3-4-5) come back exactly, supersonic ones in 4 knot units
+		for _, speed := range []float64{500, 2000} {
+			heading := math.Atan2(3, 4) * 180 / math.Pi
+			f = mustDecode(t)(EncodeExtendedSquitter(0x7C7DAA, AdsbAirborneVelocity{Heading: heading, GroundSpeed: speed}))
+			if speed != f.MustVelocity() || heading != f.MustHeading() || (speed > 1000) != f.superSonic {
+				t.Errorf("Incorrect velocity %0.2f or heading %0.2f for %0.0f knots", f.MustVelocity(), f.MustHeading(), speed)
+			}
+		}
+	})
+	t.Run("DF17 Identification", func(t *testing.T) {
+		f := mustDecode(t)(EncodeExtendedSquitter(0x7C7DAA, AdsbIdentification{Category: 3, Callsign: "vozdi"}))
+		if "VOZDI   " != f.FlightNumber() || !f.ValidCategory() || "0/3" != f.CategoryType() {
+			t.Errorf("Incorrect callsign %q or category %s", f.FlightNumber(), f.CategoryType())
+		}
+	})
+}
+
+func TestEncodeBds5060(t *testing.T) {
+	mb, err := BdsTrackAndTurn{RollAngle: -10.5, HasRollAngle: true, TrueTrack: 270, HasTrueTrack: true, GroundSpeed: 450, HasGroundSpeed: true, TrueAirSpeed: 420, HasTrueAirSpeed: true}.Encode()
+	if nil != err {
+		t.Fatal(err)
+	}
+	var b bds
+	b.decodeBds50(mb[:])
+	if math.Abs(-10.5-b.rollAngle) > 0.2 || math.Abs(270-b.trueTrack) > 0.2 || 450 != b.groundSpeed || 420 != b.trueAirSpeed || b.validTrackRate {
+		t.Errorf("Incorrect BDS 5,0: %+v", b)
+	}
+
+	mb, err = BdsHeadingAndSpeed{MagneticHeading: 350, HasMagneticHeading: true, IndicatedAirSpeed: 250, HasIndicatedAirSpeed: true, Mach: 0.78, HasMach: true, BaroVerticalRate: -1024, HasBaroVerticalRate: true}.Encode()
+	if nil != err {
+		t.Fatal(err)
+	}
+	b = bds{}
+	b.decodeBds60(mb[:])
+	if math.Abs(350-b.magneticHeading) > 0.2 || 250 != b.indicatedAirSpeed || math.Abs(0.78-b.mach) > 0.004 || -1024 != b.baroVerticalRate || b.validInertialVerticalRate {
+		t.Errorf("Incorrect BDS 6,0: %+v", b)
+	}
+}
+
+func TestEncodeErrors(t *testing.T) {
+	if _, err := EncodeSurveillanceIdentity(1, 0, 7800); ErrSquawk != err {
+		t.Errorf("Expected a squawk error, got %v", err)
+	}
+	if _, err := EncodeSurveillanceAltitude(1, 0, 60000); ErrAltitudeRange != err {
+		t.Errorf("Expected an altitude error, got %v", err)
+	}
+	if _, err := EncodeExtendedSquitter(1, AdsbIdentification{Callsign: "TOO_LONG_"}); nil == err {
+		t.Error("Expected a callsign error")
+	}
+	if _, err := EncodeExtendedSquitter(1, AdsbIdentification{Callsign: "QF?1"}); nil == err {
+		t.Error("Expected a callsign error for a character we cannot send")
+	}
+}
+
+func TestEncodeBdsRanges(t *testing.T) {
+	tests := []struct {
+		name    string
+		mb      interface{ Encode() ([7]byte, error) }
+		wantErr bool
+	}{
+		{name: "baro setting low", mb: BdsSelectedVerticalIntent{BaroSetting: 799.9, HasBaroSetting: true}, wantErr: true},
+		{name: "baro setting min", mb: BdsSelectedVerticalIntent{BaroSetting: 800, HasBaroSetting: true}},
+		{name: "baro setting max", mb: BdsSelectedVerticalIntent{BaroSetting: 1209.5, HasBaroSetting: true}},
+		{name: "baro setting high", mb: BdsSelectedVerticalIntent{BaroSetting: 1209.6, HasBaroSetting: true}, wantErr: true},
+		{name: "mcp altitude low", mb: BdsSelectedVerticalIntent{McpSelectedAltitude: -16, HasMcpSelectedAltitude: true}, wantErr: true},
+		{name: "mcp altitude min", mb: BdsSelectedVerticalIntent{McpSelectedAltitude: 0, HasMcpSelectedAltitude: true}},
+		{name: "mcp altitude max", mb: BdsSelectedVerticalIntent{McpSelectedAltitude: 65520, HasMcpSelectedAltitude: true}},
+		{name: "mcp altitude high", mb: BdsSelectedVerticalIntent{McpSelectedAltitude: 65536, HasMcpSelectedAltitude: true}, wantErr: true},
+		{name: "fms altitude low", mb: BdsSelectedVerticalIntent{FmsSelectedAltitude: -16, HasFmsSelectedAltitude: true}, wantErr: true},
+		{name: "fms altitude max", mb: BdsSelectedVerticalIntent{FmsSelectedAltitude: 65520, HasFmsSelectedAltitude: true}},
+		{name: "fms altitude high", mb: BdsSelectedVerticalIntent{FmsSelectedAltitude: 65536, HasFmsSelectedAltitude: true}, wantErr: true},
+		{name: "ground speed max", mb: BdsTrackAndTurn{GroundSpeed: 2046, HasGroundSpeed: true}},
+		{name: "ground speed high", mb: BdsTrackAndTurn{GroundSpeed: 2048, HasGroundSpeed: true}, wantErr: true},
+		{name: "ground speed negative", mb: BdsTrackAndTurn{GroundSpeed: -2, HasGroundSpeed: true}, wantErr: true},
+		{name: "true air speed max", mb: BdsTrackAndTurn{TrueAirSpeed: 2046, HasTrueAirSpeed: true}},
+		{name: "true air speed high", mb: BdsTrackAndTurn{TrueAirSpeed: 2048, HasTrueAirSpeed: true}, wantErr: true},
+		{name: "indicated air speed max", mb: BdsHeadingAndSpeed{IndicatedAirSpeed: 1023, HasIndicatedAirSpeed: true}},
+		{name: "indicated air speed high", mb: BdsHeadingAndSpeed{IndicatedAirSpeed: 1024, HasIndicatedAirSpeed: true}, wantErr: true},
+		{name: "mach max", mb: BdsHeadingAndSpeed{Mach: 4.092, HasMach: true}},
+		{name: "mach high", mb: BdsHeadingAndSpeed{Mach: 4.096, HasMach: true}, wantErr: true},
+		{name: "mach negative", mb: BdsHeadingAndSpeed{Mach: -0.1, HasMach: true}, wantErr: true},
+		{name: "baro vertical rate max", mb: BdsHeadingAndSpeed{BaroVerticalRate: 16352, HasBaroVerticalRate: true}},
+		{name: "baro vertical rate min", mb: BdsHeadingAndSpeed{BaroVerticalRate: -16352, HasBaroVerticalRate: true}},
+		{name: "baro vertical rate high", mb: BdsHeadingAndSpeed{BaroVerticalRate: 16384, HasBaroVerticalRate: true}, wantErr: true},
+		{name: "baro vertical rate low", mb: BdsHeadingAndSpeed{BaroVerticalRate: -16384, HasBaroVerticalRate: true}, wantErr: true},
+		{name: "inertial vertical rate max", mb: BdsHeadingAndSpeed{InertialVerticalRate: 16352, HasInertialVerticalRate: true}},
+		{name: "inertial vertical rate min", mb: BdsHeadingAndSpeed{InertialVerticalRate: -16352, HasInertialVerticalRate: true}},
+		{name: "inertial vertical rate high", mb: BdsHeadingAndSpeed{InertialVerticalRate: 16384, HasInertialVerticalRate: true}, wantErr: true},
+		{name: "inertial vertical rate low", mb: BdsHeadingAndSpeed{InertialVerticalRate: -16384, HasInertialVerticalRate: true}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.mb.Encode(); (nil != err) != tt.wantErr {
+				t.Errorf("Encode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEncodeBdsRangeEdges(t *testing.T) {
+	// the largest values we can send should come back out as they went in
+	mb, err := BdsSelectedVerticalIntent{McpSelectedAltitude: 65520, HasMcpSelectedAltitude: true, FmsSelectedAltitude: 0, HasFmsSelectedAltitude: true, BaroSetting: 1209.5, HasBaroSetting: true}.Encode()
+	if nil != err {
+		t.Fatal(err)
+	}
+	var b bds
+	b.decodeBds40(mb[:])
+	if 65520 != b.mcpSelectedAltitude || 0 != b.fmsSelectedAltitude || math.Abs(1209.5-b.baroSetting) > 0.01 {
+		t.Errorf("Incorrect BDS 4,0: %+v", b)
+	}
+
+	mb, err = BdsTrackAndTurn{GroundSpeed: 2046, HasGroundSpeed: true, TrueAirSpeed: 2046, HasTrueAirSpeed: true}.Encode()
+	if nil != err {
+		t.Fatal(err)
+	}
+	b = bds{}
+	b.decodeBds50(mb[:])
+	if 2046 != b.groundSpeed || 2046 != b.trueAirSpeed {
+		t.Errorf("Incorrect BDS 5,0: %+v", b)
+	}
+
+	mb, err = BdsHeadingAndSpeed{IndicatedAirSpeed: 1023, HasIndicatedAirSpeed: true, Mach: 4.092, HasMach: true, BaroVerticalRate: 16352, HasBaroVerticalRate: true, InertialVerticalRate: -16352, HasInertialVerticalRate: true}.Encode()
+	if nil != err {
+		t.Fatal(err)
+	}
+	b = bds{}
+	b.decodeBds60(mb[:])
+	if 1023 != b.indicatedAirSpeed || math.Abs(4.092-b.mach) > 0.004 || 16352 != b.baroVerticalRate || -16352 != b.inertialVerticalRate {
+		t.Errorf("Incorrect BDS 6,0: %+v", b)
+	}
+}
+
+func TestEncodedFrame_Formats(t *testing.T) {
+	frame := EncodeAllCall(0x1A1A1A, 5)
+
+	f, err := DecodeString(frame.AvrMlat(0x1A0000000001), time.Now())
+	if nil != err {
+		t.Fatal(err)
+	}
+	if 0x1A1A1A != f.Icao() || !bytes.Equal(frame.Bytes(), f.message) {
+		t.Errorf("AVR MLAT frame did not decode to the same frame: %X", f.message)
+	}
+
+	beast := frame.Beast(0x1A0000000001, 0x1A)
+	expected := []byte{0x1A, 0x32, 0x1A, 0x1A, 0, 0, 0, 0, 1, 0x1A, 0x1A, 0x5D, 0x1A, 0x1A, 0x1A, 0x1A, 0x1A, 0x1A}
+	if !bytes.Equal(expected, beast[:len(expected)]) {
+		t.Errorf("BEAST frame is not escaped correctly: %X", beast)
+	}
+}
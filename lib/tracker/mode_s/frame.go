@@ -27,8 +27,62 @@ const (
 	DF17FrameTcasRA                   = "Extended Squitter Aircraft status (1090ES TCAS Resolution Advisory)"
 	DF17FrameTargetStateStatus        = "Target State and status Message"
 	DF17FrameAircraftOperational      = "Aircraft Operational status Message"
+	DF18FrameTisbCoarsePos            = "TIS-B Coarse Airborne Position"
+	DF18FrameTisbManagement           = "TIS-B/ADS-R Management"
 )
 
+// MessageKind is what a DF17/18 message is about, worked out from the type code and sub type
+type MessageKind byte
+
+const (
+	MessageKindUnknown MessageKind = iota
+	MessageKindIdCat
+	MessageKindSurfacePos
+	MessageKindAirPositionBarometric
+	MessageKindAirVelocity
+	MessageKindAirVelocityUnknown
+	MessageKindAirPositionGnss
+	MessageKindTestMessage
+	MessageKindTestMessageSquawk
+	MessageKindSurfaceSystemStatus
+	MessageKindEmergencyPriority
+	MessageKindEmergencyPriorityUnknown
+	MessageKindTcasRA
+	MessageKindTargetStateStatus
+	MessageKindTargetStateStatusUnknown
+	MessageKindAircraftOperational
+	MessageKindTisbCoarsePos
+	MessageKindTisbManagement
+)
+
+var messageKindNames = [...]string{
+	MessageKindUnknown:                  "Unknown",
+	MessageKindIdCat:                    DF17FrameIdCat,
+	MessageKindSurfacePos:               DF17FrameSurfacePos,
+	MessageKindAirPositionBarometric:    DF17FrameAirPositionBarometric,
+	MessageKindAirVelocity:              DF17FrameAirVelocity,
+	MessageKindAirVelocityUnknown:       DF17FrameAirVelocityUnknown,
+	MessageKindAirPositionGnss:          DF17FrameAirPositionGnss,
+	MessageKindTestMessage:              DF17FrameTestMessage,
+	MessageKindTestMessageSquawk:        DF17FrameTestMessageSquawk,
+	MessageKindSurfaceSystemStatus:      DF17FrameSurfaceSystemStatus,
+	MessageKindEmergencyPriority:        DF17FrameEmergencyPriority,
+	MessageKindEmergencyPriorityUnknown: DF17FrameEmergencyPriorityUnknown,
+	MessageKindTcasRA:                   DF17FrameTcasRA,
+	MessageKindTargetStateStatus:        DF17FrameTargetStateStatus,
+	MessageKindTargetStateStatusUnknown: DF17FrameTargetStateStatus,
+	MessageKindAircraftOperational:      DF17FrameAircraftOperational,
+	MessageKindTisbCoarsePos:            DF18FrameTisbCoarsePos,
+	MessageKindTisbManagement:           DF18FrameTisbManagement,
+}
+
+func (k MessageKind) String() string {
+	if int(k) < len(messageKindNames) {
+		return messageKindNames[k]
+	}
+	return messageKindNames[MessageKindUnknown]
+}
+
 type (
 	Position struct {
 		validAltitude bool
@@ -90,6 +144,12 @@ type (
 		nicCrossCheck       byte // whether or not the alt or heading is cross checked
 		northReference      byte // 0=true north, 1 = magnetic north
 
+		validOperationalStatus bool // we have decoded a TC 31, so adsbVersion and the NIC supplements are good
+		validNacP              bool
+		validSil               bool
+		silSupplement          byte // 0 = per hour, 1 = per sample
+		nicBaro                byte
+
 		surveillanceStatus byte
 		nicSupplementA     byte
 		nicSupplementB     byte
@@ -99,6 +159,13 @@ type (
 		intentChange  byte
 		ifrCapability byte
 		nacV          byte
+
+		// Target State and Status (TC 29), selected altitude, baro setting and VNAV/alt hold/approach are in bds
+		validSelectedHeading bool
+		selectedHeading      float64
+		validAutopilotModes  bool
+		autopilotEngaged     bool
+		lnavMode             bool
 	}
 
 	extendedSquitter struct {
@@ -281,6 +348,8 @@ type (
 		rawFields
 		bds
 		df17
+		acas
+		addressing
 		Position
 		mode string
 		// the timestamp we are processing this message at
@@ -292,15 +361,26 @@ type (
 		beastTicksNs   uint64
 		beastAvrUptime time.Duration
 		// raw is our semi processed string, full is the original string
-		raw, full      string
-		message        []byte
-		downLinkFormat byte // Down link Format (DF)
+		raw, full string
+		// binary frames were created from bytes, message is all we have and raw is worked out when needed
+		binary     bool
+		message    []byte
+		messageBuf [modesLongMsgBytes]byte // message points in here, saves an allocation per frame
+		// received is the frame as it was given to us, Decode (and error correction) never touch it
+		received       string
+		receivedBuf    [modesLongMsgBytes]byte // the received bytes of a binary frame
+		downLinkFormat byte                    // Down link Format (DF)
 		icao           uint32
 		crc, checkSum  uint32
-		identity       uint32
-		special        string
-		emergency      string
-		alert          bool
+		correctedBits  byte // how many bit errors error correction fixed
+		// errorCorrection is how hard we try to fix a bad checksum, see the Correction* constants
+		errorCorrection int
+		icaoFromParity  bool // the icao was recovered from the Address/Parity field
+		checksumPassed  bool // the Parity/Interrogator field was checked (and corrected if needed) and is good
+		identity        uint32
+		special         string
+		emergency       string
+		alert           bool
 		// if we have trouble decoding our frame, the message ends up here
 		err error
 	}
@@ -525,50 +605,68 @@ var (
 	}
 )
 
-func (f *Frame) MessageTypeString() string {
-	name := "Unknown"
-	if f.messageType >= 1 && f.messageType <= 4 {
-		name = DF17FrameIdCat
-	} else if f.messageType >= 5 && f.messageType <= 8 {
-		name = DF17FrameSurfacePos
-	} else if f.messageType >= 9 && f.messageType <= 18 {
-		name = DF17FrameAirPositionBarometric
-	} else if f.messageType == 19 {
+// MessageKind tells us what sort of DF17/18 message this is, it is the typed (and cheaper) version of
+// MessageTypeString
+func (f *Frame) MessageKind() MessageKind {
+	if 18 == f.downLinkFormat {
+		// these DF18 control fields do not use the DF17 ME layout
+		switch f.cf {
+		case cfTisbCoarse:
+			return MessageKindTisbCoarsePos
+		case cfTisbManagement:
+			return MessageKindTisbManagement
+		}
+	}
+	switch f.messageType {
+	case 1, 2, 3, 4:
+		return MessageKindIdCat
+	case 5, 6, 7, 8:
+		return MessageKindSurfacePos
+	case 9, 10, 11, 12, 13, 14, 15, 16, 17, 18:
+		return MessageKindAirPositionBarometric
+	case 19:
 		if f.messageSubType >= 1 && f.messageSubType <= 4 {
-			name = DF17FrameAirVelocity
-		} else {
-			name = DF17FrameAirVelocityUnknown
+			return MessageKindAirVelocity
 		}
-	} else if f.messageType >= 20 && f.messageType <= 22 {
-		name = DF17FrameAirPositionGnss
-	} else if f.messageType == 23 {
+		return MessageKindAirVelocityUnknown
+	case 20, 21, 22:
+		return MessageKindAirPositionGnss
+	case 23:
 		if f.messageSubType == 7 {
-			name = DF17FrameTestMessageSquawk
-		} else {
-			name = DF17FrameTestMessage
+			return MessageKindTestMessageSquawk
 		}
-	} else if f.messageType == 24 && f.messageSubType == 1 {
-		name = DF17FrameSurfaceSystemStatus
-	} else if f.messageType == 28 {
+		return MessageKindTestMessage
+	case 24:
 		if f.messageSubType == 1 {
-			name = DF17FrameEmergencyPriority
-			f.decodeFlightNumber()
-
-		} else if f.messageSubType == 2 {
-			name = DF17FrameTcasRA
-		} else {
-			name = DF17FrameEmergencyPriorityUnknown
+			return MessageKindSurfaceSystemStatus
 		}
-	} else if f.messageType == 29 {
+	case 28:
+		switch f.messageSubType {
+		case 1:
+			return MessageKindEmergencyPriority
+		case 2:
+			return MessageKindTcasRA
+		}
+		return MessageKindEmergencyPriorityUnknown
+	case 29:
 		if f.messageSubType == 0 || f.messageSubType == 1 {
-			name = DF17FrameTargetStateStatus
-		} else {
-			name = fmt.Sprintf("%s (Unknown Sub Message %d)", DF17FrameTargetStateStatus, f.messageSubType)
+			return MessageKindTargetStateStatus
 		}
-	} else if f.messageType == 31 && (f.messageSubType == 0 || f.messageSubType == 1) {
-		name = DF17FrameAircraftOperational
+		return MessageKindTargetStateStatusUnknown
+	case 31:
+		if f.messageSubType == 0 || f.messageSubType == 1 {
+			return MessageKindAircraftOperational
+		}
+	}
+	return MessageKindUnknown
+}
+
+func (f *Frame) MessageTypeString() string {
+	kind := f.MessageKind()
+	if MessageKindTargetStateStatusUnknown == kind {
+		return fmt.Sprintf("%s (Unknown Sub Message %d)", DF17FrameTargetStateStatus, f.messageSubType)
 	}
-	return name
+	return kind.String()
 }
 
 func (f *Frame) DownLinkType() byte {
@@ -583,7 +681,29 @@ func (f *Frame) Raw() []byte {
 	if nil == f {
 		return []byte{}
 	}
-	return []byte(f.raw)
+	return []byte(f.rawString())
+}
+
+// Avr is the frame in AVR format, e.g. *8D4840D6202CC371C32CE0576098;
+func (f *Frame) Avr() string {
+	return "*" + f.rawString() + ";"
+}
+
+// ReceivedHex is the frame as hex, exactly as we received it (before any error correction). Unlike Raw() it
+// never changes, so it is safe to call while the frame is being decoded
+func (f *Frame) ReceivedHex() string {
+	if f.binary {
+		return fmt.Sprintf("%X", f.receivedBuf[:len(f.message)])
+	}
+	return f.received
+}
+
+// rawString is the hex version of the frame, without any timestamp
+func (f *Frame) rawString() string {
+	if f.binary {
+		return fmt.Sprintf("%X", f.message)
+	}
+	return f.raw
 }
 
 func (f *Frame) IcaoStr() string {
@@ -746,6 +866,12 @@ func (f *Frame) Special() string {
 func (f *Frame) HasSurveillanceStatus() bool {
 	return f.surveillanceStatus > 0
 }
+
+// SurveillanceStatusCode is the raw surveillance status from an airborne position, 1 is a permanent alert
+// (emergency), 2 a temporary alert (squawk change) and 3 is SPI
+func (f *Frame) SurveillanceStatusCode() byte {
+	return f.surveillanceStatus
+}
 func (f *Frame) SurveillanceStatus() string {
 	if int(f.surveillanceStatus) < len(surveillanceStatus) {
 		return surveillanceStatus[f.surveillanceStatus]
@@ -761,6 +887,14 @@ func (f *Frame) Emergency() string {
 var noopRw = regexp.MustCompile("^[*@]?0+$")
 
 func (f *Frame) isNoOp() bool {
+	if f.binary {
+		for _, b := range f.message {
+			if 0 != b {
+				return false
+			}
+		}
+		return true
+	}
 	if "" == f.raw {
 		return true
 	}
@@ -836,8 +970,7 @@ func (f *Frame) NavigationIntegrityCategory(nicSupplA bool) (byte, error) {
 		err = fmt.Errorf("unknown navigation integrity category")
 	case 9, 20:
 		nic = 11
-	case 10:
-	case 21:
+	case 10, 21:
 		nic = 10
 	case 11:
 		if nicSupplA {
@@ -930,3 +1063,109 @@ func (f *Frame) getAirplaneLengthWidth() (float32, float32, error) {
 
 	return length, width, err
 }
+
+// SelectedHeadingValid tells us if this frame has a selected heading (from ADS-B Target State)
+func (f *Frame) SelectedHeadingValid() bool {
+	return f.validSelectedHeading
+}
+
+// MustSelectedHeading is the selected heading (or track) in degrees
+func (f *Frame) MustSelectedHeading() float64 {
+	if f.validSelectedHeading {
+		return f.selectedHeading
+	}
+	panic("selected heading is not valid")
+}
+
+// AutopilotModesValid tells us if this frame has the autopilot and LNAV mode flags (from ADS-B Target State)
+func (f *Frame) AutopilotModesValid() bool {
+	return f.validAutopilotModes
+}
+
+// MustAutopilotEngaged tells us if the autopilot is engaged
+func (f *Frame) MustAutopilotEngaged() bool {
+	if f.validAutopilotModes {
+		return f.autopilotEngaged
+	}
+	panic("autopilot modes are not valid")
+}
+
+// MustLnavMode tells us if LNAV mode is engaged
+func (f *Frame) MustLnavMode() bool {
+	if f.validAutopilotModes {
+		return f.lnavMode
+	}
+	panic("autopilot modes are not valid")
+}
+
+// AdsbVersionValid tells us if this frame has the ADS-B version (from an Aircraft Operational Status message)
+func (f *Frame) AdsbVersionValid() bool {
+	return f.validOperationalStatus
+}
+
+// MustAdsbVersion is the ADS-B (MOPS) version, 0 = DO-260, 1 = DO-260A, 2 = DO-260B
+func (f *Frame) MustAdsbVersion() byte {
+	if f.validOperationalStatus {
+		return f.adsbVersion
+	}
+	panic("ADS-B version is not valid")
+}
+
+// NicSupplementA is from the Aircraft Operational Status message and is needed to work out the NIC of a position
+func (f *Frame) NicSupplementA() byte {
+	return f.nicSupplementA
+}
+
+// NicSupplementB is from an airborne position message
+func (f *Frame) NicSupplementB() byte {
+	return f.nicSupplementB
+}
+
+// NicSupplementC is from a surface Aircraft Operational Status message
+func (f *Frame) NicSupplementC() byte {
+	return f.nicSupplementC
+}
+
+// NacPValid tells us if this frame has a Navigation Accuracy Category for Position
+func (f *Frame) NacPValid() bool {
+	return f.validNacP
+}
+
+// MustNacP is the Navigation Accuracy Category for Position
+func (f *Frame) MustNacP() byte {
+	if f.validNacP {
+		return f.nacP
+	}
+	panic("NACp is not valid")
+}
+
+// NacVValid tells us if this frame has a Navigation Accuracy Category for Velocity
+func (f *Frame) NacVValid() bool {
+	return f.validNacV
+}
+
+// MustNacV is the Navigation Accuracy Category for Velocity (NUCr for ADS-B version 0)
+func (f *Frame) MustNacV() byte {
+	if f.validNacV {
+		return f.nacV
+	}
+	panic("NACv is not valid")
+}
+
+// SilValid tells us if this frame has a Source Integrity Level
+func (f *Frame) SilValid() bool {
+	return f.validSil
+}
+
+// MustSil is the Source Integrity Level
+func (f *Frame) MustSil() byte {
+	if f.validSil {
+		return f.sil
+	}
+	panic("SIL is not valid")
+}
+
+// SilSupplement tells us if the SIL is per hour (0) or per sample (1). ADS-B version 2 only
+func (f *Frame) SilSupplement() byte {
+	return f.silSupplement
+}
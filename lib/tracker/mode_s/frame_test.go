@@ -2,6 +2,7 @@ package mode_s
 
 import (
 	"testing"
+	"time"
 )
 
 func TestIsNoop(t *testing.T) {
@@ -93,3 +94,22 @@ func TestFrame_isNoOp(t *testing.T) {
 		})
 	}
 }
+
+func TestFrame_Avr(t *testing.T) {
+	tests := []struct {
+		name  string
+		frame *Frame
+		want  string
+	}{
+		{name: "AVR", frame: NewFrame("*8D4840D6202CC371C32CE0576098;", time.Now()), want: "*8D4840D6202CC371C32CE0576098;"},
+		{name: "AVR MLAT", frame: NewFrame("@0000000000018D4840D6202CC371C32CE0576098;", time.Now()), want: "*8D4840D6202CC371C32CE0576098;"},
+		{name: "Bytes", frame: NewFrameFromBytes([]byte{0x5D, 0x7C, 0x49, 0xF8, 0x28, 0xE9, 0x43}, time.Now()), want: "*5D7C49F828E943;"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.frame.Avr(); got != tt.want {
+				t.Errorf("Avr() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
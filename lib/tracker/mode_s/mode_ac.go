@@ -0,0 +1,49 @@
+package mode_s
+
+import "fmt"
+
+type (
+	// ModeAC is a Mode A (identity) or Mode C (altitude) reply. The replies look the same on the air, so we
+	// decode both and leave it to the caller to work out which one it was
+	ModeAC struct {
+		// Code is the reply in the dump1090 layout: 0xABCD, one octal digit per nibble, SPI is 0x0080
+		Code uint16
+		// Squawk is the Mode A code as a decimal number that reads like the octal squawk (e.g. 7700)
+		Squawk uint32
+		// Ident is the Special Position Identification pulse
+		Ident bool
+		// Altitude is in feet, if the code is a valid Mode C altitude
+		Altitude    int32
+		HasAltitude bool
+	}
+)
+
+// DecodeModeAC decodes the 2 byte Mode A/C reply from a Beast 0x31 message
+func DecodeModeAC(msg []byte) (ModeAC, error) {
+	if len(msg) < 2 {
+		return ModeAC{}, fmt.Errorf("mode A/C reply is too short, %d bytes", len(msg))
+	}
+	code := uint16(msg[0])<<8 | uint16(msg[1])
+	ac := ModeAC{
+		Code:  code,
+		Ident: code&0x0080 != 0,
+	}
+	squawk := code & 0x7777
+	ac.Squawk = uint32(squawk>>12)*1000 + uint32(squawk>>8&0x7)*100 + uint32(squawk>>4&0x7)*10 + uint32(squawk&0x7)
+
+	// an ident pulse cannot be part of a Mode C reply
+	if !ac.Ident {
+		if n := modeAToModeC(int32(code)); -9999 != n {
+			ac.HasAltitude = true
+			ac.Altitude = n * 100
+		}
+	}
+	return ac, nil
+}
+
+func (ac ModeAC) String() string {
+	if ac.HasAltitude {
+		return fmt.Sprintf("Mode A/C %04X: squawk %04d or %d feet", ac.Code, ac.Squawk, ac.Altitude)
+	}
+	return fmt.Sprintf("Mode A/C %04X: squawk %04d", ac.Code, ac.Squawk)
+}
@@ -0,0 +1,40 @@
+package mode_s
+
+import "testing"
+
+func TestDecodeModeAC(t *testing.T) {
+	tests := []struct {
+		name        string
+		msg         []byte
+		squawk      uint32
+		ident       bool
+		hasAltitude bool
+		altitude    int32
+	}{
+		{name: "Emergency", msg: []byte{0x77, 0x00}, squawk: 7700},
+		{name: "Squawk or 10000 feet", msg: []byte{0x65, 0x20}, squawk: 6520, hasAltitude: true, altitude: 10000},
+		{name: "Squawk or 3500 feet", msg: []byte{0x45, 0x20}, squawk: 4520, hasAltitude: true, altitude: 3500},
+		{name: "Ident", msg: []byte{0x65, 0xA0}, squawk: 6520, ident: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ac, err := DecodeModeAC(tt.msg)
+			if nil != err {
+				t.Fatal(err)
+			}
+			if tt.squawk != ac.Squawk {
+				t.Errorf("Incorrect squawk. %04d != %04d", tt.squawk, ac.Squawk)
+			}
+			if tt.ident != ac.Ident {
+				t.Errorf("Incorrect ident. %t != %t", tt.ident, ac.Ident)
+			}
+			if tt.hasAltitude != ac.HasAltitude || tt.altitude != ac.Altitude {
+				t.Errorf("Incorrect altitude. %d != %d (%t)", tt.altitude, ac.Altitude, ac.HasAltitude)
+			}
+		})
+	}
+
+	if _, err := DecodeModeAC([]byte{0x77}); nil == err {
+		t.Error("Expected a short reply to fail")
+	}
+}
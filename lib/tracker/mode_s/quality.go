@@ -0,0 +1,194 @@
+package mode_s
+
+// ADS-B quality indicators change meaning between versions of the MOPS:
+//  version 0 (DO-260) only sends a Navigation Uncertainty Category (NUCp) implied by the position type code
+//  version 1 (DO-260A) adds NIC supplement A and sends NACp/SIL in the Aircraft Operational Status message
+//  version 2 (DO-260B) adds NIC supplements B and C and the SIL supplement
+
+// NavigationIntegrity works out the Navigation Integrity Category and the containment radius (Rc, in metres)
+// of a position message, taking into account the ADS-B version of the sender.
+// A containment radius of 0 means it is unknown
+func NavigationIntegrity(version, typeCode, nicA, nicB, nicC byte) (nic byte, rc float64) {
+	if 0 == version {
+		return nucPIntegrity(typeCode)
+	}
+	switch typeCode {
+	case 5:
+		return 11, 7.5
+	case 6:
+		return 10, 25
+	case 7:
+		if 2 == version {
+			if 1 == nicA && 0 == nicC {
+				return 9, 75
+			}
+			if 0 == nicA && 0 == nicC {
+				return 8, 185.2
+			}
+			return 0, 0
+		}
+		if 1 == nicA {
+			return 9, 75
+		}
+		return 8, 185.2
+	case 8:
+		if 2 == version {
+			switch {
+			case 1 == nicA && 1 == nicC:
+				return 7, 370.4
+			case 1 == nicA && 0 == nicC:
+				return 6, 555.6
+			case 0 == nicA && 1 == nicC:
+				return 6, 1111.2
+			}
+		}
+		return 0, 0
+	case 9, 20:
+		return 11, 7.5
+	case 10, 21:
+		return 10, 25
+	case 11:
+		// version 2 needs both supplements set for NIC 9
+		if 1 == nicA && (1 == version || 1 == nicB) {
+			return 9, 75
+		}
+		return 8, 185.2
+	case 12:
+		return 7, 370.4
+	case 13:
+		if 1 == version {
+			// version 1 has no NIC supplement B, so the 0.3 NM radius can not be signalled
+			if 1 == nicA {
+				return 6, 1111.2
+			}
+			return 6, 926
+		}
+		switch {
+		case 0 == nicA && 0 == nicB:
+			return 6, 926
+		case 0 == nicA && 1 == nicB:
+			return 6, 555.6
+		case 1 == nicA && 1 == nicB:
+			return 6, 1111.2
+		}
+		return 0, 0
+	case 14:
+		return 5, 1852
+	case 15:
+		return 4, 3704
+	case 16:
+		if 1 == nicA && (1 == version || 1 == nicB) {
+			return 3, 7408
+		}
+		return 2, 14816
+	case 17:
+		return 1, 37040
+	}
+	return 0, 0
+}
+
+// nucPIntegrity is the version 0 mapping of the position type code (NUCp) onto NIC
+func nucPIntegrity(typeCode byte) (byte, float64) {
+	switch typeCode {
+	case 5, 9, 20:
+		return 11, 7.5
+	case 6, 10, 21:
+		return 10, 25
+	case 7, 11:
+		return 8, 185.2
+	case 8, 12:
+		return 7, 370.4
+	case 13:
+		return 6, 926
+	case 14:
+		return 5, 1852
+	case 15:
+		return 4, 3704
+	case 16:
+		return 1, 18520
+	case 17:
+		return 1, 37040
+	}
+	return 0, 0
+}
+
+// NucPToNacP gives us the version 0 equivalent of a NACp from the position type code
+func NucPToNacP(typeCode byte) byte {
+	switch typeCode {
+	case 5, 9, 20:
+		return 11
+	case 6, 10, 21:
+		return 10
+	case 7, 11:
+		return 8
+	case 8, 12:
+		return 7
+	case 13:
+		return 6
+	case 14:
+		return 5
+	case 15:
+		return 4
+	case 16, 17:
+		return 1
+	}
+	return 0
+}
+
+// NacPUncertainty is the Estimated Position Uncertainty (EPU, in metres) for a NACp. 0 means unknown
+func NacPUncertainty(nacP byte) float64 {
+	switch nacP {
+	case 1:
+		return 18520
+	case 2:
+		return 7408
+	case 3:
+		return 3704
+	case 4:
+		return 1852
+	case 5:
+		return 926
+	case 6:
+		return 555.6
+	case 7:
+		return 185.2
+	case 8:
+		return 92.6
+	case 9:
+		return 30
+	case 10:
+		return 10
+	case 11:
+		return 3
+	}
+	return 0
+}
+
+// NacVUncertainty is the horizontal velocity error (in m/s) for a NACv. 0 means unknown
+func NacVUncertainty(nacV byte) float64 {
+	switch nacV {
+	case 1:
+		return 10
+	case 2:
+		return 3
+	case 3:
+		return 1
+	case 4:
+		return 0.3
+	}
+	return 0
+}
+
+// SilProbability is the probability of exceeding the containment radius without being alerted for a SIL.
+// 0 means unknown (or more than 1 in 1000)
+func SilProbability(sil byte) float64 {
+	switch sil {
+	case 1:
+		return 1e-3
+	case 2:
+		return 1e-5
+	case 3:
+		return 1e-7
+	}
+	return 0
+}
@@ -0,0 +1,44 @@
+package mode_s
+
+import "testing"
+
+func TestNavigationIntegrity(t *testing.T) {
+	tests := []struct {
+		name              string
+		version, typeCode byte
+		nicA, nicB, nicC  byte
+		nic               byte
+		rc                float64
+	}{
+		{name: "v0 TC9", version: 0, typeCode: 9, nic: 11, rc: 7.5},
+		{name: "v0 TC11 ignores supplements", version: 0, typeCode: 11, nicA: 1, nicB: 1, nic: 8, rc: 185.2},
+		{name: "v0 TC16", version: 0, typeCode: 16, nic: 1, rc: 18520},
+		{name: "v1 TC11 A", version: 1, typeCode: 11, nicA: 1, nic: 9, rc: 75},
+		{name: "v1 TC13 A", version: 1, typeCode: 13, nicA: 1, nic: 6, rc: 1111.2},
+		{name: "v1 TC16", version: 1, typeCode: 16, nic: 2, rc: 14816},
+		{name: "v2 TC11 A only", version: 2, typeCode: 11, nicA: 1, nic: 8, rc: 185.2},
+		{name: "v2 TC11 A and B", version: 2, typeCode: 11, nicA: 1, nicB: 1, nic: 9, rc: 75},
+		{name: "v2 TC13 B", version: 2, typeCode: 13, nicB: 1, nic: 6, rc: 555.6},
+		{name: "v2 TC13 A and B", version: 2, typeCode: 13, nicA: 1, nicB: 1, nic: 6, rc: 1111.2},
+		{name: "v2 TC13 A only", version: 2, typeCode: 13, nicA: 1, nic: 0, rc: 0},
+		{name: "v2 TC16 A and B", version: 2, typeCode: 16, nicA: 1, nicB: 1, nic: 3, rc: 7408},
+		{name: "v2 surface TC8 A and C", version: 2, typeCode: 8, nicA: 1, nicC: 1, nic: 7, rc: 370.4},
+		{name: "v2 TC18 unknown", version: 2, typeCode: 18, nic: 0, rc: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nic, rc := NavigationIntegrity(tt.version, tt.typeCode, tt.nicA, tt.nicB, tt.nicC)
+			if tt.nic != nic || tt.rc != rc {
+				t.Errorf("Expected NIC %d (Rc %0.1f), got NIC %d (Rc %0.1f)", tt.nic, tt.rc, nic, rc)
+			}
+		})
+	}
+}
+
+func TestNucPToNacP(t *testing.T) {
+	for typeCode, nacP := range map[byte]byte{9: 11, 11: 8, 13: 6, 17: 1, 18: 0} {
+		if got := NucPToNacP(typeCode); nacP != got {
+			t.Errorf("TC %d: expected NACp %d, got %d", typeCode, nacP, got)
+		}
+	}
+}
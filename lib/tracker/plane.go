@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"math"
 	"os"
+	"plane.watch/lib/tracker/mode_s"
 	"strings"
 	"sync"
 	"time"
@@ -26,6 +27,7 @@ type (
 
 		latitude, longitude  float64
 		altitude             int32
+		hasAltitude          bool
 		hasVerticalRate      bool
 		hasVelocity          bool
 		verticalRate         int
@@ -41,6 +43,86 @@ type (
 		gridTileLocation string
 	}
 
+	ehsInfo struct {
+		mcpSelectedAltitude  int32
+		fmsSelectedAltitude  int32
+		baroSetting          float64
+		rollAngle            float64
+		trueTrack            float64
+		trackRate            float64
+		groundSpeed          int
+		trueAirSpeed         int
+		magneticHeading      float64
+		indicatedAirSpeed    int
+		mach                 float64
+		baroVerticalRate     int
+		inertialVerticalRate int
+
+		hasMcpSelectedAltitude  bool
+		hasFmsSelectedAltitude  bool
+		hasBaroSetting          bool
+		hasRollAngle            bool
+		hasTrueTrack            bool
+		hasTrackRate            bool
+		hasGroundSpeed          bool
+		hasTrueAirSpeed         bool
+		hasMagneticHeading      bool
+		hasIndicatedAirSpeed    bool
+		hasMach                 bool
+		hasBaroVerticalRate     bool
+		hasInertialVerticalRate bool
+	}
+
+	// intentInfo is what the crew has asked the aircraft to do, from ADS-B Target State (TC 29) and EHS BDS 4,0.
+	// the selected altitudes and baro setting are kept in ehsInfo
+	intentInfo struct {
+		selectedHeading    float64
+		hasSelectedHeading bool
+
+		vnavMode, altHoldMode, approachMode bool
+		hasMcpModes                         bool
+
+		autopilotEngaged, lnavMode bool
+		hasAutopilotModes          bool
+	}
+
+	// qualityInfo is how much we can trust the position and velocity of the plane. The meaning of
+	// NIC and NACp changes with the ADS-B version, so we keep the latest version we have seen
+	qualityInfo struct {
+		adsbVersion    byte
+		hasAdsbVersion bool
+		nicSupplementA byte
+		nicSupplementC byte
+
+		nic               byte
+		containmentRadius float64
+		hasNic            bool
+
+		nacP         byte
+		hasNacP      bool
+		nacPFromNucP bool // a version 0 NACp, implied by the position type code
+
+		nacV    byte
+		hasNacV bool
+
+		sil           byte
+		silSupplement byte
+		hasSil        bool
+	}
+
+	// signalInfo is the signal strength (RSSI) of the frames we have received for this plane
+	signalInfo struct {
+		last, min, max float64 // dBFS
+		sumPower       float64 // linear, for the average
+		count          uint64
+	}
+
+	tcasInfo struct {
+		ra      mode_s.ResolutionAdvisory
+		hasRA   bool
+		updated time.Time
+	}
+
 	flight struct {
 		identifier string
 		status     string
@@ -51,6 +133,7 @@ type (
 		tracker          *Tracker
 		trackedSince     time.Time
 		lastSeen         time.Time
+		icaoConfirmed    time.Time // the last time we saw this ICAO in a frame that passed its CRC check
 		icaoIdentifier   uint32
 		icao             string
 		squawk           uint32
@@ -64,6 +147,14 @@ type (
 		msgCount         uint64
 		airframeCategory string
 		airframeType     string
+		ehs              ehsInfo
+		tcas             tcasInfo
+		intent           intentInfo
+		quality          qualityInfo
+		signal           signalInfo
+		addressType      byte
+		source           byte
+		heardBy          map[*FrameSource]time.Time // when each receiver last heard this plane
 
 		rwLock sync.RWMutex
 	}
@@ -133,6 +224,43 @@ func (p *Plane) setLastSeen(lastSeen time.Time) {
 	p.lastSeen = lastSeen
 }
 
+// setIcaoConfirmed records that we have seen this ICAO in a frame with a good checksum
+func (p *Plane) setIcaoConfirmed(t time.Time) {
+	p.rwLock.Lock()
+	defer p.rwLock.Unlock()
+	p.icaoConfirmed = t
+}
+
+// IcaoConfirmedSince tells us if we have seen this ICAO in a frame with a good checksum since the given time
+func (p *Plane) IcaoConfirmedSince(t time.Time) bool {
+	p.rwLock.RLock()
+	defer p.rwLock.RUnlock()
+	return !p.icaoConfirmed.IsZero() && !p.icaoConfirmed.Before(t)
+}
+
+// setHeardBy records that a receiver heard this plane, so we can match its Mode A/C replies
+func (p *Plane) setHeardBy(source *FrameSource, t time.Time) {
+	p.rwLock.Lock()
+	defer p.rwLock.Unlock()
+	if nil == p.heardBy {
+		p.heardBy = map[*FrameSource]time.Time{}
+	}
+	p.heardBy[source] = t
+}
+
+// heardBySince is every receiver that has heard this plane since the given time
+func (p *Plane) heardBySince(t time.Time) []*FrameSource {
+	p.rwLock.RLock()
+	defer p.rwLock.RUnlock()
+	var sources []*FrameSource
+	for source, when := range p.heardBy {
+		if !when.Before(t) {
+			sources = append(sources, source)
+		}
+	}
+	return sources
+}
+
 // MsgCount is the number of messages we have received from this plane while we have been tracking it
 func (p *Plane) MsgCount() uint64 {
 	p.rwLock.RLock()
@@ -166,7 +294,48 @@ func (p *Plane) setIcaoIdentifier(icaoIdentifier uint32) {
 	p.rwLock.Lock()
 	defer p.rwLock.Unlock()
 	p.icaoIdentifier = icaoIdentifier
-	p.icao = fmt.Sprintf("%06X", icaoIdentifier)
+	if icaoIdentifier&nonIcaoAddressFlag != 0 {
+		// the same way dump1090 shows non ICAO addresses
+		p.icao = fmt.Sprintf("~%06X", icaoIdentifier&0xFFFFFF)
+		p.addressType = mode_s.AddressTypeNonIcao
+	} else {
+		p.icao = fmt.Sprintf("%06X", icaoIdentifier)
+	}
+}
+
+// setAddressing records what sort of address this plane has and where we are hearing about it from.
+// A plane that squitters ADS-B also answers Mode S interrogations, so Mode S never replaces a better source
+func (p *Plane) setAddressing(addressType, source byte) {
+	p.rwLock.Lock()
+	defer p.rwLock.Unlock()
+	p.addressType = addressType
+	if mode_s.SourceModeS != source {
+		p.source = source
+	}
+}
+
+// AddressType is one of the mode_s.AddressType* constants
+func (p *Plane) AddressType() byte {
+	p.rwLock.RLock()
+	defer p.rwLock.RUnlock()
+	return p.addressType
+}
+
+// AddressTypeStr is a short name for the AddressType, e.g. "icao"
+func (p *Plane) AddressTypeStr() string {
+	return mode_s.AddressTypeString(p.AddressType())
+}
+
+// Source is where we last heard about this plane from, one of the mode_s.Source* constants
+func (p *Plane) Source() byte {
+	p.rwLock.RLock()
+	defer p.rwLock.RUnlock()
+	return p.source
+}
+
+// SourceStr is a short name for the Source, e.g. "tis-b"
+func (p *Plane) SourceStr() string {
+	return mode_s.SourceString(p.Source())
 }
 
 // resetLocationHistory Zeros out the tracking history for this aircraft
@@ -196,6 +365,13 @@ func (p *Plane) Special() string {
 	return strings.TrimSpace(ret)
 }
 
+// Emergency is the emergency state the aircraft is broadcasting (ADS-B TC 28), empty if we have not heard one
+func (p *Plane) Emergency() string {
+	p.rwLock.RLock()
+	defer p.rwLock.RUnlock()
+	return p.special["emergency"]
+}
+
 func haveTty() bool {
 	fi, err := os.Stdout.Stat()
 	if nil != err {
@@ -281,7 +457,8 @@ func (p *Plane) setAltitude(altitude int32, altitudeUnits string) bool {
 	p.rwLock.Lock()
 	defer p.rwLock.Unlock()
 	// set the current altitude
-	var hasChanged bool
+	hasChanged := !p.location.hasAltitude
+	p.location.hasAltitude = true
 	if p.location.altitude != altitude {
 		p.location.altitude = altitude
 		hasChanged = true
@@ -300,6 +477,13 @@ func (p *Plane) Altitude() int32 {
 	return p.location.altitude
 }
 
+// HasAltitude tells us if the plane has reported its Altitude (0 is a perfectly good altitude)
+func (p *Plane) HasAltitude() bool {
+	p.rwLock.RLock()
+	defer p.rwLock.RUnlock()
+	return p.location.hasAltitude
+}
+
 // AltitudeUnits how we are measuring altitude (feet / metres)
 func (p *Plane) AltitudeUnits() string {
 	p.rwLock.RLock()
@@ -601,15 +785,7 @@ func (p *Plane) addLatLong(lat, lon float64, ts time.Time) (warn error) {
 	p.location.longitude = lon
 	p.location.hasLatLon = true
 
-	needsLookup := true
-	if "" != p.location.gridTileLocation {
-		if InGridLocation(lat, lon, p.location.gridTileLocation) {
-			needsLookup = false
-		}
-	}
-	if needsLookup {
-		p.location.gridTileLocation = lookupTile(lat, lon)
-	}
+	p.location.gridTileLocation = lookupTile(lat, lon)
 	p.locationHistory = append(p.locationHistory, p.location.Copy())
 	return
 }
@@ -648,6 +824,20 @@ func (p *Plane) decodeCpr(refLat, refLon float64, ts time.Time) error {
 	return err
 }
 
+// decodeAirborneCpr decodes our latest airborne CPR frame, using the receiver location as a reference when we
+// do not have a recent position of our own. Positions that do not depend on the receiver location help the
+// source estimate where it is, even the ones too far from the receiver location for us to use
+func (p *Plane) decodeAirborneCpr(refLat, refLon *float64, source *FrameSource, ts time.Time) error {
+	loc, independent, err := p.cprLocation.decodeAir(refLat, refLon, source.localDecodeRange())
+	if independent && nil != loc {
+		source.addObservation(loc.latitude, loc.longitude)
+	}
+	if nil != err || loc == nil {
+		return err
+	}
+	return p.addLatLong(loc.latitude, loc.longitude, ts)
+}
+
 // LocationHistory returns the track history of the Plane
 func (p *Plane) LocationHistory() []*PlaneLocation {
 	p.rwLock.RLock()
@@ -714,6 +904,7 @@ func (pl *PlaneLocation) Copy() *PlaneLocation {
 		latitude:          pl.latitude,
 		longitude:         pl.longitude,
 		altitude:          pl.altitude,
+		hasAltitude:       pl.hasAltitude,
 		hasVerticalRate:   pl.hasVerticalRate,
 		verticalRate:      pl.verticalRate,
 		altitudeUnits:     pl.altitudeUnits,
@@ -742,3 +933,653 @@ func (pl *PlaneLocation) Lon() float64 {
 	defer pl.rwlock.RUnlock()
 	return pl.longitude
 }
+
+// TimeStamp is when we were at this location
+func (pl *PlaneLocation) TimeStamp() time.Time {
+	pl.rwlock.RLock()
+	defer pl.rwlock.RUnlock()
+	return pl.timeStamp
+}
+
+// setMcpSelectedAltitude records the altitude selected on the MCP/FCU, in feet
+func (p *Plane) setMcpSelectedAltitude(mcpSelectedAltitude int32) bool {
+	p.rwLock.Lock()
+	defer p.rwLock.Unlock()
+	hasChanged := !p.ehs.hasMcpSelectedAltitude || p.ehs.mcpSelectedAltitude != mcpSelectedAltitude
+	p.ehs.hasMcpSelectedAltitude = true
+	p.ehs.mcpSelectedAltitude = mcpSelectedAltitude
+	return hasChanged
+}
+
+// McpSelectedAltitude is the altitude selected on the MCP/FCU, in feet
+func (p *Plane) McpSelectedAltitude() int32 {
+	p.rwLock.RLock()
+	defer p.rwLock.RUnlock()
+	return p.ehs.mcpSelectedAltitude
+}
+
+// HasMcpSelectedAltitude tells us if the plane has reported its MCP/FCU selected altitude
+func (p *Plane) HasMcpSelectedAltitude() bool {
+	p.rwLock.RLock()
+	defer p.rwLock.RUnlock()
+	return p.ehs.hasMcpSelectedAltitude
+}
+
+// setFmsSelectedAltitude records the altitude selected in the FMS, in feet
+func (p *Plane) setFmsSelectedAltitude(fmsSelectedAltitude int32) bool {
+	p.rwLock.Lock()
+	defer p.rwLock.Unlock()
+	hasChanged := !p.ehs.hasFmsSelectedAltitude || p.ehs.fmsSelectedAltitude != fmsSelectedAltitude
+	p.ehs.hasFmsSelectedAltitude = true
+	p.ehs.fmsSelectedAltitude = fmsSelectedAltitude
+	return hasChanged
+}
+
+// FmsSelectedAltitude is the altitude selected in the FMS, in feet
+func (p *Plane) FmsSelectedAltitude() int32 {
+	p.rwLock.RLock()
+	defer p.rwLock.RUnlock()
+	return p.ehs.fmsSelectedAltitude
+}
+
+// HasFmsSelectedAltitude tells us if the plane has reported its FMS selected altitude
+func (p *Plane) HasFmsSelectedAltitude() bool {
+	p.rwLock.RLock()
+	defer p.rwLock.RUnlock()
+	return p.ehs.hasFmsSelectedAltitude
+}
+
+// setBaroSetting records the barometric pressure setting in millibars
+func (p *Plane) setBaroSetting(baroSetting float64) bool {
+	p.rwLock.Lock()
+	defer p.rwLock.Unlock()
+	hasChanged := !p.ehs.hasBaroSetting || p.ehs.baroSetting != baroSetting
+	p.ehs.hasBaroSetting = true
+	p.ehs.baroSetting = baroSetting
+	return hasChanged
+}
+
+// BaroSetting is the barometric pressure setting in millibars
+func (p *Plane) BaroSetting() float64 {
+	p.rwLock.RLock()
+	defer p.rwLock.RUnlock()
+	return p.ehs.baroSetting
+}
+
+// HasBaroSetting tells us if the plane has reported its barometric pressure setting
+func (p *Plane) HasBaroSetting() bool {
+	p.rwLock.RLock()
+	defer p.rwLock.RUnlock()
+	return p.ehs.hasBaroSetting
+}
+
+// setRollAngle records the roll angle in degrees, negative is left wing down
+func (p *Plane) setRollAngle(rollAngle float64) bool {
+	p.rwLock.Lock()
+	defer p.rwLock.Unlock()
+	hasChanged := !p.ehs.hasRollAngle || p.ehs.rollAngle != rollAngle
+	p.ehs.hasRollAngle = true
+	p.ehs.rollAngle = rollAngle
+	return hasChanged
+}
+
+// RollAngle is the roll angle in degrees, negative is left wing down
+func (p *Plane) RollAngle() float64 {
+	p.rwLock.RLock()
+	defer p.rwLock.RUnlock()
+	return p.ehs.rollAngle
+}
+
+// HasRollAngle tells us if the plane has reported its roll angle
+func (p *Plane) HasRollAngle() bool {
+	p.rwLock.RLock()
+	defer p.rwLock.RUnlock()
+	return p.ehs.hasRollAngle
+}
+
+// setTrueTrack records the true track angle in degrees
+func (p *Plane) setTrueTrack(trueTrack float64) bool {
+	p.rwLock.Lock()
+	defer p.rwLock.Unlock()
+	hasChanged := !p.ehs.hasTrueTrack || p.ehs.trueTrack != trueTrack
+	p.ehs.hasTrueTrack = true
+	p.ehs.trueTrack = trueTrack
+	return hasChanged
+}
+
+// TrueTrack is the true track angle in degrees
+func (p *Plane) TrueTrack() float64 {
+	p.rwLock.RLock()
+	defer p.rwLock.RUnlock()
+	return p.ehs.trueTrack
+}
+
+// HasTrueTrack tells us if the plane has reported its true track
+func (p *Plane) HasTrueTrack() bool {
+	p.rwLock.RLock()
+	defer p.rwLock.RUnlock()
+	return p.ehs.hasTrueTrack
+}
+
+// setTrackRate records the track angle rate in degrees per second
+func (p *Plane) setTrackRate(trackRate float64) bool {
+	p.rwLock.Lock()
+	defer p.rwLock.Unlock()
+	hasChanged := !p.ehs.hasTrackRate || p.ehs.trackRate != trackRate
+	p.ehs.hasTrackRate = true
+	p.ehs.trackRate = trackRate
+	return hasChanged
+}
+
+// TrackRate is the track angle rate in degrees per second
+func (p *Plane) TrackRate() float64 {
+	p.rwLock.RLock()
+	defer p.rwLock.RUnlock()
+	return p.ehs.trackRate
+}
+
+// HasTrackRate tells us if the plane has reported its track angle rate
+func (p *Plane) HasTrackRate() bool {
+	p.rwLock.RLock()
+	defer p.rwLock.RUnlock()
+	return p.ehs.hasTrackRate
+}
+
+// setGroundSpeed records the ground speed in knots, as reported by Comm-B
+func (p *Plane) setGroundSpeed(groundSpeed int) bool {
+	p.rwLock.Lock()
+	defer p.rwLock.Unlock()
+	hasChanged := !p.ehs.hasGroundSpeed || p.ehs.groundSpeed != groundSpeed
+	p.ehs.hasGroundSpeed = true
+	p.ehs.groundSpeed = groundSpeed
+	return hasChanged
+}
+
+// GroundSpeed is the ground speed in knots, as reported by Comm-B
+func (p *Plane) GroundSpeed() int {
+	p.rwLock.RLock()
+	defer p.rwLock.RUnlock()
+	return p.ehs.groundSpeed
+}
+
+// HasGroundSpeed tells us if the plane has reported its Comm-B ground speed
+func (p *Plane) HasGroundSpeed() bool {
+	p.rwLock.RLock()
+	defer p.rwLock.RUnlock()
+	return p.ehs.hasGroundSpeed
+}
+
+// setTrueAirSpeed records the true airspeed in knots
+func (p *Plane) setTrueAirSpeed(trueAirSpeed int) bool {
+	p.rwLock.Lock()
+	defer p.rwLock.Unlock()
+	hasChanged := !p.ehs.hasTrueAirSpeed || p.ehs.trueAirSpeed != trueAirSpeed
+	p.ehs.hasTrueAirSpeed = true
+	p.ehs.trueAirSpeed = trueAirSpeed
+	return hasChanged
+}
+
+// TrueAirSpeed is the true airspeed in knots
+func (p *Plane) TrueAirSpeed() int {
+	p.rwLock.RLock()
+	defer p.rwLock.RUnlock()
+	return p.ehs.trueAirSpeed
+}
+
+// HasTrueAirSpeed tells us if the plane has reported its true airspeed
+func (p *Plane) HasTrueAirSpeed() bool {
+	p.rwLock.RLock()
+	defer p.rwLock.RUnlock()
+	return p.ehs.hasTrueAirSpeed
+}
+
+// setMagneticHeading records the magnetic heading in degrees
+func (p *Plane) setMagneticHeading(magneticHeading float64) bool {
+	p.rwLock.Lock()
+	defer p.rwLock.Unlock()
+	hasChanged := !p.ehs.hasMagneticHeading || p.ehs.magneticHeading != magneticHeading
+	p.ehs.hasMagneticHeading = true
+	p.ehs.magneticHeading = magneticHeading
+	return hasChanged
+}
+
+// MagneticHeading is the magnetic heading in degrees
+func (p *Plane) MagneticHeading() float64 {
+	p.rwLock.RLock()
+	defer p.rwLock.RUnlock()
+	return p.ehs.magneticHeading
+}
+
+// HasMagneticHeading tells us if the plane has reported its magnetic heading
+func (p *Plane) HasMagneticHeading() bool {
+	p.rwLock.RLock()
+	defer p.rwLock.RUnlock()
+	return p.ehs.hasMagneticHeading
+}
+
+// setIndicatedAirSpeed records the indicated airspeed in knots
+func (p *Plane) setIndicatedAirSpeed(indicatedAirSpeed int) bool {
+	p.rwLock.Lock()
+	defer p.rwLock.Unlock()
+	hasChanged := !p.ehs.hasIndicatedAirSpeed || p.ehs.indicatedAirSpeed != indicatedAirSpeed
+	p.ehs.hasIndicatedAirSpeed = true
+	p.ehs.indicatedAirSpeed = indicatedAirSpeed
+	return hasChanged
+}
+
+// IndicatedAirSpeed is the indicated airspeed in knots
+func (p *Plane) IndicatedAirSpeed() int {
+	p.rwLock.RLock()
+	defer p.rwLock.RUnlock()
+	return p.ehs.indicatedAirSpeed
+}
+
+// HasIndicatedAirSpeed tells us if the plane has reported its indicated airspeed
+func (p *Plane) HasIndicatedAirSpeed() bool {
+	p.rwLock.RLock()
+	defer p.rwLock.RUnlock()
+	return p.ehs.hasIndicatedAirSpeed
+}
+
+// setMach records the mach number
+func (p *Plane) setMach(mach float64) bool {
+	p.rwLock.Lock()
+	defer p.rwLock.Unlock()
+	hasChanged := !p.ehs.hasMach || p.ehs.mach != mach
+	p.ehs.hasMach = true
+	p.ehs.mach = mach
+	return hasChanged
+}
+
+// Mach is the mach number
+func (p *Plane) Mach() float64 {
+	p.rwLock.RLock()
+	defer p.rwLock.RUnlock()
+	return p.ehs.mach
+}
+
+// HasMach tells us if the plane has reported its mach number
+func (p *Plane) HasMach() bool {
+	p.rwLock.RLock()
+	defer p.rwLock.RUnlock()
+	return p.ehs.hasMach
+}
+
+// setBaroVerticalRate records the barometric altitude rate in feet per minute
+func (p *Plane) setBaroVerticalRate(baroVerticalRate int) bool {
+	p.rwLock.Lock()
+	defer p.rwLock.Unlock()
+	hasChanged := !p.ehs.hasBaroVerticalRate || p.ehs.baroVerticalRate != baroVerticalRate
+	p.ehs.hasBaroVerticalRate = true
+	p.ehs.baroVerticalRate = baroVerticalRate
+	return hasChanged
+}
+
+// BaroVerticalRate is the barometric altitude rate in feet per minute
+func (p *Plane) BaroVerticalRate() int {
+	p.rwLock.RLock()
+	defer p.rwLock.RUnlock()
+	return p.ehs.baroVerticalRate
+}
+
+// HasBaroVerticalRate tells us if the plane has reported its barometric vertical rate
+func (p *Plane) HasBaroVerticalRate() bool {
+	p.rwLock.RLock()
+	defer p.rwLock.RUnlock()
+	return p.ehs.hasBaroVerticalRate
+}
+
+// setInertialVerticalRate records the inertial vertical velocity in feet per minute
+func (p *Plane) setInertialVerticalRate(inertialVerticalRate int) bool {
+	p.rwLock.Lock()
+	defer p.rwLock.Unlock()
+	hasChanged := !p.ehs.hasInertialVerticalRate || p.ehs.inertialVerticalRate != inertialVerticalRate
+	p.ehs.hasInertialVerticalRate = true
+	p.ehs.inertialVerticalRate = inertialVerticalRate
+	return hasChanged
+}
+
+// InertialVerticalRate is the inertial vertical velocity in feet per minute
+func (p *Plane) InertialVerticalRate() int {
+	p.rwLock.RLock()
+	defer p.rwLock.RUnlock()
+	return p.ehs.inertialVerticalRate
+}
+
+// HasInertialVerticalRate tells us if the plane has reported its inertial vertical rate
+func (p *Plane) HasInertialVerticalRate() bool {
+	p.rwLock.RLock()
+	defer p.rwLock.RUnlock()
+	return p.ehs.hasInertialVerticalRate
+}
+
+// setSelectedHeading records the heading (or track) the crew has selected
+func (p *Plane) setSelectedHeading(heading float64) bool {
+	p.rwLock.Lock()
+	defer p.rwLock.Unlock()
+	hasChanged := !p.intent.hasSelectedHeading || p.intent.selectedHeading != heading
+	p.intent.hasSelectedHeading = true
+	p.intent.selectedHeading = heading
+	return hasChanged
+}
+
+// SelectedHeading is the heading (or track) the crew has selected, in degrees
+func (p *Plane) SelectedHeading() float64 {
+	p.rwLock.RLock()
+	defer p.rwLock.RUnlock()
+	return p.intent.selectedHeading
+}
+
+// HasSelectedHeading tells us if the plane has reported its selected heading
+func (p *Plane) HasSelectedHeading() bool {
+	p.rwLock.RLock()
+	defer p.rwLock.RUnlock()
+	return p.intent.hasSelectedHeading
+}
+
+// setMcpModes records the VNAV, altitude hold and approach mode flags
+func (p *Plane) setMcpModes(vnav, altHold, approach bool) bool {
+	p.rwLock.Lock()
+	defer p.rwLock.Unlock()
+	hasChanged := !p.intent.hasMcpModes || p.intent.vnavMode != vnav || p.intent.altHoldMode != altHold || p.intent.approachMode != approach
+	p.intent.hasMcpModes = true
+	p.intent.vnavMode = vnav
+	p.intent.altHoldMode = altHold
+	p.intent.approachMode = approach
+	return hasChanged
+}
+
+// VnavMode tells us if the plane is in VNAV mode
+func (p *Plane) VnavMode() bool {
+	p.rwLock.RLock()
+	defer p.rwLock.RUnlock()
+	return p.intent.vnavMode
+}
+
+// AltHoldMode tells us if the plane is holding its altitude
+func (p *Plane) AltHoldMode() bool {
+	p.rwLock.RLock()
+	defer p.rwLock.RUnlock()
+	return p.intent.altHoldMode
+}
+
+// ApproachMode tells us if the plane is in approach mode
+func (p *Plane) ApproachMode() bool {
+	p.rwLock.RLock()
+	defer p.rwLock.RUnlock()
+	return p.intent.approachMode
+}
+
+// HasMcpModes tells us if the plane has reported its VNAV, altitude hold and approach modes
+func (p *Plane) HasMcpModes() bool {
+	p.rwLock.RLock()
+	defer p.rwLock.RUnlock()
+	return p.intent.hasMcpModes
+}
+
+// setAutopilotModes records the autopilot engaged and LNAV mode flags
+func (p *Plane) setAutopilotModes(autopilot, lnav bool) bool {
+	p.rwLock.Lock()
+	defer p.rwLock.Unlock()
+	hasChanged := !p.intent.hasAutopilotModes || p.intent.autopilotEngaged != autopilot || p.intent.lnavMode != lnav
+	p.intent.hasAutopilotModes = true
+	p.intent.autopilotEngaged = autopilot
+	p.intent.lnavMode = lnav
+	return hasChanged
+}
+
+// AutopilotEngaged tells us if the autopilot is flying the plane
+func (p *Plane) AutopilotEngaged() bool {
+	p.rwLock.RLock()
+	defer p.rwLock.RUnlock()
+	return p.intent.autopilotEngaged
+}
+
+// LnavMode tells us if the plane is in LNAV mode
+func (p *Plane) LnavMode() bool {
+	p.rwLock.RLock()
+	defer p.rwLock.RUnlock()
+	return p.intent.lnavMode
+}
+
+// HasAutopilotModes tells us if the plane has reported its autopilot and LNAV modes
+func (p *Plane) HasAutopilotModes() bool {
+	p.rwLock.RLock()
+	defer p.rwLock.RUnlock()
+	return p.intent.hasAutopilotModes
+}
+
+// setResolutionAdvisory records the latest TCAS Resolution Advisory this plane has told us about. wasActive
+// tells us if the one it replaces was in effect
+func (p *Plane) setResolutionAdvisory(ra mode_s.ResolutionAdvisory, t time.Time) (hasChanged, wasActive bool) {
+	p.rwLock.Lock()
+	defer p.rwLock.Unlock()
+	hasChanged = !p.tcas.hasRA || p.tcas.ra != ra
+	wasActive = p.tcas.hasRA && p.tcas.ra.Active()
+	p.tcas.hasRA = true
+	p.tcas.ra = ra
+	p.tcas.updated = t
+	return hasChanged, wasActive
+}
+
+// ResolutionAdvisory is the last TCAS Resolution Advisory we have seen for this plane
+func (p *Plane) ResolutionAdvisory() mode_s.ResolutionAdvisory {
+	p.rwLock.RLock()
+	defer p.rwLock.RUnlock()
+	return p.tcas.ra
+}
+
+// ResolutionAdvisoryTime is when we last saw a TCAS Resolution Advisory for this plane
+func (p *Plane) ResolutionAdvisoryTime() time.Time {
+	p.rwLock.RLock()
+	defer p.rwLock.RUnlock()
+	return p.tcas.updated
+}
+
+// HasResolutionAdvisory tells us if the plane has ever reported a TCAS Resolution Advisory
+func (p *Plane) HasResolutionAdvisory() bool {
+	p.rwLock.RLock()
+	defer p.rwLock.RUnlock()
+	return p.tcas.hasRA
+}
+
+// setAdsbVersion records the ADS-B version and NIC supplements from an Aircraft Operational Status message
+func (p *Plane) setAdsbVersion(version, nicSupplementA, nicSupplementC byte) bool {
+	p.rwLock.Lock()
+	defer p.rwLock.Unlock()
+	hasChanged := !p.quality.hasAdsbVersion || p.quality.adsbVersion != version
+	p.quality.hasAdsbVersion = true
+	p.quality.adsbVersion = version
+	p.quality.nicSupplementA = nicSupplementA
+	p.quality.nicSupplementC = nicSupplementC
+	return hasChanged
+}
+
+// AdsbVersion is the ADS-B version the plane is using, 0 = DO-260, 1 = DO-260A, 2 = DO-260B
+func (p *Plane) AdsbVersion() byte {
+	p.rwLock.RLock()
+	defer p.rwLock.RUnlock()
+	return p.quality.adsbVersion
+}
+
+// HasAdsbVersion tells us if the plane has told us which ADS-B version it uses
+func (p *Plane) HasAdsbVersion() bool {
+	p.rwLock.RLock()
+	defer p.rwLock.RUnlock()
+	return p.quality.hasAdsbVersion
+}
+
+// setNicFromPosition works out the NIC of a position message using what we know of the ADS-B version.
+// Until we have seen an Aircraft Operational Status message we treat the plane as version 0
+func (p *Plane) setNicFromPosition(typeCode, nicSupplementB byte) bool {
+	p.rwLock.Lock()
+	defer p.rwLock.Unlock()
+	q := &p.quality
+	nic, rc := mode_s.NavigationIntegrity(q.adsbVersion, typeCode, q.nicSupplementA, nicSupplementB, q.nicSupplementC)
+	hasChanged := !q.hasNic || q.nic != nic || q.containmentRadius != rc
+	q.hasNic = true
+	q.nic = nic
+	q.containmentRadius = rc
+	if 0 == q.adsbVersion && (!q.hasNacP || q.nacPFromNucP) {
+		// version 0 does not send a NACp, it is implied by the position type code
+		nacP := mode_s.NucPToNacP(typeCode)
+		hasChanged = hasChanged || !q.hasNacP || q.nacP != nacP
+		q.hasNacP = true
+		q.nacP = nacP
+		q.nacPFromNucP = true
+	}
+	return hasChanged
+}
+
+// Nic is the Navigation Integrity Category of the last position
+func (p *Plane) Nic() byte {
+	p.rwLock.RLock()
+	defer p.rwLock.RUnlock()
+	return p.quality.nic
+}
+
+// ContainmentRadius is the integrity containment radius (Rc) in metres of the last position, 0 is unknown
+func (p *Plane) ContainmentRadius() float64 {
+	p.rwLock.RLock()
+	defer p.rwLock.RUnlock()
+	return p.quality.containmentRadius
+}
+
+// HasNic tells us if we have worked out a NIC for this plane
+func (p *Plane) HasNic() bool {
+	p.rwLock.RLock()
+	defer p.rwLock.RUnlock()
+	return p.quality.hasNic
+}
+
+func (p *Plane) setNacP(nacP byte) bool {
+	p.rwLock.Lock()
+	defer p.rwLock.Unlock()
+	hasChanged := !p.quality.hasNacP || p.quality.nacP != nacP
+	p.quality.hasNacP = true
+	p.quality.nacP = nacP
+	p.quality.nacPFromNucP = false
+	return hasChanged
+}
+
+// NacP is the Navigation Accuracy Category for Position
+func (p *Plane) NacP() byte {
+	p.rwLock.RLock()
+	defer p.rwLock.RUnlock()
+	return p.quality.nacP
+}
+
+// HasNacP tells us if we know the NACp of this plane
+func (p *Plane) HasNacP() bool {
+	p.rwLock.RLock()
+	defer p.rwLock.RUnlock()
+	return p.quality.hasNacP
+}
+
+func (p *Plane) setNacV(nacV byte) bool {
+	p.rwLock.Lock()
+	defer p.rwLock.Unlock()
+	hasChanged := !p.quality.hasNacV || p.quality.nacV != nacV
+	p.quality.hasNacV = true
+	p.quality.nacV = nacV
+	return hasChanged
+}
+
+// NacV is the Navigation Accuracy Category for Velocity
+func (p *Plane) NacV() byte {
+	p.rwLock.RLock()
+	defer p.rwLock.RUnlock()
+	return p.quality.nacV
+}
+
+// HasNacV tells us if we know the NACv of this plane
+func (p *Plane) HasNacV() bool {
+	p.rwLock.RLock()
+	defer p.rwLock.RUnlock()
+	return p.quality.hasNacV
+}
+
+func (p *Plane) setSil(sil, silSupplement byte) bool {
+	p.rwLock.Lock()
+	defer p.rwLock.Unlock()
+	hasChanged := !p.quality.hasSil || p.quality.sil != sil || p.quality.silSupplement != silSupplement
+	p.quality.hasSil = true
+	p.quality.sil = sil
+	p.quality.silSupplement = silSupplement
+	return hasChanged
+}
+
+// Sil is the Source Integrity Level
+func (p *Plane) Sil() byte {
+	p.rwLock.RLock()
+	defer p.rwLock.RUnlock()
+	return p.quality.sil
+}
+
+// SilPerSample tells us if the SIL probability is per sample (true) or per hour (false)
+func (p *Plane) SilPerSample() bool {
+	p.rwLock.RLock()
+	defer p.rwLock.RUnlock()
+	return 1 == p.quality.silSupplement
+}
+
+// HasSil tells us if we know the SIL of this plane
+func (p *Plane) HasSil() bool {
+	p.rwLock.RLock()
+	defer p.rwLock.RUnlock()
+	return p.quality.hasSil
+}
+
+// setSignalLevel records the signal level (in dBFS) of a frame. Signal strength changes with every frame,
+// so this does not count as the plane changing
+func (p *Plane) setSignalLevel(dbfs float64) {
+	p.rwLock.Lock()
+	defer p.rwLock.Unlock()
+	if 0 == p.signal.count || dbfs < p.signal.min {
+		p.signal.min = dbfs
+	}
+	if 0 == p.signal.count || dbfs > p.signal.max {
+		p.signal.max = dbfs
+	}
+	p.signal.last = dbfs
+	p.signal.sumPower += math.Pow(10, dbfs/10)
+	p.signal.count++
+}
+
+// SignalLast is the signal level (dBFS) of the last frame we received from this plane
+func (p *Plane) SignalLast() float64 {
+	p.rwLock.RLock()
+	defer p.rwLock.RUnlock()
+	return p.signal.last
+}
+
+// SignalMin is the weakest signal level (dBFS) we have received from this plane
+func (p *Plane) SignalMin() float64 {
+	p.rwLock.RLock()
+	defer p.rwLock.RUnlock()
+	return p.signal.min
+}
+
+// SignalMax is the strongest signal level (dBFS) we have received from this plane
+func (p *Plane) SignalMax() float64 {
+	p.rwLock.RLock()
+	defer p.rwLock.RUnlock()
+	return p.signal.max
+}
+
+// SignalAverage is the average signal level (dBFS) of this plane. The average is of the signal power, not of the dBFS values
+func (p *Plane) SignalAverage() float64 {
+	p.rwLock.RLock()
+	defer p.rwLock.RUnlock()
+	if 0 == p.signal.count {
+		return 0
+	}
+	return 10 * math.Log10(p.signal.sumPower/float64(p.signal.count))
+}
+
+// HasSignal tells us if we have received a frame with a signal level for this plane
+func (p *Plane) HasSignal() bool {
+	p.rwLock.RLock()
+	defer p.rwLock.RUnlock()
+	return p.signal.count > 0
+}
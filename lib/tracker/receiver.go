@@ -0,0 +1,273 @@
+package tracker
+
+import (
+	"github.com/rs/zerolog/log"
+	"math"
+	"sync"
+)
+
+const (
+	// receiverSectors is how many bearings (from the estimated location) we split our observations into
+	receiverSectors = 8
+	// receiverMinObservations is how many positions we want before we are fully confident in our estimate
+	receiverMinObservations = 200
+	// receiverMaxObservations is when we start forgetting old observations, so the estimate can follow a receiver that moves
+	receiverMaxObservations = 20000
+	// receiverMinConfidence is how confident we need to be before we use our estimate for decoding
+	receiverMinConfidence = 0.5
+	// receiverDisagreeRange is the least distance (in metres) a configured reference can be from our estimate before
+	// we complain. The further a receiver can hear, the less sure of our estimate we are, see receiverDisagreeFraction
+	receiverDisagreeRange = 100 * 1000.0
+	// receiverDisagreeFraction is how far, as a fraction of the furthest position heard, a configured reference can
+	// be from our estimate before we complain
+	receiverDisagreeFraction = 0.5
+	// receiverCellSize is the size (in degrees) of the grid cells we count coverage in
+	receiverCellSize = 0.25
+	// receiverBalanceIterations is how many times we re-balance the estimate by sector
+	receiverBalanceIterations = 5
+)
+
+type (
+	// receiverLocation estimates where a receiver is from the positions it hears. Traffic is rarely even (think of a
+	// receiver on the coast), so instead of the centroid of every position heard we only count each grid cell once,
+	// and give every sector around our estimate an equal say. That is good enough for a reference position (local
+	// decodes only trust it out to the range we hear).
+	// Alongside it, we keep the furthest position heard in each sector as the receiver's range envelope
+	receiverLocation struct {
+		sync.Mutex
+
+		count float64
+		// the last position we heard in each cell
+		cells map[receiverCell]receiverSample
+		heard uint64
+
+		sectorCount [receiverSectors]float64
+		sectorRange [receiverSectors]float64
+
+		lat, lon, confidence float64
+		// estimate is only set once we are confident, it is never modified so we can hand out pointers into it
+		estimate *[2]float64
+
+		disagrees bool
+	}
+
+	// receiverCell is a receiverCellSize square of coverage
+	receiverCell struct {
+		lat, lon int32
+	}
+
+	// receiverSample is a position we heard, and which observation it was
+	receiverSample struct {
+		lat, lon float64
+		heard    uint64
+	}
+)
+
+// addObservation adds a position heard by this source to our estimate of where it is
+func (s *FrameSource) addObservation(lat, lon float64) {
+	if nil == s {
+		return
+	}
+	r := &s.location
+	r.Lock()
+	defer r.Unlock()
+
+	if r.count > 0 {
+		dist := distance(r.lat, r.lon, lat, lon)
+		sector := r.sector(lat, lon)
+		r.sectorCount[sector]++
+		if dist > r.sectorRange[sector] {
+			r.sectorRange[sector] = dist
+		}
+	}
+
+	r.count++
+	r.heard++
+	if r.count >= receiverMaxObservations {
+		r.count = r.count / 2
+		for i := range r.sectorCount {
+			r.sectorCount[i] /= 2
+			r.sectorRange[i] = 0
+		}
+		for cell, sample := range r.cells {
+			if r.heard-sample.heard > receiverMaxObservations {
+				delete(r.cells, cell)
+			}
+		}
+	}
+
+	if nil == r.cells {
+		r.cells = make(map[receiverCell]receiverSample)
+	}
+	cell := receiverCell{lat: int32(math.Floor(lat / receiverCellSize)), lon: int32(math.Floor(lon / receiverCellSize))}
+	_, known := r.cells[cell]
+	r.cells[cell] = receiverSample{lat: lat, lon: lon, heard: r.heard}
+	if !known {
+		// only new coverage moves our estimate
+		r.locate()
+	}
+
+	// we want plenty of observations, from all around us
+	var sectorsHeard float64
+	for _, c := range r.sectorCount {
+		if c > 0 {
+			sectorsHeard++
+		}
+	}
+	r.confidence = math.Min(1, r.count/receiverMinObservations) * sectorsHeard / receiverSectors
+
+	if r.confidence < receiverMinConfidence {
+		return
+	}
+	if nil == r.estimate {
+		// the first time we are confident enough to use it, so let the operator know where we think they are
+		log.Info().Str("source", s.OriginIdentifier).Float64("lat", r.lat).Float64("lon", r.lon).
+			Float64("confidence", r.confidence).Float64("range", r.furthest()).Msg("Estimated receiver location")
+	}
+	r.estimate = &[2]float64{r.lat, r.lon}
+
+	if nil != s.RefLat && nil != s.RefLon {
+		dist := distance(*s.RefLat, *s.RefLon, r.lat, r.lon)
+		disagreeRange := math.Max(receiverDisagreeRange, r.furthest()*receiverDisagreeFraction)
+		if dist > disagreeRange && !r.disagrees {
+			log.Warn().Str("source", s.OriginIdentifier).
+				Float64("refLat", *s.RefLat).Float64("refLon", *s.RefLon).
+				Float64("lat", r.lat).Float64("lon", r.lon).Float64("distance", dist).
+				Msg("Configured receiver location disagrees with the positions it hears")
+		}
+		r.disagrees = dist > disagreeRange
+	}
+}
+
+// sector is which of our sectors (by bearing from our estimate) a position is in
+func (r *receiverLocation) sector(lat, lon float64) int {
+	sector := int(bearing(r.lat, r.lon, lat, lon) / (360 / receiverSectors))
+	if sector >= receiverSectors {
+		sector = receiverSectors - 1
+	}
+	return sector
+}
+
+// locate works out our estimate from the positions we have heard, one per cell. We start from their centroid, then
+// move to the average of the centroids of each sector around us (so a busy sector cannot drag us towards it), a few
+// times over. The range envelope then starts again from the new estimate
+func (r *receiverLocation) locate() {
+	var x, y, z float64
+	for _, sample := range r.cells {
+		sx, sy, sz := unitVector(sample.lat, sample.lon)
+		x, y, z = x+sx, y+sy, z+sz
+	}
+	r.lat, r.lon = unitVectorLatLon(x, y, z)
+
+	for i := 0; i < receiverBalanceIterations; i++ {
+		var sums [receiverSectors][3]float64
+		for _, sample := range r.cells {
+			sector := r.sector(sample.lat, sample.lon)
+			sx, sy, sz := unitVector(sample.lat, sample.lon)
+			sums[sector][0] += sx
+			sums[sector][1] += sy
+			sums[sector][2] += sz
+		}
+		x, y, z = 0, 0, 0
+		for _, sum := range sums {
+			length := math.Sqrt(sum[0]*sum[0] + sum[1]*sum[1] + sum[2]*sum[2])
+			if 0 == length {
+				continue
+			}
+			x, y, z = x+sum[0]/length, y+sum[1]/length, z+sum[2]/length
+		}
+		r.lat, r.lon = unitVectorLatLon(x, y, z)
+	}
+
+	// our range envelope was measured from where we used to think we are
+	r.sectorRange = [receiverSectors]float64{}
+	for _, sample := range r.cells {
+		sector := r.sector(sample.lat, sample.lon)
+		r.sectorRange[sector] = math.Max(r.sectorRange[sector], distance(r.lat, r.lon, sample.lat, sample.lon))
+	}
+}
+
+// unitVector is a position as a unit vector, so we can average positions without caring about the date line
+func unitVector(lat, lon float64) (x, y, z float64) {
+	latRad := lat * math.Pi / 180
+	lonRad := lon * math.Pi / 180
+	return math.Cos(latRad) * math.Cos(lonRad), math.Cos(latRad) * math.Sin(lonRad), math.Sin(latRad)
+}
+
+// unitVectorLatLon is the lat/lon a (not necessarily unit length) vector points at
+func unitVectorLatLon(x, y, z float64) (lat, lon float64) {
+	return math.Atan2(z, math.Sqrt(x*x+y*y)) * 180 / math.Pi, math.Atan2(y, x) * 180 / math.Pi
+}
+
+// EstimatedLocation is where we think the receiver is, based on the positions it hears. confidence goes from 0
+// (no idea) to 1 (plenty of positions from all around)
+func (s *FrameSource) EstimatedLocation() (lat, lon, confidence float64) {
+	s.location.Lock()
+	defer s.location.Unlock()
+	return s.location.lat, s.location.lon, s.location.confidence
+}
+
+// EstimatedRange is the distance (in metres) to the furthest position this source has heard
+func (s *FrameSource) EstimatedRange() float64 {
+	s.location.Lock()
+	defer s.location.Unlock()
+	return s.location.furthest()
+}
+
+// furthest is the distance (in metres) to the furthest position heard in any sector
+func (r *receiverLocation) furthest() float64 {
+	var furthest float64
+	for _, sr := range r.sectorRange {
+		furthest = math.Max(furthest, sr)
+	}
+	return furthest
+}
+
+// localDecodeRange is how far (in metres) from our reference a position decoded locally against it can be. Once we
+// are confident in how far this source can hear, that is its range
+func (s *FrameSource) localDecodeRange() float64 {
+	if nil == s {
+		return cprReceiverLocalRange
+	}
+	s.location.Lock()
+	defer s.location.Unlock()
+	if s.location.confidence < receiverMinConfidence || s.location.disagrees {
+		return cprReceiverLocalRange
+	}
+	furthest := cprReceiverLocalRange
+	for _, r := range s.location.sectorRange {
+		furthest = math.Max(furthest, r+cprPositionSlack)
+	}
+	return math.Min(furthest, cprReceiverMaxLocalRange)
+}
+
+// ReferenceLocation is the location we use to decode positions from this source. That is the configured
+// refLat/refLon, or our estimate once we are confident in it
+func (s *FrameSource) ReferenceLocation() (*float64, *float64) {
+	if nil == s {
+		return nil, nil
+	}
+	if nil != s.RefLat && nil != s.RefLon {
+		return s.RefLat, s.RefLon
+	}
+	s.location.Lock()
+	defer s.location.Unlock()
+	if nil == s.location.estimate {
+		return nil, nil
+	}
+	return &s.location.estimate[0], &s.location.estimate[1]
+}
+
+// bearing is the initial bearing (0-360 degrees) from one point to another
+func bearing(lat1, lon1, lat2, lon2 float64) float64 {
+	la1 := lat1 * math.Pi / 180
+	la2 := lat2 * math.Pi / 180
+	dLon := (lon2 - lon1) * math.Pi / 180
+	y := math.Sin(dLon) * math.Cos(la2)
+	x := math.Cos(la1)*math.Sin(la2) - math.Sin(la1)*math.Cos(la2)*math.Cos(dLon)
+	b := math.Atan2(y, x) * 180 / math.Pi
+	if b < 0 {
+		b += 360
+	}
+	return b
+}
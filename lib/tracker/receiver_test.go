@@ -0,0 +1,164 @@
+package tracker
+
+import (
+	"math"
+	"plane.watch/lib/tracker/mode_s"
+	"testing"
+	"time"
+)
+
+// hearAround feeds the source positions in a ring around a point, as if it was the receiver
+func hearAround(s *FrameSource, lat, lon float64, num int) {
+	for i := 0; i < num; i++ {
+		angle := float64(i) * 2 * math.Pi / float64(num)
+		radius := 0.5 + float64(i%5)*0.3
+		s.addObservation(lat+radius*math.Cos(angle), lon+radius*math.Sin(angle)/math.Cos(lat*math.Pi/180))
+	}
+}
+
+func TestFrameSource_EstimatedLocation(t *testing.T) {
+	tests := []struct {
+		name               string
+		lat, lon           float64
+		observations       int
+		expectedConfidence float64
+		expectedReference  bool
+	}{
+		{name: "Perth", lat: -31.95, lon: 115.86, observations: 400, expectedConfidence: 1, expectedReference: true},
+		{name: "Fiji (date line)", lat: -17.75, lon: 179.9, observations: 400, expectedConfidence: 1, expectedReference: true},
+		{name: "Not enough", lat: -31.95, lon: 115.86, observations: 40, expectedConfidence: 0.2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &FrameSource{}
+			hearAround(s, tt.lat, tt.lon, tt.observations)
+
+			lat, lon, confidence := s.EstimatedLocation()
+			if d := distance(tt.lat, tt.lon, lat, lon); d > 10000 {
+				t.Errorf("Estimate {%0.4f,%0.4f} is %0.0fm from the receiver", lat, lon, d)
+			}
+			if math.Abs(tt.expectedConfidence-confidence) > 0.01 {
+				t.Errorf("Incorrect confidence. expected %0.2f, got %0.2f", tt.expectedConfidence, confidence)
+			}
+			refLat, refLon := s.ReferenceLocation()
+			if tt.expectedReference != (nil != refLat && nil != refLon) {
+				t.Errorf("Expected a reference location: %t", tt.expectedReference)
+			}
+			if r := s.EstimatedRange(); r < 150000 || r > 250000 {
+				t.Errorf("Incorrect range %0.0fm", r)
+			}
+			// until we are confident in the range we only trust local decodes close to the receiver
+			expectedLocalRange := cprReceiverLocalRange
+			if tt.expectedReference {
+				expectedLocalRange = s.EstimatedRange() + cprPositionSlack
+			}
+			if r := s.localDecodeRange(); expectedLocalRange != r {
+				t.Errorf("Incorrect local decode range. expected %0.0fm, got %0.0fm", expectedLocalRange, r)
+			}
+		})
+	}
+}
+
+func TestFrameSource_EstimatedLocationOneSided(t *testing.T) {
+	// a receiver on the coast, there is plenty of traffic over the land to the east but not much out to sea
+	lat, lon := -31.95, 115.86
+	s := &FrameSource{RefLat: &lat, RefLon: &lon}
+	var x, y, z float64
+	for i := 0; i < 4000; i++ {
+		angle := float64(i) * 2 * math.Pi / 4000
+		if math.Sin(angle) < 0 && 0 != i%20 {
+			continue
+		}
+		radius := 0.3 + float64(i%7)*0.35
+		obsLat, obsLon := lat+radius*math.Cos(angle), lon+radius*math.Sin(angle)/math.Cos(lat*math.Pi/180)
+		s.addObservation(obsLat, obsLon)
+
+		latRad, lonRad := obsLat*math.Pi/180, obsLon*math.Pi/180
+		x, y, z = x+math.Cos(latRad)*math.Cos(lonRad), y+math.Cos(latRad)*math.Sin(lonRad), z+math.Sin(latRad)
+	}
+
+	centroidLat, centroidLon := unitVectorLatLon(x, y, z)
+	if d := distance(lat, lon, centroidLat, centroidLon); d < cprReceiverLocalRange {
+		t.Fatalf("The centroid of what we heard should be well away from the receiver, it is only %0.0fm", d)
+	}
+	estLat, estLon, _ := s.EstimatedLocation()
+	if d := distance(lat, lon, estLat, estLon); d > cprReceiverLocalRange/2 {
+		t.Errorf("Estimate {%0.4f,%0.4f} is %0.0fm from the receiver", estLat, estLon, d)
+	}
+	if s.location.disagrees {
+		t.Error("The configured location is right, it should not disagree with our estimate")
+	}
+}
+
+func TestFrameSource_ConfiguredReference(t *testing.T) {
+	// a receiver configured for London that is really in Perth, every global decode is too far away to use but
+	// they still tell us the configured location is wrong
+	refLat, refLon := 51.47, -0.4543
+	source := &FrameSource{RefLat: &refLat, RefLon: &refLon}
+	trk := NewTracker(WithDecodeWorkerCount(1))
+	ts := time.Now()
+	for i := 0; i < 400; i++ {
+		angle := float64(i) * 2 * math.Pi / 400
+		radius := 0.5 + float64(i%5)*0.3
+		lat, lon := -31.95+radius*math.Cos(angle), 115.86+radius*math.Sin(angle)/math.Cos(-31.95*math.Pi/180)
+		for j, odd := range []bool{false, true} {
+			encoded, err := mode_s.EncodeExtendedSquitter(uint32(0x7C0000+i), mode_s.AdsbAirbornePosition{Altitude: 30000, Lat: lat, Lon: lon, Odd: odd})
+			if nil != err {
+				t.Fatal(err)
+			}
+			trk.decodingQueue <- NewFrameEvent(mode_s.NewFrame(encoded.Avr(), ts.Add(time.Duration(j)*time.Second)), source)
+		}
+	}
+	trk.Finish()
+	trk.decodingQueueWaiter.Wait()
+
+	lat, lon := source.ReferenceLocation()
+	if refLat != *lat || refLon != *lon {
+		t.Errorf("The configured reference should win, got {%0.4f,%0.4f}", *lat, *lon)
+	}
+	if !source.location.disagrees {
+		t.Error("Expected the configured reference to disagree with what we heard")
+	}
+	if estLat, estLon, _ := source.EstimatedLocation(); distance(-31.95, 115.86, estLat, estLon) > receiverDisagreeRange/2 {
+		t.Errorf("Expected to estimate the receiver in Perth, got {%0.4f,%0.4f}", estLat, estLon)
+	}
+	if r := source.localDecodeRange(); cprReceiverLocalRange != r {
+		t.Errorf("A reference we disagree with should not use our range, got %0.0fm", r)
+	}
+
+	var nilSource *FrameSource
+	if lat, lon = nilSource.ReferenceLocation(); nil != lat || nil != lon {
+		t.Error("A nil source has no reference")
+	}
+	if r := nilSource.localDecodeRange(); cprReceiverLocalRange != r {
+		t.Errorf("A nil source should use the unambiguous range, got %0.0fm", r)
+	}
+}
+
+func TestPlane_FeedsReceiverEstimate(t *testing.T) {
+	trk := NewTracker()
+	defer trk.Finish()
+	source := &FrameSource{}
+	p := trk.GetPlane(0x7C7DAA)
+
+	ts := time.Now()
+	for i, odd := range []bool{false, true, false} {
+		encoded, err := mode_s.EncodeExtendedSquitter(0x7C7DAA, mode_s.AdsbAirbornePosition{Altitude: 30000, Lat: -31.9403, Lon: 115.9669 + float64(i)*0.001, Odd: odd})
+		if nil != err {
+			t.Fatal(err)
+		}
+		frame, err := mode_s.DecodeBytes(encoded.Bytes(), ts.Add(time.Duration(i)*time.Second))
+		if nil != err {
+			t.Fatal(err)
+		}
+		refLat, refLon := source.ReferenceLocation()
+		p.handleModeSFrame(frame, refLat, refLon, source)
+	}
+	if !p.HasLocation() {
+		t.Fatal("Expected a location")
+	}
+	// the global decode and the local decode that follows it
+	if 2 != source.location.count {
+		t.Errorf("Expected 2 observations, got %0.0f", source.location.count)
+	}
+}
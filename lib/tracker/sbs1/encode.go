@@ -0,0 +1,135 @@
+package sbs1
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	sbsDateFormat = "2006/01/02"
+	sbsTimeFormat = "15:04:05.000"
+	sbsNumFields  = 22
+
+	// the session, aircraft and flight IDs are BaseStation database IDs, we do not have them so (like dump1090)
+	// we always send 1
+	sbsSessionId  = "1"
+	sbsAircraftId = "1"
+	sbsFlightId   = "1"
+)
+
+// Format writes this frame out as a BaseStation (SBS-1) line, including the trailing \r\n. Only the fields
+// that belong to the MsgType/TransmissionType are written, and only if we know them
+func (f *Frame) Format() string {
+	bits := make([]string, sbsNumFields)
+	bits[sbsMsgTypeField] = f.MsgType
+	bits[2] = sbsSessionId
+	bits[3] = sbsAircraftId
+	bits[5] = sbsFlightId
+
+	icao := fmt.Sprintf("%06X", f.IcaoInt&0xFFFFFF)
+	if f.NonIcao {
+		icao = "~" + icao
+	}
+	bits[sbsIcaoField] = icao
+
+	logged := f.Logged
+	if logged.IsZero() {
+		logged = f.Received
+	}
+	bits[sbsRecvDate] = f.Received.Format(sbsDateFormat)
+	bits[sbsRecvTime] = f.Received.Format(sbsTimeFormat)
+	bits[sbsRecvTime+1] = logged.Format(sbsDateFormat)
+	bits[sbsRecvTime+2] = logged.Format(sbsTimeFormat)
+
+	switch f.MsgType {
+	case "ID":
+		bits[sbsCallsignField] = f.CallSign
+		return strings.Join(bits[:sbsCallsignField+1], ",") + "\r\n"
+	case "STA":
+		bits[sbsCallsignField] = f.Status
+		return strings.Join(bits[:sbsCallsignField+1], ",") + "\r\n"
+	case "MSG":
+	default:
+		// AIR, SEL, CLK
+		return strings.Join(bits[:sbsCallsignField], ",") + "\r\n"
+	}
+
+	bits[sbsMsgSubCatField] = strconv.Itoa(f.TransmissionType)
+
+	altitude := func() {
+		if f.HasAltitude {
+			bits[sbsAltitudeField] = strconv.Itoa(f.Altitude)
+		}
+	}
+	position := func() {
+		if f.HasPosition {
+			bits[sbsLatField] = strconv.FormatFloat(f.Lat, 'f', 5, 64)
+			bits[sbsLonField] = strconv.FormatFloat(f.Lon, 'f', 5, 64)
+		}
+	}
+	velocity := func() {
+		if f.HasGroundSpeed {
+			bits[sbsGroundSpeedField] = strconv.Itoa(f.GroundSpeed)
+		}
+		if f.HasTrack {
+			bits[sbsTrackField] = strconv.FormatFloat(f.Track, 'f', 0, 64)
+		}
+	}
+	onGround := func() {
+		if f.HasOnGround {
+			bits[sbsOnGroundField] = Flag(f.OnGround)
+		}
+	}
+
+	switch f.TransmissionType {
+	case 1: // ES Identification and Category
+		bits[sbsCallsignField] = f.CallSign
+	case 2: // ES Surface Position Message
+		altitude()
+		velocity()
+		position()
+		onGround()
+	case 3: // ES Airborne Position Message
+		altitude()
+		position()
+		bits[sbsAlertSquawkField] = f.Alert
+		bits[sbsEmergencyField] = f.Emergency
+		bits[sbsSpiIdentField] = f.Spi
+		onGround()
+	case 4: // ES Airborne velocity Message
+		velocity()
+		if f.HasVerticalRate {
+			bits[sbsVerticalRateField] = strconv.Itoa(f.VerticalRate)
+		}
+		onGround()
+	case 5: // Surveillance Alt Message
+		bits[sbsCallsignField] = f.CallSign
+		altitude()
+		bits[sbsAlertSquawkField] = f.Alert
+		bits[sbsSpiIdentField] = f.Spi
+		onGround()
+	case 6: // Surveillance ID Message
+		bits[sbsCallsignField] = f.CallSign
+		altitude()
+		bits[sbsSquawkField] = f.Squawk
+		bits[sbsAlertSquawkField] = f.Alert
+		bits[sbsEmergencyField] = f.Emergency
+		bits[sbsSpiIdentField] = f.Spi
+		onGround()
+	case 7: // Air To Air Message
+		altitude()
+		onGround()
+	case 8: // All Call Reply
+		onGround()
+	}
+	return strings.Join(bits, ",") + "\r\n"
+}
+
+// Flag is how BaseStation writes a boolean, "-1" (true) or "0" (false). For Alert, Emergency and Spi
+func Flag(b bool) string {
+	if b {
+		return "-1"
+	}
+	return "0"
+}
@@ -0,0 +1,93 @@
+package sbs1
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFrame_Format(t *testing.T) {
+	when := time.Date(2021, 9, 12, 6, 7, 8, 123000000, time.UTC)
+	tests := []struct {
+		name  string
+		frame Frame
+		want  string
+	}{
+		{
+			name:  "AIR",
+			frame: Frame{MsgType: "AIR", IcaoInt: 0x7C7DAA, Received: when},
+			want:  "AIR,,1,1,7C7DAA,1,2021/09/12,06:07:08.123,2021/09/12,06:07:08.123\r\n",
+		},
+		{
+			name:  "ID",
+			frame: Frame{MsgType: "ID", IcaoInt: 0x7C7DAA, Received: when, CallSign: "QFA123"},
+			want:  "ID,,1,1,7C7DAA,1,2021/09/12,06:07:08.123,2021/09/12,06:07:08.123,QFA123\r\n",
+		},
+		{
+			name:  "STA",
+			frame: Frame{MsgType: "STA", IcaoInt: 0x7C7DAA, Received: when, Logged: when.Add(time.Second), Status: "RM"},
+			want:  "STA,,1,1,7C7DAA,1,2021/09/12,06:07:08.123,2021/09/12,06:07:09.123,RM\r\n",
+		},
+		{
+			name:  "Identification",
+			frame: Frame{MsgType: "MSG", TransmissionType: 1, IcaoInt: 0x7C7DAA, Received: when, CallSign: "QFA123"},
+			want:  "MSG,1,1,1,7C7DAA,1,2021/09/12,06:07:08.123,2021/09/12,06:07:08.123,QFA123,,,,,,,,,,,\r\n",
+		},
+		{
+			name: "Airborne position",
+			frame: Frame{MsgType: "MSG", TransmissionType: 3, IcaoInt: 0x7C7DAA, Received: when,
+				Altitude: 35000, HasAltitude: true, Lat: -31.95, Lon: 115.94, HasPosition: true,
+				Alert: "0", Emergency: "0", Spi: "0", HasOnGround: true},
+			want: "MSG,3,1,1,7C7DAA,1,2021/09/12,06:07:08.123,2021/09/12,06:07:08.123,,35000,,,-31.95000,115.94000,,,0,0,0,0\r\n",
+		},
+		{
+			name: "Airborne position, no location",
+			frame: Frame{MsgType: "MSG", TransmissionType: 3, IcaoInt: 0x7C7DAA, Received: when,
+				Altitude: 35000, HasAltitude: true},
+			want: "MSG,3,1,1,7C7DAA,1,2021/09/12,06:07:08.123,2021/09/12,06:07:08.123,,35000,,,,,,,,,,\r\n",
+		},
+		{
+			name: "Velocity",
+			frame: Frame{MsgType: "MSG", TransmissionType: 4, IcaoInt: 0x7C7DAA, Received: when,
+				GroundSpeed: 401, HasGroundSpeed: true, Track: 90.2, HasTrack: true, VerticalRate: -640, HasVerticalRate: true},
+			want: "MSG,4,1,1,7C7DAA,1,2021/09/12,06:07:08.123,2021/09/12,06:07:08.123,,,401,90,,,-640,,,,,\r\n",
+		},
+		{
+			name: "Surveillance ID, on the ground",
+			frame: Frame{MsgType: "MSG", TransmissionType: 6, IcaoInt: 0x7C7DAA, Received: when,
+				Squawk: "7700", Alert: "-1", Emergency: "-1", Spi: "0", OnGround: true, HasOnGround: true},
+			want: "MSG,6,1,1,7C7DAA,1,2021/09/12,06:07:08.123,2021/09/12,06:07:08.123,,,,,,,,7700,-1,-1,0,-1\r\n",
+		},
+		{
+			name:  "All call, non ICAO",
+			frame: Frame{MsgType: "MSG", TransmissionType: 8, IcaoInt: 0x7C7DAA, NonIcao: true, Received: when},
+			want:  "MSG,8,1,1,~7C7DAA,1,2021/09/12,06:07:08.123,2021/09/12,06:07:08.123,,,,,,,,,,,,\r\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.frame.Format(); got != tt.want {
+				t.Errorf("Format() =\n%q, want\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFrame_FormatParse(t *testing.T) {
+	when := time.Date(2021, 9, 12, 6, 7, 8, 123000000, time.UTC)
+	out := Frame{MsgType: "MSG", TransmissionType: 3, IcaoInt: 0x7C7DAA, Received: when,
+		Altitude: 35000, HasAltitude: true, Lat: -31.95, Lon: 115.94, HasPosition: true, HasOnGround: true}
+
+	in := NewFrame(out.Format()[:len(out.Format())-2])
+	if err := in.Parse(); nil != err {
+		t.Fatal(err)
+	}
+	if "MSG" != in.MsgType || 3 != in.TransmissionType || 0x7C7DAA != in.Icao() || 35000 != in.Altitude {
+		t.Errorf("Incorrect frame %+v", in)
+	}
+	if !in.HasPosition || -31.95 != float32(in.Lat) || 115.94 != float32(in.Lon) || in.OnGround {
+		t.Errorf("Incorrect position %f,%f (on ground %t)", in.Lat, in.Lon, in.OnGround)
+	}
+	if !when.Equal(in.TimeStamp()) {
+		t.Errorf("Incorrect time %s", in.TimeStamp())
+	}
+}
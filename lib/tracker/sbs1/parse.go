@@ -47,6 +47,19 @@ type Frame struct {
 	OnGround     bool
 
 	HasPosition bool
+
+	// TransmissionType is the MSG sub type, 1 to 8
+	TransmissionType int
+	Spi              string
+	// Status is the STA status, e.g. "RM" when the aircraft has been removed
+	Status string
+	// Logged is when we logged the message, Received is when it was generated. Logged defaults to Received
+	Logged time.Time
+	// NonIcao addresses are written with a ~ in front of them
+	NonIcao bool
+
+	// which of the optional fields we know, when writing out
+	HasAltitude, HasGroundSpeed, HasTrack, HasVerticalRate, HasOnGround bool
 }
 
 func NewFrame(sbsString string) *Frame {
@@ -97,6 +110,7 @@ func (f *Frame) Parse() error {
 	// 	OK (used to reset time-outs if aircraft returns into cover).
 	case "CLK": // CLICK
 	case "MSG": // TRANSMISSION
+		f.TransmissionType, _ = strconv.Atoi(bits[sbsMsgSubCatField])
 		switch bits[sbsMsgSubCatField] {
 		case "1": // ES Identification and Category
 			f.CallSign = bits[sbsCallsignField]
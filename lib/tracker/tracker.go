@@ -3,14 +3,21 @@ package tracker
 import (
 	"fmt"
 	"github.com/rs/zerolog/log"
+	"plane.watch/lib/tracker/beast"
 	"plane.watch/lib/tracker/mode_s"
 	"plane.watch/lib/tracker/sbs1"
+	"runtime"
 	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
 )
 
+const (
+	// nonIcaoAddressFlag is set on the plane key of targets that do not have a real ICAO address
+	nonIcaoAddressFlag = 1 << 24
+)
+
 type (
 	Tracker struct {
 		planeList sync.Map
@@ -23,6 +30,8 @@ type (
 		producers   []Producer
 		middlewares []Middleware
 		sinks       []Sink
+		// decodedFrames is set once a sink wants a DecodedFrameEvent for every Mode S frame
+		decodedFrames bool
 
 		producerWaiter   sync.WaitGroup
 		middlewareWaiter sync.WaitGroup
@@ -38,23 +47,37 @@ type (
 
 		pruneExitChan chan bool
 
-		startTime time.Time
-		numFrames uint64
+		// Mode A/C replies, keyed by the Mode A/C code
+		modeACLock    sync.Mutex
+		modeACTargets map[modeACKey]*ModeACTarget
+
+		// icaoConfirmTimeout is how long an ICAO stays confirmed after a checksummed frame
+		icaoConfirmTimeout time.Duration
+		// errorCorrection is how hard we try to fix frames with a bad checksum, see mode_s.Correction*
+		errorCorrection int
+
+		startTime          time.Time
+		numFrames          uint64
+		numCorrectedFrames uint64
+		numRejectedFrames  uint64
+		numTisbManagement  uint64
 	}
 )
 
 // NewTracker creates a new tracker with which we can populate with plane tracking data
 func NewTracker(opts ...Option) *Tracker {
 	t := &Tracker{
-		producers:         []Producer{},
-		middlewares:       []Middleware{},
-		decodeWorkerCount: 5,
-		pruneTick:         10 * time.Second,
-		pruneAfter:        5 * time.Minute,
-		decodingQueue:     make(chan *FrameEvent, 1000), // a nice deep buffer
-		events:            make(chan Event, 10000),
-		eventsOpen:        true,
-		pruneExitChan:     make(chan bool),
+		producers:          []Producer{},
+		middlewares:        []Middleware{},
+		decodeWorkerCount:  runtime.NumCPU(),
+		pruneTick:          10 * time.Second,
+		pruneAfter:         5 * time.Minute,
+		icaoConfirmTimeout: time.Minute,
+		decodingQueue:      make(chan *FrameEvent, 1000), // a nice deep buffer
+		events:             make(chan Event, 10000),
+		eventsOpen:         true,
+		pruneExitChan:      make(chan bool),
+		modeACTargets:      map[modeACKey]*ModeACTarget{},
 
 		startTime: time.Now(),
 	}
@@ -64,6 +87,7 @@ func NewTracker(opts ...Option) *Tracker {
 	}
 
 	// Process our event queue and send them to all the Sinks that are currently listening to us
+	t.eventsWaiter.Add(1)
 	go t.processEvents()
 
 	t.decodingQueueWaiter.Add(t.decodeWorkerCount)
@@ -116,7 +140,29 @@ func (t *Tracker) EachPlane(pi PlaneIterator) {
 	})
 }
 
+// planeKey is the key we track a plane under. Non ICAO addresses (anonymous, TIS-B track numbers) share the
+// same 24 bit space as real airframes, so they get a bit of their own to keep them apart
+func planeKey(frame Frame) uint32 {
+	var modeS *mode_s.Frame
+	switch frame.(type) {
+	case *beast.Frame:
+		modeS = frame.(*beast.Frame).AvrFrame()
+	case *mode_s.Frame:
+		modeS = frame.(*mode_s.Frame)
+	}
+	if nil != modeS && mode_s.AddressTypeNonIcao == modeS.AddressType() {
+		return frame.Icao() | nonIcaoAddressFlag
+	}
+	return frame.Icao()
+}
+
 func (p *Plane) HandleModeSFrame(frame *mode_s.Frame, refLat, refLon *float64) {
+	p.handleModeSFrame(frame, refLat, refLon, nil)
+}
+
+// handleModeSFrame does the work for HandleModeSFrame, positions we decode are fed back into the source
+// so it can work out where it is
+func (p *Plane) handleModeSFrame(frame *mode_s.Frame, refLat, refLon *float64, source *FrameSource) {
 	if nil == frame {
 		return
 	}
@@ -129,19 +175,31 @@ func (p *Plane) HandleModeSFrame(frame *mode_s.Frame, refLat, refLon *float64) {
 
 	p.setLastSeen(frame.TimeStamp())
 	p.incMsgCount()
+	p.setAddressing(frame.AddressType(), frame.Source())
+	if frame.ChecksumPassed() {
+		p.setIcaoConfirmed(frame.TimeStamp())
+	}
+	if nil != source {
+		p.setHeardBy(source, frame.TimeStamp())
+	}
 
 	debugMessage := func(sfmt string, a ...interface{}) {
+		if !log.Debug().Enabled() {
+			// skip the formatting, this gets called for every frame
+			return
+		}
 		planeFormat = fmt.Sprintf("DF%02d - \033[0;97mPlane (\033[38;5;118m%s %-8s\033[0;97m)", frame.DownLinkType(), p.IcaoIdentifierStr(), p.FlightNumber())
 		p.tracker.debugMessage(planeFormat+sfmt, a...)
 	}
 
-	log.Trace().
-		Str("frame", frame.String()).
-		Str("icao", frame.IcaoStr()).
-		Str("Downlink Type", "DF"+strconv.Itoa(int(frame.DownLinkType()))).
-		Int("Downlink Format", int(frame.DownLinkType())).
-		Str("DF17 Msg Type", frame.MessageTypeString()).
-		Send()
+	if e := log.Trace(); e.Enabled() {
+		e.Str("frame", frame.String()).
+			Str("icao", frame.IcaoStr()).
+			Str("Downlink Type", "DF"+strconv.Itoa(int(frame.DownLinkType()))).
+			Int("Downlink Format", int(frame.DownLinkType())).
+			Str("DF17 Msg Type", frame.MessageTypeString()).
+			Send()
+	}
 
 	// determine what to do with our given frame
 	switch frame.DownLinkType() {
@@ -149,44 +207,44 @@ func (p *Plane) HandleModeSFrame(frame *mode_s.Frame, refLat, refLon *float64) {
 		// grab the altitude
 		if frame.AltitudeValid() {
 			alt, _ := frame.Altitude()
-			hasChanged = hasChanged || p.setAltitude(alt, frame.AltitudeUnits())
+			hasChanged = p.setAltitude(alt, frame.AltitudeUnits()) || hasChanged
 		}
 		if frame.VerticalStatusValid() {
-			hasChanged = hasChanged || p.setGroundStatus(frame.MustOnGround())
+			hasChanged = p.setGroundStatus(frame.MustOnGround()) || hasChanged
 		}
 		p.setLocationUpdateTime(frame.TimeStamp())
 		debugMessage(" is at %d %s \033[0m", p.Altitude(), p.AltitudeUnits())
 
 	case 1, 2, 3:
 		if frame.VerticalStatusValid() {
-			hasChanged = hasChanged || p.setGroundStatus(frame.MustOnGround())
+			hasChanged = p.setGroundStatus(frame.MustOnGround()) || hasChanged
 		}
 		p.setLocationUpdateTime(frame.TimeStamp())
 		if frame.Alert() {
-			hasChanged = hasChanged || p.setSpecial("alert", "Alert")
+			hasChanged = p.setSpecial("alert", "Alert") || hasChanged
 		}
 	case 6, 7, 8, 9, 10, 12, 13, 14, 15, 22, 23, 24, 25, 26, 27, 28, 29, 30, 31:
 		debugMessage(" \033[38;5;52mIgnoring Mode S Frame: %d (%s)\033[0m\n", frame.DownLinkType(), frame.DownLinkFormat())
 		break
 	case 11:
 		if frame.VerticalStatusValid() {
-			hasChanged = hasChanged || p.setGroundStatus(frame.MustOnGround())
+			hasChanged = p.setGroundStatus(frame.MustOnGround()) || hasChanged
 		}
 	case 4, 5:
 		if frame.VerticalStatusValid() {
-			hasChanged = hasChanged || p.setGroundStatus(frame.MustOnGround())
+			hasChanged = p.setGroundStatus(frame.MustOnGround()) || hasChanged
 		}
 		if frame.Alert() {
-			hasChanged = hasChanged || p.setSpecial("alert", "Alert")
+			hasChanged = p.setSpecial("alert", "Alert") || hasChanged
 		}
 		if frame.AltitudeValid() {
 			alt, _ := frame.Altitude()
-			hasChanged = hasChanged || p.setAltitude(alt, frame.AltitudeUnits())
+			hasChanged = p.setAltitude(alt, frame.AltitudeUnits()) || hasChanged
 		}
-		hasChanged = hasChanged || p.setFlightStatus(frame.FlightStatus(), frame.FlightStatusString())
+		hasChanged = p.setFlightStatus(frame.FlightStatus(), frame.FlightStatusString()) || hasChanged
 
 		if 5 == frame.DownLinkType() { // || 21 == frame.DownLinkType()
-			hasChanged = hasChanged || p.setSquawkIdentity(frame.SquawkIdentity())
+			hasChanged = p.setSquawkIdentity(frame.SquawkIdentity()) || hasChanged
 		}
 
 		p.setLocationUpdateTime(frame.TimeStamp())
@@ -197,43 +255,74 @@ func (p *Plane) HandleModeSFrame(frame *mode_s.Frame, refLat, refLon *float64) {
 	case 16:
 		if frame.AltitudeValid() {
 			alt, _ := frame.Altitude()
-			hasChanged = hasChanged || p.setAltitude(alt, frame.AltitudeUnits())
+			hasChanged = p.setAltitude(alt, frame.AltitudeUnits()) || hasChanged
 		}
 		if frame.VerticalStatusValid() {
-			hasChanged = hasChanged || p.setGroundStatus(frame.MustOnGround())
+			hasChanged = p.setGroundStatus(frame.MustOnGround()) || hasChanged
 		}
+		hasChanged = p.handleResolutionAdvisory(frame) || hasChanged
 		p.setLocationUpdateTime(frame.TimeStamp())
 
 	case 17, 18: // ADS-B
+		if mode_s.MessageKindTisbCoarsePos == frame.MessageKind() {
+			// not in the DF17 ME layout, but it is an airborne position with a track and speed
+			hasChanged = p.setGroundStatus(frame.MustOnGround()) || hasChanged
+			if frame.AltitudeValid() {
+				hasChanged = p.setAltitude(frame.MustAltitude(), frame.AltitudeUnits()) || hasChanged
+			}
+			if frame.HeadingValid() {
+				hasChanged = p.setHeading(frame.MustHeading()) || hasChanged
+			}
+			if frame.VelocityValid() {
+				hasChanged = p.setVelocity(frame.MustVelocity()) || hasChanged
+			}
+			p.setLocationUpdateTime(frame.TimeStamp())
+			if frame.IsEven() {
+				_ = p.setCprEvenLocation(float64(frame.Latitude()), float64(frame.Longitude()), frame.TimeStamp())
+			} else {
+				_ = p.setCprOddLocation(float64(frame.Latitude()), float64(frame.Longitude()), frame.TimeStamp())
+			}
+			if err := p.decodeAirborneCpr(refLat, refLon, source, frame.TimeStamp()); nil != err {
+				debugMessage("%s", err)
+			} else {
+				hasChanged = true
+			}
+			debugMessage(" %s (SVID %d)", frame.MessageKind(), frame.TisbServiceVolume())
+			break
+		}
+		if !frame.AdsbValid() {
+			debugMessage(" \033[38;5;52mIgnoring DF18 Frame: %s\033[0m\n", frame.ControlFieldString())
+			break
+		}
 		//if debug {
 		//	frame.Describe(os.Stdout)
 		//}
 
-		// i am using the text version because it is easier to program with.
-		// if performance is an issue, change over to byte comparing
-		messageType := frame.MessageTypeString()
-		switch messageType {
-		case mode_s.DF17FrameIdCat: // "Aircraft Identification and Category"
+		messageKind := frame.MessageKind()
+		switch messageKind {
+		case mode_s.MessageKindIdCat: // "Aircraft Identification and Category"
 			{
-				hasChanged = hasChanged || p.setFlightNumber(frame.FlightNumber())
+				hasChanged = p.setFlightNumber(frame.FlightNumber()) || hasChanged
 				if frame.ValidCategory() {
-					hasChanged = hasChanged || p.setAirFrameCategory(frame.Category())
-					hasChanged = hasChanged || p.setAirFrameCategoryType(frame.CategoryType())
+					hasChanged = p.setAirFrameCategory(frame.Category()) || hasChanged
+					hasChanged = p.setAirFrameCategoryType(frame.CategoryType()) || hasChanged
 				}
 				break
 			}
-		case mode_s.DF17FrameSurfacePos: // "Surface Position"
+		case mode_s.MessageKindSurfacePos: // "Surface Position"
 			{
 				if frame.HeadingValid() {
-					hasChanged = hasChanged || p.setHeading(frame.MustHeading())
+					hasChanged = p.setHeading(frame.MustHeading()) || hasChanged
 				}
 				if frame.VelocityValid() {
-					hasChanged = hasChanged || p.setVelocity(frame.MustVelocity())
+					hasChanged = p.setVelocity(frame.MustVelocity()) || hasChanged
 				}
 				if frame.VerticalStatusValid() {
-					hasChanged = hasChanged || p.setGroundStatus(frame.MustOnGround())
+					hasChanged = p.setGroundStatus(frame.MustOnGround()) || hasChanged
 				}
 
+				hasChanged = p.setNicFromPosition(frame.MessageType(), frame.NicSupplementB()) || hasChanged
+
 				if frame.IsEven() {
 					_ = p.setCprEvenLocation(float64(frame.Latitude()), float64(frame.Longitude()), frame.TimeStamp())
 				} else {
@@ -249,12 +338,13 @@ func (p *Plane) HandleModeSFrame(frame *mode_s.Frame, refLat, refLon *float64) {
 				debugMessage(" is on the ground and has heading %s and is travelling at %0.2f knots\033[0m", p.HeadingStr(), p.Velocity())
 				break
 			}
-		case mode_s.DF17FrameAirPositionBarometric, mode_s.DF17FrameAirPositionGnss: // "Airborne Position (with Barometric altitude)"
+		case mode_s.MessageKindAirPositionBarometric, mode_s.MessageKindAirPositionGnss: // "Airborne Position (with Barometric altitude)"
 			{
 				if frame.VerticalStatusValid() {
-					hasChanged = hasChanged || p.setGroundStatus(frame.MustOnGround())
+					hasChanged = p.setGroundStatus(frame.MustOnGround()) || hasChanged
 				}
 				p.setLocationUpdateTime(frame.TimeStamp())
+				hasChanged = p.setNicFromPosition(frame.MessageType(), frame.NicSupplementB()) || hasChanged
 
 				if frame.IsEven() {
 					_ = p.setCprEvenLocation(float64(frame.Latitude()), float64(frame.Longitude()), frame.TimeStamp())
@@ -262,9 +352,11 @@ func (p *Plane) HandleModeSFrame(frame *mode_s.Frame, refLat, refLon *float64) {
 					_ = p.setCprOddLocation(float64(frame.Latitude()), float64(frame.Longitude()), frame.TimeStamp())
 				}
 
-				altitude, _ := frame.Altitude()
-				p.setAltitude(altitude, frame.AltitudeUnits())
-				if err := p.decodeCpr(0, 0, frame.TimeStamp()); nil != err {
+				// GNSS height is not the barometric altitude everything else reports, so leave it out
+				if mode_s.MessageKindAirPositionBarometric == frame.MessageKind() && frame.AltitudeValid() {
+					hasChanged = p.setAltitude(frame.MustAltitude(), frame.AltitudeUnits()) || hasChanged
+				}
+				if err := p.decodeAirborneCpr(refLat, refLon, source, frame.TimeStamp()); nil != err {
 					debugMessage("%s", err)
 				} else {
 					hasChanged = true
@@ -275,26 +367,29 @@ func (p *Plane) HandleModeSFrame(frame *mode_s.Frame, refLat, refLon *float64) {
 				}
 
 				if frame.HasSurveillanceStatus() {
-					hasChanged = hasChanged || p.setSpecial("surveillance", frame.SurveillanceStatus())
+					hasChanged = p.setSpecial("surveillance", frame.SurveillanceStatus()) || hasChanged
 				} else {
-					hasChanged = hasChanged || p.setSpecial("surveillance", "")
+					hasChanged = p.setSpecial("surveillance", "") || hasChanged
 				}
 
 				break
 			}
-		case mode_s.DF17FrameAirVelocity: // "Airborne velocity"
+		case mode_s.MessageKindAirVelocity: // "Airborne velocity"
 			{
 				if frame.HeadingValid() {
-					hasChanged = hasChanged || p.setHeading(frame.MustHeading())
+					hasChanged = p.setHeading(frame.MustHeading()) || hasChanged
 				}
 				if frame.VelocityValid() {
-					hasChanged = hasChanged || p.setVelocity(frame.MustVelocity())
+					hasChanged = p.setVelocity(frame.MustVelocity()) || hasChanged
 				}
 				if frame.VerticalStatusValid() {
-					hasChanged = hasChanged || p.setGroundStatus(frame.MustOnGround())
+					hasChanged = p.setGroundStatus(frame.MustOnGround()) || hasChanged
 				}
 				if frame.VerticalRateValid() {
-					hasChanged = hasChanged || p.setVerticalRate(frame.MustVerticalRate())
+					hasChanged = p.setVerticalRate(frame.MustVerticalRate()) || hasChanged
+				}
+				if frame.NacVValid() {
+					hasChanged = p.setNacV(frame.MustNacV()) || hasChanged
 				}
 				p.setLocationUpdateTime(frame.TimeStamp())
 
@@ -305,46 +400,62 @@ func (p *Plane) HandleModeSFrame(frame *mode_s.Frame, refLat, refLon *float64) {
 				debugMessage(" has %s and is travelling at %0.2f knots\033[0m", headingStr, p.Velocity())
 				break
 			}
-		case mode_s.DF17FrameTestMessage: //, "Test Message":
-			debugMessage("\033[2m Ignoring: DF%d %s\033[0m", frame.DownLinkType(), messageType)
+		case mode_s.MessageKindTestMessage: //, "Test Message":
+			debugMessage("\033[2m Ignoring: DF%d %s\033[0m", frame.DownLinkType(), messageKind)
 			break
-		case mode_s.DF17FrameTestMessageSquawk: //, "Test Message":
+		case mode_s.MessageKindTestMessageSquawk: //, "Test Message":
 			{
 				if frame.SquawkIdentity() > 0 {
-					hasChanged = hasChanged || p.setSquawkIdentity(frame.SquawkIdentity())
+					hasChanged = p.setSquawkIdentity(frame.SquawkIdentity()) || hasChanged
 				}
 				break
 			}
-		case mode_s.DF17FrameSurfaceSystemStatus: //, "Surface System status":
+		case mode_s.MessageKindSurfaceSystemStatus: //, "Surface System status":
 			{
-				debugMessage("\033[2m Ignoring: DF%d %s\033[0m", frame.DownLinkType(), messageType)
+				debugMessage("\033[2m Ignoring: DF%d %s\033[0m", frame.DownLinkType(), messageKind)
 				break
 			}
-		case mode_s.DF17FrameEmergencyPriority: //, "Extended Squitter Aircraft status (Emergency)":
+		case mode_s.MessageKindEmergencyPriority: //, "Extended Squitter Aircraft status (Emergency)":
 			{
-				debugMessage("\033[2m %s\033[0m", messageType)
+				debugMessage("\033[2m %s\033[0m", messageKind)
 				if frame.Alert() {
-					hasChanged = hasChanged || p.setSpecial("special", frame.Special())
-					hasChanged = hasChanged || p.setSpecial("emergency", frame.Emergency())
+					hasChanged = p.setSpecial("special", frame.Special()) || hasChanged
+					hasChanged = p.setSpecial("emergency", frame.Emergency()) || hasChanged
 				}
-				hasChanged = hasChanged || p.setSquawkIdentity(frame.SquawkIdentity())
+				hasChanged = p.setSquawkIdentity(frame.SquawkIdentity()) || hasChanged
 				break
 			}
-		case mode_s.DF17FrameTcasRA: //, "Extended Squitter Aircraft status (1090ES TCAS RA)":
+		case mode_s.MessageKindTcasRA: //, "Extended Squitter Aircraft status (1090ES TCAS RA)":
 			{
-				debugMessage("\033[2m Ignoring: DF%d %s\033[0m", frame.DownLinkType(), messageType)
+				hasChanged = p.handleResolutionAdvisory(frame) || hasChanged
+				debugMessage("\033[2m %s: %s\033[0m", messageKind, p.ResolutionAdvisory())
 				break
 			}
-		case mode_s.DF17FrameTargetStateStatus: //, "Target State and status Message":
+		case mode_s.MessageKindTargetStateStatus: //, "Target State and status Message":
 			{
-				debugMessage("\033[2m Ignoring: DF%d %s\033[0m", frame.DownLinkType(), messageType)
+				hasChanged = p.handleIntent(frame) || hasChanged
+				if frame.SelectedHeadingValid() {
+					hasChanged = p.setSelectedHeading(frame.MustSelectedHeading()) || hasChanged
+				}
+				if frame.AutopilotModesValid() {
+					hasChanged = p.setAutopilotModes(frame.MustAutopilotEngaged(), frame.MustLnavMode()) || hasChanged
+				}
+				hasChanged = p.handleQuality(frame) || hasChanged
+				debugMessage("\033[2m %s\033[0m", messageKind)
 				break
 			}
-		case mode_s.DF17FrameAircraftOperational: //, "Aircraft Operational status Message":
+		case mode_s.MessageKindAircraftOperational: //, "Aircraft Operational status Message":
 			{
 				if frame.VerticalStatusValid() {
-					hasChanged = hasChanged || p.setGroundStatus(frame.MustOnGround())
+					hasChanged = p.setGroundStatus(frame.MustOnGround()) || hasChanged
+				}
+				if frame.AdsbVersionValid() {
+					hasChanged = p.setAdsbVersion(frame.MustAdsbVersion(), frame.NicSupplementA(), frame.NicSupplementC()) || hasChanged
 				}
+				if frame.NacVValid() {
+					hasChanged = p.setNacV(frame.MustNacV()) || hasChanged
+				}
+				hasChanged = p.handleQuality(frame) || hasChanged
 
 				break
 			}
@@ -353,13 +464,51 @@ func (p *Plane) HandleModeSFrame(frame *mode_s.Frame, refLat, refLon *float64) {
 	case 20, 21:
 		switch frame.BdsMessageType() {
 		case mode_s.BdsElsDataLinkCap: // 1.0
-			hasChanged = hasChanged || p.setSquawkIdentity(frame.SquawkIdentity())
+			hasChanged = p.setSquawkIdentity(frame.SquawkIdentity()) || hasChanged
 		case mode_s.BdsElsGicbCap: // 1.7
 			if frame.AltitudeValid() {
-				hasChanged = hasChanged || p.setAltitude(frame.MustAltitude(), frame.AltitudeUnits())
+				hasChanged = p.setAltitude(frame.MustAltitude(), frame.AltitudeUnits()) || hasChanged
 			}
 		case mode_s.BdsElsAircraftIdent: // 2.0
-			hasChanged = hasChanged || p.setFlightNumber(frame.FlightNumber())
+			hasChanged = p.setFlightNumber(frame.FlightNumber()) || hasChanged
+		case mode_s.BdsElsAcasRA: // 3.0
+			hasChanged = p.handleResolutionAdvisory(frame) || hasChanged
+		case mode_s.BdsEhsSelVertIntent: // 4.0
+			hasChanged = p.handleIntent(frame) || hasChanged
+		case mode_s.BdsEhsTrackTurnReport: // 5.0
+			if frame.RollAngleValid() {
+				hasChanged = p.setRollAngle(frame.MustRollAngle()) || hasChanged
+			}
+			if frame.TrueTrackValid() {
+				hasChanged = p.setTrueTrack(frame.MustTrueTrack()) || hasChanged
+			}
+			if frame.TrackRateValid() {
+				hasChanged = p.setTrackRate(frame.MustTrackRate()) || hasChanged
+			}
+			if frame.GroundSpeedValid() {
+				hasChanged = p.setGroundSpeed(frame.MustGroundSpeed()) || hasChanged
+			}
+			if frame.TrueAirSpeedValid() {
+				hasChanged = p.setTrueAirSpeed(frame.MustTrueAirSpeed()) || hasChanged
+			}
+		case mode_s.BdsEhsHeadingSpeed: // 6.0
+			if frame.MagneticHeadingValid() {
+				hasChanged = p.setMagneticHeading(frame.MustMagneticHeading()) || hasChanged
+			}
+			if frame.IndicatedAirSpeedValid() {
+				hasChanged = p.setIndicatedAirSpeed(frame.MustIndicatedAirSpeed()) || hasChanged
+			}
+			if frame.MachValid() {
+				hasChanged = p.setMach(frame.MustMach()) || hasChanged
+			}
+			if frame.BaroVerticalRateValid() {
+				hasChanged = p.setBaroVerticalRate(frame.MustBaroVerticalRate()) || hasChanged
+			}
+			if frame.InertialVerticalRateValid() {
+				hasChanged = p.setInertialVerticalRate(frame.MustInertialVerticalRate()) || hasChanged
+			}
+		case mode_s.BdsMetRoutineAirReport, mode_s.BdsMetHazartReport: // 4.4, 4.5
+			p.tracker.AddEvent(newWeatherEvent(p, frame))
 		default:
 			// let's see if we can decode more BDS info
 			// TODO: Decode Other BDS frames
@@ -371,6 +520,55 @@ func (p *Plane) HandleModeSFrame(frame *mode_s.Frame, refLat, refLon *float64) {
 	}
 }
 
+// handleIntent records the selected altitudes, baro setting and modes from a BDS 4,0 or a Target State (TC 29) frame
+func (p *Plane) handleIntent(frame *mode_s.Frame) bool {
+	var hasChanged bool
+	// setter first, so a change earlier in the frame does not short circuit the rest
+	if frame.McpSelectedAltitudeValid() {
+		hasChanged = p.setMcpSelectedAltitude(frame.MustMcpSelectedAltitude()) || hasChanged
+	}
+	if frame.FmsSelectedAltitudeValid() {
+		hasChanged = p.setFmsSelectedAltitude(frame.MustFmsSelectedAltitude()) || hasChanged
+	}
+	if frame.BaroSettingValid() {
+		hasChanged = p.setBaroSetting(frame.MustBaroSetting()) || hasChanged
+	}
+	if frame.McpModesValid() {
+		hasChanged = p.setMcpModes(frame.MustVnavMode(), frame.MustAltHoldMode(), frame.MustApproachMode()) || hasChanged
+	}
+	return hasChanged
+}
+
+// handleQuality records the NACp and SIL from an Aircraft Operational Status or (version 2) Target State message
+func (p *Plane) handleQuality(frame *mode_s.Frame) bool {
+	var hasChanged bool
+	if frame.NacPValid() {
+		hasChanged = p.setNacP(frame.MustNacP()) || hasChanged
+	}
+	if frame.SilValid() {
+		hasChanged = p.setSil(frame.MustSil(), frame.SilSupplement()) || hasChanged
+	}
+	return hasChanged
+}
+
+// handleResolutionAdvisory records a TCAS RA (DF16, DF17 or DF20/21) and lets everyone know when an RA comes
+// into effect, changes or ends
+func (p *Plane) handleResolutionAdvisory(frame *mode_s.Frame) bool {
+	if !frame.ResolutionAdvisoryValid() {
+		return false
+	}
+	ra := frame.MustResolutionAdvisory()
+	hasChanged, wasActive := p.setResolutionAdvisory(ra, frame.TimeStamp())
+	if !hasChanged {
+		return false
+	}
+	// an empty RA report is nothing to be alarmed about
+	if ra.Active() || wasActive {
+		p.tracker.AddEvent(newTcasAlertEvent(p, frame))
+	}
+	return true
+}
+
 func (p *Plane) HandleSbs1Frame(frame *sbs1.Frame) {
 	var hasChanged bool
 	p.setLastSeen(frame.TimeStamp())
@@ -407,6 +605,7 @@ func (t *Tracker) prunePlanes() {
 				return true
 			})
 
+			// this also matches and prunes our Mode A/C targets
 			t.AddEvent(t.newInfoEvent())
 		case <-t.pruneExitChan:
 			return
@@ -415,9 +614,20 @@ func (t *Tracker) prunePlanes() {
 }
 
 func (t *Tracker) newInfoEvent() *InfoEvent {
+	var numDropped uint64
+	for _, p := range t.producers {
+		if dp, ok := p.(DroppingProducer); ok {
+			numDropped += dp.NumDropped()
+		}
+	}
 	return &InfoEvent{
-		receivedFrames: atomic.LoadUint64(&t.numFrames),
-		numReceivers:   len(t.producers),
-		uptime:         time.Now().Sub(t.startTime).Seconds(),
+		receivedFrames:    atomic.LoadUint64(&t.numFrames),
+		correctedFrames:   atomic.LoadUint64(&t.numCorrectedFrames),
+		rejectedFrames:    atomic.LoadUint64(&t.numRejectedFrames),
+		numModeAC:         len(t.UnidentifiedModeACTargets()),
+		numTisbManagement: atomic.LoadUint64(&t.numTisbManagement),
+		numReceivers:      len(t.producers),
+		numDropped:        numDropped,
+		uptime:            time.Now().Sub(t.startTime).Seconds(),
 	}
 }
@@ -4,7 +4,11 @@ import (
 	"flag"
 	"fmt"
 	"github.com/rs/zerolog"
+	"math"
 	"plane.watch/lib/tracker/mode_s"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -300,3 +304,706 @@ func TestPlane_HasVelocity(t *testing.T) {
 		t.Error("Did not correctly set velocity")
 	}
 }
+
+func TestPlane_HandleModeSFrameSetsEveryField(t *testing.T) {
+	trk := NewTracker()
+	// airborne velocity: heading 182.88, ground speed 159, vertical rate -832
+	frame, err := mode_s.DecodeString("8D485020994409940838175B284F", time.Now())
+	if nil != err {
+		t.Fatal(err)
+	}
+	p := trk.GetPlane(frame.Icao())
+	p.HandleModeSFrame(frame, nil, nil)
+
+	// an earlier change must not stop the later fields from being recorded
+	if !p.HasHeading() || !p.HasVelocity() || !p.HasVerticalRate() {
+		t.Errorf("Expected heading, velocity and vertical rate from a single frame. got %t, %t, %t", p.HasHeading(), p.HasVelocity(), p.HasVerticalRate())
+	}
+	if -832 != p.VerticalRate() {
+		t.Errorf("Incorrect vertical rate -832 != %d", p.VerticalRate())
+	}
+}
+
+func TestPlane_HasEhs(t *testing.T) {
+	trk := NewTracker()
+	p := trk.GetPlane(0x010101)
+	if p.HasMcpSelectedAltitude() || p.HasRollAngle() || p.HasMach() {
+		t.Error("Did not expect to have any EHS information")
+	}
+
+	if !p.setMcpSelectedAltitude(3008) {
+		t.Error("Expected that setting our MCP selected altitude got a change")
+	}
+	if p.setMcpSelectedAltitude(3008) {
+		t.Error("Did not expect setting the same MCP selected altitude to be a change")
+	}
+	if !p.setRollAngle(-2.1) || !p.setMach(0.42) {
+		t.Error("Expected that setting our roll angle and mach got a change")
+	}
+
+	if !p.HasMcpSelectedAltitude() || 3008 != p.McpSelectedAltitude() {
+		t.Error("Did not correctly set MCP selected altitude")
+	}
+	if !p.HasRollAngle() || -2.1 != p.RollAngle() {
+		t.Error("Did not correctly set roll angle")
+	}
+	if !p.HasMach() || 0.42 != p.Mach() {
+		t.Error("Did not correctly set mach")
+	}
+	if p.HasTrueAirSpeed() {
+		t.Error("Did not expect to have a true airspeed")
+	}
+}
+
+func TestNewWeatherEvent(t *testing.T) {
+	trk := NewTracker()
+	p := trk.GetPlane(0x7C1234)
+	if err := p.addLatLong(-31.95, 115.94, time.Now()); nil != err {
+		t.Fatal(err)
+	}
+	p.setAltitude(35000, "feet")
+
+	frame, err := mode_s.DecodeString("A0001692185BD5CF400000DFC696", time.Now())
+	if nil != err {
+		t.Fatal(err)
+	}
+	we := newWeatherEvent(p, frame)
+	if WeatherEventType != we.Type() {
+		t.Errorf("Incorrect event type %s", we.Type())
+	}
+	if 0x7C1234 != we.Icao || !we.HasLocation || -31.95 != we.Lat || 115.94 != we.Lon || 35000 != we.Altitude {
+		t.Errorf("Weather event did not capture the plane position: %s", we)
+	}
+	if !we.HasWind || 22 != we.WindSpeed || !we.HasStaticAirTemperature || -48.75 != we.StaticAirTemperature {
+		t.Errorf("Weather event did not capture the report: %s", we)
+	}
+	if we.HasTurbulence || we.HasIcing {
+		t.Errorf("Did not expect a hazard in a routine air report: %s", we)
+	}
+}
+
+func TestEvents_NonIcao(t *testing.T) {
+	// anonymous and TIS-B targets are tracked with nonIcaoAddressFlag set, it should not end up in what we report
+	trk := NewTracker()
+	p := trk.GetPlane(0x7C1234 | nonIcaoAddressFlag)
+	frame, err := mode_s.DecodeString("A0001692185BD5CF400000DFC696", time.Now())
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	we := newWeatherEvent(p, frame)
+	if "~7C1234" != we.IcaoStr || !strings.HasPrefix(we.String(), "Weather: ~7C1234 BDS") {
+		t.Errorf("Incorrect non ICAO address in weather event %s: %s", we.IcaoStr, we)
+	}
+	te := newTcasAlertEvent(p, frame)
+	if "~7C1234" != te.IcaoStr || !strings.HasPrefix(te.String(), "TCAS RA: ~7C1234 ") {
+		t.Errorf("Incorrect non ICAO address in TCAS alert event %s: %s", te.IcaoStr, te)
+	}
+
+	icao := newWeatherEvent(trk.GetPlane(0x7C1234), frame)
+	if "7C1234" != icao.IcaoStr || !strings.HasPrefix(icao.String(), "Weather: 7C1234 BDS") {
+		t.Errorf("Incorrect ICAO address in weather event %s: %s", icao.IcaoStr, icao)
+	}
+}
+
+type eventCollector struct {
+	sync.Mutex
+	events []Event
+}
+
+func (e *eventCollector) OnEvent(ev Event) {
+	e.Lock()
+	defer e.Unlock()
+	e.events = append(e.events, ev)
+}
+
+func (e *eventCollector) Stop() {}
+
+// decodedFrameCollector is an eventCollector that asks for a DecodedFrameEvent for every frame
+type decodedFrameCollector struct {
+	eventCollector
+}
+
+func (d *decodedFrameCollector) WantsDecodedFrames() bool {
+	return true
+}
+
+func TestTracker_DecodedFrameEvents(t *testing.T) {
+	tests := []struct {
+		name     string
+		wants    bool
+		expected int
+	}{
+		{name: "no sink wants them", wants: false, expected: 0},
+		{name: "a sink wants them", wants: true, expected: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collector := &decodedFrameCollector{}
+			var sink Sink = &collector.eventCollector
+			if tt.wants {
+				sink = collector
+			}
+			trk := NewTracker(WithDecodeWorkerCount(1))
+			trk.AddSink(sink)
+			trk.decodingQueue <- NewFrameEvent(mode_s.NewFrame("*8D40621D58C382D690C8AC2863A7;", time.Now()), &FrameSource{})
+			// with one worker, once the next plane turns up we are done with the first frame
+			trk.decodingQueue <- NewFrameEvent(mode_s.NewFrame("*8D7C4A0CF9105300004920A7CD97;", time.Now()), &FrameSource{})
+			for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+				if _, ok := trk.planeList.Load(uint32(0x7C4A0C)); ok {
+					break
+				}
+			}
+			trk.Finish()
+			trk.eventsWaiter.Wait()
+
+			var decoded int
+			collector.Lock()
+			for _, e := range collector.events {
+				if de, ok := e.(*DecodedFrameEvent); ok && 0x40621D == de.Frame().Icao() {
+					decoded++
+				}
+			}
+			collector.Unlock()
+			if tt.expected != decoded {
+				t.Errorf("Expected %d decoded frame events, got %d", tt.expected, decoded)
+			}
+		})
+	}
+}
+
+type testProducer struct {
+	events chan Event
+}
+
+func (p *testProducer) Listen() chan Event {
+	return p.events
+}
+
+func (p *testProducer) Stop() {
+	close(p.events)
+}
+
+func (p *testProducer) String() string {
+	return "test"
+}
+
+type droppingTestProducer struct {
+	testProducer
+	dropped uint64
+}
+
+func (p *droppingTestProducer) NumDropped() uint64 {
+	return p.dropped
+}
+
+func TestTracker_InfoEventDropped(t *testing.T) {
+	trk := NewTracker()
+	trk.AddProducer(&testProducer{events: make(chan Event)})
+	trk.AddProducer(&droppingTestProducer{testProducer: testProducer{events: make(chan Event)}, dropped: 3})
+	trk.AddProducer(&droppingTestProducer{testProducer: testProducer{events: make(chan Event)}, dropped: 4})
+	defer trk.Finish()
+
+	info := trk.newInfoEvent()
+	if 3 != info.NumReceivers() || 7 != info.NumDropped() || !strings.Contains(info.String(), "#dropped=7") {
+		t.Errorf("Expected 7 dropped from 3 receivers, got %s", info)
+	}
+}
+
+func TestPlane_ResolutionAdvisory(t *testing.T) {
+	collector := &eventCollector{}
+	trk := NewTracker()
+	trk.AddSink(collector)
+
+	frame, err := mode_s.DecodeString("8D7C1234E2C20005F159E0A47217", time.Now())
+	if nil != err {
+		t.Fatal(err)
+	}
+	p := trk.GetPlane(frame.Icao())
+	p.HandleModeSFrame(frame, nil, nil)
+	// the same RA again should not give us another alert
+	p.HandleModeSFrame(frame, nil, nil)
+
+	if !p.HasResolutionAdvisory() {
+		t.Fatal("Expected the plane to have a resolution advisory")
+	}
+	if ra := p.ResolutionAdvisory(); !ra.Active() || 0x7C5678 != ra.ThreatIcao {
+		t.Errorf("Incorrect resolution advisory: %s", ra)
+	}
+
+	trk.Finish()
+	trk.eventsWaiter.Wait()
+
+	var alerts []*TcasAlertEvent
+	collector.Lock()
+	for _, e := range collector.events {
+		if alert, ok := e.(*TcasAlertEvent); ok {
+			alerts = append(alerts, alert)
+		}
+	}
+	collector.Unlock()
+	if 1 != len(alerts) {
+		t.Fatalf("Expected 1 TCAS alert event, got %d", len(alerts))
+	}
+	if 0x7C1234 != alerts[0].Icao || 17 != alerts[0].DownLinkFormat {
+		t.Errorf("Incorrect TCAS alert event: %s", alerts[0])
+	}
+}
+
+func TestPlane_ResolutionAdvisoryInactive(t *testing.T) {
+	collector := &eventCollector{}
+	trk := NewTracker()
+	trk.AddSink(collector)
+	p := trk.GetPlane(0x7C1234)
+	for _, msg := range []string{
+		"8D7C1234E20000000000000179E4", // nothing going on
+		"8D7C1234E2C20005F159E0A47217", // climb
+		"8D7C1234E2C20005F159E0A47217", // still climbing
+		"8D7C1234E2C20025F159E0241448", // terminated
+		"8D7C1234E20000000000000179E4", // nothing going on again
+	} {
+		frame, err := mode_s.DecodeString(msg, time.Now())
+		if nil != err {
+			t.Fatal(err)
+		}
+		p.HandleModeSFrame(frame, nil, nil)
+	}
+	trk.Finish()
+	trk.eventsWaiter.Wait()
+
+	var alerts []*TcasAlertEvent
+	collector.Lock()
+	for _, e := range collector.events {
+		if alert, ok := e.(*TcasAlertEvent); ok {
+			alerts = append(alerts, alert)
+		}
+	}
+	collector.Unlock()
+	if 2 != len(alerts) {
+		t.Fatalf("Expected alerts for the RA and its end, got %d", len(alerts))
+	}
+	if !alerts[0].RA.Active() || !alerts[1].RA.Terminated {
+		t.Errorf("Incorrect TCAS alert events: %s, %s", alerts[0], alerts[1])
+	}
+}
+
+func TestPlane_TargetState(t *testing.T) {
+	trk := performTrackingTest([]string{"8DA05629EA21485CBF3F8CADAEEB"}, t)
+	defer trk.Finish()
+	p := trk.GetPlane(0xA05629)
+
+	if !p.HasMcpSelectedAltitude() || 16992 != p.McpSelectedAltitude() {
+		t.Errorf("Incorrect MCP selected altitude: %d", p.McpSelectedAltitude())
+	}
+	if p.HasFmsSelectedAltitude() {
+		t.Error("Did not expect an FMS selected altitude")
+	}
+	if !p.HasBaroSetting() || math.Abs(1012.8-p.BaroSetting()) > 0.01 {
+		t.Errorf("Incorrect baro setting: %0.2f", p.BaroSetting())
+	}
+	if !p.HasSelectedHeading() || 66.796875 != p.SelectedHeading() {
+		t.Errorf("Incorrect selected heading: %0.2f", p.SelectedHeading())
+	}
+	if !p.HasAutopilotModes() || !p.AutopilotEngaged() || !p.LnavMode() {
+		t.Error("Expected the autopilot and LNAV to be engaged")
+	}
+	if !p.HasMcpModes() || !p.VnavMode() || p.AltHoldMode() || p.ApproachMode() {
+		t.Error("Expected VNAV only")
+	}
+}
+
+func TestPlane_Quality(t *testing.T) {
+	// version 0 until we see the operational status, so the NACp comes from the position type code
+	trk := performTrackingTest([]string{"*8D40621D58C382D690C8AC2863A7;"}, t)
+	p := trk.GetPlane(0x40621D)
+	if p.HasAdsbVersion() {
+		t.Error("Did not expect an ADS-B version")
+	}
+	if !p.HasNic() || 8 != p.Nic() || 185.2 != p.ContainmentRadius() {
+		t.Errorf("Incorrect v0 NIC %d (Rc %0.1f)", p.Nic(), p.ContainmentRadius())
+	}
+	if !p.HasNacP() || 8 != p.NacP() {
+		t.Errorf("Incorrect v0 NACp %d", p.NacP())
+	}
+	trk.Finish()
+
+	// version 1 with NIC supplement A, then a TC 11 position gives us NIC 9
+	trk = performTrackingTest([]string{"8D40621DF80000000039300172CF", "*8D40621D58C382D690C8AC2863A7;"}, t)
+	defer trk.Finish()
+	p = trk.GetPlane(0x40621D)
+	if !p.HasAdsbVersion() || 1 != p.AdsbVersion() {
+		t.Errorf("Incorrect ADS-B version %d", p.AdsbVersion())
+	}
+	if !p.HasNic() || 9 != p.Nic() || 75 != p.ContainmentRadius() {
+		t.Errorf("Incorrect v1 NIC %d (Rc %0.1f)", p.Nic(), p.ContainmentRadius())
+	}
+	if !p.HasNacP() || 9 != p.NacP() {
+		t.Errorf("Incorrect v1 NACp %d", p.NacP())
+	}
+	if !p.HasSil() || 3 != p.Sil() || p.SilPerSample() {
+		t.Errorf("Incorrect SIL %d", p.Sil())
+	}
+}
+
+func TestTracker_IcaoFromParity(t *testing.T) {
+	trk := NewTracker(WithDecodeWorkerCount(1))
+	source := &FrameSource{}
+	frames := []string{
+		"*28001808F0DEBF;",               // DF5 for 40621D, we have not seen it yet
+		"*8D40621D58C382D690C8AC2863A7;", // DF17 confirms 40621D
+		"*28001808F0DEBF;",               // DF5 for 40621D, now we believe it
+		"*28001808CCC108;",               // DF5 for 7C7DAA, never confirmed
+	}
+	for _, f := range frames {
+		trk.decodingQueue <- NewFrameEvent(mode_s.NewFrame(f, time.Now()), source)
+	}
+	trk.Finish()
+	trk.decodingQueueWaiter.Wait()
+
+	if rejected := atomic.LoadUint64(&trk.numRejectedFrames); 2 != rejected {
+		t.Errorf("Expected 2 rejected frames, got %d", rejected)
+	}
+	if _, ok := trk.planeList.Load(uint32(0x7C7DAA)); ok {
+		t.Error("Created a plane from an unconfirmed ICAO")
+	}
+	p := trk.GetPlane(0x40621D)
+	if 2 != p.MsgCount() {
+		t.Errorf("Expected 2 messages for the plane, got %d", p.MsgCount())
+	}
+	if !p.IcaoConfirmedSince(time.Now().Add(-time.Minute)) {
+		t.Error("DF17 should have confirmed the ICAO")
+	}
+}
+
+func TestPlane_IcaoConfirmed(t *testing.T) {
+	trk := NewTracker()
+	p := trk.GetPlane(0x40621D)
+
+	// DF5 for 40621D, the address is only as good as the parity
+	ap, err := mode_s.DecodeString("*28001808F0DEBF;", time.Now())
+	if nil != err {
+		t.Fatal(err)
+	}
+	p.HandleModeSFrame(ap, nil, nil)
+	if p.IcaoConfirmedSince(time.Now().Add(-time.Minute)) {
+		t.Error("An Address/Parity frame should not confirm the ICAO")
+	}
+
+	// DF17 for 40621D with a bit error in the parity field, which gets corrected
+	pi := mode_s.NewFrame("*8D40621D58C382D690C8AC2863A6;", time.Now())
+	if _, err = pi.DecodeWithCorrection(mode_s.CorrectionSingleBit); nil != err {
+		t.Fatal(err)
+	}
+	p.HandleModeSFrame(pi, nil, nil)
+	if !p.IcaoConfirmedSince(time.Now().Add(-time.Minute)) {
+		t.Error("A frame that passed its checksum should confirm the ICAO")
+	}
+}
+
+func TestTracker_WithErrorCorrection(t *testing.T) {
+	// each tracker has its own error correction, the same bad frame is fixed by one and rejected by the other
+	correcting := NewTracker(WithDecodeWorkerCount(1), WithErrorCorrection(mode_s.CorrectionSingleBit))
+	strict := NewTracker(WithDecodeWorkerCount(1))
+	for _, trk := range []*Tracker{correcting, strict} {
+		trk.decodingQueue <- NewFrameEvent(mode_s.NewFrame("*8D40621D58C382D690C8AC2863A6;", time.Now()), &FrameSource{})
+		trk.Finish()
+		trk.decodingQueueWaiter.Wait()
+	}
+
+	if 1 != correcting.GetPlane(0x40621D).MsgCount() {
+		t.Error("Expected the correcting tracker to fix the frame")
+	}
+	if 0 != strict.GetPlane(0x40621D).MsgCount() {
+		t.Error("Expected the strict tracker to reject the frame")
+	}
+	// and only counts the frames it fixed itself
+	if corrected := atomic.LoadUint64(&correcting.numCorrectedFrames); 1 != corrected {
+		t.Errorf("Expected the correcting tracker to count 1 corrected frame, got %d", corrected)
+	}
+	if corrected := atomic.LoadUint64(&strict.numCorrectedFrames); 0 != corrected {
+		t.Errorf("Expected the strict tracker to count no corrected frames, got %d", corrected)
+	}
+}
+
+func TestTracker_NonIcaoAddress(t *testing.T) {
+	trk := NewTracker(WithDecodeWorkerCount(1))
+	source := &FrameSource{}
+	frames := []string{
+		"*8D40621D58C382D690C8AC2863A7;", // DF17 from the real 40621D
+		"*9140621D58C382D690C8AC0D1E2A;", // DF18 CF1, an anonymous address that happens to be 40621D
+		"*9240621D58C382D690C8ACE58DA2;", // DF18 CF2, TIS-B about the real 40621D
+		"*9440621D58C382D690C8ACCB5EBB;", // DF18 CF4, TIS-B management, no target
+	}
+	for _, f := range frames {
+		trk.decodingQueue <- NewFrameEvent(mode_s.NewFrame(f, time.Now()), source)
+	}
+	trk.Finish()
+	trk.decodingQueueWaiter.Wait()
+
+	airframe := trk.GetPlane(0x40621D)
+	if 2 != airframe.MsgCount() {
+		t.Errorf("Expected 2 messages for the real plane, got %d", airframe.MsgCount())
+	}
+	if "icao" != airframe.AddressTypeStr() || "tis-b" != airframe.SourceStr() {
+		t.Errorf("Incorrect real plane addressing: %s %s", airframe.AddressTypeStr(), airframe.SourceStr())
+	}
+
+	if 1 != atomic.LoadUint64(&trk.numTisbManagement) {
+		t.Errorf("Expected to count 1 TIS-B management message, got %d", atomic.LoadUint64(&trk.numTisbManagement))
+	}
+
+	anonymous := trk.GetPlane(0x40621D | nonIcaoAddressFlag)
+	if 1 != anonymous.MsgCount() {
+		t.Errorf("Expected 1 message for the anonymous plane, got %d", anonymous.MsgCount())
+	}
+	if "~40621D" != anonymous.IcaoIdentifierStr() {
+		t.Errorf("Incorrect anonymous ICAO string: %s", anonymous.IcaoIdentifierStr())
+	}
+	if "non-icao" != anonymous.AddressTypeStr() || "adsb" != anonymous.SourceStr() {
+		t.Errorf("Incorrect anonymous plane addressing: %s %s", anonymous.AddressTypeStr(), anonymous.SourceStr())
+	}
+}
+
+func TestTracker_TisbCoarse(t *testing.T) {
+	trk := NewTracker(WithDecodeWorkerCount(1))
+	source := &FrameSource{}
+	frames := []string{
+		"*937C12340A2E942AACD177456E66;", // coarse TIS-B, even
+		"*937C12340A2E942BC38C51357A8E;", // coarse TIS-B, odd
+	}
+	for _, f := range frames {
+		trk.decodingQueue <- NewFrameEvent(mode_s.NewFrame(f, time.Now()), source)
+	}
+	trk.Finish()
+	trk.decodingQueueWaiter.Wait()
+
+	p := trk.GetPlane(0x7C1234)
+	if 2 != p.MsgCount() {
+		t.Fatalf("Expected 2 messages for the TIS-B target, got %d", p.MsgCount())
+	}
+	if "tis-b" != p.SourceStr() {
+		t.Errorf("Incorrect source %s", p.SourceStr())
+	}
+	if 3500 != p.Altitude() || 90 != p.Heading() || 320 != p.Velocity() {
+		t.Errorf("Incorrect altitude %d, heading %0.2f or velocity %0.2f", p.Altitude(), p.Heading(), p.Velocity())
+	}
+	// 12 bit CPR is good to a few hundred metres
+	if !p.HasLocation() || math.Abs(-31.95-p.Lat()) > 0.01 || math.Abs(115.86-p.Lon()) > 0.01 {
+		t.Errorf("Incorrect location %0.4f,%0.4f (has location %t)", p.Lat(), p.Lon(), p.HasLocation())
+	}
+}
+
+func TestTracker_ModeAC(t *testing.T) {
+	trk := NewTracker()
+	defer trk.Finish()
+	now := time.Now()
+	source := &FrameSource{OriginIdentifier: "beast://receiver"}
+
+	squawking := trk.GetPlane(0x40621D)
+	squawking.setLastSeen(now)
+	squawking.setHeardBy(source, now)
+	squawking.setSquawkIdentity(6520)
+	climbing := trk.GetPlane(0x7C7DAA)
+	climbing.setLastSeen(now)
+	climbing.setHeardBy(source, now)
+	climbing.setAltitude(3525, "feet")
+	climbing.setSquawkIdentity(2000)
+
+	for _, code := range [][]byte{{0x65, 0x20}, {0x20, 0x00}, {0x45, 0x20}, {0x12, 0x00}, {0x12, 0x00}, {0x12, 0x00}, {0x77, 0x00}} {
+		ac, err := mode_s.DecodeModeAC(code)
+		if nil != err {
+			t.Fatal(err)
+		}
+		trk.handleModeAC(ac, now, source)
+	}
+
+	targets := trk.ModeACTargets()
+	if 5 != len(targets) {
+		t.Fatalf("Expected 5 Mode A/C targets, got %d", len(targets))
+	}
+	// 4520 is 3500ft, which we can match to 7C7DAA because we are hearing its squawk as well
+	expected := map[uint16]uint32{0x1200: 0, 0x2000: 0x7C7DAA, 0x4520: 0x7C7DAA, 0x6520: 0x40621D, 0x7700: 0}
+	for _, target := range targets {
+		if expected[target.Code] != target.Icao {
+			t.Errorf("Mode A/C %04X should belong to %06X, not %06X", target.Code, expected[target.Code], target.Icao)
+		}
+	}
+
+	// 7700 has not been seen enough times to be believable
+	unidentified := trk.UnidentifiedModeACTargets()
+	if 1 != len(unidentified) || 0x1200 != unidentified[0].Code || 3 != unidentified[0].Replies {
+		t.Errorf("Expected 1200 to be the only unidentified target, got %+v", unidentified)
+	}
+}
+
+func TestTracker_ModeACAltitudeOnly(t *testing.T) {
+	trk := NewTracker()
+	defer trk.Finish()
+	now := time.Now()
+	source := &FrameSource{OriginIdentifier: "beast://receiver"}
+
+	// a Mode S plane at 3525ft, but the receiver is not hearing its squawk as a Mode A reply
+	climbing := trk.GetPlane(0x7C7DAA)
+	climbing.setLastSeen(now)
+	climbing.setHeardBy(source, now)
+	climbing.setAltitude(3525, "feet")
+	climbing.setSquawkIdentity(2000)
+
+	// an A/C only aircraft at 3500ft (Mode C 4520)
+	ac, err := mode_s.DecodeModeAC([]byte{0x45, 0x20})
+	if nil != err {
+		t.Fatal(err)
+	}
+	for i := 0; i < modeACMinReplies; i++ {
+		trk.handleModeAC(ac, now, source)
+	}
+
+	unidentified := trk.UnidentifiedModeACTargets()
+	if 1 != len(unidentified) || 0x4520 != unidentified[0].Code {
+		t.Errorf("Expected 4520 to stay unidentified, got %+v", unidentified)
+	}
+}
+
+func TestTracker_ModeACSources(t *testing.T) {
+	trk := NewTracker()
+	defer trk.Finish()
+	now := time.Now()
+	near := &FrameSource{OriginIdentifier: "beast://near"}
+	far := &FrameSource{OriginIdentifier: "beast://far"}
+
+	// both at 3500ft (Mode C 4520), one of them is also squawking 4520
+	climbing := trk.GetPlane(0x7C7DAA)
+	climbing.setLastSeen(now)
+	climbing.setHeardBy(near, now)
+	climbing.setAltitude(3500, "feet")
+	squawking := trk.GetPlane(0x40621D)
+	squawking.setLastSeen(now)
+	squawking.setHeardBy(near, now)
+	squawking.setAltitude(3500, "feet")
+	squawking.setSquawkIdentity(4520)
+
+	ac, err := mode_s.DecodeModeAC([]byte{0x45, 0x20})
+	if nil != err {
+		t.Fatal(err)
+	}
+	trk.handleModeAC(ac, now, near)
+	trk.handleModeAC(ac, now, far)
+
+	targets := trk.ModeACTargets()
+	if 2 != len(targets) {
+		t.Fatalf("Expected a Mode A/C target per receiver, got %d", len(targets))
+	}
+	for _, target := range targets {
+		switch target.Source {
+		case near:
+			if 0x40621D != target.Icao || !target.MatchedSquawk {
+				t.Errorf("Expected the near receiver's 4520 to match 40621D on squawk, got %06X (squawk %t)", target.Icao, target.MatchedSquawk)
+			}
+		case far:
+			if 0 != target.Icao {
+				t.Errorf("The far receiver has not heard any Mode S planes, but matched %06X", target.Icao)
+			}
+		default:
+			t.Errorf("Unexpected source %+v", target.Source)
+		}
+	}
+}
+
+func TestPlane_Signal(t *testing.T) {
+	p := newPlane(0x7C7DAA)
+	if p.HasSignal() {
+		t.Error("A new plane should not have a signal level")
+	}
+	for _, dbfs := range []float64{-10, -20, -15} {
+		p.setSignalLevel(dbfs)
+	}
+	if !p.HasSignal() || -15 != p.SignalLast() || -20 != p.SignalMin() || -10 != p.SignalMax() {
+		t.Errorf("Incorrect signal stats. last %0.1f, min %0.1f, max %0.1f", p.SignalLast(), p.SignalMin(), p.SignalMax())
+	}
+	// the average is of the power: (0.1 + 0.01 + 0.0316) / 3 = 0.0472
+	if avg := p.SignalAverage(); math.Abs(avg-(-13.260)) > 0.001 {
+		t.Errorf("Incorrect average signal: %0.3f", avg)
+	}
+}
+
+func TestTracker_EncodedRoundTrip(t *testing.T) {
+	const icao = 0x7C7DAA
+	var frames []mode_s.EncodedFrame
+	for _, payload := range []mode_s.Payload{
+		mode_s.AdsbIdentification{Callsign: "QFA123"},
+		mode_s.AdsbAirbornePosition{Altitude: 35000, Lat: -31.95, Lon: 115.94},
+		mode_s.AdsbAirbornePosition{Altitude: 35000, Lat: -31.95, Lon: 115.94, Odd: true},
+		mode_s.AdsbAirborneVelocity{Heading: 90, GroundSpeed: 400},
+	} {
+		frame, err := mode_s.EncodeExtendedSquitter(icao, payload)
+		if nil != err {
+			t.Fatal(err)
+		}
+		frames = append(frames, frame)
+	}
+
+	trk := NewTracker()
+	defer trk.Finish()
+	p := trk.GetPlane(icao)
+	for _, encoded := range frames {
+		frame, err := mode_s.DecodeBytes(encoded.Bytes(), time.Now())
+		if nil != err {
+			t.Fatal(err)
+		}
+		p.HandleModeSFrame(frame, nil, nil)
+	}
+
+	if "QFA123  " != p.FlightNumber() || 35000 != p.Altitude() {
+		t.Errorf("Incorrect flight %q or altitude %d", p.FlightNumber(), p.Altitude())
+	}
+	if !p.HasLocation() || math.Abs(-31.95-p.Lat()) > 0.0001 || math.Abs(115.94-p.Lon()) > 0.0001 {
+		t.Errorf("Incorrect location %0.5f,%0.5f", p.Lat(), p.Lon())
+	}
+	if 90 != p.Heading() || 400 != p.Velocity() {
+		t.Errorf("Incorrect heading %0.2f or velocity %0.2f", p.Heading(), p.Velocity())
+	}
+}
+
+func TestPlane_PositionWithoutAltitude(t *testing.T) {
+	trk := NewTracker()
+	defer trk.Finish()
+	p := trk.GetPlane(0x7C7DAA)
+	for _, msg := range []string{
+		"8D7C7DAA58B502B334349F0DEA9B", // even, 35000ft
+		"8D7C7DAA580002B334349F4BCC1D", // even, no altitude
+		"8D7C7DAAA0B5070E158FBB60B7DE", // odd, GNSS height
+	} {
+		frame, err := mode_s.DecodeString(msg, time.Now())
+		if nil != err {
+			t.Fatal(err)
+		}
+		p.HandleModeSFrame(frame, nil, nil)
+		if !p.HasAltitude() || 35000 != p.Altitude() {
+			t.Errorf("%s: expected altitude 35000, got %d", msg, p.Altitude())
+		}
+	}
+}
+
+func BenchmarkPlane_HandleModeSFrame(b *testing.B) {
+	trk := NewTracker()
+	defer trk.Finish()
+	var frames []*mode_s.Frame
+	for _, msg := range []string{
+		"8D40621D58C382D690C8AC2863A7",
+		"8D40621D58C386435CC412692AD6",
+		"8D7C4A0CF9105300004920A7CD97",
+		"28001808F0DEBF",
+	} {
+		frame, err := mode_s.DecodeString(msg, time.Now())
+		if nil != err {
+			b.Fatal(err)
+		}
+		frames = append(frames, frame)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		frame := frames[i%len(frames)]
+		trk.GetPlane(frame.Icao()).HandleModeSFrame(frame, nil, nil)
+	}
+}